@@ -38,8 +38,24 @@ type UIConfig struct {
 // SyntaxConfig holds syntax highlighting settings
 type SyntaxConfig struct {
 	DefaultTheme string          `json:"defaultTheme"`
+	LightTheme   string          `json:"lightTheme"` // Used when UIConfig.Theme is "light", or "auto" and the OS is light
+	DarkTheme    string          `json:"darkTheme"`  // Used when UIConfig.Theme is "dark", or "auto" and the OS is dark
 	Languages    map[string]bool `json:"languages"`
 	Enabled      bool            `json:"enabled"`
+
+	// Heuristics for languages embedded in others, such as SQL in Go
+	// strings. Code fences in Markdown and script and style elements in
+	// HTML are always highlighted in their language.
+	Embedded []EmbeddedLanguage `json:"embedded"`
+}
+
+// EmbeddedLanguage highlights the parts of documents in a host language
+// that match a pattern as another language
+type EmbeddedLanguage struct {
+	Host     string      `json:"host"`     // Language of the documents, such as "go"
+	Language string      `json:"language"` // Language of the embedded text, such as "sql"
+	Scope    SearchScope `json:"scope"`    // Where to match: "strings", "comments", or "" for anywhere
+	Pattern  string      `json:"pattern"`  // Regex whose group "code", or else first group, is the embedded text
 }
 
 // ConfigManager manages application configuration
@@ -85,6 +101,8 @@ func DefaultConfiguration() *Configuration {
 		},
 		Syntax: SyntaxConfig{
 			DefaultTheme: "github",
+			LightTheme:   "github",
+			DarkTheme:    "monokai",
 			Languages: map[string]bool{
 				"go":         true,
 				"javascript": true,
@@ -97,7 +115,8 @@ func DefaultConfiguration() *Configuration {
 				"xml":        true,
 				"yaml":       true,
 			},
-			Enabled: true,
+			Enabled:  true,
+			Embedded: DefaultEmbeddedLanguages(),
 		},
 	}
 }
@@ -146,6 +165,26 @@ func (cm *ConfigManager) Save() error {
 	return nil
 }
 
+// GetConfigDir returns the directory holding the configuration file
+func (cm *ConfigManager) GetConfigDir() string {
+	return filepath.Dir(cm.configPath)
+}
+
+// GetThemesDir returns the directory holding custom syntax themes
+func (cm *ConfigManager) GetThemesDir() string {
+	return filepath.Join(cm.GetConfigDir(), "themes")
+}
+
+// GetLanguagesDir returns the directory holding user language definitions
+func (cm *ConfigManager) GetLanguagesDir() string {
+	return filepath.Join(cm.GetConfigDir(), "languages")
+}
+
+// GetLexersDir returns the directory holding user lexer definitions
+func (cm *ConfigManager) GetLexersDir() string {
+	return filepath.Join(cm.GetConfigDir(), "lexers")
+}
+
 // GetConfig returns the current configuration
 func (cm *ConfigManager) GetConfig() *Configuration {
 	return cm.config
@@ -208,9 +247,31 @@ func (cm *ConfigManager) mergeWithDefaults(config *Configuration) {
 	if config.Syntax.DefaultTheme == "" {
 		config.Syntax.DefaultTheme = defaults.Syntax.DefaultTheme
 	}
+	if config.Syntax.LightTheme == "" {
+		config.Syntax.LightTheme = defaults.Syntax.LightTheme
+	}
+	if config.Syntax.DarkTheme == "" {
+		config.Syntax.DarkTheme = defaults.Syntax.DarkTheme
+	}
 	if config.Syntax.Languages == nil {
 		config.Syntax.Languages = defaults.Syntax.Languages
 	}
+	if config.Syntax.Embedded == nil {
+		config.Syntax.Embedded = defaults.Syntax.Embedded
+	}
+}
+
+// DefaultEmbeddedLanguages returns the built-in embedded language
+// heuristics: SQL statements in Go raw strings
+func DefaultEmbeddedLanguages() []EmbeddedLanguage {
+	return []EmbeddedLanguage{
+		{
+			Host:     "go",
+			Language: "sql",
+			Scope:    ScopeStrings,
+			Pattern:  `(?is)^(?P<code>\s*(?:select|insert|update|delete|with|create|alter|drop)\s.*)$`,
+		},
+	}
 }
 
 // getConfigDir returns the configuration directory path
@@ -37,6 +37,10 @@ func TestDefaultConfiguration(t *testing.T) {
 		t.Errorf("Expected DefaultTheme 'github', got '%s'", config.Syntax.DefaultTheme)
 	}
 	
+	if config.Syntax.LightTheme != "github" || config.Syntax.DarkTheme != "monokai" {
+		t.Errorf("Expected the github/monokai theme pair, got '%s'/'%s'", config.Syntax.LightTheme, config.Syntax.DarkTheme)
+	}
+	
 	if !config.Syntax.Enabled {
 		t.Error("Expected Syntax.Enabled to be true")
 	}
@@ -146,4 +150,8 @@ func TestConfigManagerMergeWithDefaults(t *testing.T) {
 	if cm.config.UI.WindowWidth != 800 {
 		t.Errorf("Expected WindowWidth 800 (default), got %d", cm.config.UI.WindowWidth)
 	}
+
+	if len(cm.config.Syntax.Embedded) != len(DefaultEmbeddedLanguages()) {
+		t.Errorf("Expected the default embedded languages, got %v", cm.config.Syntax.Embedded)
+	}
 }
\ No newline at end of file
@@ -1,6 +1,7 @@
 package backend
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -30,6 +31,13 @@ func (e *FileError) Error() string {
 	return fmt.Sprintf("文件%s失败 '%s': %v", e.Operation, e.Path, e.Err)
 }
 
+// FileWrite describes one file written by WriteFilesAtomic
+type FileWrite struct {
+	Path     string
+	Content  string
+	Expected string // Content the file must still have on disk before writing
+}
+
 // FileManager handles file operations
 type FileManager struct {
 	fileTypeManager *FileTypeManager
@@ -38,7 +46,7 @@ type FileManager struct {
 // NewFileManager creates a new file manager
 func NewFileManager() *FileManager {
 	return &FileManager{
-		fileTypeManager: NewFileTypeManager(),
+		fileTypeManager: GetLanguageRegistry(),
 	}
 }
 
@@ -104,6 +112,105 @@ func (fm *FileManager) SaveFileWithBackup(path, content string) error {
 	return nil
 }
 
+// WriteFilesAtomic writes a set of files so that either all of them are
+// updated or none are. Every file is first checked against its expected
+// content and written to a temporary file next to it; the temporary files
+// are then renamed over the originals. If a rename fails, the files already
+// replaced are restored.
+func (fm *FileManager) WriteFilesAtomic(writes []FileWrite) error {
+	// Verify that nothing changed on disk since the writes were planned,
+	// remembering the permissions to restore on rollback
+	modes := make([]os.FileMode, len(writes))
+	for i, w := range writes {
+		data, err := os.ReadFile(w.Path)
+		if err != nil {
+			return &FileError{Operation: "读取", Path: w.Path, Err: err}
+		}
+		if string(data) != w.Expected {
+			return &FileError{Operation: "保存", Path: w.Path, Err: fmt.Errorf("file was modified on disk")}
+		}
+		stat, err := os.Stat(w.Path)
+		if err != nil {
+			return &FileError{Operation: "读取", Path: w.Path, Err: err}
+		}
+		modes[i] = stat.Mode().Perm()
+	}
+
+	// Stage new contents in temporary files
+	temps := make([]string, 0, len(writes))
+	cleanup := func() {
+		for _, temp := range temps {
+			os.Remove(temp)
+		}
+	}
+	for _, w := range writes {
+		temp, err := fm.writeTempFile(w.Path, w.Content)
+		if err != nil {
+			cleanup()
+			return &FileError{Operation: "保存", Path: w.Path, Err: err}
+		}
+		temps = append(temps, temp)
+	}
+
+	// Swap the temporary files in, rolling back on failure
+	for i, w := range writes {
+		if err := renameFile(temps[i], w.Path); err != nil {
+			errs := []error{err}
+			for j := 0; j < i; j++ {
+				if err := restoreFile(writes[j].Path, writes[j].Expected, modes[j]); err != nil {
+					errs = append(errs, fmt.Errorf("failed to restore %s: %w", writes[j].Path, err))
+				}
+			}
+			cleanup()
+			return &FileError{Operation: "保存", Path: w.Path, Err: errors.Join(errs...)}
+		}
+	}
+
+	return nil
+}
+
+// renameFile moves the staged files of WriteFilesAtomic into place
+var renameFile = os.Rename
+
+// restoreFile writes back the original content and permissions of a file
+// replaced by WriteFilesAtomic
+func restoreFile(path, content string, mode os.FileMode) error {
+	if err := os.WriteFile(path, []byte(content), mode); err != nil {
+		return err
+	}
+	return os.Chmod(path, mode)
+}
+
+// writeTempFile writes content to a temporary file in the directory of path,
+// preserving the permissions of path
+func (fm *FileManager) writeTempFile(path, content string) (string, error) {
+	mode := os.FileMode(0644)
+	if stat, err := os.Stat(path); err == nil {
+		mode = stat.Mode().Perm()
+	}
+
+	file, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp*")
+	if err != nil {
+		return "", err
+	}
+	name := file.Name()
+
+	if _, err := file.WriteString(content); err != nil {
+		file.Close()
+		os.Remove(name)
+		return "", err
+	}
+	if err := file.Close(); err != nil {
+		os.Remove(name)
+		return "", err
+	}
+	if err := os.Chmod(name, mode); err != nil {
+		os.Remove(name)
+		return "", err
+	}
+	return name, nil
+}
+
 // GetFileInfo returns information about a file
 func (fm *FileManager) GetFileInfo(path string) (*FileInfo, error) {
 	stat, err := os.Stat(path)
@@ -164,6 +271,23 @@ func (fm *FileManager) GetFileType(path string) FileType {
 	return fm.fileTypeManager.DetectFileType(path)
 }
 
+// DetectFileType returns the file type of a document from its content and
+// path, see FileTypeManager.DetectFileTypeFromContent
+func (fm *FileManager) DetectFileType(path, content string) (FileType, bool) {
+	return fm.fileTypeManager.DetectFileTypeFromContent(path, content)
+}
+
+// GetFileTypeByName returns the file type with a name, lexer, extension
+// or interpreter
+func (fm *FileManager) GetFileTypeByName(name string) (FileType, bool) {
+	return fm.fileTypeManager.GetFileTypeByName(name)
+}
+
+// GetAllFileTypes returns all known file types
+func (fm *FileManager) GetAllFileTypes() []FileType {
+	return fm.fileTypeManager.GetAllFileTypes()
+}
+
 // GetLexerName returns the lexer name for syntax highlighting
 func (fm *FileManager) GetLexerName(path string) string {
 	return fm.fileTypeManager.GetLexerName(path)
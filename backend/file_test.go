@@ -0,0 +1,58 @@
+package backend
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestWriteFilesAtomic_Rollback(t *testing.T) {
+	dir := t.TempDir()
+	first, second := filepath.Join(dir, "first.sh"), filepath.Join(dir, "second.txt")
+	if err := os.WriteFile(first, []byte("old first"), 0755); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	if err := os.WriteFile(second, []byte("old second"), 0600); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	writes := []FileWrite{
+		{Path: first, Content: "new first", Expected: "old first"},
+		{Path: second, Content: "new second", Expected: "old second"},
+	}
+
+	// Fail the second rename so the first file is restored
+	failRename := func(restorable bool) {
+		renames := 0
+		renameFile = func(from, to string) error {
+			renames++
+			if renames == 1 {
+				return os.Rename(from, to)
+			}
+			if !restorable {
+				os.Remove(first)
+				os.Mkdir(first, 0755)
+			}
+			return errors.New("rename failed")
+		}
+	}
+	defer func() { renameFile = os.Rename }()
+
+	failRename(true)
+	err := NewFileManager().WriteFilesAtomic(writes)
+	if err == nil || !strings.Contains(err.Error(), "rename failed") {
+		t.Fatalf("Expected the rename error, got %v", err)
+	}
+	data, _ := os.ReadFile(first)
+	stat, _ := os.Stat(first)
+	if string(data) != "old first" || stat.Mode().Perm() != 0755 {
+		t.Errorf("Expected the first file to be restored with its mode, got %q with %v", data, stat.Mode().Perm())
+	}
+
+	failRename(false)
+	err = NewFileManager().WriteFilesAtomic(writes)
+	if err == nil || !strings.Contains(err.Error(), "failed to restore "+first) {
+		t.Errorf("Expected the failed rollback to be reported, got %v", err)
+	}
+}
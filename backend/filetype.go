@@ -1,162 +1,381 @@
 package backend
 
 import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
 	"path/filepath"
+	"sort"
 	"strings"
+	"sync"
 )
 
-// FileType represents a supported file type
+// FileType describes a language: how its files are recognized, the lexer
+// that highlights it, and how it is commented, bracketed and indented
 type FileType struct {
 	Name       string   `json:"name"`
 	Extensions []string `json:"extensions"`
 	LexerName  string   `json:"lexerName"`
 	MimeType   string   `json:"mimeType"`
+
+	// Exact file names, such as "Makefile", file name patterns, such as
+	// "Dockerfile.*", and interpreters named by shebang lines, such as
+	// "python3"
+	Filenames    []string `json:"filenames,omitempty"`
+	Globs        []string `json:"globs,omitempty"`
+	Interpreters []string `json:"interpreters,omitempty"`
+
+	// Other names the language goes by, as in Vim and Emacs modelines
+	Aliases []string `json:"aliases,omitempty"`
+
+	// Comment tokens, such as "//" and ["/*", "*/"]
+	LineComment  string   `json:"lineComment,omitempty"`
+	BlockComment []string `json:"blockComment,omitempty"`
+
+	// Bracket pairs, such as ["(", ")"]
+	Brackets [][2]string `json:"brackets,omitempty"`
+
+	// Indentation defaults: "tabs" or "spaces", and the tab size. Empty
+	// values leave the editor settings in effect.
+	Indentation string `json:"indentation,omitempty"`
+	TabSize     int    `json:"tabSize,omitempty"`
 }
 
-// FileTypeManager manages file type detection and handling
+// FileTypeManager is the language registry. It detects the file type of
+// documents and maps language names onto file types, for highlighting as
+// well as the status bar.
 type FileTypeManager struct {
-	fileTypes map[string]FileType
+	mu           sync.RWMutex        // Guards the fields, as highlighting runs in the background
+	types        []FileType          // In order of registration
+	fileTypes    map[string]FileType // By extension
+	filenames    map[string]FileType // By exact file name
+	interpreters map[string]FileType // By shebang interpreter
+}
+
+var (
+	languageRegistry     *FileTypeManager
+	languageRegistryOnce sync.Once
+)
+
+// GetLanguageRegistry returns the registry shared by file handling and
+// syntax highlighting
+func GetLanguageRegistry() *FileTypeManager {
+	languageRegistryOnce.Do(func() {
+		languageRegistry = NewFileTypeManager()
+	})
+	return languageRegistry
 }
 
 // NewFileTypeManager creates a new file type manager
 func NewFileTypeManager() *FileTypeManager {
-	manager := &FileTypeManager{
-		fileTypes: make(map[string]FileType),
-	}
-	
+	manager := &FileTypeManager{}
+
 	// Register default file types
 	manager.registerDefaultFileTypes()
-	
+
 	return manager
 }
 
-// registerDefaultFileTypes registers the default supported file types
-func (ftm *FileTypeManager) registerDefaultFileTypes() {
-	defaultTypes := []FileType{
+// Comment tokens and brackets shared by several languages
+var (
+	cBlockComment = []string{"/*", "*/"}
+	cBrackets     = [][2]string{{"(", ")"}, {"[", "]"}, {"{", "}"}}
+	tagBrackets   = [][2]string{{"<", ">"}}
+)
+
+// defaultFileTypes returns the built-in file types
+func defaultFileTypes() []FileType {
+	return []FileType{
+		{
+			Name:         "Go",
+			Extensions:   []string{".go"},
+			LexerName:    "go",
+			MimeType:     "text/x-go",
+			Aliases:      []string{"golang"},
+			LineComment:  "//",
+			BlockComment: cBlockComment,
+			Brackets:     cBrackets,
+			Indentation:  "tabs",
+		},
 		{
-			Name:       "Go",
-			Extensions: []string{".go"},
-			LexerName:  "go",
-			MimeType:   "text/x-go",
+			Name:         "JavaScript",
+			Extensions:   []string{".js", ".jsx", ".mjs", ".cjs"},
+			LexerName:    "javascript",
+			MimeType:     "application/javascript",
+			Interpreters: []string{"node", "nodejs"},
+			Aliases:      []string{"js2"},
+			LineComment:  "//",
+			BlockComment: cBlockComment,
+			Brackets:     cBrackets,
+			Indentation:  "spaces",
+			TabSize:      2,
 		},
 		{
-			Name:       "JavaScript",
-			Extensions: []string{".js", ".jsx", ".mjs"},
-			LexerName:  "javascript",
-			MimeType:   "application/javascript",
+			Name:         "TypeScript",
+			Extensions:   []string{".ts", ".tsx", ".mts", ".cts"},
+			LexerName:    "typescript",
+			MimeType:     "application/typescript",
+			Interpreters: []string{"deno", "ts-node"},
+			LineComment:  "//",
+			BlockComment: cBlockComment,
+			Brackets:     cBrackets,
+			Indentation:  "spaces",
+			TabSize:      2,
 		},
 		{
-			Name:       "TypeScript",
-			Extensions: []string{".ts", ".tsx"},
-			LexerName:  "typescript",
-			MimeType:   "application/typescript",
+			Name:         "Python",
+			Extensions:   []string{".py", ".pyw", ".pyi"},
+			LexerName:    "python",
+			MimeType:     "text/x-python",
+			Filenames:    []string{"SConstruct", "SConscript"},
+			Interpreters: []string{"python", "python2", "python3", "pypy", "pypy3"},
+			LineComment:  "#",
+			BlockComment: []string{`"""`, `"""`},
+			Brackets:     cBrackets,
+			Indentation:  "spaces",
+			TabSize:      4,
 		},
 		{
-			Name:       "Python",
-			Extensions: []string{".py", ".pyw", ".pyi"},
-			LexerName:  "python",
-			MimeType:   "text/x-python",
+			Name:         "Java",
+			Extensions:   []string{".java"},
+			LexerName:    "java",
+			MimeType:     "text/x-java-source",
+			LineComment:  "//",
+			BlockComment: cBlockComment,
+			Brackets:     cBrackets,
 		},
 		{
-			Name:       "Java",
-			Extensions: []string{".java"},
-			LexerName:  "java",
-			MimeType:   "text/x-java-source",
+			Name:         "C",
+			Extensions:   []string{".c", ".h"},
+			LexerName:    "c",
+			MimeType:     "text/x-c",
+			LineComment:  "//",
+			BlockComment: cBlockComment,
+			Brackets:     cBrackets,
 		},
 		{
-			Name:       "C",
-			Extensions: []string{".c", ".h"},
-			LexerName:  "c",
-			MimeType:   "text/x-c",
+			Name:         "C++",
+			Extensions:   []string{".cpp", ".cxx", ".cc", ".hpp", ".hxx", ".hh"},
+			LexerName:    "cpp",
+			MimeType:     "text/x-c++",
+			Aliases:      []string{"c++"},
+			LineComment:  "//",
+			BlockComment: cBlockComment,
+			Brackets:     cBrackets,
 		},
 		{
-			Name:       "C++",
-			Extensions: []string{".cpp", ".cxx", ".cc", ".hpp", ".hxx"},
-			LexerName:  "cpp",
-			MimeType:   "text/x-c++",
+			Name:         "HTML",
+			Extensions:   []string{".html", ".htm", ".xhtml"},
+			LexerName:    "html",
+			MimeType:     "text/html",
+			BlockComment: []string{"<!--", "-->"},
+			Brackets:     tagBrackets,
+			Indentation:  "spaces",
+			TabSize:      2,
 		},
 		{
-			Name:       "HTML",
-			Extensions: []string{".html", ".htm"},
-			LexerName:  "html",
-			MimeType:   "text/html",
+			Name:         "Go HTML Template",
+			Extensions:   []string{".gohtml"},
+			LexerName:    "go-html-template",
+			MimeType:     "text/html",
+			Globs:        []string{"*.html.tmpl", "*.tmpl.html"},
+			BlockComment: []string{"{{/*", "*/}}"},
+			Brackets:     append([][2]string{{"{{", "}}"}}, tagBrackets...),
+			Indentation:  "spaces",
+			TabSize:      2,
 		},
 		{
-			Name:       "CSS",
-			Extensions: []string{".css"},
-			LexerName:  "css",
-			MimeType:   "text/css",
+			Name:         "Go Text Template",
+			Extensions:   []string{".tmpl", ".gotmpl"},
+			LexerName:    "go-text-template",
+			MimeType:     "text/plain",
+			BlockComment: []string{"{{/*", "*/}}"},
+			Brackets:     [][2]string{{"{{", "}}"}},
 		},
 		{
-			Name:       "JSON",
-			Extensions: []string{".json"},
-			LexerName:  "json",
-			MimeType:   "application/json",
+			Name:         "CSS",
+			Extensions:   []string{".css"},
+			LexerName:    "css",
+			MimeType:     "text/css",
+			BlockComment: cBlockComment,
+			Brackets:     cBrackets,
+			Indentation:  "spaces",
+			TabSize:      2,
 		},
 		{
-			Name:       "XML",
-			Extensions: []string{".xml", ".xsd", ".xsl"},
-			LexerName:  "xml",
-			MimeType:   "application/xml",
+			Name:        "JSON",
+			Extensions:  []string{".json"},
+			LexerName:   "json",
+			MimeType:    "application/json",
+			Filenames:   []string{".babelrc", ".eslintrc"},
+			Brackets:    [][2]string{{"[", "]"}, {"{", "}"}},
+			Indentation: "spaces",
+			TabSize:     2,
 		},
 		{
-			Name:       "YAML",
-			Extensions: []string{".yaml", ".yml"},
-			LexerName:  "yaml",
-			MimeType:   "application/x-yaml",
+			Name:         "XML",
+			Extensions:   []string{".xml", ".xsd", ".xsl", ".svg", ".plist"},
+			LexerName:    "xml",
+			MimeType:     "application/xml",
+			BlockComment: []string{"<!--", "-->"},
+			Brackets:     tagBrackets,
 		},
 		{
-			Name:       "Markdown",
-			Extensions: []string{".md", ".markdown"},
-			LexerName:  "markdown",
-			MimeType:   "text/markdown",
+			Name:        "YAML",
+			Extensions:  []string{".yaml", ".yml"},
+			LexerName:   "yaml",
+			MimeType:    "application/x-yaml",
+			LineComment: "#",
+			Brackets:    [][2]string{{"[", "]"}, {"{", "}"}},
+			Indentation: "spaces",
+			TabSize:     2,
+		},
+		{
+			Name:         "Markdown",
+			Extensions:   []string{".md", ".markdown"},
+			LexerName:    "markdown",
+			MimeType:     "text/markdown",
+			Aliases:      []string{"gfm"},
+			BlockComment: []string{"<!--", "-->"},
+			Brackets:     [][2]string{{"[", "]"}, {"(", ")"}},
 		},
 		{
 			Name:       "Shell",
-			Extensions: []string{".sh", ".bash", ".zsh"},
+			Extensions: []string{".sh", ".bash", ".zsh", ".ksh"},
 			LexerName:  "bash",
 			MimeType:   "application/x-sh",
+			Filenames: []string{
+				".bashrc", ".bash_profile", ".bash_login", ".bash_logout", ".bash_aliases",
+				".profile", ".zshrc", ".zprofile", ".zshenv", ".zlogin", "PKGBUILD",
+			},
+			Interpreters: []string{"sh", "bash", "zsh", "dash", "ksh", "ash"},
+			Aliases:      []string{"shell-script", "shell"},
+			LineComment:  "#",
+			Brackets:     cBrackets,
+		},
+		{
+			Name:         "SQL",
+			Extensions:   []string{".sql"},
+			LexerName:    "sql",
+			MimeType:     "application/sql",
+			LineComment:  "--",
+			BlockComment: cBlockComment,
+			Brackets:     [][2]string{{"(", ")"}},
+		},
+		{
+			Name:        "Dockerfile",
+			Extensions:  []string{".dockerfile"},
+			LexerName:   "docker",
+			MimeType:    "text/x-dockerfile",
+			Filenames:   []string{"Dockerfile", "Containerfile"},
+			Globs:       []string{"Dockerfile.*", "*.Dockerfile"},
+			Aliases:     []string{"dockerfile"},
+			LineComment: "#",
+			Brackets:    [][2]string{{"[", "]"}},
 		},
 		{
-			Name:       "SQL",
-			Extensions: []string{".sql"},
-			LexerName:  "sql",
-			MimeType:   "application/sql",
+			Name:         "Makefile",
+			Extensions:   []string{".mk", ".mak"},
+			LexerName:    "makefile",
+			MimeType:     "text/x-makefile",
+			Filenames:    []string{"Makefile", "makefile", "GNUmakefile"},
+			Interpreters: []string{"make"},
+			Aliases:      []string{"make"},
+			LineComment:  "#",
+			Brackets:     [][2]string{{"(", ")"}, {"{", "}"}},
+			Indentation:  "tabs",
 		},
 		{
-			Name:       "Dockerfile",
-			Extensions: []string{".dockerfile"},
-			LexerName:  "dockerfile",
-			MimeType:   "text/x-dockerfile",
+			Name:         "Ruby",
+			Extensions:   []string{".rb", ".rake", ".gemspec"},
+			LexerName:    "ruby",
+			MimeType:     "text/x-ruby",
+			Filenames:    []string{"Rakefile", "Gemfile"},
+			Interpreters: []string{"ruby"},
+			LineComment:  "#",
+			Brackets:     cBrackets,
+			Indentation:  "spaces",
+			TabSize:      2,
+		},
+		{
+			Name:         "Perl",
+			Extensions:   []string{".pl", ".pm"},
+			LexerName:    "perl",
+			MimeType:     "text/x-perl",
+			Interpreters: []string{"perl"},
+			LineComment:  "#",
+			Brackets:     cBrackets,
 		},
 		{
 			Name:       "Plain Text",
 			Extensions: []string{".txt", ".text"},
 			LexerName:  "text",
 			MimeType:   "text/plain",
+			Aliases:    []string{"plain", "txt"},
 		},
 	}
+}
 
-	for _, fileType := range defaultTypes {
+// registerDefaultFileTypes registers the default supported file types
+func (ftm *FileTypeManager) registerDefaultFileTypes() {
+	for _, fileType := range defaultFileTypes() {
 		ftm.RegisterFileType(fileType)
 	}
 }
 
-// RegisterFileType registers a new file type
+// RegisterFileType registers a new file type, replacing a registered type
+// of the same name
 func (ftm *FileTypeManager) RegisterFileType(fileType FileType) {
-	for _, ext := range fileType.Extensions {
-		ftm.fileTypes[strings.ToLower(ext)] = fileType
+	ftm.mu.Lock()
+	defer ftm.mu.Unlock()
+
+	if i := ftm.indexOf(fileType.Name); i >= 0 {
+		ftm.types[i] = fileType
+	} else {
+		ftm.types = append(ftm.types, fileType)
+	}
+	ftm.reindex()
+}
+
+// indexOf returns the index of the type with a name, or -1. The caller
+// holds the lock.
+func (ftm *FileTypeManager) indexOf(name string) int {
+	for i, fileType := range ftm.types {
+		if strings.EqualFold(fileType.Name, name) {
+			return i
+		}
+	}
+	return -1
+}
+
+// reindex rebuilds the lookup maps. Types registered later win conflicts.
+// The caller holds the lock.
+func (ftm *FileTypeManager) reindex() {
+	ftm.fileTypes = make(map[string]FileType)
+	ftm.filenames = make(map[string]FileType)
+	ftm.interpreters = make(map[string]FileType)
+	for _, fileType := range ftm.types {
+		for _, ext := range fileType.Extensions {
+			ftm.fileTypes[strings.ToLower(ext)] = fileType
+		}
+		for _, name := range fileType.Filenames {
+			ftm.filenames[name] = fileType
+		}
+		for _, interpreter := range fileType.Interpreters {
+			ftm.interpreters[interpreter] = fileType
+		}
 	}
 }
 
-// DetectFileType detects the file type based on file extension
+// DetectFileType detects the file type based on the exact file name, a
+// file name pattern or the file extension
 func (ftm *FileTypeManager) DetectFileType(filename string) FileType {
-	ext := strings.ToLower(filepath.Ext(filename))
-	
-	if fileType, exists := ftm.fileTypes[ext]; exists {
+	if fileType, exists := ftm.DetectFileTypeByFilename(filename); exists {
 		return fileType
 	}
-	
+	ext := strings.ToLower(filepath.Ext(filename))
+
 	// Return default plain text type if not found
 	return FileType{
 		Name:      "Plain Text",
@@ -166,19 +385,104 @@ func (ftm *FileTypeManager) DetectFileType(filename string) FileType {
 	}
 }
 
+// DetectFileTypeByFilename looks a file up by its exact name, then by the
+// file name patterns, then by extension
+func (ftm *FileTypeManager) DetectFileTypeByFilename(filename string) (FileType, bool) {
+	ftm.mu.RLock()
+	defer ftm.mu.RUnlock()
+
+	base := filepath.Base(filename)
+	if fileType, exists := ftm.filenames[base]; exists {
+		return fileType, true
+	}
+	for i := len(ftm.types) - 1; i >= 0; i-- {
+		for _, glob := range ftm.types[i].Globs {
+			if matched, _ := filepath.Match(glob, base); matched {
+				return ftm.types[i], true
+			}
+		}
+	}
+	fileType, exists := ftm.fileTypes[strings.ToLower(filepath.Ext(filename))]
+	return fileType, exists
+}
+
+// DetectFileTypeFromContent detects the file type of a document from a Vim
+// or Emacs modeline, its file name, or the interpreter of its shebang
+// line, in that order. ok is false when none of them is known.
+func (ftm *FileTypeManager) DetectFileTypeFromContent(filename, content string) (fileType FileType, ok bool) {
+	if name := ModelineLanguage(content); name != "" {
+		if fileType, exists := ftm.GetFileTypeByName(name); exists {
+			return fileType, true
+		}
+	}
+	if filename != "" {
+		if fileType, exists := ftm.DetectFileTypeByFilename(filename); exists {
+			return fileType, true
+		}
+	}
+	if interpreter := ShebangInterpreter(content); interpreter != "" {
+		ftm.mu.RLock()
+		defer ftm.mu.RUnlock()
+		if fileType, exists := ftm.interpreters[interpreter]; exists {
+			return fileType, true
+		}
+		if fileType, exists := ftm.interpreters[trimVersion(interpreter)]; exists {
+			return fileType, true
+		}
+	}
+	return FileType{}, false
+}
+
+// GetFileTypeByName looks a file type up by its name, lexer, alias,
+// extension or interpreter, case-insensitively, as languages are named in
+// modelines and settings
+func (ftm *FileTypeManager) GetFileTypeByName(name string) (FileType, bool) {
+	ftm.mu.RLock()
+	defer ftm.mu.RUnlock()
+
+	name = strings.ToLower(strings.TrimSpace(name))
+	if name == "" {
+		return FileType{}, false
+	}
+
+	// Types registered later replace earlier ones
+	for i := len(ftm.types) - 1; i >= 0; i-- {
+		fileType := ftm.types[i]
+		if strings.ToLower(fileType.Name) == name || strings.ToLower(fileType.LexerName) == name {
+			return fileType, true
+		}
+		for _, alias := range fileType.Aliases {
+			if strings.ToLower(alias) == name {
+				return fileType, true
+			}
+		}
+	}
+	if fileType, exists := ftm.fileTypes["."+name]; exists {
+		return fileType, true
+	}
+	fileType, exists := ftm.interpreters[name]
+	return fileType, exists
+}
+
 // GetFileTypeByExtension returns the file type for a given extension
 func (ftm *FileTypeManager) GetFileTypeByExtension(extension string) (FileType, bool) {
+	ftm.mu.RLock()
+	defer ftm.mu.RUnlock()
+
 	ext := strings.ToLower(extension)
 	if !strings.HasPrefix(ext, ".") {
 		ext = "." + ext
 	}
-	
+
 	fileType, exists := ftm.fileTypes[ext]
 	return fileType, exists
 }
 
 // GetSupportedExtensions returns all supported file extensions
 func (ftm *FileTypeManager) GetSupportedExtensions() []string {
+	ftm.mu.RLock()
+	defer ftm.mu.RUnlock()
+
 	extensions := make([]string, 0, len(ftm.fileTypes))
 	for ext := range ftm.fileTypes {
 		extensions = append(extensions, ext)
@@ -188,25 +492,17 @@ func (ftm *FileTypeManager) GetSupportedExtensions() []string {
 
 // GetAllFileTypes returns all registered file types
 func (ftm *FileTypeManager) GetAllFileTypes() []FileType {
-	typeMap := make(map[string]FileType)
-	
-	// Deduplicate by name
-	for _, fileType := range ftm.fileTypes {
-		typeMap[fileType.Name] = fileType
-	}
-	
-	types := make([]FileType, 0, len(typeMap))
-	for _, fileType := range typeMap {
-		types = append(types, fileType)
-	}
-	
+	ftm.mu.RLock()
+	defer ftm.mu.RUnlock()
+
+	types := make([]FileType, len(ftm.types))
+	copy(types, ftm.types)
 	return types
 }
 
 // IsSupported checks if a file extension is supported
 func (ftm *FileTypeManager) IsSupported(filename string) bool {
-	ext := strings.ToLower(filepath.Ext(filename))
-	_, exists := ftm.fileTypes[ext]
+	_, exists := ftm.DetectFileTypeByFilename(filename)
 	return exists
 }
 
@@ -220,4 +516,153 @@ func (ftm *FileTypeManager) GetLexerName(filename string) string {
 func (ftm *FileTypeManager) GetMimeType(filename string) string {
 	fileType := ftm.DetectFileType(filename)
 	return fileType.MimeType
-}
\ No newline at end of file
+}
+
+// LoadLanguageDefinitions resets the registry to the built-in file types
+// and applies the definitions in the *.json files of dir. A file holds one
+// definition or an array of them. A definition named after a registered
+// type changes the fields it sets; others are added as new types. Invalid
+// files are reported together in the error, one per file, and skipped.
+func (ftm *FileTypeManager) LoadLanguageDefinitions(dir string) error {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return fmt.Errorf("failed to list language definitions: %w", err)
+	}
+	sort.Strings(paths)
+
+	types := defaultFileTypes()
+	var errs []error
+	for _, path := range paths {
+		definitions, err := readLanguageDefinitions(path)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", filepath.Base(path), err))
+			continue
+		}
+		for _, definition := range definitions {
+			types = applyLanguageDefinition(types, definition)
+		}
+	}
+
+	ftm.mu.Lock()
+	ftm.types = types
+	ftm.reindex()
+	ftm.mu.Unlock()
+
+	return errors.Join(errs...)
+}
+
+// readLanguageDefinitions reads and validates the definitions in a file
+func readLanguageDefinitions(path string) ([]FileType, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read language definition: %w", err)
+	}
+
+	var definitions []FileType
+	if trimmed := strings.TrimSpace(string(data)); strings.HasPrefix(trimmed, "[") {
+		err = json.Unmarshal(data, &definitions)
+	} else {
+		var definition FileType
+		err = json.Unmarshal(data, &definition)
+		definitions = []FileType{definition}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse language definition: %w", err)
+	}
+
+	for i := range definitions {
+		if err := validateLanguageDefinition(&definitions[i]); err != nil {
+			return nil, err
+		}
+	}
+	return definitions, nil
+}
+
+// validateLanguageDefinition checks a definition and normalizes its
+// extensions to start with a dot
+func validateLanguageDefinition(definition *FileType) error {
+	if strings.TrimSpace(definition.Name) == "" {
+		return fmt.Errorf("language definition needs a name")
+	}
+	for i, ext := range definition.Extensions {
+		if ext == "" || strings.ContainsAny(ext, `/\`) {
+			return fmt.Errorf("language %q: invalid extension %q", definition.Name, ext)
+		}
+		if !strings.HasPrefix(ext, ".") {
+			definition.Extensions[i] = "." + ext
+		}
+	}
+	for _, glob := range definition.Globs {
+		if _, err := filepath.Match(glob, ""); err != nil {
+			return fmt.Errorf("language %q: invalid glob %q: %w", definition.Name, glob, err)
+		}
+	}
+	if len(definition.BlockComment) != 0 && len(definition.BlockComment) != 2 {
+		return fmt.Errorf("language %q: block comment needs a start and an end token", definition.Name)
+	}
+	for _, pair := range definition.Brackets {
+		if pair[0] == "" || pair[1] == "" {
+			return fmt.Errorf("language %q: bracket pairs need an opening and a closing bracket", definition.Name)
+		}
+	}
+	if definition.Indentation != "" && definition.Indentation != "tabs" && definition.Indentation != "spaces" {
+		return fmt.Errorf("language %q: indentation must be \"tabs\" or \"spaces\", got %q", definition.Name, definition.Indentation)
+	}
+	if definition.TabSize < 0 {
+		return fmt.Errorf("language %q: tab size cannot be negative", definition.Name)
+	}
+	return nil
+}
+
+// applyLanguageDefinition adds a definition to types, or changes the fields
+// it sets of the type with its name
+func applyLanguageDefinition(types []FileType, definition FileType) []FileType {
+	for i := range types {
+		if !strings.EqualFold(types[i].Name, definition.Name) {
+			continue
+		}
+		merged := &types[i]
+		if definition.Extensions != nil {
+			merged.Extensions = definition.Extensions
+		}
+		if definition.LexerName != "" {
+			merged.LexerName = definition.LexerName
+		}
+		if definition.MimeType != "" {
+			merged.MimeType = definition.MimeType
+		}
+		if definition.Filenames != nil {
+			merged.Filenames = definition.Filenames
+		}
+		if definition.Globs != nil {
+			merged.Globs = definition.Globs
+		}
+		if definition.Interpreters != nil {
+			merged.Interpreters = definition.Interpreters
+		}
+		if definition.Aliases != nil {
+			merged.Aliases = definition.Aliases
+		}
+		if definition.LineComment != "" {
+			merged.LineComment = definition.LineComment
+		}
+		if definition.BlockComment != nil {
+			merged.BlockComment = definition.BlockComment
+		}
+		if definition.Brackets != nil {
+			merged.Brackets = definition.Brackets
+		}
+		if definition.Indentation != "" {
+			merged.Indentation = definition.Indentation
+		}
+		if definition.TabSize != 0 {
+			merged.TabSize = definition.TabSize
+		}
+		return types
+	}
+
+	if definition.LexerName == "" {
+		definition.LexerName = strings.ToLower(definition.Name)
+	}
+	return append(types, definition)
+}
@@ -1,6 +1,9 @@
 package backend
 
 import (
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 )
 
@@ -24,6 +27,9 @@ func TestFileTypeDetection(t *testing.T) {
 		{"docker-compose.yml", "YAML", "yaml"},
 		{"README.md", "Markdown", "markdown"},
 		{"script.sh", "Shell", "bash"},
+		{"page.gohtml", "Go HTML Template", "go-html-template"},
+		{"index.html.tmpl", "Go HTML Template", "go-html-template"},
+		{"mail.tmpl", "Go Text Template", "go-text-template"},
 		{"unknown.xyz", "Plain Text", "text"},
 	}
 	
@@ -144,4 +150,116 @@ func TestGetAllFileTypes(t *testing.T) {
 			t.Errorf("Expected file type '%s' to be present", expected)
 		}
 	}
-}
\ No newline at end of file
+}
+
+func TestDetectFileTypeFromContent(t *testing.T) {
+	ftm := NewFileTypeManager()
+
+	testCases := []struct {
+		filename      string
+		content       string
+		expectedLexer string
+	}{
+		{"/project/Dockerfile", "FROM golang\n", "docker"},
+		{"/project/Makefile", "all:\n", "makefile"},
+		{"/home/user/.bashrc", "alias ll='ls -l'\n", "bash"},
+		{"/usr/local/bin/deploy", "#!/usr/bin/env python3.12\nprint(1)\n", "python"},
+		{"/usr/local/bin/serve", "#!/usr/bin/env node\n", "javascript"},
+		{"notes.txt", "# vim: set ft=markdown :\n", "markdown"},
+		{"script", "#!/bin/sh\n# -*- mode: python -*-\n", "python"},
+		{"build", "# -*- mode: make -*-\n", "makefile"},
+		{"main.go", "#!/bin/bash\n", "go"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.filename, func(t *testing.T) {
+			fileType, ok := ftm.DetectFileTypeFromContent(tc.filename, tc.content)
+			if !ok || fileType.LexerName != tc.expectedLexer {
+				t.Errorf("Expected lexer '%s', got '%s' (%v)", tc.expectedLexer, fileType.LexerName, ok)
+			}
+		})
+	}
+
+	if _, ok := ftm.DetectFileTypeFromContent("README", "Nothing to see here\n"); ok {
+		t.Error("Expected no file type for an unknown extensionless file")
+	}
+	if _, ok := ftm.DetectFileTypeFromContent("notes", "# vim: set ft=klingon :\n"); ok {
+		t.Error("Expected no file type for an unknown modeline language")
+	}
+	if fileType := ftm.DetectFileType("/src/Dockerfile"); fileType.Name != "Dockerfile" {
+		t.Errorf("Expected exact file names to be detected, got '%s'", fileType.Name)
+	}
+}
+
+func TestGetFileTypeByName(t *testing.T) {
+	ftm := NewFileTypeManager()
+
+	testCases := map[string]string{
+		"Go":           "go",
+		"dockerfile":   "docker",
+		"docker":       "docker",
+		"shell-script": "bash",
+		"sh":           "bash",
+		"python3":      "python",
+		"C++":          "cpp",
+		"yml":          "yaml",
+	}
+	for name, expected := range testCases {
+		fileType, ok := ftm.GetFileTypeByName(name)
+		if !ok || fileType.LexerName != expected {
+			t.Errorf("Expected lexer '%s' for '%s', got '%s'", expected, name, fileType.LexerName)
+		}
+	}
+	if _, ok := ftm.GetFileTypeByName("klingon"); ok {
+		t.Error("Expected no file type for an unknown name")
+	}
+	if fileType := ftm.DetectFileType("Dockerfile.dev"); fileType.Name != "Dockerfile" {
+		t.Errorf("Expected file name patterns to be matched, got '%s'", fileType.Name)
+	}
+}
+
+func TestLoadLanguageDefinitions(t *testing.T) {
+	dir := t.TempDir()
+	definitions := map[string]string{
+		"go.json": `{"name": "go", "tabSize": 8, "indentation": "spaces"}`,
+		"extra.json": `[
+			{"name": "Terraform", "extensions": ["tf", ".tfvars"], "lexerName": "terraform", "lineComment": "#",
+			 "brackets": [["{", "}"]]},
+			{"name": "Jenkinsfile", "filenames": ["Jenkinsfile"], "globs": ["*.jenkinsfile"], "lexerName": "groovy"}
+		]`,
+		"broken.json": `{"name": "Broken", "blockComment": ["/*"]}`,
+		"invalid.json": `{"name": `,
+	}
+	for name, content := range definitions {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	ftm := NewFileTypeManager()
+	ftm.RegisterFileType(FileType{Name: "Leftover", Extensions: []string{".left"}})
+	err := ftm.LoadLanguageDefinitions(dir)
+	if err == nil || !strings.Contains(err.Error(), "broken.json") || !strings.Contains(err.Error(), "invalid.json") {
+		t.Errorf("Expected both invalid files to be reported, got %v", err)
+	}
+
+	goType := ftm.DetectFileType("main.go")
+	if goType.TabSize != 8 || goType.Indentation != "spaces" || goType.LexerName != "go" || goType.LineComment != "//" {
+		t.Errorf("Expected the definition to change only the fields it sets, got %+v", goType)
+	}
+	if fileType := ftm.DetectFileType("vars.tfvars"); fileType.Name != "Terraform" || fileType.Brackets[0] != [2]string{"{", "}"} {
+		t.Errorf("Expected the new language to be detected, got %+v", fileType)
+	}
+	if fileType := ftm.DetectFileType("main.tf"); fileType.Name != "Terraform" {
+		t.Errorf("Expected extensions without a dot to be accepted, got '%s'", fileType.Name)
+	}
+	if fileType := ftm.DetectFileType("/ci/Jenkinsfile"); fileType.LexerName != "groovy" {
+		t.Errorf("Expected the exact file name to be detected, got '%s'", fileType.LexerName)
+	}
+	if fileType := ftm.DetectFileType("deploy.jenkinsfile"); fileType.LexerName != "groovy" {
+		t.Errorf("Expected the glob to be detected, got '%s'", fileType.LexerName)
+	}
+	if ftm.IsSupported("x.left") {
+		t.Error("Expected loading to start over from the built-in types")
+	}
+}
@@ -0,0 +1,101 @@
+package backend
+
+import (
+	"context"
+	"strings"
+)
+
+// FilterMode selects which lines a line filter keeps
+type FilterMode int
+
+const (
+	FilterKeep FilterMode = iota // Keep the lines that match, like grep
+	FilterHide                   // Hide the lines that match, like grep -v
+)
+
+// FilteredText is the result of filtering the lines of a text
+type FilteredText struct {
+	Text        string `json:"text"`        // The kept lines joined by line breaks
+	LineNumbers []int  `json:"lineNumbers"` // Original 1-based line number of each kept line
+	TotalLines  int    `json:"totalLines"`  // Number of lines in the original text
+}
+
+// Lines returns the number of lines kept
+func (f *FilteredText) Lines() int {
+	return len(f.LineNumbers)
+}
+
+// FilterLines keeps or hides the lines of text on which pattern matches,
+// searching with the current options and scope. A line matches if any match
+// touches it, so a multi-line match selects every line it spans. The search
+// is not limited by the match limit and does not change the current search.
+// An empty pattern matches no line. Line breaks, including \r\n, are kept;
+// the text ends with a line break if the original did.
+func (sm *SearchManager) FilterLines(text, pattern string, mode FilterMode) (*FilteredText, error) {
+	return filterLines(sm.newRun(context.Background(), text, pattern, 0), text, pattern, mode)
+}
+
+// FilterLinesAsync filters text like FilterLines on a background goroutine
+// and passes the result to onDone there. The options and scope are captured
+// as for FindAsync. Cancelling ctx stops the filter without calling onDone.
+func (sm *SearchManager) FilterLinesAsync(ctx context.Context, text, pattern string, mode FilterMode, onDone func(*FilteredText, error)) {
+	r, prepare := sm.newAsyncRun(ctx, text, pattern, 0)
+	go func() {
+		prepare()
+		filtered, err := filterLines(r, text, pattern, mode)
+		if ctx.Err() != nil {
+			return
+		}
+		onDone(filtered, err)
+	}()
+}
+
+// filterLines filters the lines of text by the matches of run r
+func filterLines(r *searchRun, text, pattern string, mode FilterMode) (*FilteredText, error) {
+	lines := strings.SplitAfter(text, "\n")
+	if lines[len(lines)-1] == "" && len(lines) > 1 {
+		// A trailing line break does not start another line
+		lines = lines[:len(lines)-1]
+	}
+
+	matched := make([]bool, len(lines)+1)
+	if pattern != "" {
+		result := r.run(text, pattern)
+		if result == nil {
+			return nil, r.ctx.Err()
+		}
+		if result.Err != nil {
+			return nil, result.Err
+		}
+		for _, match := range result.Matches {
+			last := match.End.Line
+			if last > match.Start.Line && match.End.Column == 1 {
+				// The match ends with a line break, not on the next line
+				last--
+			}
+			for line := match.Start.Line; line <= last && line <= len(lines); line++ {
+				matched[line] = true
+			}
+		}
+	}
+
+	filtered := &FilteredText{
+		LineNumbers: make([]int, 0),
+		TotalLines:  len(lines),
+	}
+	var builder strings.Builder
+	for i, line := range lines {
+		if matched[i+1] != (mode == FilterKeep) {
+			continue
+		}
+		builder.WriteString(line)
+		filtered.LineNumbers = append(filtered.LineNumbers, i+1)
+	}
+
+	filtered.Text = builder.String()
+	if kept := len(filtered.LineNumbers); kept > 0 && !strings.HasSuffix(text, "\n") && filtered.LineNumbers[kept-1] != len(lines) {
+		// The last kept line has a break only because it was not last
+		filtered.Text = strings.TrimSuffix(strings.TrimSuffix(filtered.Text, "\n"), "\r")
+	}
+	return filtered, nil
+}
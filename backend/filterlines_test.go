@@ -0,0 +1,116 @@
+package backend
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFilterLines(t *testing.T) {
+	text := "INFO start\nERROR disk full\nINFO retry\r\nerror again\nDEBUG done\n"
+	sm := NewSearchManager()
+
+	filtered, err := sm.FilterLines(text, "error", FilterKeep)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if filtered.Text != "ERROR disk full\nerror again\n" {
+		t.Errorf("Unexpected kept text %q", filtered.Text)
+	}
+	if !reflect.DeepEqual(filtered.LineNumbers, []int{2, 4}) || filtered.TotalLines != 5 {
+		t.Errorf("Unexpected line numbers %v of %d", filtered.LineNumbers, filtered.TotalLines)
+	}
+
+	filtered, err = sm.FilterLines(text, "error", FilterHide)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if filtered.Text != "INFO start\nINFO retry\r\nDEBUG done\n" {
+		t.Errorf("Unexpected remaining text %q", filtered.Text)
+	}
+	if !reflect.DeepEqual(filtered.LineNumbers, []int{1, 3, 5}) {
+		t.Errorf("Unexpected line numbers %v", filtered.LineNumbers)
+	}
+
+	// The current search is left alone
+	if sm.GetMatchCount() != 0 {
+		t.Errorf("Expected filtering not to change the current search, got %d matches", sm.GetMatchCount())
+	}
+}
+
+func TestFilterLines_Options(t *testing.T) {
+	text := "a1\nb22\nc\nd333"
+	sm := NewSearchManager()
+	sm.SetOptions(SearchOptions{CaseSensitive: true, RegularExpression: true, MultiLine: true})
+
+	filtered, err := sm.FilterLines(text, `\d{2,}`, FilterKeep)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if filtered.Text != "b22\nd333" || !reflect.DeepEqual(filtered.LineNumbers, []int{2, 4}) {
+		t.Errorf("Unexpected result %q %v", filtered.Text, filtered.LineNumbers)
+	}
+
+	// The last line has no break, so none is added to the lines before it
+	filtered, _ = sm.FilterLines(text, `\d`, FilterHide)
+	if filtered.Text != "c" || filtered.Lines() != 1 {
+		t.Errorf("Expected only line 3 without a line break, got %q", filtered.Text)
+	}
+
+	// A match across lines selects every line it spans
+	filtered, _ = sm.FilterLines(text, `1\nb`, FilterKeep)
+	if !reflect.DeepEqual(filtered.LineNumbers, []int{1, 2}) {
+		t.Errorf("Expected lines 1 and 2, got %v", filtered.LineNumbers)
+	}
+
+	// A match ending with a line break stays on its line
+	filtered, _ = sm.FilterLines(text, `c\n`, FilterKeep)
+	if !reflect.DeepEqual(filtered.LineNumbers, []int{3}) {
+		t.Errorf("Expected line 3, got %v", filtered.LineNumbers)
+	}
+
+	if _, err := sm.FilterLines(text, `(`, FilterKeep); err == nil {
+		t.Error("Expected an error for an invalid pattern")
+	}
+
+	// An empty pattern matches nothing
+	filtered, _ = sm.FilterLines(text, "", FilterHide)
+	if filtered.Text != text || filtered.Lines() != 4 {
+		t.Errorf("Expected every line for an empty pattern, got %q", filtered.Text)
+	}
+}
+
+func TestFilterLinesAsync(t *testing.T) {
+	sm := NewSearchManager()
+	text := strings.Repeat("keep\ndrop\n", 1000)
+
+	done := make(chan *FilteredText, 1)
+	sm.FilterLinesAsync(context.Background(), text, "keep", FilterKeep, func(filtered *FilteredText, err error) {
+		if err != nil {
+			t.Errorf("Unexpected error: %v", err)
+		}
+		done <- filtered
+	})
+	select {
+	case filtered := <-done:
+		if filtered == nil || filtered.Lines() != 1000 || filtered.LineNumbers[1] != 3 {
+			t.Errorf("Expected every other line, got %+v", filtered)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Filter did not finish")
+	}
+
+	// A cancelled filter never reports
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	sm.FilterLinesAsync(ctx, text, "keep", FilterKeep, func(*FilteredText, error) {
+		done <- nil
+	})
+	select {
+	case <-done:
+		t.Error("Expected no result for a cancelled filter")
+	case <-time.After(200 * time.Millisecond):
+	}
+}
@@ -0,0 +1,168 @@
+package backend
+
+import (
+	"bufio"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// IgnoreRule represents a single pattern from a .gitignore file
+type IgnoreRule struct {
+	Pattern  string `json:"pattern"`
+	Base     string `json:"base"`     // Directory (relative to the root) that contains the .gitignore
+	Negate   bool   `json:"negate"`   // Pattern started with "!"
+	DirOnly  bool   `json:"dirOnly"`  // Pattern ended with "/"
+	Anchored bool   `json:"anchored"` // Pattern contains a "/" and matches relative to Base only
+}
+
+// IgnoreMatcher evaluates .gitignore rules against slash-separated paths
+// relative to a root directory. Later rules override earlier ones, so rules
+// from nested .gitignore files take precedence over their parents.
+type IgnoreMatcher struct {
+	rules []IgnoreRule
+}
+
+// NewIgnoreMatcher creates an empty ignore matcher
+func NewIgnoreMatcher() *IgnoreMatcher {
+	return &IgnoreMatcher{
+		rules: make([]IgnoreRule, 0),
+	}
+}
+
+// AddPattern parses a single .gitignore line and adds it as a rule for base
+func (im *IgnoreMatcher) AddPattern(line, base string) {
+	line = strings.TrimRight(line, " \t\r")
+	if line == "" || strings.HasPrefix(line, "#") {
+		return
+	}
+
+	rule := IgnoreRule{Base: strings.Trim(base, "/")}
+	if strings.HasPrefix(line, "!") {
+		rule.Negate = true
+		line = line[1:]
+	} else if strings.HasPrefix(line, `\!`) || strings.HasPrefix(line, `\#`) {
+		line = line[1:]
+	}
+	if strings.HasSuffix(line, "/") {
+		rule.DirOnly = true
+		line = strings.TrimRight(line, "/")
+	}
+	if strings.Contains(line, "/") {
+		rule.Anchored = true
+		line = strings.TrimPrefix(line, "/")
+	}
+	if line == "" {
+		return
+	}
+
+	rule.Pattern = line
+	im.rules = append(im.rules, rule)
+}
+
+// LoadFile reads the .gitignore file at path and adds its rules for base
+func (im *IgnoreMatcher) LoadFile(path, base string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		im.AddPattern(scanner.Text(), base)
+	}
+	return scanner.Err()
+}
+
+// LoadDir adds the rules of dir/.gitignore if the file exists. rel is the
+// path of dir relative to the matcher root.
+func (im *IgnoreMatcher) LoadDir(dir, rel string) {
+	gitignore := filepath.Join(dir, ".gitignore")
+	if _, err := os.Stat(gitignore); err == nil {
+		_ = im.LoadFile(gitignore, filepath.ToSlash(rel))
+	}
+}
+
+// Clone returns a copy of the matcher that can be extended independently
+func (im *IgnoreMatcher) Clone() *IgnoreMatcher {
+	rules := make([]IgnoreRule, len(im.rules))
+	copy(rules, im.rules)
+	return &IgnoreMatcher{rules: rules}
+}
+
+// IsIgnored reports whether the slash-separated relative path is ignored
+func (im *IgnoreMatcher) IsIgnored(relPath string, isDir bool) bool {
+	relPath = strings.Trim(filepath.ToSlash(relPath), "/")
+	ignored := false
+
+	for _, rule := range im.rules {
+		if rule.DirOnly && !isDir {
+			continue
+		}
+
+		// Rules only apply to paths below the directory that defined them
+		target := relPath
+		if rule.Base != "" && rule.Base != "." {
+			if !strings.HasPrefix(relPath, rule.Base+"/") {
+				continue
+			}
+			target = strings.TrimPrefix(relPath, rule.Base+"/")
+		}
+
+		var matched bool
+		if rule.Anchored {
+			matched = MatchGlob(rule.Pattern, target)
+		} else {
+			matched = MatchGlob(rule.Pattern, path.Base(target))
+		}
+
+		if matched {
+			ignored = !rule.Negate
+		}
+	}
+
+	return ignored
+}
+
+// MatchGlob matches a slash-separated path against a glob pattern. In
+// addition to the syntax of path.Match, "**" matches any number of
+// directories.
+func MatchGlob(pattern, name string) bool {
+	if !strings.Contains(pattern, "**") {
+		matched, _ := path.Match(pattern, name)
+		return matched
+	}
+	return matchGlobSegments(strings.Split(pattern, "/"), strings.Split(name, "/"))
+}
+
+// matchGlobSegments matches pattern segments against path segments
+func matchGlobSegments(pattern, name []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			// Collapse repeated "**" and try every possible split point
+			for len(pattern) > 0 && pattern[0] == "**" {
+				pattern = pattern[1:]
+			}
+			if len(pattern) == 0 {
+				return true
+			}
+			for i := 0; i <= len(name); i++ {
+				if matchGlobSegments(pattern, name[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+
+		if len(name) == 0 {
+			return false
+		}
+		if matched, _ := path.Match(pattern[0], name[0]); !matched {
+			return false
+		}
+		pattern, name = pattern[1:], name[1:]
+	}
+	return len(name) == 0
+}
@@ -0,0 +1,87 @@
+package backend
+
+import (
+	"testing"
+)
+
+func TestMatchGlob(t *testing.T) {
+	tests := []struct {
+		pattern  string
+		name     string
+		expected bool
+	}{
+		{"*.go", "main.go", true},
+		{"*.go", "ui/main.go", false},
+		{"ui/*.go", "ui/main.go", true},
+		{"**/*.go", "ui/dialogs/find.go", true},
+		{"**/*.go", "main.go", true},
+		{"ui/**", "ui/dialogs/find.go", true},
+		{"ui/**/find.go", "ui/find.go", true},
+		{"ui/**/find.go", "backend/find.go", false},
+		{"a/**/b/*.txt", "a/x/y/b/c.txt", true},
+	}
+
+	for _, tt := range tests {
+		if got := MatchGlob(tt.pattern, tt.name); got != tt.expected {
+			t.Errorf("MatchGlob(%q, %q) = %v, expected %v", tt.pattern, tt.name, got, tt.expected)
+		}
+	}
+}
+
+func TestIgnoreMatcher_Rules(t *testing.T) {
+	im := NewIgnoreMatcher()
+	for _, line := range []string{
+		"# comment",
+		"",
+		"*.log",
+		"!keep.log",
+		"build/",
+		"/output",
+		"docs/*.tmp",
+	} {
+		im.AddPattern(line, "")
+	}
+
+	tests := []struct {
+		path     string
+		isDir    bool
+		expected bool
+	}{
+		{"app.log", false, true},
+		{"logs/app.log", false, true},
+		{"keep.log", false, false},
+		{"build", true, true},
+		{"build", false, false},
+		{"src/build", true, true},
+		{"output", true, true},
+		{"src/output", true, false},
+		{"docs/a.tmp", false, true},
+		{"other/docs/a.tmp", false, false},
+		{"main.go", false, false},
+	}
+
+	for _, tt := range tests {
+		if got := im.IsIgnored(tt.path, tt.isDir); got != tt.expected {
+			t.Errorf("IsIgnored(%q, %v) = %v, expected %v", tt.path, tt.isDir, got, tt.expected)
+		}
+	}
+}
+
+func TestIgnoreMatcher_NestedBase(t *testing.T) {
+	im := NewIgnoreMatcher()
+	im.AddPattern("*.gen.go", "pkg")
+	im.AddPattern("/local.txt", "pkg")
+
+	if !im.IsIgnored("pkg/types.gen.go", false) {
+		t.Error("Expected nested rule to ignore pkg/types.gen.go")
+	}
+	if im.IsIgnored("types.gen.go", false) {
+		t.Error("Nested rule should not apply outside its directory")
+	}
+	if !im.IsIgnored("pkg/local.txt", false) {
+		t.Error("Expected anchored nested rule to ignore pkg/local.txt")
+	}
+	if im.IsIgnored("pkg/sub/local.txt", false) {
+		t.Error("Anchored rule should only match relative to its directory")
+	}
+}
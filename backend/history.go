@@ -1,6 +1,7 @@
 package backend
 
 import (
+	"strings"
 	"time"
 )
 
@@ -131,6 +132,55 @@ func (h *History) RecordReplace(position Position, oldText, newText string) {
 	h.RecordOperation(op)
 }
 
+// RecordEdit records the change of a whole text from oldText to newText as
+// a single replace operation
+func (h *History) RecordEdit(oldText, newText string) {
+	h.RecordOperation(NewEditOperation(oldText, newText))
+}
+
+// NewEditOperation returns the replace operation that turns oldText into
+// newText, narrowed to the part between their common prefix and suffix
+func NewEditOperation(oldText, newText string) Operation {
+	prefix := 0
+	for prefix < len(oldText) && prefix < len(newText) && oldText[prefix] == newText[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(oldText)-prefix && suffix < len(newText)-prefix &&
+		oldText[len(oldText)-1-suffix] == newText[len(newText)-1-suffix] {
+		suffix++
+	}
+
+	return Operation{
+		Type:      Replace,
+		Position:  newLineIndex(oldText).position(prefix),
+		OldText:   oldText[prefix : len(oldText)-suffix],
+		NewText:   newText[prefix : len(newText)-suffix],
+		Timestamp: time.Now(),
+	}
+}
+
+// Apply performs a replace operation on text. It returns false if text
+// does not hold OldText at the operation's position.
+func (op Operation) Apply(text string) (string, bool) {
+	return swapAt(text, op.Position, op.OldText, op.NewText)
+}
+
+// Revert undoes a replace operation on text. It returns false if text does
+// not hold NewText at the operation's position.
+func (op Operation) Revert(text string) (string, bool) {
+	return swapAt(text, op.Position, op.NewText, op.OldText)
+}
+
+// swapAt replaces from with to at position in text, if from is there
+func swapAt(text string, position Position, from, to string) (string, bool) {
+	start := newLineIndex(text).offset(position)
+	if start < 0 || start > len(text) || !strings.HasPrefix(text[start:], from) {
+		return text, false
+	}
+	return text[:start] + to + text[start+len(from):], true
+}
+
 // CanUndo returns true if there are operations that can be undone
 func (h *History) CanUndo() bool {
 	return len(h.undoStack) > 0
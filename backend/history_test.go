@@ -411,4 +411,37 @@ func TestGetOperationHistory(t *testing.T) {
 	if originalHistory[0].NewText == "modified" {
 		t.Error("GetOperationHistory should return a copy, not the original slice")
 	}
-}
\ No newline at end of file
+}
+
+func TestRecordEdit(t *testing.T) {
+	history := NewHistory()
+	oldText := "line one\nline two\nline three"
+	newText := "line one\nline 2\nline three"
+
+	history.RecordEdit(oldText, newText)
+	if history.GetUndoCount() != 1 {
+		t.Fatalf("Expected one operation, got %d", history.GetUndoCount())
+	}
+
+	op := history.Undo()
+	if op.Type != Replace || op.OldText != "two" || op.NewText != "2" {
+		t.Errorf("Expected the edit narrowed to the changed text, got %+v", op)
+	}
+	if op.Position.Line != 2 || op.Position.Column != 6 {
+		t.Errorf("Expected position 2:6, got %+v", op.Position)
+	}
+
+	reverted, ok := op.Revert(newText)
+	if !ok || reverted != oldText {
+		t.Errorf("Expected revert to restore the old text, got %q", reverted)
+	}
+	applied, ok := op.Apply(reverted)
+	if !ok || applied != newText {
+		t.Errorf("Expected apply to produce the new text, got %q", applied)
+	}
+
+	// An operation does not apply to text that has changed since
+	if _, ok := op.Revert("something else"); ok {
+		t.Error("Expected revert to fail on different text")
+	}
+}
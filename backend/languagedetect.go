@@ -0,0 +1,157 @@
+package backend
+
+import (
+	"path"
+	"regexp"
+	"strings"
+)
+
+// modelineLines is how many lines at the start and end of a document are
+// searched for a Vim modeline, as Vim does by default
+const modelineLines = 5
+
+// localVariablesWindow is how many bytes at the end of a document are
+// searched for an Emacs local variables block, as Emacs does
+const localVariablesWindow = 3000
+
+var (
+	// vimModeline matches "vim: set ft=go :", "vi:syntax=go" and the like
+	vimModeline = regexp.MustCompile(`(?:^|\s)(?:vim?|ex):\s*(?:set?\s+)?(.*)`)
+	// vimLanguageOption matches the options naming the language
+	vimLanguageOption = regexp.MustCompile(`(?:^|[\s:])(?:ft|filetype|syn|syntax)=([\w+.#-]+)`)
+	// emacsModeline matches "-*- mode: python -*-" and "-*- python -*-"
+	emacsModeline = regexp.MustCompile(`-\*-\s*(.*?)\s*-\*-`)
+	// emacsLocalMode matches the mode in an Emacs local variables block
+	emacsLocalMode = regexp.MustCompile(`(?im)^\W*mode:\s*([\w+.#-]+)`)
+)
+
+// ModelineLanguage returns the language named by a Vim modeline in the
+// first or last lines of content, or by an Emacs mode line or local
+// variables block. The name is returned as written, such as "python" or
+// "shell-script", and is empty when there is no modeline.
+func ModelineLanguage(content string) string {
+	lines := strings.Split(content, "\n")
+
+	// Emacs reads the mode from the first line, or the second after a
+	// shebang
+	for i := 0; i < len(lines) && i < 2; i++ {
+		if match := emacsModeline.FindStringSubmatch(lines[i]); match != nil {
+			if name := emacsMode(match[1]); name != "" {
+				return name
+			}
+		}
+	}
+
+	candidates := lines
+	if len(lines) > 2*modelineLines {
+		candidates = append(append([]string{}, lines[:modelineLines]...), lines[len(lines)-modelineLines:]...)
+	}
+	for _, line := range candidates {
+		match := vimModeline.FindStringSubmatch(line)
+		if match == nil {
+			continue
+		}
+		if option := vimLanguageOption.FindStringSubmatch(match[1]); option != nil {
+			return option[1]
+		}
+	}
+
+	// Emacs local variables are at the end of the document
+	tail := content
+	if len(tail) > localVariablesWindow {
+		tail = tail[len(tail)-localVariablesWindow:]
+	}
+	if start := strings.LastIndex(tail, "Local Variables:"); start >= 0 {
+		block := tail[start:]
+		if end := strings.Index(block, "End:"); end >= 0 {
+			block = block[:end]
+		}
+		if match := emacsLocalMode.FindStringSubmatch(block); match != nil {
+			return strings.TrimSuffix(match[1], "-mode")
+		}
+	}
+	return ""
+}
+
+// ModelineRegion returns the parts of content that modelines and shebangs
+// are read from: the first modelineLines lines, and the last modelineLines
+// lines or localVariablesWindow bytes, whichever is longer. The language
+// named by them can only change when this region does.
+func ModelineRegion(content string) string {
+	head := content
+	for i, lines := 0, 0; i < len(content); i++ {
+		if content[i] == '\n' {
+			if lines++; lines == modelineLines {
+				head = content[:i]
+				break
+			}
+		}
+	}
+
+	start := 0
+	for i, lines := len(content)-1, 0; i >= 0; i-- {
+		if content[i] == '\n' {
+			if lines++; lines == modelineLines {
+				start = i + 1
+				break
+			}
+		}
+	}
+	if window := len(content) - localVariablesWindow; window < start {
+		start = window
+	}
+	if start < len(head) {
+		return content
+	}
+	return head + "\n" + content[start:]
+}
+
+// emacsMode returns the mode of an Emacs mode line's contents, which are
+// either "mode: name; var: value" or just the mode name
+func emacsMode(contents string) string {
+	if !strings.Contains(contents, ":") {
+		return strings.TrimSuffix(strings.TrimSpace(contents), "-mode")
+	}
+	for _, variable := range strings.Split(contents, ";") {
+		name, value, found := strings.Cut(variable, ":")
+		if found && strings.EqualFold(strings.TrimSpace(name), "mode") {
+			return strings.TrimSuffix(strings.TrimSpace(value), "-mode")
+		}
+	}
+	return ""
+}
+
+// ShebangInterpreter returns the interpreter a "#!" line runs the content
+// with, such as "python3" for "#!/usr/bin/env python3". It is empty when
+// the content does not start with a shebang.
+func ShebangInterpreter(content string) string {
+	if !strings.HasPrefix(content, "#!") {
+		return ""
+	}
+	line, _, _ := strings.Cut(content[2:], "\n")
+	fields := strings.Fields(line)
+	if len(fields) == 0 {
+		return ""
+	}
+
+	interpreter := path.Base(fields[0])
+	if interpreter == "env" {
+		// Skip env's options and variable assignments, as in
+		// "#!/usr/bin/env -S VAR=1 node --flag"
+		interpreter = ""
+		for _, field := range fields[1:] {
+			if strings.HasPrefix(field, "-") || strings.Contains(field, "=") {
+				continue
+			}
+			interpreter = path.Base(field)
+			break
+		}
+	}
+	return interpreter
+}
+
+// trimVersion removes a version from an interpreter name, such as
+// "python3.12" to "python"
+func trimVersion(interpreter string) string {
+	return strings.TrimRight(interpreter, "0123456789.-")
+}
@@ -0,0 +1,74 @@
+package backend
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestModelineLanguage(t *testing.T) {
+	testCases := []struct {
+		name     string
+		content  string
+		expected string
+	}{
+		{"vim set", "x = 1\n# vim: set ft=python ts=4 :\n", "python"},
+		{"vim without set", "// vim:ts=4:sw=4:filetype=go\npackage main\n", "go"},
+		{"vi syntax", "<!-- vi: syntax=html -->\n", "html"},
+		{"emacs mode", "#!/bin/sh\n# -*- mode: shell-script; coding: utf-8 -*-\n", "shell-script"},
+		{"emacs short", "/* -*- c++ -*- */\n", "c++"},
+		{"emacs local variables", "text\n# Local Variables:\n# mode: python\n# End:\n", "python"},
+		{"vim in the middle", "a\n" + strings.Repeat("x\n", 6) + "vim: ft=go\n" + strings.Repeat("x\n", 6), ""},
+		{"emacs local variables too early", "# Local Variables:\n# mode: python\n# End:\n" + strings.Repeat("x", localVariablesWindow), ""},
+		{"options without language", "# vim: set ts=4 :\n", ""},
+		{"none", "package main\n", ""},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if language := ModelineLanguage(tc.content); language != tc.expected {
+				t.Errorf("Expected %q, got %q", tc.expected, language)
+			}
+		})
+	}
+}
+
+func TestModelineRegion(t *testing.T) {
+	short := "#!/bin/sh\necho hi\n"
+	if region := ModelineRegion(short); region != short {
+		t.Errorf("Expected a short document to be its own region, got %q", region)
+	}
+
+	middle := strings.Repeat("line\n", 2000)
+	content := "#!/bin/sh\n" + middle + "# vim: ft=go\n"
+	region := ModelineRegion(content)
+	if !strings.HasPrefix(region, "#!/bin/sh\n") || !strings.HasSuffix(region, "# vim: ft=go\n") {
+		t.Errorf("Expected the region to hold the first and last lines, got %q", region)
+	}
+	if len(region) > localVariablesWindow+100 {
+		t.Errorf("Expected the middle of the document to be left out, got %d bytes", len(region))
+	}
+
+	// Edits in the middle leave the region unchanged
+	edited := "#!/bin/sh\n" + middle + "edit\n" + middle + "# vim: ft=go\n"
+	if ModelineRegion(edited) != region {
+		t.Error("Expected an edit in the middle not to change the region")
+	}
+}
+
+func TestShebangInterpreter(t *testing.T) {
+	testCases := map[string]string{
+		"#!/bin/bash\necho hi\n":            "bash",
+		"#! /usr/bin/python3 -u\n":          "python3",
+		"#!/usr/bin/env node\n":             "node",
+		"#!/usr/bin/env -S VAR=1 ruby -w\n": "ruby",
+		"#!\n":                              "",
+		"echo hi\n":                         "",
+		"# !/bin/bash\n":                    "",
+	}
+
+	for content, expected := range testCases {
+		if interpreter := ShebangInterpreter(content); interpreter != expected {
+			t.Errorf("Expected %q for %q, got %q", expected, content, interpreter)
+		}
+	}
+}
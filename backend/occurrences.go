@@ -0,0 +1,108 @@
+package backend
+
+import (
+	"fmt"
+	"strings"
+)
+
+// OccurrenceLine is a row of an occurrences list: a line on which matches
+// start, a context line around one, or a separator between groups of lines
+// that are not adjacent
+type OccurrenceLine struct {
+	Line    int     `json:"line"`              // 1-based line number, 0 for a separator
+	Text    string  `json:"text"`              // Line content without the line break
+	Matches []Match `json:"matches,omitempty"` // Matches starting on the line, empty for context lines
+}
+
+// IsMatch reports whether matches start on the line
+func (l OccurrenceLine) IsMatch() bool {
+	return len(l.Matches) > 0
+}
+
+// IsSeparator reports whether the row separates two groups of lines
+func (l OccurrenceLine) IsSeparator() bool {
+	return l.Line == 0
+}
+
+// CollectOccurrences lists every line of text on which one of matches
+// starts, in the style of grep -C: each line is surrounded by up to context
+// lines before and after it, and groups that are not adjacent are divided
+// by a separator row. Matches must be sorted by position.
+func CollectOccurrences(text string, matches []Match, context int) []OccurrenceLine {
+	rows := make([]OccurrenceLine, 0)
+	if len(matches) == 0 {
+		return rows
+	}
+	if context < 0 {
+		context = 0
+	}
+
+	lines := strings.Split(text, "\n")
+	lineText := func(line int) string {
+		return strings.TrimSuffix(lines[line-1], "\r")
+	}
+
+	last := 0 // Last line added
+	for i := 0; i < len(matches); {
+		line := matches[i].Start.Line
+		if line < 1 || line > len(lines) {
+			i++
+			continue
+		}
+
+		// Gather the matches starting on this line
+		j := i
+		for j < len(matches) && matches[j].Start.Line == line {
+			j++
+		}
+
+		first := line - context
+		if first <= last {
+			first = last + 1
+		} else if last > 0 {
+			rows = append(rows, OccurrenceLine{})
+		}
+		if first < 1 {
+			first = 1
+		}
+		for n := first; n < line; n++ {
+			rows = append(rows, OccurrenceLine{Line: n, Text: lineText(n)})
+		}
+		rows = append(rows, OccurrenceLine{Line: line, Text: lineText(line), Matches: matches[i:j]})
+		last = line
+
+		// Context after the line, stopping short of the next match line
+		next := len(lines) + 1
+		if j < len(matches) {
+			next = matches[j].Start.Line
+		}
+		for n := line + 1; n <= line+context && n < next && n <= len(lines); n++ {
+			rows = append(rows, OccurrenceLine{Line: n, Text: lineText(n)})
+			last = n
+		}
+		i = j
+	}
+	return rows
+}
+
+// ReplaceLine replaces the content of a 1-based line of text, keeping its
+// line break. It fails if the line no longer reads oldText, so an edit made
+// on a stale copy of the line cannot overwrite a newer change.
+func ReplaceLine(text string, line int, oldText, newText string) (string, error) {
+	lines := strings.Split(text, "\n")
+	if line < 1 || line > len(lines) {
+		return text, fmt.Errorf("line %d does not exist", line)
+	}
+
+	current := lines[line-1]
+	ending := ""
+	if strings.HasSuffix(current, "\r") {
+		current, ending = current[:len(current)-1], "\r"
+	}
+	if current != oldText {
+		return text, fmt.Errorf("line %d has changed", line)
+	}
+
+	lines[line-1] = newText + ending
+	return strings.Join(lines, "\n"), nil
+}
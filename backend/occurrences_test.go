@@ -0,0 +1,65 @@
+package backend
+
+import (
+	"testing"
+)
+
+func TestCollectOccurrences(t *testing.T) {
+	text := "one\nfoo two\nthree\nfour\nfive\nsix foo foo\nseven\r\nfoo\n"
+	sm := NewSearchManager()
+	matches := sm.Find(text, "foo")
+
+	rows := CollectOccurrences(text, matches, 0)
+	if len(rows) != 5 {
+		t.Fatalf("Expected 3 lines and 2 separators, got %+v", rows)
+	}
+	if rows[0].Line != 2 || rows[0].Text != "foo two" || len(rows[0].Matches) != 1 {
+		t.Errorf("Unexpected first row %+v", rows[0])
+	}
+	if !rows[1].IsSeparator() || len(rows[2].Matches) != 2 {
+		t.Errorf("Expected a separator and two matches on line 6, got %+v %+v", rows[1], rows[2])
+	}
+	if rows[4].Line != 8 || rows[4].Text != "foo" {
+		t.Errorf("Unexpected last row %+v", rows[4])
+	}
+
+	// Context lines, merged where groups touch
+	rows = CollectOccurrences(text, matches, 1)
+	expected := []int{1, 2, 3, 0, 5, 6, 7, 8, 9}
+	if len(rows) != len(expected) {
+		t.Fatalf("Expected %d rows, got %+v", len(expected), rows)
+	}
+	for i, line := range expected {
+		if rows[i].Line != line {
+			t.Errorf("Row %d: expected line %d, got %d", i, line, rows[i].Line)
+		}
+	}
+	if rows[6].IsMatch() || rows[6].Text != "seven" {
+		t.Errorf("Expected context line without line break, got %+v", rows[6])
+	}
+
+	if rows := CollectOccurrences(text, nil, 2); len(rows) != 0 {
+		t.Errorf("Expected no rows without matches, got %+v", rows)
+	}
+}
+
+func TestReplaceLine(t *testing.T) {
+	text := "first\r\nsecond\nthird"
+
+	result, err := ReplaceLine(text, 1, "first", "1st")
+	if err != nil || result != "1st\r\nsecond\nthird" {
+		t.Errorf("Unexpected result %q (%v)", result, err)
+	}
+
+	result, err = ReplaceLine(text, 3, "third", "3rd")
+	if err != nil || result != "first\r\nsecond\n3rd" {
+		t.Errorf("Unexpected result %q (%v)", result, err)
+	}
+
+	if _, err := ReplaceLine(text, 2, "changed", "x"); err == nil {
+		t.Error("Expected an error when the line has changed")
+	}
+	if _, err := ReplaceLine(text, 4, "", "x"); err == nil {
+		t.Error("Expected an error for a missing line")
+	}
+}
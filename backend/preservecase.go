@@ -0,0 +1,177 @@
+package backend
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// CaseStyle is the casing style of an identifier or word
+type CaseStyle int
+
+const (
+	CaseUnknown        CaseStyle = iota
+	CaseLower                    // lower
+	CaseUpper                    // UPPER
+	CaseTitle                    // Title
+	CaseCamel                    // camelCase
+	CasePascal                   // PascalCase
+	CaseSnake                    // snake_case
+	CaseScreamingSnake           // SCREAMING_SNAKE
+	CaseKebab                    // kebab-case
+)
+
+// String returns a readable name for the style
+func (cs CaseStyle) String() string {
+	switch cs {
+	case CaseLower:
+		return "lower"
+	case CaseUpper:
+		return "UPPER"
+	case CaseTitle:
+		return "Title"
+	case CaseCamel:
+		return "camelCase"
+	case CasePascal:
+		return "PascalCase"
+	case CaseSnake:
+		return "snake_case"
+	case CaseScreamingSnake:
+		return "SCREAMING_SNAKE"
+	case CaseKebab:
+		return "kebab-case"
+	}
+	return "unknown"
+}
+
+// DetectCaseStyle classifies the casing style of text
+func DetectCaseStyle(text string) CaseStyle {
+	var upper, lower int
+	for _, r := range text {
+		if unicode.IsUpper(r) {
+			upper++
+		} else if unicode.IsLower(r) {
+			lower++
+		}
+	}
+	if upper+lower == 0 {
+		return CaseUnknown
+	}
+
+	switch {
+	case strings.Contains(text, "_"):
+		if lower == 0 {
+			return CaseScreamingSnake
+		}
+		if upper == 0 {
+			return CaseSnake
+		}
+		return CaseUnknown
+	case strings.Contains(text, "-"):
+		if upper == 0 {
+			return CaseKebab
+		}
+		return CaseUnknown
+	case upper == 0:
+		return CaseLower
+	case lower == 0:
+		if upper == 1 {
+			// A single capital such as "A" reads as a title
+			return CaseTitle
+		}
+		return CaseUpper
+	}
+
+	first, _ := utf8.DecodeRuneInString(text)
+	if unicode.IsUpper(first) {
+		if upper == 1 {
+			return CaseTitle
+		}
+		return CasePascal
+	}
+	return CaseCamel
+}
+
+// PreserveCase adapts replacement to the casing style of original, so that
+// replacing "userId" with "accountId" turns "UserId" into "AccountId" and
+// "USER_ID" into "ACCOUNT_ID". Replacements for text without a recognizable
+// style are returned unchanged.
+func PreserveCase(original, replacement string) string {
+	switch DetectCaseStyle(original) {
+	case CaseLower:
+		return strings.ToLower(replacement)
+	case CaseUpper:
+		return strings.ToUpper(replacement)
+	case CaseTitle:
+		return upperFirst(replacement)
+	case CaseCamel:
+		words := splitWords(replacement)
+		for i, word := range words {
+			if i == 0 {
+				words[i] = strings.ToLower(word)
+			} else {
+				words[i] = upperFirst(strings.ToLower(word))
+			}
+		}
+		return strings.Join(words, "")
+	case CasePascal:
+		words := splitWords(replacement)
+		for i, word := range words {
+			words[i] = upperFirst(strings.ToLower(word))
+		}
+		return strings.Join(words, "")
+	case CaseSnake:
+		return strings.ToLower(strings.Join(splitWords(replacement), "_"))
+	case CaseScreamingSnake:
+		return strings.ToUpper(strings.Join(splitWords(replacement), "_"))
+	case CaseKebab:
+		return strings.ToLower(strings.Join(splitWords(replacement), "-"))
+	}
+	return replacement
+}
+
+// splitWords splits an identifier into words at separators and case
+// changes: "accountId", "account_id" and "HTTPServer" become
+// ["account" "Id"], ["account" "id"] and ["HTTP" "Server"]
+func splitWords(text string) []string {
+	words := make([]string, 0)
+	runes := []rune(text)
+	start := 0
+
+	flush := func(end int) {
+		if end > start {
+			words = append(words, string(runes[start:end]))
+		}
+	}
+
+	for i, r := range runes {
+		if r == '_' || r == '-' || unicode.IsSpace(r) {
+			flush(i)
+			start = i + 1
+			continue
+		}
+		if i == start || !unicode.IsUpper(r) {
+			continue
+		}
+
+		prev := runes[i-1]
+		nextIsLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+		// Split before "Id" in "accountId" and before "Server" in "HTTPServer"
+		if unicode.IsLower(prev) || unicode.IsDigit(prev) || (unicode.IsUpper(prev) && nextIsLower) {
+			flush(i)
+			start = i
+		}
+	}
+	flush(len(runes))
+
+	return words
+}
+
+// upperFirst uppercases the first rune of text
+func upperFirst(text string) string {
+	r, size := utf8.DecodeRuneInString(text)
+	if size == 0 {
+		return text
+	}
+	return string(unicode.ToUpper(r)) + text[size:]
+}
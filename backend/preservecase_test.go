@@ -0,0 +1,78 @@
+package backend
+
+import "testing"
+
+func TestDetectCaseStyle(t *testing.T) {
+	tests := []struct {
+		text     string
+		expected CaseStyle
+	}{
+		{"userid", CaseLower},
+		{"USERID", CaseUpper},
+		{"User", CaseTitle},
+		{"userId", CaseCamel},
+		{"UserId", CasePascal},
+		{"user_id", CaseSnake},
+		{"USER_ID", CaseScreamingSnake},
+		{"user-id", CaseKebab},
+		{"User_id", CaseUnknown},
+		{"42", CaseUnknown},
+	}
+
+	for _, tt := range tests {
+		if style := DetectCaseStyle(tt.text); style != tt.expected {
+			t.Errorf("DetectCaseStyle(%q) = %s, expected %s", tt.text, style, tt.expected)
+		}
+	}
+}
+
+func TestPreserveCase(t *testing.T) {
+	tests := []struct {
+		original    string
+		replacement string
+		expected    string
+	}{
+		{"userId", "accountId", "accountId"},
+		{"UserId", "accountId", "AccountId"},
+		{"USER_ID", "accountId", "ACCOUNT_ID"},
+		{"user_id", "accountId", "account_id"},
+		{"user-id", "accountId", "account-id"},
+		{"userid", "accountId", "accountid"},
+		{"USERID", "accountId", "ACCOUNTID"},
+		{"User", "account", "Account"},
+		{"userId", "account_id", "accountId"},
+		{"serverName", "HTTPServer", "httpServer"},
+		{"User_id", "accountId", "accountId"},
+	}
+
+	for _, tt := range tests {
+		if result := PreserveCase(tt.original, tt.replacement); result != tt.expected {
+			t.Errorf("PreserveCase(%q, %q) = %q, expected %q", tt.original, tt.replacement, result, tt.expected)
+		}
+	}
+}
+
+func TestSearchManager_ReplacePreserveCase(t *testing.T) {
+	sm := NewSearchManager()
+	text := "userId := getUserId(USER_ID)\nvar user_id int"
+	options := ReplaceOptions{
+		SearchOptions: SearchOptions{RegularExpression: true},
+		ReplaceAll:    true,
+		PreserveCase:  true,
+	}
+
+	result, count := sm.Replace(text, `user_?id`, "accountId", options)
+	if count != 4 {
+		t.Errorf("Expected 4 replacements, got %d", count)
+	}
+	if expected := "accountId := getAccountId(ACCOUNT_ID)\nvar account_id int"; result != expected {
+		t.Errorf("Expected %q, got %q", expected, result)
+	}
+
+	// Replacing the current match adapts the case too
+	options.ReplaceAll = false
+	result, count = sm.Replace("UserId", "userid", "accountId", options)
+	if count != 1 || result != "AccountId" {
+		t.Errorf("Expected %q, got %d giving %q", "AccountId", count, result)
+	}
+}
@@ -0,0 +1,371 @@
+package backend
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ReplacementEdit is a single match that can be replaced in a file
+type ReplacementEdit struct {
+	LineMatch
+	Replacement string `json:"replacement"`
+	Enabled     bool   `json:"enabled"`
+
+	offset int // Byte offset of the match in the original content
+}
+
+// FileReplacement holds the planned replacements for a single file
+type FileReplacement struct {
+	Path            string            `json:"path"`
+	RelPath         string            `json:"relPath"`
+	OriginalContent string            `json:"-"`
+	Edits           []ReplacementEdit `json:"edits"`
+	Enabled         bool              `json:"enabled"`
+}
+
+// DiffHunk is a changed region of a file in a replacement preview
+type DiffHunk struct {
+	OldStart int      `json:"oldStart"` // 1-based first line in the original content
+	NewStart int      `json:"newStart"` // 1-based first line in the new content
+	OldLines []string `json:"oldLines"`
+	NewLines []string `json:"newLines"`
+}
+
+// FileChange records the content of a file before and after a batch replace
+type FileChange struct {
+	Path       string `json:"path"`
+	OldContent string `json:"-"`
+	NewContent string `json:"-"`
+	EditCount  int    `json:"editCount"`
+}
+
+// ReplaceBatch is a set of file changes applied as one undoable action
+type ReplaceBatch struct {
+	Pattern     string       `json:"pattern"`
+	Replacement string       `json:"replacement"`
+	Files       []FileChange `json:"files"`
+	Timestamp   time.Time    `json:"timestamp"`
+}
+
+// ProjectReplacer plans, applies and undoes replacements across files
+type ProjectReplacer struct {
+	fileManager *FileManager
+	undoStack   []ReplaceBatch
+	maxUndo     int
+}
+
+// NewProjectReplacer creates a new project replacer
+func NewProjectReplacer(fileManager *FileManager) *ProjectReplacer {
+	if fileManager == nil {
+		fileManager = NewFileManager()
+	}
+	return &ProjectReplacer{
+		fileManager: fileManager,
+		undoStack:   make([]ReplaceBatch, 0),
+		maxUndo:     20,
+	}
+}
+
+// Preview reads every file in results and computes the replacement for each
+// match. All files and edits start enabled.
+func (pr *ProjectReplacer) Preview(results []FileSearchResult, pattern, replacement string, options SearchOptions) ([]FileReplacement, error) {
+	if err := ValidateSearchPattern(pattern, options); err != nil {
+		return nil, err
+	}
+
+	sm := NewSearchManager()
+	sm.SetOptions(options)
+	// Every match is replaced, so none may be dropped
+	sm.SetMatchLimit(0)
+
+	template, err := sm.CompileReplacement(pattern, replacement)
+	if err != nil {
+		return nil, err
+	}
+
+	replacements := make([]FileReplacement, 0, len(results))
+	for _, result := range results {
+		data, err := os.ReadFile(result.Path)
+		if err != nil {
+			return nil, &FileError{Operation: "读取", Path: result.Path, Err: err}
+		}
+		content := string(data)
+
+		// Search again so positions match the content we will modify
+		matches := sm.Find(content, pattern)
+		if len(matches) == 0 {
+			continue
+		}
+
+		lines := strings.Split(content, "\n")
+		lineOffsets := computeLineOffsets(lines)
+
+		fr := FileReplacement{
+			Path:            result.Path,
+			RelPath:         result.RelPath,
+			OriginalContent: content,
+			Edits:           make([]ReplacementEdit, 0, len(matches)),
+			Enabled:         true,
+		}
+		for _, match := range matches {
+			text := template.Expand(match.Groups)
+			fr.Edits = append(fr.Edits, ReplacementEdit{
+				LineMatch: LineMatch{
+					Match:    match,
+					LineText: strings.TrimRight(lines[match.Start.Line-1], "\r"),
+				},
+				Replacement: text,
+				Enabled:     true,
+				offset:      lineOffsets[match.Start.Line-1] + match.Start.Column - 1,
+			})
+		}
+		replacements = append(replacements, fr)
+	}
+
+	sort.Slice(replacements, func(i, j int) bool {
+		return replacements[i].RelPath < replacements[j].RelPath
+	})
+	return replacements, nil
+}
+
+// EnabledEditCount returns how many edits will be applied for this file
+func (fr *FileReplacement) EnabledEditCount() int {
+	if !fr.Enabled {
+		return 0
+	}
+	count := 0
+	for _, edit := range fr.Edits {
+		if edit.Enabled {
+			count++
+		}
+	}
+	return count
+}
+
+// SetAllEdits enables or disables every edit in the file
+func (fr *FileReplacement) SetAllEdits(enabled bool) {
+	for i := range fr.Edits {
+		fr.Edits[i].Enabled = enabled
+	}
+}
+
+// NewContent returns the file content with the enabled edits applied
+func (fr *FileReplacement) NewContent() string {
+	if fr.EnabledEditCount() == 0 {
+		return fr.OriginalContent
+	}
+
+	var sb strings.Builder
+	last := 0
+	for _, edit := range fr.Edits {
+		if !edit.Enabled {
+			continue
+		}
+		end := edit.offset + len(edit.Text)
+		if edit.offset < last || end > len(fr.OriginalContent) {
+			// Overlapping or stale edit; leave the text untouched
+			continue
+		}
+		sb.WriteString(fr.OriginalContent[last:edit.offset])
+		sb.WriteString(edit.Replacement)
+		last = end
+	}
+	sb.WriteString(fr.OriginalContent[last:])
+	return sb.String()
+}
+
+// Diff returns the changed regions for the enabled edits. Edits on the same
+// or adjacent lines are merged into one hunk.
+func (fr *FileReplacement) Diff() []DiffHunk {
+	hunks := make([]DiffHunk, 0)
+	if fr.EnabledEditCount() == 0 {
+		return hunks
+	}
+
+	oldLines := strings.Split(fr.OriginalContent, "\n")
+	lineOffsets := computeLineOffsets(oldLines)
+
+	// Group enabled edits into runs of overlapping line ranges
+	type lineRange struct {
+		first, last int // 0-based inclusive line indexes
+		edits       []ReplacementEdit
+	}
+	ranges := make([]lineRange, 0)
+	for _, edit := range fr.Edits {
+		if !edit.Enabled {
+			continue
+		}
+		first := edit.Start.Line - 1
+		last := first + strings.Count(edit.Text, "\n")
+		if n := len(ranges); n > 0 && first <= ranges[n-1].last+1 {
+			if last > ranges[n-1].last {
+				ranges[n-1].last = last
+			}
+			ranges[n-1].edits = append(ranges[n-1].edits, edit)
+			continue
+		}
+		ranges = append(ranges, lineRange{first: first, last: last, edits: []ReplacementEdit{edit}})
+	}
+
+	lineDelta := 0
+	for _, r := range ranges {
+		start := lineOffsets[r.first]
+		end := len(fr.OriginalContent)
+		if r.last+1 < len(lineOffsets) {
+			end = lineOffsets[r.last+1] - 1
+		}
+		oldText := fr.OriginalContent[start:end]
+
+		// Apply the edits of this range to its text
+		var sb strings.Builder
+		last := start
+		for _, edit := range r.edits {
+			editEnd := edit.offset + len(edit.Text)
+			if edit.offset < last || editEnd > end {
+				continue
+			}
+			sb.WriteString(fr.OriginalContent[last:edit.offset])
+			sb.WriteString(edit.Replacement)
+			last = editEnd
+		}
+		sb.WriteString(fr.OriginalContent[last:end])
+		newText := sb.String()
+
+		hunk := DiffHunk{
+			OldStart: r.first + 1,
+			NewStart: r.first + 1 + lineDelta,
+			OldLines: strings.Split(oldText, "\n"),
+			NewLines: strings.Split(newText, "\n"),
+		}
+		lineDelta += len(hunk.NewLines) - len(hunk.OldLines)
+		hunks = append(hunks, hunk)
+	}
+
+	return hunks
+}
+
+// FormatDiff renders the hunks of a file replacement as unified-diff text
+func (fr *FileReplacement) FormatDiff() string {
+	var sb strings.Builder
+	for _, hunk := range fr.Diff() {
+		sb.WriteString(fmt.Sprintf("@@ -%d,%d +%d,%d @@\n", hunk.OldStart, len(hunk.OldLines), hunk.NewStart, len(hunk.NewLines)))
+		for _, line := range hunk.OldLines {
+			sb.WriteString("-" + strings.TrimRight(line, "\r") + "\n")
+		}
+		for _, line := range hunk.NewLines {
+			sb.WriteString("+" + strings.TrimRight(line, "\r") + "\n")
+		}
+	}
+	return sb.String()
+}
+
+// Apply writes all enabled replacements atomically and records them as a
+// single undoable batch. Files modified on disk since the preview was
+// computed cause the whole batch to fail without writing anything.
+func (pr *ProjectReplacer) Apply(replacements []FileReplacement, pattern, replacement string) (*ReplaceBatch, error) {
+	batch := ReplaceBatch{
+		Pattern:     pattern,
+		Replacement: replacement,
+		Files:       make([]FileChange, 0),
+		Timestamp:   time.Now(),
+	}
+
+	writes := make([]FileWrite, 0)
+	for i := range replacements {
+		fr := &replacements[i]
+		count := fr.EnabledEditCount()
+		if count == 0 {
+			continue
+		}
+
+		newContent := fr.NewContent()
+		if newContent == fr.OriginalContent {
+			continue
+		}
+
+		batch.Files = append(batch.Files, FileChange{
+			Path:       fr.Path,
+			OldContent: fr.OriginalContent,
+			NewContent: newContent,
+			EditCount:  count,
+		})
+		writes = append(writes, FileWrite{
+			Path:     fr.Path,
+			Content:  newContent,
+			Expected: fr.OriginalContent,
+		})
+	}
+
+	if len(writes) == 0 {
+		return nil, fmt.Errorf("no replacements selected")
+	}
+
+	if err := pr.fileManager.WriteFilesAtomic(writes); err != nil {
+		return nil, err
+	}
+
+	pr.pushBatch(batch)
+	return &batch, nil
+}
+
+// CanUndo returns true if there is a batch that can be undone
+func (pr *ProjectReplacer) CanUndo() bool {
+	return len(pr.undoStack) > 0
+}
+
+// GetLastBatch returns the most recent batch without undoing it
+func (pr *ProjectReplacer) GetLastBatch() *ReplaceBatch {
+	if len(pr.undoStack) == 0 {
+		return nil
+	}
+	batch := pr.undoStack[len(pr.undoStack)-1]
+	return &batch
+}
+
+// UndoLastBatch restores every file of the most recent batch to its previous
+// content. Files changed since the batch was applied make the undo fail
+// without writing anything, and the batch stays on the stack.
+func (pr *ProjectReplacer) UndoLastBatch() (*ReplaceBatch, error) {
+	if len(pr.undoStack) == 0 {
+		return nil, fmt.Errorf("nothing to undo")
+	}
+
+	batch := pr.undoStack[len(pr.undoStack)-1]
+	writes := make([]FileWrite, 0, len(batch.Files))
+	for _, change := range batch.Files {
+		writes = append(writes, FileWrite{
+			Path:     change.Path,
+			Content:  change.OldContent,
+			Expected: change.NewContent,
+		})
+	}
+
+	if err := pr.fileManager.WriteFilesAtomic(writes); err != nil {
+		return nil, err
+	}
+
+	pr.undoStack = pr.undoStack[:len(pr.undoStack)-1]
+	return &batch, nil
+}
+
+// pushBatch records a batch on the undo stack
+func (pr *ProjectReplacer) pushBatch(batch ReplaceBatch) {
+	pr.undoStack = append(pr.undoStack, batch)
+	if len(pr.undoStack) > pr.maxUndo {
+		pr.undoStack = pr.undoStack[1:]
+	}
+}
+
+// computeLineOffsets returns the byte offset of the start of every line
+func computeLineOffsets(lines []string) []int {
+	offsets := make([]int, len(lines))
+	offset := 0
+	for i, line := range lines {
+		offsets[i] = offset
+		offset += len(line) + 1 // +1 for newline
+	}
+	return offsets
+}
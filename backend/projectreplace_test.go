@@ -0,0 +1,216 @@
+package backend
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// searchForReplace runs a project search and returns its results
+func searchForReplace(t *testing.T, root, pattern string, options SearchOptions) []FileSearchResult {
+	t.Helper()
+	ps := NewProjectSearcher()
+	projectOptions := ps.GetOptions()
+	projectOptions.SearchOptions = options
+	ps.SetOptions(projectOptions)
+
+	results := make([]FileSearchResult, 0)
+	if _, err := ps.Search(context.Background(), root, pattern, func(result FileSearchResult) {
+		results = append(results, result)
+	}); err != nil {
+		t.Fatalf("Search failed: %v", err)
+	}
+	return results
+}
+
+// readTestFile reads a file relative to root
+func readTestFile(t *testing.T, root, name string) string {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join(root, filepath.FromSlash(name)))
+	if err != nil {
+		t.Fatalf("Failed to read %s: %v", name, err)
+	}
+	return string(data)
+}
+
+func TestProjectReplacer_PreviewAndApply(t *testing.T) {
+	root := t.TempDir()
+	writeTestTree(t, root, map[string]string{
+		"a.go":   "userId := 1\nfmt.Println(userId)\n",
+		"b/c.go": "return userId\n",
+		"d.txt":  "no match\n",
+	})
+
+	options := SearchOptions{CaseSensitive: true}
+	results := searchForReplace(t, root, "userId", options)
+
+	pr := NewProjectReplacer(nil)
+	replacements, err := pr.Preview(results, "userId", "accountId", options)
+	if err != nil {
+		t.Fatalf("Preview failed: %v", err)
+	}
+	if len(replacements) != 2 {
+		t.Fatalf("Expected 2 files, got %d", len(replacements))
+	}
+	if replacements[0].RelPath != "a.go" || len(replacements[0].Edits) != 2 {
+		t.Errorf("Unexpected first replacement: %s with %d edits", replacements[0].RelPath, len(replacements[0].Edits))
+	}
+
+	// Preview must not touch the files
+	if readTestFile(t, root, "a.go") != "userId := 1\nfmt.Println(userId)\n" {
+		t.Error("Preview should not modify files")
+	}
+
+	// Uncheck the second edit in a.go
+	replacements[0].Edits[1].Enabled = false
+
+	batch, err := pr.Apply(replacements, "userId", "accountId")
+	if err != nil {
+		t.Fatalf("Apply failed: %v", err)
+	}
+	if len(batch.Files) != 2 {
+		t.Errorf("Expected 2 changed files in batch, got %d", len(batch.Files))
+	}
+
+	if got := readTestFile(t, root, "a.go"); got != "accountId := 1\nfmt.Println(userId)\n" {
+		t.Errorf("Unexpected a.go content: %q", got)
+	}
+	if got := readTestFile(t, root, "b/c.go"); got != "return accountId\n" {
+		t.Errorf("Unexpected b/c.go content: %q", got)
+	}
+}
+
+func TestProjectReplacer_UndoBatch(t *testing.T) {
+	root := t.TempDir()
+	writeTestTree(t, root, map[string]string{
+		"a.txt": "foo bar foo\n",
+		"b.txt": "foo\n",
+	})
+
+	options := SearchOptions{}
+	results := searchForReplace(t, root, "foo", options)
+
+	pr := NewProjectReplacer(nil)
+	replacements, err := pr.Preview(results, "foo", "baz", options)
+	if err != nil {
+		t.Fatalf("Preview failed: %v", err)
+	}
+	if _, err := pr.Apply(replacements, "foo", "baz"); err != nil {
+		t.Fatalf("Apply failed: %v", err)
+	}
+	if !pr.CanUndo() {
+		t.Fatal("Expected batch to be undoable")
+	}
+
+	if _, err := pr.UndoLastBatch(); err != nil {
+		t.Fatalf("Undo failed: %v", err)
+	}
+	if got := readTestFile(t, root, "a.txt"); got != "foo bar foo\n" {
+		t.Errorf("Undo did not restore a.txt: %q", got)
+	}
+	if got := readTestFile(t, root, "b.txt"); got != "foo\n" {
+		t.Errorf("Undo did not restore b.txt: %q", got)
+	}
+	if pr.CanUndo() {
+		t.Error("Undo stack should be empty")
+	}
+}
+
+func TestProjectReplacer_Conflicts(t *testing.T) {
+	root := t.TempDir()
+	writeTestTree(t, root, map[string]string{
+		"a.txt": "foo\n",
+		"b.txt": "foo\n",
+	})
+
+	options := SearchOptions{}
+	results := searchForReplace(t, root, "foo", options)
+
+	pr := NewProjectReplacer(nil)
+	replacements, err := pr.Preview(results, "foo", "bar", options)
+	if err != nil {
+		t.Fatalf("Preview failed: %v", err)
+	}
+
+	// A file changed after the preview aborts the whole batch
+	writeTestTree(t, root, map[string]string{"b.txt": "foo changed\n"})
+	if _, err := pr.Apply(replacements, "foo", "bar"); err == nil {
+		t.Fatal("Expected Apply to fail for a file modified since preview")
+	}
+	if got := readTestFile(t, root, "a.txt"); got != "foo\n" {
+		t.Errorf("No file should be written when Apply fails, a.txt is %q", got)
+	}
+
+	// A file changed after Apply blocks the undo
+	results = searchForReplace(t, root, "foo", options)
+	replacements, _ = pr.Preview(results, "foo", "bar", options)
+	if _, err := pr.Apply(replacements, "foo", "bar"); err != nil {
+		t.Fatalf("Apply failed: %v", err)
+	}
+	writeTestTree(t, root, map[string]string{"a.txt": "edited\n"})
+	if _, err := pr.UndoLastBatch(); err == nil {
+		t.Error("Expected undo to fail for a file modified since apply")
+	}
+	if !pr.CanUndo() {
+		t.Error("Failed undo should keep the batch on the stack")
+	}
+}
+
+func TestProjectReplacer_RegexReplacement(t *testing.T) {
+	root := t.TempDir()
+	writeTestTree(t, root, map[string]string{
+		"a.txt": "key=value\nname=goeditor\n",
+	})
+
+	options := SearchOptions{RegularExpression: true}
+	results := searchForReplace(t, root, `(\w+)=(\w+)`, options)
+
+	pr := NewProjectReplacer(nil)
+	replacements, err := pr.Preview(results, `(\w+)=(\w+)`, "$2=$1", options)
+	if err != nil {
+		t.Fatalf("Preview failed: %v", err)
+	}
+	if got := replacements[0].NewContent(); got != "value=key\ngoeditor=name\n" {
+		t.Errorf("Unexpected regex replacement: %q", got)
+	}
+}
+
+func TestFileReplacement_Diff(t *testing.T) {
+	fr := FileReplacement{
+		OriginalContent: "one\ntwo foo\nthree\nfour foo foo\n",
+		Enabled:         true,
+	}
+	sm := NewSearchManager()
+	lines := strings.Split(fr.OriginalContent, "\n")
+	offsets := computeLineOffsets(lines)
+	for _, match := range sm.Find(fr.OriginalContent, "foo") {
+		fr.Edits = append(fr.Edits, ReplacementEdit{
+			LineMatch:   LineMatch{Match: match},
+			Replacement: "bar",
+			Enabled:     true,
+			offset:      offsets[match.Start.Line-1] + match.Start.Column - 1,
+		})
+	}
+
+	hunks := fr.Diff()
+	if len(hunks) != 2 {
+		t.Fatalf("Expected 2 hunks, got %d", len(hunks))
+	}
+	if hunks[0].OldStart != 2 || hunks[0].OldLines[0] != "two foo" || hunks[0].NewLines[0] != "two bar" {
+		t.Errorf("Unexpected first hunk: %+v", hunks[0])
+	}
+	if hunks[1].OldStart != 4 || hunks[1].NewLines[0] != "four bar bar" {
+		t.Errorf("Unexpected second hunk: %+v", hunks[1])
+	}
+
+	// Disabling the whole file removes all hunks
+	fr.Enabled = false
+	if len(fr.Diff()) != 0 {
+		t.Error("Disabled file should have no hunks")
+	}
+	if fr.NewContent() != fr.OriginalContent {
+		t.Error("Disabled file should keep its content")
+	}
+}
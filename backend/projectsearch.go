@@ -0,0 +1,367 @@
+package backend
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"unicode/utf8"
+)
+
+const (
+	// defaultMaxSearchFileSize skips files larger than this many bytes
+	defaultMaxSearchFileSize = 4 * 1024 * 1024
+	// binarySniffLength is how many leading bytes are inspected for binary content
+	binarySniffLength = 8000
+)
+
+// ProjectSearchOptions defines options for searching across a directory
+type ProjectSearchOptions struct {
+	SearchOptions
+	Include          []string `json:"include"`          // Glob patterns a file must match (any)
+	Exclude          []string `json:"exclude"`          // Glob patterns that exclude files and directories
+	RespectGitignore bool     `json:"respectGitignore"` // Skip paths ignored by .gitignore files
+	MaxWorkers       int      `json:"maxWorkers"`       // Number of files searched concurrently
+	MaxFileSize      int64    `json:"maxFileSize"`      // Larger files are skipped
+}
+
+// LineMatch is a match within a file together with its line preview
+type LineMatch struct {
+	Match
+	LineText string `json:"lineText"`
+}
+
+// FileSearchResult holds all matches found in a single file
+type FileSearchResult struct {
+	Path    string      `json:"path"`    // Absolute path
+	RelPath string      `json:"relPath"` // Slash-separated path relative to the search root
+	Matches []LineMatch `json:"matches"`
+}
+
+// ProjectSearchSummary describes a completed project search
+type ProjectSearchSummary struct {
+	FilesSearched int `json:"filesSearched"`
+	FilesMatched  int `json:"filesMatched"`
+	FilesSkipped  int `json:"filesSkipped"`
+	MatchCount    int `json:"matchCount"`
+}
+
+// ProjectSearcher searches files under a directory tree
+type ProjectSearcher struct {
+	mu      sync.Mutex // Guards options, as searches run in the background
+	options ProjectSearchOptions
+}
+
+// DefaultProjectSearchOptions returns the default project search options
+func DefaultProjectSearchOptions() ProjectSearchOptions {
+	return ProjectSearchOptions{
+		SearchOptions: SearchOptions{
+			CaseSensitive:     false,
+			WholeWord:         false,
+			RegularExpression: false,
+			WrapAround:        true,
+			MultiLine:         true,
+		},
+		Include:          []string{},
+		Exclude:          []string{".git", "node_modules"},
+		RespectGitignore: true,
+		MaxWorkers:       runtime.NumCPU(),
+		MaxFileSize:      defaultMaxSearchFileSize,
+	}
+}
+
+// NewProjectSearcher creates a new project searcher with default options
+func NewProjectSearcher() *ProjectSearcher {
+	return &ProjectSearcher{
+		options: DefaultProjectSearchOptions(),
+	}
+}
+
+// SetOptions updates the project search options
+func (ps *ProjectSearcher) SetOptions(options ProjectSearchOptions) {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+	ps.options = options.clone()
+}
+
+// GetOptions returns a copy of the current project search options
+func (ps *ProjectSearcher) GetOptions() ProjectSearchOptions {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+	return ps.options.clone()
+}
+
+// clone returns a copy of the options that shares no slices with o
+func (o ProjectSearchOptions) clone() ProjectSearchOptions {
+	o.Include = append([]string{}, o.Include...)
+	o.Exclude = append([]string{}, o.Exclude...)
+	return o
+}
+
+// Search walks root and searches every eligible file for pattern using a
+// bounded pool of workers. onResult is called once for every file that
+// contains matches; calls are serialized but happen in no particular file
+// order. Search blocks until the walk finishes or ctx is cancelled.
+func (ps *ProjectSearcher) Search(ctx context.Context, root, pattern string, onResult func(FileSearchResult)) (ProjectSearchSummary, error) {
+	var summary ProjectSearchSummary
+	options := ps.GetOptions()
+
+	if pattern == "" {
+		return summary, fmt.Errorf("search pattern cannot be empty")
+	}
+	if err := ValidateSearchPattern(pattern, options.SearchOptions); err != nil {
+		return summary, err
+	}
+
+	info, err := os.Stat(root)
+	if err != nil {
+		return summary, &FileError{Operation: "搜索", Path: root, Err: err}
+	}
+	if !info.IsDir() {
+		return summary, &FileError{Operation: "搜索", Path: root, Err: fmt.Errorf("not a directory")}
+	}
+
+	workers := options.MaxWorkers
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+	maxSize := options.MaxFileSize
+	if maxSize <= 0 {
+		maxSize = defaultMaxSearchFileSize
+	}
+
+	paths := make(chan string, workers*4)
+	results := make(chan FileSearchResult, workers*4)
+
+	var searched, skipped int64
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			sm := NewSearchManager()
+			sm.SetOptions(options.SearchOptions)
+
+			for filePath := range paths {
+				if ctx.Err() != nil {
+					continue
+				}
+				result, ok := searchFile(ctx, sm, root, filePath, pattern, maxSize)
+				if !ok {
+					atomic.AddInt64(&skipped, 1)
+					continue
+				}
+				atomic.AddInt64(&searched, 1)
+				if len(result.Matches) > 0 {
+					select {
+					case results <- result:
+					case <-ctx.Done():
+					}
+				}
+			}
+		}()
+	}
+
+	// Walk the tree and feed the workers
+	walkErr := make(chan error, 1)
+	go func() {
+		defer close(paths)
+		walkErr <- ps.walk(ctx, root, options, paths)
+	}()
+
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	for result := range results {
+		summary.FilesMatched++
+		summary.MatchCount += len(result.Matches)
+		if onResult != nil {
+			onResult(result)
+		}
+	}
+
+	summary.FilesSearched = int(atomic.LoadInt64(&searched))
+	summary.FilesSkipped = int(atomic.LoadInt64(&skipped))
+
+	if err := <-walkErr; err != nil {
+		return summary, err
+	}
+	return summary, ctx.Err()
+}
+
+// walk sends every file under root that passes the filters to paths
+func (ps *ProjectSearcher) walk(ctx context.Context, root string, options ProjectSearchOptions, paths chan<- string) error {
+	ignore := NewIgnoreMatcher()
+	if options.RespectGitignore {
+		ignore.LoadDir(root, "")
+	}
+
+	return filepath.WalkDir(root, func(filePath string, d fs.DirEntry, err error) error {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return ctxErr
+		}
+		if err != nil {
+			if d != nil && d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if filePath == root {
+			return nil
+		}
+
+		rel, err := filepath.Rel(root, filePath)
+		if err != nil {
+			return nil
+		}
+		rel = filepath.ToSlash(rel)
+
+		if d.IsDir() {
+			if matchesAnyGlob(options.Exclude, rel) {
+				return filepath.SkipDir
+			}
+			if options.RespectGitignore {
+				if ignore.IsIgnored(rel, true) {
+					return filepath.SkipDir
+				}
+				ignore.LoadDir(filePath, rel)
+			}
+			return nil
+		}
+
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		if matchesAnyGlob(options.Exclude, rel) {
+			return nil
+		}
+		if len(options.Include) > 0 && !matchesAnyGlob(options.Include, rel) {
+			return nil
+		}
+		if options.RespectGitignore && ignore.IsIgnored(rel, false) {
+			return nil
+		}
+
+		select {
+		case paths <- filePath:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		return nil
+	})
+}
+
+// searchFile searches a single file. It returns false if the file was skipped.
+func searchFile(ctx context.Context, sm *SearchManager, root, filePath, pattern string, maxSize int64) (FileSearchResult, bool) {
+	info, err := os.Stat(filePath)
+	if err != nil || info.Size() > maxSize {
+		return FileSearchResult{}, false
+	}
+
+	data, err := os.ReadFile(filePath)
+	if err != nil || IsBinaryContent(data) {
+		return FileSearchResult{}, false
+	}
+
+	text := string(data)
+	matches, err := sm.FindContext(ctx, text, pattern)
+	if err != nil {
+		return FileSearchResult{}, false
+	}
+
+	rel, _ := filepath.Rel(root, filePath)
+	result := FileSearchResult{
+		Path:    filePath,
+		RelPath: filepath.ToSlash(rel),
+		Matches: make([]LineMatch, 0, len(matches)),
+	}
+	if len(matches) == 0 {
+		return result, true
+	}
+
+	lines := strings.Split(text, "\n")
+	for _, match := range matches {
+		lineText := ""
+		if match.Start.Line-1 < len(lines) {
+			lineText = strings.TrimRight(lines[match.Start.Line-1], "\r")
+		}
+		result.Matches = append(result.Matches, LineMatch{
+			Match:    match,
+			LineText: lineText,
+		})
+	}
+
+	return result, true
+}
+
+// IsBinaryContent reports whether data looks like binary rather than text
+func IsBinaryContent(data []byte) bool {
+	sniff := data
+	if len(sniff) > binarySniffLength {
+		sniff = sniff[:binarySniffLength]
+	}
+	if bytes.IndexByte(sniff, 0) != -1 {
+		return true
+	}
+
+	// Trim a possibly truncated rune at the end before validating
+	for i := 0; i < utf8.UTFMax && len(sniff) > 0 && !utf8.Valid(sniff); i++ {
+		sniff = sniff[:len(sniff)-1]
+	}
+	return !utf8.Valid(sniff)
+}
+
+// ValidateSearchPattern checks that a regular expression pattern compiles
+func ValidateSearchPattern(pattern string, options SearchOptions) error {
+	sm := NewSearchManager()
+	sm.SetOptions(options)
+	return sm.ValidatePattern(pattern)
+}
+
+// matchesAnyGlob reports whether rel matches any of the glob patterns.
+// Patterns without a "/" are also matched against every path segment, so
+// "*.go" matches "ui/editor.go" and "testdata" matches "pkg/testdata/a.txt".
+func matchesAnyGlob(patterns []string, rel string) bool {
+	for _, pattern := range patterns {
+		pattern = strings.TrimSpace(filepath.ToSlash(pattern))
+		if pattern == "" {
+			continue
+		}
+		pattern = strings.TrimPrefix(strings.TrimSuffix(pattern, "/"), "./")
+
+		if MatchGlob(pattern, rel) {
+			return true
+		}
+		if !strings.Contains(pattern, "/") {
+			if MatchGlob(pattern, path.Base(rel)) {
+				return true
+			}
+			for _, segment := range strings.Split(path.Dir(rel), "/") {
+				if segment != "." && MatchGlob(pattern, segment) {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// ParseGlobList splits a comma-separated list of glob patterns
+func ParseGlobList(text string) []string {
+	patterns := make([]string, 0)
+	for _, part := range strings.Split(text, ",") {
+		if part = strings.TrimSpace(part); part != "" {
+			patterns = append(patterns, part)
+		}
+	}
+	return patterns
+}
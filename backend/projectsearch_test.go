@@ -0,0 +1,253 @@
+package backend
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+)
+
+// writeTestTree creates files under root from a map of relative path to content
+func writeTestTree(t *testing.T, root string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write file: %v", err)
+		}
+	}
+}
+
+// collectProjectSearch runs a search and returns the matched relative paths in order
+func collectProjectSearch(t *testing.T, ps *ProjectSearcher, root, pattern string) ([]string, ProjectSearchSummary) {
+	t.Helper()
+	paths := make([]string, 0)
+	summary, err := ps.Search(context.Background(), root, pattern, func(result FileSearchResult) {
+		paths = append(paths, result.RelPath)
+	})
+	if err != nil {
+		t.Fatalf("Search failed: %v", err)
+	}
+	sort.Strings(paths)
+	return paths, summary
+}
+
+func TestProjectSearcher_Search(t *testing.T) {
+	root := t.TempDir()
+	writeTestTree(t, root, map[string]string{
+		"main.go":           "package main\n\nfunc main() {\n\tTODO()\n}\n",
+		"ui/editor.go":      "package ui\n// TODO: cursor\n// todo: selection\n",
+		"ui/editor_test.go": "package ui\n// TODO: tests\n",
+		"README.md":         "nothing here\n",
+	})
+
+	ps := NewProjectSearcher()
+	paths, summary := collectProjectSearch(t, ps, root, "TODO")
+
+	expected := []string{"main.go", "ui/editor.go", "ui/editor_test.go"}
+	if len(paths) != len(expected) {
+		t.Fatalf("Expected %v, got %v", expected, paths)
+	}
+	for i := range expected {
+		if paths[i] != expected[i] {
+			t.Errorf("Expected %s, got %s", expected[i], paths[i])
+		}
+	}
+	if summary.MatchCount != 4 {
+		t.Errorf("Expected 4 matches, got %d", summary.MatchCount)
+	}
+	if summary.FilesSearched != 4 {
+		t.Errorf("Expected 4 files searched, got %d", summary.FilesSearched)
+	}
+}
+
+func TestProjectSearcher_LinePreview(t *testing.T) {
+	root := t.TempDir()
+	writeTestTree(t, root, map[string]string{
+		"a.txt": "first line\r\nsecond needle line\r\n",
+	})
+
+	ps := NewProjectSearcher()
+	var results []FileSearchResult
+	_, err := ps.Search(context.Background(), root, "needle", func(result FileSearchResult) {
+		results = append(results, result)
+	})
+	if err != nil {
+		t.Fatalf("Search failed: %v", err)
+	}
+
+	if len(results) != 1 || len(results[0].Matches) != 1 {
+		t.Fatalf("Expected a single match, got %v", results)
+	}
+	match := results[0].Matches[0]
+	if match.Start.Line != 2 || match.Start.Column != 8 {
+		t.Errorf("Unexpected match position: %+v", match.Start)
+	}
+	if match.LineText != "second needle line" {
+		t.Errorf("Unexpected line preview: %q", match.LineText)
+	}
+	if results[0].Path != filepath.Join(root, "a.txt") {
+		t.Errorf("Unexpected path: %s", results[0].Path)
+	}
+}
+
+func TestProjectSearcher_Filters(t *testing.T) {
+	root := t.TempDir()
+	writeTestTree(t, root, map[string]string{
+		".gitignore":        "*.gen.go\nbuild/\n",
+		"main.go":           "needle",
+		"types.gen.go":      "needle",
+		"build/out.go":      "needle",
+		"ui/editor.go":      "needle",
+		"ui/editor_test.go": "needle",
+		"docs/notes.txt":    "needle",
+		"pkg/.gitignore":    "local.go\n",
+		"pkg/local.go":      "needle",
+		"image.png":         "needle\x00\x01\x02",
+	})
+
+	ps := NewProjectSearcher()
+	options := ps.GetOptions()
+	options.Include = []string{"*.go"}
+	options.Exclude = []string{"*_test.go"}
+	ps.SetOptions(options)
+
+	paths, summary := collectProjectSearch(t, ps, root, "needle")
+	expected := []string{"main.go", "ui/editor.go"}
+	if len(paths) != len(expected) || paths[0] != expected[0] || paths[1] != expected[1] {
+		t.Errorf("Expected %v, got %v", expected, paths)
+	}
+	if summary.FilesSkipped != 0 {
+		t.Errorf("Expected no skipped files, got %d", summary.FilesSkipped)
+	}
+
+	// Without filters or .gitignore, only the binary file is left out
+	options.Include = nil
+	options.Exclude = nil
+	options.RespectGitignore = false
+	ps.SetOptions(options)
+
+	paths, summary = collectProjectSearch(t, ps, root, "needle")
+	if len(paths) != 7 {
+		t.Errorf("Expected 7 files, got %d: %v", len(paths), paths)
+	}
+	if summary.FilesSkipped != 1 {
+		t.Errorf("Expected the binary file to be skipped, got %d skipped", summary.FilesSkipped)
+	}
+}
+
+func TestProjectSearcher_Options(t *testing.T) {
+	root := t.TempDir()
+	writeTestTree(t, root, map[string]string{
+		"a.go": "Value value values\nval := 42\n",
+	})
+
+	ps := NewProjectSearcher()
+	options := ps.GetOptions()
+	options.CaseSensitive = true
+	options.WholeWord = true
+	ps.SetOptions(options)
+
+	_, summary := collectProjectSearch(t, ps, root, "value")
+	if summary.MatchCount != 1 {
+		t.Errorf("Case sensitive whole word: expected 1 match, got %d", summary.MatchCount)
+	}
+
+	options.CaseSensitive = false
+	options.WholeWord = false
+	options.RegularExpression = true
+	ps.SetOptions(options)
+
+	_, summary = collectProjectSearch(t, ps, root, `\d+`)
+	if summary.MatchCount != 1 {
+		t.Errorf("Regex: expected 1 match, got %d", summary.MatchCount)
+	}
+}
+
+func TestProjectSearcher_OptionsAreCopied(t *testing.T) {
+	ps := NewProjectSearcher()
+	options := ps.GetOptions()
+	options.Include = []string{"*.go"}
+	ps.SetOptions(options)
+
+	// Changing the caller's slices must not reach a search in progress
+	options.Include[0] = "*.txt"
+	if got := ps.GetOptions().Include[0]; got != "*.go" {
+		t.Errorf("Expected stored include *.go, got %q", got)
+	}
+
+	got := ps.GetOptions()
+	got.Exclude[0] = "changed"
+	if ps.GetOptions().Exclude[0] == "changed" {
+		t.Error("Expected GetOptions to return a copy")
+	}
+}
+
+func TestProjectSearcher_Errors(t *testing.T) {
+	ps := NewProjectSearcher()
+	root := t.TempDir()
+
+	if _, err := ps.Search(context.Background(), root, "", nil); err == nil {
+		t.Error("Expected error for empty pattern")
+	}
+	if _, err := ps.Search(context.Background(), filepath.Join(root, "missing"), "x", nil); err == nil {
+		t.Error("Expected error for missing directory")
+	}
+
+	options := ps.GetOptions()
+	options.RegularExpression = true
+	ps.SetOptions(options)
+	if _, err := ps.Search(context.Background(), root, "(", nil); err == nil {
+		t.Error("Expected error for invalid regular expression")
+	}
+}
+
+func TestProjectSearcher_Cancel(t *testing.T) {
+	root := t.TempDir()
+	files := make(map[string]string)
+	for i := 0; i < 50; i++ {
+		files[filepath.Join("dir", string(rune('a'+i%26)), string(rune('a'+i/26))+".txt")] = "needle"
+	}
+	writeTestTree(t, root, files)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	ps := NewProjectSearcher()
+	_, err := ps.Search(ctx, root, "needle", nil)
+	if err != context.Canceled {
+		t.Errorf("Expected context.Canceled, got %v", err)
+	}
+}
+
+func TestIsBinaryContent(t *testing.T) {
+	if IsBinaryContent([]byte("plain text\nwith lines")) {
+		t.Error("Plain text should not be binary")
+	}
+	if IsBinaryContent([]byte("héllo wörld")) {
+		t.Error("UTF-8 text should not be binary")
+	}
+	if !IsBinaryContent([]byte{0x89, 'P', 'N', 'G', 0x00}) {
+		t.Error("Content with NUL bytes should be binary")
+	}
+	if !IsBinaryContent([]byte{0xff, 0xfe, 0xfd, 'a', 'b'}) {
+		t.Error("Invalid UTF-8 should be binary")
+	}
+}
+
+func TestParseGlobList(t *testing.T) {
+	patterns := ParseGlobList(" *.go, ,ui/**,  *.md ")
+	expected := []string{"*.go", "ui/**", "*.md"}
+	if len(patterns) != len(expected) {
+		t.Fatalf("Expected %v, got %v", expected, patterns)
+	}
+	for i := range expected {
+		if patterns[i] != expected[i] {
+			t.Errorf("Expected %s, got %s", expected[i], patterns[i])
+		}
+	}
+}
@@ -0,0 +1,456 @@
+package backend
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// Fuzzy scoring weights
+const (
+	scoreMatch            = 16
+	scoreConsecutiveBonus = 24
+	scoreBoundaryBonus    = 30
+	scoreBasenameBonus    = 20
+	scoreFirstCharBonus   = 12
+	scoreCaseBonus        = 2
+	scoreGapPenalty       = 2
+	scoreLengthPenalty    = 1
+)
+
+// defaultMaxIndexedFiles caps the number of files kept in a FileIndex
+const defaultMaxIndexedFiles = 50000
+
+// FileMatch represents a file matched by a fuzzy query
+type FileMatch struct {
+	Path      string `json:"path"`      // Path relative to the index root
+	Score     int    `json:"score"`     // Higher scores rank first
+	Positions []int  `json:"positions"` // Byte offsets in Path of the matched characters
+}
+
+// QuickOpenQuery is a parsed quick-open query such as "editor.go:42"
+type QuickOpenQuery struct {
+	Path string `json:"path"`
+	Line int    `json:"line"` // 0 when no line was given
+}
+
+var quickOpenLinePattern = regexp.MustCompile(`^(.*?):(\d+)(?::\d+)?$`)
+
+// ParseQuickOpenQuery splits a query of the form "path:line" into its parts
+func ParseQuickOpenQuery(query string) QuickOpenQuery {
+	query = strings.TrimSpace(query)
+	if m := quickOpenLinePattern.FindStringSubmatch(query); m != nil {
+		if line, err := strconv.Atoi(m[2]); err == nil && line > 0 {
+			return QuickOpenQuery{Path: strings.TrimSpace(m[1]), Line: line}
+		}
+	}
+	return QuickOpenQuery{Path: query}
+}
+
+// FileIndex indexes the files under a workspace root for quick-open
+type FileIndex struct {
+	root        string
+	files       []string
+	ignoredDirs map[string]bool
+	maxFiles    int
+	indexing    bool
+	rescan      bool // Start was called again while indexing
+	mu          sync.RWMutex
+
+	// OnIndexed is called from the indexing goroutine when a scan completes
+	OnIndexed func(count int)
+}
+
+// NewFileIndex creates a new file index for the given workspace root
+func NewFileIndex(root string) *FileIndex {
+	return &FileIndex{
+		root:     root,
+		files:    make([]string, 0),
+		maxFiles: defaultMaxIndexedFiles,
+		ignoredDirs: map[string]bool{
+			".git":         true,
+			".hg":          true,
+			".svn":         true,
+			".idea":        true,
+			".vscode":      true,
+			"node_modules": true,
+			"vendor":       true,
+			"output":       true,
+		},
+	}
+}
+
+// GetRoot returns the workspace root of the index
+func (fi *FileIndex) GetRoot() string {
+	fi.mu.RLock()
+	defer fi.mu.RUnlock()
+	return fi.root
+}
+
+// SetRoot changes the workspace root and clears the current index
+func (fi *FileIndex) SetRoot(root string) {
+	fi.mu.Lock()
+	defer fi.mu.Unlock()
+	fi.root = root
+	fi.files = make([]string, 0)
+}
+
+// AddIgnoredDir adds a directory name that is skipped while indexing
+func (fi *FileIndex) AddIgnoredDir(name string) {
+	fi.mu.Lock()
+	defer fi.mu.Unlock()
+	fi.ignoredDirs[name] = true
+}
+
+// IsIndexing returns true while a background scan is running
+func (fi *FileIndex) IsIndexing() bool {
+	fi.mu.RLock()
+	defer fi.mu.RUnlock()
+	return fi.indexing
+}
+
+// Start rebuilds the index in a background goroutine. Calling Start while
+// a scan is running, for example after SetRoot, scans again once it ends,
+// so the index always ends up built for the latest root.
+func (fi *FileIndex) Start() {
+	fi.mu.Lock()
+	if fi.indexing {
+		fi.rescan = true
+		fi.mu.Unlock()
+		return
+	}
+	fi.indexing = true
+	fi.mu.Unlock()
+
+	go func() {
+		for {
+			count := fi.Refresh()
+
+			fi.mu.Lock()
+			if fi.rescan {
+				fi.rescan = false
+				fi.mu.Unlock()
+				continue
+			}
+			fi.indexing = false
+			onIndexed := fi.OnIndexed
+			fi.mu.Unlock()
+
+			if onIndexed != nil {
+				onIndexed(count)
+			}
+			return
+		}
+	}()
+}
+
+// Refresh rebuilds the index synchronously and returns the number of files found
+func (fi *FileIndex) Refresh() int {
+	fi.mu.RLock()
+	root := fi.root
+	maxFiles := fi.maxFiles
+	ignored := make(map[string]bool, len(fi.ignoredDirs))
+	for name := range fi.ignoredDirs {
+		ignored[name] = true
+	}
+	fi.mu.RUnlock()
+
+	files := make([]string, 0)
+	if root != "" {
+		_ = filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				// Skip unreadable entries but keep walking
+				if d != nil && d.IsDir() {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if d.IsDir() {
+				if path != root && ignored[d.Name()] {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if !d.Type().IsRegular() {
+				return nil
+			}
+			rel, err := filepath.Rel(root, path)
+			if err != nil {
+				return nil
+			}
+			files = append(files, filepath.ToSlash(rel))
+			if len(files) >= maxFiles {
+				return filepath.SkipAll
+			}
+			return nil
+		})
+	}
+	sort.Strings(files)
+
+	fi.mu.Lock()
+	if fi.root == root {
+		fi.files = files
+	}
+	fi.mu.Unlock()
+
+	return len(files)
+}
+
+// workspaceMarkers are the entries that mark the root of a project
+var workspaceMarkers = []string{".git", ".hg", ".svn", "go.mod", "package.json", "Cargo.toml", "pyproject.toml"}
+
+// FindWorkspaceRoot returns the root of the project containing path: the
+// nearest directory at or above it with a version control directory or a
+// project file. It returns the directory of path itself if there is none.
+func FindWorkspaceRoot(path string) string {
+	dir := path
+	if info, err := os.Stat(path); err != nil || !info.IsDir() {
+		dir = filepath.Dir(path)
+	}
+	dir = filepath.Clean(dir)
+
+	for candidate := dir; ; {
+		for _, marker := range workspaceMarkers {
+			if _, err := os.Stat(filepath.Join(candidate, marker)); err == nil {
+				return candidate
+			}
+		}
+		parent := filepath.Dir(candidate)
+		if parent == candidate {
+			return dir
+		}
+		candidate = parent
+	}
+}
+
+// Contains reports whether path is inside the workspace root of the index
+func (fi *FileIndex) Contains(path string) bool {
+	root := fi.GetRoot()
+	if root == "" {
+		return false
+	}
+	rel, err := filepath.Rel(root, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// GetFiles returns a copy of the indexed relative paths
+func (fi *FileIndex) GetFiles() []string {
+	fi.mu.RLock()
+	defer fi.mu.RUnlock()
+	files := make([]string, len(fi.files))
+	copy(files, fi.files)
+	return files
+}
+
+// GetFileCount returns the number of indexed files
+func (fi *FileIndex) GetFileCount() int {
+	fi.mu.RLock()
+	defer fi.mu.RUnlock()
+	return len(fi.files)
+}
+
+// AbsPath converts an indexed relative path to an absolute path
+func (fi *FileIndex) AbsPath(rel string) string {
+	return filepath.Join(fi.GetRoot(), filepath.FromSlash(rel))
+}
+
+// Search fuzzy-matches query against the indexed paths and returns the best
+// matches in ranked order. A limit of zero or less returns all matches.
+func (fi *FileIndex) Search(query string, limit int) []FileMatch {
+	return FuzzyFind(fi.GetFiles(), query, limit)
+}
+
+// FuzzyFind ranks candidates by how well they fuzzy-match query
+func FuzzyFind(candidates []string, query string, limit int) []FileMatch {
+	query = strings.TrimSpace(query)
+	results := make([]FileMatch, 0)
+
+	for _, candidate := range candidates {
+		score, positions, ok := FuzzyScore(query, candidate)
+		if !ok {
+			continue
+		}
+		results = append(results, FileMatch{
+			Path:      candidate,
+			Score:     score,
+			Positions: positions,
+		})
+	}
+
+	sort.SliceStable(results, func(i, j int) bool {
+		if results[i].Score != results[j].Score {
+			return results[i].Score > results[j].Score
+		}
+		if len(results[i].Path) != len(results[j].Path) {
+			return len(results[i].Path) < len(results[j].Path)
+		}
+		return results[i].Path < results[j].Path
+	})
+
+	if limit > 0 && len(results) > limit {
+		results = results[:limit]
+	}
+	return results
+}
+
+// FuzzyScore scores how well pattern matches candidate as an ordered
+// subsequence. Matching is case-insensitive and spaces in the pattern are
+// ignored. Consecutive characters, characters at word boundaries and
+// characters inside the base name score higher; gaps and long candidates
+// score lower. It returns the byte offsets of the matched characters and
+// false if pattern is not a subsequence of candidate.
+func FuzzyScore(pattern, candidate string) (int, []int, bool) {
+	pattern = strings.ReplaceAll(pattern, " ", "")
+	if pattern == "" {
+		return 0, []int{}, true
+	}
+
+	// Normalize path separators so "ui\editor" matches "ui/editor"
+	pattern = strings.ReplaceAll(pattern, "\\", "/")
+
+	pat := []rune(pattern)
+	cand := []rune(candidate)
+	if len(pat) > len(cand) {
+		return 0, nil, false
+	}
+
+	// Byte offset of every rune, used to report positions
+	offsets := make([]int, len(cand))
+	offset := 0
+	for i, r := range cand {
+		offsets[i] = offset
+		offset += len(string(r))
+	}
+
+	baseStart := strings.LastIndex(candidate, "/") + 1
+
+	// Quick rejection: pattern must be a subsequence of candidate
+	if !isFuzzySubsequence(pat, cand) {
+		return 0, nil, false
+	}
+
+	// Dynamic programming over (pattern index, candidate index) keeping the
+	// best score for a match that ends with pat[i] matched at cand[j].
+	// A gap between consecutive matched characters is penalized linearly,
+	// so the best predecessor can be tracked as a running maximum of
+	// best[i-1][k] + gap*k.
+	const negInf = -1 << 30
+	n, m := len(pat), len(cand)
+	best := make([][]int, n)
+	from := make([][]int, n)
+	for i := range best {
+		best[i] = make([]int, m)
+		from[i] = make([]int, m)
+		for j := range best[i] {
+			best[i][j] = negInf
+			from[i][j] = -1
+		}
+	}
+
+	for i := 0; i < n; i++ {
+		runMax, runIdx := negInf, -1
+		for j := i; j < m; j++ {
+			if i > 0 && best[i-1][j-1] > negInf {
+				if v := best[i-1][j-1] + scoreGapPenalty*(j-1); v > runMax {
+					runMax, runIdx = v, j-1
+				}
+			}
+			if !fuzzyRuneEqual(pat[i], cand[j]) {
+				continue
+			}
+
+			charScore := scoreMatch + fuzzyPositionBonus(cand, j, offsets[j] >= baseStart)
+			if pat[i] == cand[j] {
+				charScore += scoreCaseBonus
+			}
+
+			if i == 0 {
+				score := charScore - scoreGapPenalty*j
+				if j == 0 {
+					score += scoreFirstCharBonus
+				}
+				best[i][j] = score
+				continue
+			}
+			if runIdx < 0 {
+				continue
+			}
+
+			// Jump from the best earlier position, or extend a consecutive run
+			score := runMax - scoreGapPenalty*(j-1) + charScore
+			source := runIdx
+			if prev := best[i-1][j-1]; prev > negInf {
+				if consecutive := prev + charScore + scoreConsecutiveBonus; consecutive >= score {
+					score, source = consecutive, j-1
+				}
+			}
+			best[i][j] = score
+			from[i][j] = source
+		}
+	}
+
+	// Pick the best end position for the last pattern character
+	endScore, endIdx := negInf, -1
+	for j := n - 1; j < m; j++ {
+		if best[n-1][j] > endScore {
+			endScore, endIdx = best[n-1][j], j
+		}
+	}
+	if endIdx < 0 {
+		return 0, nil, false
+	}
+
+	positions := make([]int, n)
+	for i, j := n-1, endIdx; i >= 0; i-- {
+		positions[i] = offsets[j]
+		j = from[i][j]
+	}
+
+	return endScore - scoreLengthPenalty*len(cand), positions, true
+}
+
+// isFuzzySubsequence checks if pat appears in cand in order
+func isFuzzySubsequence(pat, cand []rune) bool {
+	i := 0
+	for _, r := range cand {
+		if i < len(pat) && fuzzyRuneEqual(pat[i], r) {
+			i++
+		}
+	}
+	return i == len(pat)
+}
+
+// fuzzyRuneEqual compares two runes case-insensitively
+func fuzzyRuneEqual(a, b rune) bool {
+	return a == b || unicode.ToLower(a) == unicode.ToLower(b)
+}
+
+// fuzzyPositionBonus returns the bonus for matching at index j of cand
+func fuzzyPositionBonus(cand []rune, j int, inBasename bool) int {
+	bonus := 0
+	if inBasename {
+		bonus += scoreBasenameBonus
+	}
+	if j == 0 {
+		return bonus + scoreBoundaryBonus
+	}
+
+	prev, cur := cand[j-1], cand[j]
+	switch {
+	case prev == '/' || prev == '\\':
+		bonus += scoreBoundaryBonus
+	case prev == '_' || prev == '-' || prev == '.' || prev == ' ':
+		bonus += scoreBoundaryBonus - 6
+	case unicode.IsLower(prev) && unicode.IsUpper(cur):
+		bonus += scoreBoundaryBonus - 6
+	case !unicode.IsDigit(prev) && unicode.IsDigit(cur):
+		bonus += scoreBoundaryBonus - 12
+	}
+	return bonus
+}
@@ -0,0 +1,225 @@
+package backend
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFuzzyScore_Subsequence(t *testing.T) {
+	if _, _, ok := FuzzyScore("edgo", "ui/editor.go"); !ok {
+		t.Error("Expected 'edgo' to match 'ui/editor.go'")
+	}
+	if _, _, ok := FuzzyScore("xyz", "ui/editor.go"); ok {
+		t.Error("Expected 'xyz' not to match 'ui/editor.go'")
+	}
+	if _, _, ok := FuzzyScore("EDITOR", "ui/editor.go"); !ok {
+		t.Error("Expected matching to be case-insensitive")
+	}
+}
+
+func TestFuzzyScore_Positions(t *testing.T) {
+	_, positions, ok := FuzzyScore("edit", "ui/editor.go")
+	if !ok {
+		t.Fatal("Expected match")
+	}
+
+	expected := []int{3, 4, 5, 6}
+	if len(positions) != len(expected) {
+		t.Fatalf("Expected %d positions, got %d", len(expected), len(positions))
+	}
+	for i, pos := range expected {
+		if positions[i] != pos {
+			t.Errorf("Position %d: expected %d, got %d", i, pos, positions[i])
+		}
+	}
+}
+
+func TestFuzzyFind_Ranking(t *testing.T) {
+	candidates := []string{
+		"backend/history_test.go",
+		"ui/editor_history_test.go",
+		"ui/editor.go",
+		"backend/search.go",
+		"ui/dialogs/replace.go",
+	}
+
+	tests := []struct {
+		query    string
+		expected string
+	}{
+		{"editor", "ui/editor.go"},
+		{"search", "backend/search.go"},
+		{"replace", "ui/dialogs/replace.go"},
+		{"bhist", "backend/history_test.go"},
+		{"ui/ed", "ui/editor.go"},
+	}
+
+	for _, tt := range tests {
+		results := FuzzyFind(candidates, tt.query, 0)
+		if len(results) == 0 {
+			t.Errorf("Query %q: expected results, got none", tt.query)
+			continue
+		}
+		if results[0].Path != tt.expected {
+			t.Errorf("Query %q: expected %q first, got %q", tt.query, tt.expected, results[0].Path)
+		}
+	}
+}
+
+func TestFuzzyFind_Limit(t *testing.T) {
+	candidates := []string{"a.go", "ab.go", "abc.go", "abcd.go"}
+
+	results := FuzzyFind(candidates, "a", 2)
+	if len(results) != 2 {
+		t.Errorf("Expected 2 results, got %d", len(results))
+	}
+
+	results = FuzzyFind(candidates, "", 0)
+	if len(results) != len(candidates) {
+		t.Errorf("Empty query: expected %d results, got %d", len(candidates), len(results))
+	}
+}
+
+func TestParseQuickOpenQuery(t *testing.T) {
+	tests := []struct {
+		input string
+		path  string
+		line  int
+	}{
+		{"editor.go", "editor.go", 0},
+		{"editor.go:42", "editor.go", 42},
+		{"editor.go:42:7", "editor.go", 42},
+		{":10", "", 10},
+		{"  main.go:3  ", "main.go", 3},
+		{"editor.go:", "editor.go:", 0},
+		{"editor.go:0", "editor.go:0", 0},
+	}
+
+	for _, tt := range tests {
+		query := ParseQuickOpenQuery(tt.input)
+		if query.Path != tt.path || query.Line != tt.line {
+			t.Errorf("ParseQuickOpenQuery(%q) = {%q, %d}, expected {%q, %d}",
+				tt.input, query.Path, query.Line, tt.path, tt.line)
+		}
+	}
+}
+
+func TestFileIndex_Refresh(t *testing.T) {
+	root := t.TempDir()
+
+	files := []string{
+		"main.go",
+		"ui/editor.go",
+		"backend/search.go",
+		".git/config",
+		"node_modules/pkg/index.js",
+	}
+	for _, file := range files {
+		path := filepath.Join(root, filepath.FromSlash(file))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(path, []byte("content"), 0644); err != nil {
+			t.Fatalf("Failed to write file: %v", err)
+		}
+	}
+
+	index := NewFileIndex(root)
+	count := index.Refresh()
+	if count != 3 {
+		t.Errorf("Expected 3 indexed files, got %d: %v", count, index.GetFiles())
+	}
+
+	results := index.Search("search", 1)
+	if len(results) != 1 || results[0].Path != "backend/search.go" {
+		t.Errorf("Expected backend/search.go, got %v", results)
+	}
+
+	abs := index.AbsPath("ui/editor.go")
+	if abs != filepath.Join(root, "ui", "editor.go") {
+		t.Errorf("Unexpected absolute path: %s", abs)
+	}
+}
+
+func TestFileIndex_Start(t *testing.T) {
+	root := t.TempDir()
+	if err := os.WriteFile(filepath.Join(root, "a.txt"), []byte("a"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+
+	index := NewFileIndex(root)
+	done := make(chan int, 1)
+	index.OnIndexed = func(count int) {
+		done <- count
+	}
+	index.Start()
+
+	if count := <-done; count != 1 {
+		t.Errorf("Expected 1 indexed file, got %d", count)
+	}
+	if index.IsIndexing() {
+		t.Error("Index should not be indexing after completion")
+	}
+}
+
+func TestFileIndex_SetRootWhileIndexing(t *testing.T) {
+	first, second := t.TempDir(), t.TempDir()
+	for _, name := range []string{"a.txt", "b.txt"} {
+		if err := os.WriteFile(filepath.Join(first, name), []byte("a"), 0644); err != nil {
+			t.Fatalf("Failed to write file: %v", err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(second, "c.txt"), []byte("c"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+
+	index := NewFileIndex(first)
+	done := make(chan int, 2)
+	index.OnIndexed = func(count int) {
+		done <- count
+	}
+	index.Start()
+	index.SetRoot(second)
+	index.Start()
+
+	// Wait for the scan that leaves the index idle
+	for range done {
+		if !index.IsIndexing() {
+			break
+		}
+	}
+	if files := index.GetFiles(); len(files) != 1 || files[0] != "c.txt" {
+		t.Errorf("Expected the new root to be indexed, got %v", files)
+	}
+}
+
+func TestFindWorkspaceRoot(t *testing.T) {
+	root := t.TempDir()
+	nested := filepath.Join(root, "cmd", "tool")
+	if err := os.MkdirAll(nested, 0755); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(root, "go.mod"), []byte("module x\n"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	file := filepath.Join(nested, "main.go")
+	if err := os.WriteFile(file, []byte("package main\n"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+
+	if found := FindWorkspaceRoot(file); found != root {
+		t.Errorf("Expected %s for a file, got %s", root, found)
+	}
+	if found := FindWorkspaceRoot(nested); found != root {
+		t.Errorf("Expected %s for a folder, got %s", root, found)
+	}
+
+	index := NewFileIndex(root)
+	if !index.Contains(file) {
+		t.Errorf("Expected %s to be inside %s", file, root)
+	}
+	if index.Contains(filepath.Dir(root)) {
+		t.Errorf("Expected the parent of %s to be outside it", root)
+	}
+}
@@ -0,0 +1,264 @@
+package backend
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"regexp/syntax"
+	"strconv"
+	"time"
+	"unicode/utf8"
+
+	"github.com/dlclark/regexp2"
+	regexp2syntax "github.com/dlclark/regexp2/syntax"
+)
+
+// RegexEngine selects the regular expression implementation used for search
+type RegexEngine string
+
+const (
+	// RegexEngineRE2 uses Go's regexp package: linear time, no lookarounds
+	// or backreferences. It is the default.
+	RegexEngineRE2 RegexEngine = "re2"
+	// RegexEnginePCRE uses regexp2 for .NET/PCRE syntax, including
+	// lookaheads, lookbehinds and backreferences
+	RegexEnginePCRE RegexEngine = "pcre"
+)
+
+// pcreMatchTimeout bounds a single PCRE search so catastrophic backtracking
+// cannot hang the editor
+const pcreMatchTimeout = 5 * time.Second
+
+// maxErrorScanLength limits how much of a pattern is scanned to locate an error
+const maxErrorScanLength = 512
+
+// PatternError describes an invalid regular expression
+type PatternError struct {
+	Pattern  string `json:"pattern"`
+	Position int    `json:"position"` // 1-based rune position of the error in Pattern
+	Message  string `json:"message"`
+}
+
+// Error implements the error interface
+func (e *PatternError) Error() string {
+	return fmt.Sprintf("invalid regular expression at position %d: %s", e.Position, e.Message)
+}
+
+// searchRegexp is a compiled pattern of either engine
+type searchRegexp interface {
+	// eachSubmatchIndex calls fn with the byte offsets of each match in text
+	// and of its capture groups, with -1 for groups that did not match. It
+	// stops after n matches unless n is negative, when fn returns false, or
+	// when ctx is cancelled.
+	eachSubmatchIndex(ctx context.Context, text string, n int, fn func(loc []int) bool) error
+	// captureGroups describes the groups in the order of the offsets
+	captureGroups() []CaptureGroup
+}
+
+// re2Regexp wraps a Go regexp
+type re2Regexp struct {
+	re *regexp.Regexp
+}
+
+// eachSubmatchIndex cannot be interrupted while the regexp scans, but RE2
+// runs in linear time and n bounds the matches it collects
+func (r re2Regexp) eachSubmatchIndex(ctx context.Context, text string, n int, fn func(loc []int) bool) error {
+	for _, loc := range r.re.FindAllStringSubmatchIndex(text, n) {
+		if !fn(loc) {
+			break
+		}
+	}
+	return nil
+}
+
+func (r re2Regexp) captureGroups() []CaptureGroup {
+	names := r.re.SubexpNames()
+	groups := make([]CaptureGroup, len(names))
+	for i, name := range names {
+		groups[i] = CaptureGroup{Number: i, Name: name}
+	}
+	return groups
+}
+
+// pcreRegexp wraps a regexp2 expression. regexp2 reports rune offsets, which
+// are converted to byte offsets so matches line up with Go strings.
+type pcreRegexp struct {
+	re *regexp2.Regexp
+}
+
+func (r pcreRegexp) eachSubmatchIndex(ctx context.Context, text string, n int, fn func(loc []int) bool) error {
+	offsets := runeOffsets{text: text}
+
+	m, err := r.re.FindStringMatch(text)
+	for found := 0; m != nil && err == nil && found != n; found++ {
+		if ctx.Err() != nil {
+			return nil
+		}
+		groups := m.Groups()
+		loc := make([]int, 0, 2*len(groups))
+		for _, group := range groups {
+			if len(group.Captures) == 0 {
+				loc = append(loc, -1, -1)
+				continue
+			}
+			start := offsets.byteOffset(group.Index)
+			end := offsets.byteOffset(group.Index + group.Length)
+			loc = append(loc, start, end)
+		}
+		if !fn(loc) {
+			return nil
+		}
+		m, err = r.re.FindNextMatch(m)
+	}
+	return err
+}
+
+func (r pcreRegexp) captureGroups() []CaptureGroup {
+	numbers := r.re.GetGroupNumbers()
+	groups := make([]CaptureGroup, len(numbers))
+	for i, number := range numbers {
+		groups[i] = CaptureGroup{Number: number}
+		if name := r.re.GroupNameFromNumber(number); name != strconv.Itoa(number) {
+			groups[i].Name = name
+		}
+	}
+	return groups
+}
+
+// runeOffsets converts rune offsets in text to byte offsets. It walks from
+// the previous conversion, so nearby offsets are cheap to convert.
+type runeOffsets struct {
+	text      string
+	runeIndex int
+	byteIndex int
+}
+
+// byteOffset returns the byte offset of the rune at runeIndex
+func (o *runeOffsets) byteOffset(runeIndex int) int {
+	for o.runeIndex > runeIndex && o.byteIndex > 0 {
+		_, size := utf8.DecodeLastRuneInString(o.text[:o.byteIndex])
+		o.byteIndex -= size
+		o.runeIndex--
+	}
+	for o.runeIndex < runeIndex && o.byteIndex < len(o.text) {
+		_, size := utf8.DecodeRuneInString(o.text[o.byteIndex:])
+		o.byteIndex += size
+		o.runeIndex++
+	}
+	return o.byteIndex
+}
+
+// compileSearchRegexp compiles pattern for the engine and flags in options.
+// Syntax errors are returned as *PatternError.
+func compileSearchRegexp(pattern string, options SearchOptions) (searchRegexp, error) {
+	expr := pattern
+	if options.WholeWord {
+		expr = `\b(?:` + expr + `)\b`
+	}
+
+	if options.Engine == RegexEnginePCRE {
+		re, err := regexp2.Compile(expr, pcreOptions(options))
+		if err != nil {
+			return nil, newPatternError(pattern, options)
+		}
+		re.MatchTimeout = pcreMatchTimeout
+		return pcreRegexp{re: re}, nil
+	}
+
+	re, err := regexp.Compile(re2Flags(options) + expr)
+	if err != nil {
+		return nil, newPatternError(pattern, options)
+	}
+	return re2Regexp{re: re}, nil
+}
+
+// re2Flags returns the inline flag group for options
+func re2Flags(options SearchOptions) string {
+	var flags string
+	if !options.CaseSensitive {
+		flags += "i"
+	}
+	if options.MultiLine {
+		flags += "m"
+	}
+	if options.DotAll {
+		flags += "s"
+	}
+	if flags == "" {
+		return ""
+	}
+	return "(?" + flags + ")"
+}
+
+// pcreOptions returns the regexp2 options for options
+func pcreOptions(options SearchOptions) regexp2.RegexOptions {
+	var opts regexp2.RegexOptions
+	if !options.CaseSensitive {
+		opts |= regexp2.IgnoreCase
+	}
+	if options.MultiLine {
+		opts |= regexp2.Multiline
+	}
+	if options.DotAll {
+		opts |= regexp2.Singleline
+	}
+	return opts
+}
+
+// compileError compiles pattern on its own and returns the engine's error
+// code and message, or ok false if it compiles
+func compileError(pattern string, options SearchOptions) (code, message string, ok bool) {
+	if options.Engine == RegexEnginePCRE {
+		_, err := regexp2.Compile(pattern, pcreOptions(options))
+		if err == nil {
+			return "", "", false
+		}
+		if serr, isSyntax := err.(*regexp2syntax.Error); isSyntax {
+			msg := serr.Code.String()
+			if len(serr.Args) > 0 {
+				msg = fmt.Sprintf(msg, serr.Args...)
+			}
+			return string(serr.Code), msg, true
+		}
+		return err.Error(), err.Error(), true
+	}
+
+	_, err := syntax.Parse(pattern, syntax.Perl)
+	if err == nil {
+		return "", "", false
+	}
+	if serr, isSyntax := err.(*syntax.Error); isSyntax {
+		return string(serr.Code), serr.Code.String(), true
+	}
+	return err.Error(), err.Error(), true
+}
+
+// newPatternError locates the error in pattern by finding the shortest
+// prefix that fails with the same error, which points at the offending
+// character or at the unclosed group or class
+func newPatternError(pattern string, options SearchOptions) *PatternError {
+	code, message, ok := compileError(pattern, options)
+	if !ok {
+		// The pattern only fails once wrapped or flagged
+		return &PatternError{Pattern: pattern, Position: 1, Message: "invalid pattern"}
+	}
+
+	position := utf8.RuneCountInString(pattern)
+	runes := 0
+	for i := range pattern {
+		if runes >= maxErrorScanLength {
+			break
+		}
+		runes++
+		_, size := utf8.DecodeRuneInString(pattern[i:])
+		if prefixCode, _, failed := compileError(pattern[:i+size], options); failed && prefixCode == code {
+			position = runes
+			break
+		}
+	}
+	if position < 1 {
+		position = 1
+	}
+
+	return &PatternError{Pattern: pattern, Position: position, Message: message}
+}
@@ -0,0 +1,121 @@
+package backend
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestSearchManager_PCRELookaround(t *testing.T) {
+	sm := NewSearchManager()
+	sm.SetOptions(SearchOptions{RegularExpression: true, CaseSensitive: true, Engine: RegexEnginePCRE})
+	text := "price: 10USD 20EUR 30USD"
+
+	matches := sm.Find(text, `\d+(?=USD)`)
+	if len(matches) != 2 {
+		t.Fatalf("Expected 2 matches, got %d", len(matches))
+	}
+	if matches[1].Text != "30" || matches[1].Start.Column != 20 {
+		t.Errorf("Unexpected second match: %+v", matches[1])
+	}
+
+	matches = sm.Find(text, `(?<=\d)[A-Z]{3}`)
+	if len(matches) != 3 {
+		t.Errorf("Lookbehind: expected 3 matches, got %d", len(matches))
+	}
+}
+
+func TestSearchManager_PCREBackreference(t *testing.T) {
+	sm := NewSearchManager()
+	sm.SetOptions(SearchOptions{RegularExpression: true, Engine: RegexEnginePCRE})
+	text := "this is is a test\nno no repeats here"
+
+	matches := sm.Find(text, `\b(\w+) \1\b`)
+	if len(matches) != 2 {
+		t.Fatalf("Expected 2 matches, got %d", len(matches))
+	}
+	if matches[1].Start != (Position{Line: 2, Column: 1}) || matches[1].Text != "no no" {
+		t.Errorf("Unexpected second match: %+v", matches[1])
+	}
+
+	// Backreferences are not supported by RE2
+	sm.SetOptions(SearchOptions{RegularExpression: true})
+	if matches := sm.Find(text, `\b(\w+) \1\b`); len(matches) != 0 || sm.GetError() == nil {
+		t.Error("RE2 should reject backreferences with an error")
+	}
+}
+
+func TestSearchManager_PCREUnicodeOffsets(t *testing.T) {
+	sm := NewSearchManager()
+	sm.SetOptions(SearchOptions{RegularExpression: true, Engine: RegexEnginePCRE})
+	text := "héllo wörld\nnaïve wörld"
+
+	matches := sm.Find(text, `w.rld`)
+	if len(matches) != 2 {
+		t.Fatalf("Expected 2 matches, got %d", len(matches))
+	}
+	if matches[0].Text != "wörld" || matches[0].Start.Column != 8 {
+		t.Errorf("Unexpected first match: %+v", matches[0])
+	}
+	if matches[1].Text != "wörld" || matches[1].Start != (Position{Line: 2, Column: 8}) {
+		t.Errorf("Unexpected second match: %+v", matches[1])
+	}
+}
+
+func TestSearchManager_PCREReplace(t *testing.T) {
+	sm := NewSearchManager()
+	options := ReplaceOptions{
+		SearchOptions: SearchOptions{RegularExpression: true, Engine: RegexEnginePCRE},
+		ReplaceAll:    true,
+	}
+
+	result, count := sm.Replace("a1 b2 c3", `(?<letter>[a-z])(\d)`, "$1${letter}", options)
+	if count != 3 {
+		t.Errorf("Expected 3 replacements, got %d", count)
+	}
+	if expected := "1a 2b 3c"; result != expected {
+		t.Errorf("Expected %q, got %q", expected, result)
+	}
+}
+
+func TestSearchManager_PatternError(t *testing.T) {
+	tests := []struct {
+		engine   RegexEngine
+		pattern  string
+		position int
+	}{
+		{RegexEngineRE2, `ab(cd`, 3},
+		{RegexEngineRE2, `abc[de`, 4},
+		{RegexEngineRE2, `a**`, 3},
+		{RegexEnginePCRE, `ab(cd`, 3},
+		{RegexEnginePCRE, `x\qy`, 3},
+		{RegexEnginePCRE, `wörld)`, 6},
+	}
+
+	for _, tt := range tests {
+		sm := NewSearchManager()
+		sm.SetOptions(SearchOptions{RegularExpression: true, Engine: tt.engine})
+
+		if matches := sm.Find("abcd", tt.pattern); len(matches) != 0 {
+			t.Errorf("%s %q: expected no matches", tt.engine, tt.pattern)
+		}
+
+		var patternErr *PatternError
+		if !errors.As(sm.GetError(), &patternErr) {
+			t.Errorf("%s %q: expected PatternError, got %v", tt.engine, tt.pattern, sm.GetError())
+			continue
+		}
+		if patternErr.Position != tt.position {
+			t.Errorf("%s %q: expected position %d, got %d (%s)", tt.engine, tt.pattern, tt.position, patternErr.Position, patternErr.Message)
+		}
+		if patternErr.Message == "" {
+			t.Errorf("%s %q: expected a message", tt.engine, tt.pattern)
+		}
+	}
+
+	sm := NewSearchManager()
+	sm.SetOptions(SearchOptions{RegularExpression: true})
+	sm.Find("abcd", "b")
+	if sm.GetError() != nil {
+		t.Errorf("Valid search should clear the error, got %v", sm.GetError())
+	}
+}
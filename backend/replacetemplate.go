@@ -0,0 +1,263 @@
+package backend
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// CaptureGroup describes a capture group of a compiled search pattern
+type CaptureGroup struct {
+	Number int    `json:"number"`
+	Name   string `json:"name,omitempty"` // Empty for unnamed groups
+}
+
+// caseMode is a case transform applied while expanding a template
+type caseMode int
+
+const (
+	caseNone caseMode = iota
+	caseUpper
+	caseLower
+)
+
+// templatePartKind identifies the parts of a parsed replacement template
+type templatePartKind int
+
+const (
+	partLiteral   templatePartKind = iota
+	partGroup                      // Insert a capture group
+	partCaseMode                   // \U, \L or \E
+	partCaseFirst                  // \u or \l
+)
+
+// templatePart is a single element of a parsed replacement template
+type templatePart struct {
+	kind  templatePartKind
+	text  string
+	group int // Index into the match groups
+	mode  caseMode
+}
+
+// ReplacementTemplate is a parsed regular expression replacement.
+//
+// Supported syntax:
+//
+//	$1 ${1}         numbered group ($0 is the whole match)
+//	$name ${name}   named group
+//	$$              a literal $
+//	\n \t \r \\     newline, tab, carriage return, backslash
+//	\U \L           uppercase or lowercase until \E
+//	\u \l           uppercase or lowercase the next character
+//	\E              end \U or \L
+type ReplacementTemplate struct {
+	source string
+	parts  []templatePart
+}
+
+// ParseReplacementTemplate parses replacement for a pattern with the given
+// capture groups. References to groups that do not exist expand to nothing,
+// as with regexp.Expand.
+func ParseReplacementTemplate(replacement string, groups []CaptureGroup) (*ReplacementTemplate, error) {
+	t := &ReplacementTemplate{source: replacement}
+	var literal strings.Builder
+
+	flush := func() {
+		if literal.Len() > 0 {
+			t.parts = append(t.parts, templatePart{kind: partLiteral, text: literal.String()})
+			literal.Reset()
+		}
+	}
+
+	for i := 0; i < len(replacement); {
+		c := replacement[i]
+		switch {
+		case c == '$' && i+1 < len(replacement):
+			ref, size, err := parseGroupReference(replacement, i)
+			if err != nil {
+				return nil, err
+			}
+			if ref == "$" {
+				literal.WriteByte('$')
+				i += size
+				continue
+			}
+			if ref == "" {
+				// Not a reference; keep the $
+				literal.WriteByte('$')
+				i++
+				continue
+			}
+			if index, ok := lookupGroup(ref, groups); ok {
+				flush()
+				t.parts = append(t.parts, templatePart{kind: partGroup, group: index})
+			}
+			i += size
+
+		case c == '\\' && i+1 < len(replacement):
+			next := replacement[i+1]
+			i += 2
+			switch next {
+			case 'n':
+				literal.WriteByte('\n')
+			case 't':
+				literal.WriteByte('\t')
+			case 'r':
+				literal.WriteByte('\r')
+			case '\\':
+				literal.WriteByte('\\')
+			case '$':
+				literal.WriteByte('$')
+			case 'U', 'L', 'E':
+				flush()
+				mode := caseNone
+				if next == 'U' {
+					mode = caseUpper
+				} else if next == 'L' {
+					mode = caseLower
+				}
+				t.parts = append(t.parts, templatePart{kind: partCaseMode, mode: mode})
+			case 'u', 'l':
+				flush()
+				mode := caseUpper
+				if next == 'l' {
+					mode = caseLower
+				}
+				t.parts = append(t.parts, templatePart{kind: partCaseFirst, mode: mode})
+			default:
+				// Unknown escapes are kept as written
+				literal.WriteByte('\\')
+				literal.WriteByte(next)
+			}
+
+		default:
+			literal.WriteByte(c)
+			i++
+		}
+	}
+	flush()
+
+	return t, nil
+}
+
+// literalReplacement returns a template that inserts text as written
+func literalReplacement(text string) *ReplacementTemplate {
+	t := &ReplacementTemplate{source: text}
+	if text != "" {
+		t.parts = []templatePart{{kind: partLiteral, text: text}}
+	}
+	return t
+}
+
+// parseGroupReference parses the reference starting with the $ at i. It
+// returns the group number or name, "$" for an escaped dollar, or "" if the
+// $ does not start a reference, and the number of bytes consumed.
+func parseGroupReference(s string, i int) (string, int, error) {
+	rest := s[i+1:]
+	switch {
+	case rest[0] == '$':
+		return "$", 2, nil
+	case rest[0] == '{':
+		end := strings.IndexByte(rest, '}')
+		if end == -1 {
+			return "", 0, fmt.Errorf("invalid replacement at position %d: unterminated ${", utf8.RuneCountInString(s[:i])+1)
+		}
+		name := rest[1:end]
+		if name == "" {
+			return "", 0, fmt.Errorf("invalid replacement at position %d: empty group name", utf8.RuneCountInString(s[:i])+1)
+		}
+		return name, end + 2, nil
+	case rest[0] >= '0' && rest[0] <= '9':
+		n := 1
+		for n < len(rest) && rest[n] >= '0' && rest[n] <= '9' {
+			n++
+		}
+		return rest[:n], n + 1, nil
+	}
+
+	// Names are letters, digits and underscores, not starting with a digit
+	n := 0
+	for n < len(rest) {
+		r, size := utf8.DecodeRuneInString(rest[n:])
+		if r != '_' && !unicode.IsLetter(r) && (n == 0 || !unicode.IsDigit(r)) {
+			break
+		}
+		n += size
+	}
+	if n == 0 {
+		return "", 0, nil
+	}
+	return rest[:n], n + 1, nil
+}
+
+// lookupGroup returns the index of the group referenced by number or name
+func lookupGroup(ref string, groups []CaptureGroup) (int, bool) {
+	if number, err := strconv.Atoi(ref); err == nil {
+		for i, group := range groups {
+			if group.Number == number {
+				return i, true
+			}
+		}
+		return 0, false
+	}
+	for i, group := range groups {
+		if group.Name == ref {
+			return i, true
+		}
+	}
+	return 0, false
+}
+
+// String returns the template source
+func (t *ReplacementTemplate) String() string {
+	return t.source
+}
+
+// Expand returns the replacement for a match with the given group values
+func (t *ReplacementTemplate) Expand(groups []string) string {
+	var sb strings.Builder
+	mode := caseNone
+	first := caseNone
+
+	write := func(text string) {
+		if text == "" {
+			return
+		}
+		if first != caseNone {
+			r, size := utf8.DecodeRuneInString(text)
+			sb.WriteString(applyCase(string(r), first))
+			text = text[size:]
+			first = caseNone
+		}
+		sb.WriteString(applyCase(text, mode))
+	}
+
+	for _, part := range t.parts {
+		switch part.kind {
+		case partLiteral:
+			write(part.text)
+		case partGroup:
+			if part.group < len(groups) {
+				write(groups[part.group])
+			}
+		case partCaseMode:
+			mode = part.mode
+		case partCaseFirst:
+			first = part.mode
+		}
+	}
+	return sb.String()
+}
+
+// applyCase applies a case transform to text
+func applyCase(text string, mode caseMode) string {
+	switch mode {
+	case caseUpper:
+		return strings.ToUpper(text)
+	case caseLower:
+		return strings.ToLower(text)
+	}
+	return text
+}
@@ -0,0 +1,157 @@
+package backend
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestReplacementTemplate_Expand(t *testing.T) {
+	groups := []CaptureGroup{{Number: 0}, {Number: 1}, {Number: 2, Name: "last"}}
+	values := []string{"john smith", "john", "smith"}
+
+	tests := []struct {
+		template string
+		expected string
+	}{
+		{"$2, $1", "smith, john"},
+		{"${last}_${1}", "smith_john"},
+		{"$last", "smith"},
+		{"$1x", "johnx"},
+		{"$$1", "$1"},
+		{`\$1`, "$1"},
+		{"cost: $", "cost: $"},
+		{`$1\n$2\t!`, "john\nsmith\t!"},
+		{`\\n`, `\n`},
+		{`\U$1\E $2`, "JOHN smith"},
+		{`\u$1 \u$2`, "John Smith"},
+		{`\L\uJOHN $2`, "John smith"},
+		{`\U$0`, "JOHN SMITH"},
+		{`\q`, `\q`},
+	}
+
+	for _, tt := range tests {
+		template, err := ParseReplacementTemplate(tt.template, groups)
+		if err != nil {
+			t.Errorf("%q: unexpected error: %v", tt.template, err)
+			continue
+		}
+		if result := template.Expand(values); result != tt.expected {
+			t.Errorf("%q: expected %q, got %q", tt.template, tt.expected, result)
+		}
+	}
+}
+
+func TestReplacementTemplate_UnknownGroups(t *testing.T) {
+	groups := []CaptureGroup{{Number: 0}, {Number: 1, Name: "café"}}
+	values := []string{"au lait", "noir"}
+
+	tests := []struct {
+		template string
+		expected string
+	}{
+		{"[$2]", "[]"},
+		{"a ${name}b", "a b"},
+		{"$nope!", "!"},
+		{"$café!", "noir!"},
+		{"${café}s", "noirs"},
+		{"$é", ""},
+		{"$-", "$-"},
+	}
+
+	for _, tt := range tests {
+		template, err := ParseReplacementTemplate(tt.template, groups)
+		if err != nil {
+			t.Errorf("%q: unexpected error: %v", tt.template, err)
+			continue
+		}
+		if result := template.Expand(values); result != tt.expected {
+			t.Errorf("%q: expected %q, got %q", tt.template, tt.expected, result)
+		}
+	}
+}
+
+func TestReplacementTemplate_Errors(t *testing.T) {
+	groups := []CaptureGroup{{Number: 0}, {Number: 1, Name: "word"}}
+
+	tests := []struct {
+		template string
+		message  string
+	}{
+		{"${1", "position 1: unterminated ${"},
+		{"${}", "position 1: empty group name"},
+	}
+
+	for _, tt := range tests {
+		_, err := ParseReplacementTemplate(tt.template, groups)
+		if err == nil {
+			t.Errorf("%q: expected an error", tt.template)
+			continue
+		}
+		if !strings.Contains(err.Error(), tt.message) {
+			t.Errorf("%q: expected error containing %q, got %q", tt.template, tt.message, err.Error())
+		}
+	}
+}
+
+func TestSearchManager_ReplaceKeepsContext(t *testing.T) {
+	sm := NewSearchManager()
+	options := ReplaceOptions{
+		SearchOptions: SearchOptions{RegularExpression: true, CaseSensitive: true, MultiLine: true},
+		ReplaceAll:    true,
+	}
+
+	// Anchors are evaluated against the whole text, not the matched text
+	result, count := sm.Replace("foo bar\nfoo baz", `^foo (\w+)$`, `\U$1`, options)
+	if count != 2 || result != "BAR\nBAZ" {
+		t.Errorf("Expected 2 replacements giving %q, got %d giving %q", "BAR\nBAZ", count, result)
+	}
+
+	// Lookarounds see the text around the match
+	options.Engine = RegexEnginePCRE
+	result, count = sm.Replace("a1 b2 c", `([a-z])(?=\d)`, "[$1]", options)
+	if count != 2 || result != "[a]1 [b]2 c" {
+		t.Errorf("Expected 2 replacements giving %q, got %d giving %q", "[a]1 [b]2 c", count, result)
+	}
+}
+
+func TestSearchManager_ReplaceNamedGroups(t *testing.T) {
+	sm := NewSearchManager()
+	options := ReplaceOptions{
+		SearchOptions: SearchOptions{RegularExpression: true},
+		ReplaceAll:    true,
+	}
+
+	result, _ := sm.Replace("key=value", `(?P<key>\w+)=(?P<value>\w+)`, `${value}: \u$key`, options)
+	if result != "value: Key" {
+		t.Errorf("RE2: expected %q, got %q", "value: Key", result)
+	}
+
+	options.Engine = RegexEnginePCRE
+	result, _ = sm.Replace("key=value", `(?<key>\w+)=(?<value>\w+)`, `${value}: \u$key`, options)
+	if result != "value: Key" {
+		t.Errorf("PCRE: expected %q, got %q", "value: Key", result)
+	}
+}
+
+func TestSearchManager_ReplaceTemplateError(t *testing.T) {
+	sm := NewSearchManager()
+	options := ReplaceOptions{
+		SearchOptions: SearchOptions{RegularExpression: true},
+		ReplaceAll:    true,
+	}
+
+	result, count := sm.Replace("abc", `(b)`, "${2", options)
+	if count != 0 || result != "abc" {
+		t.Errorf("Expected no replacement, got %d giving %q", count, result)
+	}
+	if sm.GetError() == nil {
+		t.Error("Expected the template error to be reported")
+	}
+
+	// Literal replacements are inserted as written
+	options.RegularExpression = false
+	result, _ = sm.Replace("abc", "b", `$1\n`, options)
+	if result != `a$1\nc` {
+		t.Errorf("Expected literal replacement, got %q", result)
+	}
+}
@@ -1,7 +1,9 @@
 package backend
 
 import (
+	"context"
 	"regexp"
+	"sort"
 	"strings"
 	"unicode"
 )
@@ -13,6 +15,11 @@ type SearchManager struct {
 	currentIndex   int
 	options        SearchOptions
 	lastSearchText string
+	lastError      error
+	groups         []CaptureGroup
+	scopeProvider  ScopeProvider
+	matchLimit     int
+	truncated      bool
 }
 
 // Match represents a search match result
@@ -20,20 +27,28 @@ type Match struct {
 	Start    Position `json:"start"`
 	End      Position `json:"end"`
 	Text     string   `json:"text"`
+	Groups   []string `json:"groups,omitempty"` // Capture group values of regex matches, starting with the whole match
 }
 
 // SearchOptions defines search behavior options
 type SearchOptions struct {
-	CaseSensitive     bool `json:"caseSensitive"`
-	WholeWord         bool `json:"wholeWord"`
-	RegularExpression bool `json:"regularExpression"`
-	WrapAround        bool `json:"wrapAround"`
+	CaseSensitive     bool        `json:"caseSensitive"`
+	WholeWord         bool        `json:"wholeWord"`
+	RegularExpression bool        `json:"regularExpression"`
+	WrapAround        bool        `json:"wrapAround"`
+	MultiLine         bool        `json:"multiLine"` // ^ and $ match at line boundaries (?m)
+	DotAll            bool        `json:"dotAll"`    // . also matches newlines (?s)
+	Engine            RegexEngine `json:"engine,omitempty"`
+	Scope             SearchScope `json:"scope,omitempty"`
+	Structural        bool        `json:"structural,omitempty"` // The pattern is a Go expression matched against the syntax tree, see StructuralRule
 }
 
 // ReplaceOptions defines replace behavior options
 type ReplaceOptions struct {
 	SearchOptions
-	ReplaceAll bool `json:"replaceAll"`
+	ReplaceAll   bool `json:"replaceAll"`
+	PreserveCase bool `json:"preserveCase"` // Adapt the replacement to the casing of each match
+	Template     bool `json:"template"`     // The replacement is a Go text/template evaluated per match
 }
 
 // NewSearchManager creates a new search manager
@@ -41,11 +56,14 @@ func NewSearchManager() *SearchManager {
 	return &SearchManager{
 		matches:      make([]Match, 0),
 		currentIndex: -1,
+		matchLimit:   DefaultMatchLimit,
 		options: SearchOptions{
 			CaseSensitive:     false,
 			WholeWord:         false,
 			RegularExpression: false,
 			WrapAround:        true,
+			MultiLine:         true,
+			DotAll:            false,
 		},
 	}
 }
@@ -65,123 +83,221 @@ func (sm *SearchManager) GetOptions() SearchOptions {
 	return sm.options
 }
 
-// Find searches for a pattern in the given text and returns all matches
+// Find searches for a pattern in the given text and returns all matches, up
+// to the match limit
 func (sm *SearchManager) Find(text, pattern string) []Match {
-	if pattern == "" {
-		sm.matches = make([]Match, 0)
-		sm.currentIndex = -1
-		return sm.matches
-	}
-
-	sm.currentPattern = pattern
-	sm.lastSearchText = text
-	sm.matches = make([]Match, 0)
-	sm.currentIndex = -1
-
-	if sm.options.RegularExpression {
-		sm.findRegex(text, pattern)
-	} else {
-		sm.findLiteral(text, pattern)
-	}
-
-	return sm.matches
+	matches, _ := sm.FindContext(context.Background(), text, pattern)
+	return matches
 }
 
-// findLiteral performs literal string search
-func (sm *SearchManager) findLiteral(text, pattern string) {
+// findLiteral performs literal string search over the whole text, so
+// patterns containing newlines match across lines
+func (r *searchRun) findLiteral(text, pattern string) {
 	searchText := text
 	searchPattern := pattern
 
 	// Handle case sensitivity
-	if !sm.options.CaseSensitive {
+	if !r.options.CaseSensitive {
 		searchText = strings.ToLower(text)
 		searchPattern = strings.ToLower(pattern)
-	}
-
-	lines := strings.Split(text, "\n")
-	searchLines := strings.Split(searchText, "\n")
 
-	for lineNum, line := range searchLines {
-		startCol := 0
-		for {
-			index := strings.Index(line[startCol:], searchPattern)
-			if index == -1 {
-				break
+		// Lowercasing some runes changes their byte length, which would
+		// misalign offsets; fall back to a case-insensitive regexp
+		if len(searchText) != len(text) || len(searchPattern) != len(pattern) {
+			quoted := regexp.QuoteMeta(pattern)
+			if r.options.WholeWord {
+				quoted = `\b` + quoted + `\b`
 			}
+			r.result.Err = r.appendRegexMatches(text, re2Regexp{re: regexp.MustCompile("(?i)" + quoted)})
+			return
+		}
+	}
 
-			actualIndex := startCol + index
-			
-			// Check whole word option
-			if sm.options.WholeWord && !sm.isWholeWord(line, actualIndex, len(searchPattern)) {
-				startCol = actualIndex + 1
-				continue
-			}
+	startOffset := 0
+	for {
+		index := strings.Index(searchText[startOffset:], searchPattern)
+		if index == -1 {
+			break
+		}
 
-			// Create match using original text (preserve case)
-			originalLine := lines[lineNum]
-			matchText := originalLine[actualIndex : actualIndex+len(searchPattern)]
-			
-			match := Match{
-				Start: Position{Line: lineNum + 1, Column: actualIndex + 1},
-				End:   Position{Line: lineNum + 1, Column: actualIndex + len(searchPattern) + 1},
-				Text:  matchText,
-			}
-			sm.matches = append(sm.matches, match)
+		actualIndex := startOffset + index
+		startOffset = actualIndex + 1
+
+		// Check whole word option
+		if r.options.WholeWord && !isWholeWord(searchText, actualIndex, len(searchPattern)) {
+			continue
+		}
 
-			startCol = actualIndex + 1
+		// Create match using original text (preserve case)
+		if !r.add(r.lines.match(text, actualIndex, actualIndex+len(searchPattern))) {
+			break
 		}
 	}
 }
 
-// findRegex performs regular expression search
-func (sm *SearchManager) findRegex(text, pattern string) {
-	var flags string
-	if !sm.options.CaseSensitive {
-		flags = "(?i)"
+// findRegex performs regular expression search over the whole text. An
+// invalid pattern yields no matches and is reported by GetError.
+func (r *searchRun) findRegex(text, pattern string) {
+	regex, err := compileSearchRegexp(pattern, r.options)
+	if err != nil {
+		r.result.Err = err
+		return
 	}
-	
-	if sm.options.WholeWord {
-		pattern = `\b` + pattern + `\b`
+
+	r.result.Err = r.appendRegexMatches(text, regex)
+}
+
+// appendRegexMatches adds every match of regex in text with its groups
+func (r *searchRun) appendRegexMatches(text string, regex searchRegexp) error {
+	r.result.Groups = regex.captureGroups()
+
+	return regex.eachSubmatchIndex(r.ctx, text, r.regexLimit(), func(loc []int) bool {
+		match := r.lines.match(text, loc[0], loc[1])
+		match.Groups = make([]string, len(loc)/2)
+		for i := range match.Groups {
+			if start, end := loc[2*i], loc[2*i+1]; start >= 0 && end >= start {
+				match.Groups[i] = text[start:end]
+			}
+		}
+		return r.add(match)
+	})
+}
+
+// compileRegex compiles pattern with the engine and flags of the current options
+func (sm *SearchManager) compileRegex(pattern string) (searchRegexp, error) {
+	return compileSearchRegexp(pattern, sm.options)
+}
+
+// ValidatePattern checks that pattern compiles with the current options.
+// Literal patterns are always valid.
+func (sm *SearchManager) ValidatePattern(pattern string) error {
+	if sm.options.Structural {
+		_, err := NewStructuralRule(pattern, "")
+		return err
+	}
+	if !sm.options.RegularExpression {
+		return nil
+	}
+	_, err := sm.compileRegex(pattern)
+	return err
+}
+
+// GetCaptureGroups returns the capture groups of the last regex search
+func (sm *SearchManager) GetCaptureGroups() []CaptureGroup {
+	return sm.groups
+}
+
+// CompileReplacement parses a replacement for pattern with the current
+// options. Structural replacements are handled by compileReplacer. Regex
+// replacements are templates (see ReplacementTemplate); literal searches
+// replace with the text as written.
+func (sm *SearchManager) CompileReplacement(pattern, replacement string) (*ReplacementTemplate, error) {
+	if !sm.options.RegularExpression {
+		return literalReplacement(replacement), nil
 	}
 
-	regex, err := regexp.Compile(flags + pattern)
+	regex, err := sm.compileRegex(pattern)
 	if err != nil {
-		// Invalid regex, return empty matches
-		return
+		return nil, err
 	}
+	return ParseReplacementTemplate(replacement, regex.captureGroups())
+}
 
-	lines := strings.Split(text, "\n")
-	for lineNum, line := range lines {
-		matches := regex.FindAllStringIndex(line, -1)
-		for _, match := range matches {
-			start, end := match[0], match[1]
-			matchText := line[start:end]
-			
-			matchObj := Match{
-				Start: Position{Line: lineNum + 1, Column: start + 1},
-				End:   Position{Line: lineNum + 1, Column: end + 1},
-				Text:  matchText,
+// replacer computes the replacement for the match at index
+type replacer func(index int, match Match) (string, error)
+
+// compileReplacer prepares the replacement for pattern in the mode chosen by
+// options: a Go text/template, a $-group template for regex searches, or the
+// text as written. Preserve-case is applied on top of any mode.
+func (sm *SearchManager) compileReplacer(pattern, replacement string, options ReplaceOptions) (replacer, error) {
+	var expand replacer
+
+	if options.Template {
+		var groups []CaptureGroup
+		if sm.options.Structural {
+			rule, err := NewStructuralRule(pattern, "")
+			if err != nil {
+				return nil, err
 			}
-			sm.matches = append(sm.matches, matchObj)
+			groups = rule.captureGroups()
+		} else if sm.options.RegularExpression {
+			regex, err := sm.compileRegex(pattern)
+			if err != nil {
+				return nil, err
+			}
+			groups = regex.captureGroups()
+		}
+
+		tmpl, err := NewTemplateReplacement(replacement)
+		if err != nil {
+			return nil, err
+		}
+		expand = func(index int, match Match) (string, error) {
+			return tmpl.Execute(newTemplateMatchData(match, index, groups))
+		}
+	} else if sm.options.Structural {
+		var err error
+		if expand, err = sm.structuralReplacer(pattern, replacement); err != nil {
+			return nil, err
+		}
+	} else {
+		template, err := sm.CompileReplacement(pattern, replacement)
+		if err != nil {
+			return nil, err
+		}
+		expand = func(index int, match Match) (string, error) {
+			return template.Expand(match.Groups), nil
 		}
 	}
+
+	if !options.PreserveCase {
+		return expand, nil
+	}
+	return func(index int, match Match) (string, error) {
+		expanded, err := expand(index, match)
+		if err != nil {
+			return "", err
+		}
+		return PreserveCase(match.Text, expanded), nil
+	}, nil
+}
+
+// ValidateReplacement checks that a replacement can be used with pattern
+// and the replace options, without applying it
+func (sm *SearchManager) ValidateReplacement(pattern, replacement string, options ReplaceOptions) error {
+	_, err := sm.compileReplacer(pattern, replacement, options)
+	return err
+}
+
+// ExpandReplacement returns the text that would replace the match at index
+func (sm *SearchManager) ExpandReplacement(match Match, index int, pattern, replacement string, options ReplaceOptions) (string, error) {
+	expand, err := sm.compileReplacer(pattern, replacement, options)
+	if err != nil {
+		return "", err
+	}
+	return expand(index, match)
+}
+
+// GetError returns the error of the last search, such as an invalid pattern
+func (sm *SearchManager) GetError() error {
+	return sm.lastError
 }
 
 // isWholeWord checks if the match at the given position is a whole word
-func (sm *SearchManager) isWholeWord(line string, start, length int) bool {
+func isWholeWord(text string, start, length int) bool {
 	end := start + length
 
 	// Check character before match
 	if start > 0 {
-		prevChar := rune(line[start-1])
+		prevChar := rune(text[start-1])
 		if unicode.IsLetter(prevChar) || unicode.IsDigit(prevChar) || prevChar == '_' {
 			return false
 		}
 	}
 
 	// Check character after match
-	if end < len(line) {
-		nextChar := rune(line[end])
+	if end < len(text) {
+		nextChar := rune(text[end])
 		if unicode.IsLetter(nextChar) || unicode.IsDigit(nextChar) || nextChar == '_' {
 			return false
 		}
@@ -190,6 +306,11 @@ func (sm *SearchManager) isWholeWord(line string, start, length int) bool {
 	return true
 }
 
+// GetPattern returns the pattern of the last search
+func (sm *SearchManager) GetPattern() string {
+	return sm.currentPattern
+}
+
 // GetMatches returns all current matches
 func (sm *SearchManager) GetMatches() []Match {
 	return sm.matches
@@ -266,65 +387,66 @@ func (sm *SearchManager) Replace(text, pattern, replacement string, options Repl
 	sm.SetOptions(options.SearchOptions)
 	
 	if options.ReplaceAll {
-		return sm.replaceAll(text, pattern, replacement)
+		return sm.replaceAll(text, pattern, replacement, options)
 	} else {
-		return sm.replaceCurrent(text, pattern, replacement)
+		return sm.replaceCurrent(text, pattern, replacement, options)
 	}
 }
 
-// replaceAll replaces all occurrences of the pattern
-func (sm *SearchManager) replaceAll(text, pattern, replacement string) (string, int) {
-	// Find all matches first
-	matches := sm.Find(text, pattern)
+// replaceAll replaces all occurrences of the pattern. Every replacement is
+// computed before the text is changed, so an error leaves it untouched.
+func (sm *SearchManager) replaceAll(text, pattern, replacement string, options ReplaceOptions) (string, int) {
+	// Find all matches first, regardless of the match limit
+	matches, _ := sm.find(context.Background(), text, pattern, 0)
 	if len(matches) == 0 {
 		return text, 0
 	}
 
-	// Replace from end to beginning to maintain position accuracy
-	result := text
+	expand, err := sm.compileReplacer(pattern, replacement, options)
+	if err != nil {
+		sm.lastError = err
+		return text, 0
+	}
+
+	lines := newLineIndex(text)
+	var sb strings.Builder
+	last := 0
 	replacedCount := 0
 
-	for i := len(matches) - 1; i >= 0; i-- {
-		match := matches[i]
-		
-		// Convert positions to string indices
-		lines := strings.Split(result, "\n")
-		if match.Start.Line-1 >= len(lines) {
-			continue
-		}
-		
-		line := lines[match.Start.Line-1]
-		if match.Start.Column-1 >= len(line) || match.End.Column-1 > len(line) {
+	for i, match := range matches {
+		absoluteStart := lines.offset(match.Start)
+		absoluteEnd := absoluteStart + len(match.Text)
+
+		// Skip matches overlapping one already replaced
+		if absoluteStart < last || absoluteEnd > len(text) {
 			continue
 		}
 
-		// Calculate absolute position in text
-		absoluteStart := 0
-		for j := 0; j < match.Start.Line-1; j++ {
-			absoluteStart += len(lines[j]) + 1 // +1 for newline
+		expanded, err := expand(i, match)
+		if err != nil {
+			sm.lastError = err
+			return text, 0
 		}
-		absoluteStart += match.Start.Column - 1
 
-		absoluteEnd := absoluteStart + (match.End.Column - match.Start.Column)
+		sb.WriteString(text[last:absoluteStart])
+		sb.WriteString(expanded)
+		last = absoluteEnd
+		replacedCount++
+	}
+	sb.WriteString(text[last:])
 
-		// Perform replacement
-		if absoluteEnd <= len(result) {
-			actualReplacement := replacement
-			if sm.options.RegularExpression {
-				// For regex, we might need to handle capture groups
-				actualReplacement = sm.processRegexReplacement(match.Text, pattern, replacement)
-			}
-			
-			result = result[:absoluteStart] + actualReplacement + result[absoluteEnd:]
-			replacedCount++
+	result := sb.String()
+	if sm.options.Structural {
+		if err := checkRewrittenSource(parseStructuralSource(text), result); err != nil {
+			sm.lastError = err
+			return text, 0
 		}
 	}
-
 	return result, replacedCount
 }
 
 // replaceCurrent replaces only the current match
-func (sm *SearchManager) replaceCurrent(text, pattern, replacement string) (string, int) {
+func (sm *SearchManager) replaceCurrent(text, pattern, replacement string, options ReplaceOptions) (string, int) {
 	currentMatch := sm.GetCurrentMatch()
 	if currentMatch == nil {
 		// No current match, find first match
@@ -337,30 +459,26 @@ func (sm *SearchManager) replaceCurrent(text, pattern, replacement string) (stri
 	}
 
 	// Calculate absolute position
-	lines := strings.Split(text, "\n")
-	if currentMatch.Start.Line-1 >= len(lines) {
+	absoluteStart := newLineIndex(text).offset(currentMatch.Start)
+	absoluteEnd := absoluteStart + len(currentMatch.Text)
+
+	if absoluteStart < 0 || absoluteEnd > len(text) {
 		return text, 0
 	}
 
-	absoluteStart := 0
-	for i := 0; i < currentMatch.Start.Line-1; i++ {
-		absoluteStart += len(lines[i]) + 1 // +1 for newline
+	expand, err := sm.compileReplacer(pattern, replacement, options)
+	if err != nil {
+		sm.lastError = err
+		return text, 0
 	}
-	absoluteStart += currentMatch.Start.Column - 1
-
-	absoluteEnd := absoluteStart + (currentMatch.End.Column - currentMatch.Start.Column)
-
-	if absoluteEnd > len(text) {
+	expanded, err := expand(sm.currentIndex, *currentMatch)
+	if err != nil {
+		sm.lastError = err
 		return text, 0
 	}
 
 	// Perform replacement
-	actualReplacement := replacement
-	if sm.options.RegularExpression {
-		actualReplacement = sm.processRegexReplacement(currentMatch.Text, pattern, replacement)
-	}
-
-	result := text[:absoluteStart] + actualReplacement + text[absoluteEnd:]
+	result := text[:absoluteStart] + expanded + text[absoluteEnd:]
 	
 	// Update matches after replacement
 	sm.Find(result, pattern)
@@ -368,34 +486,57 @@ func (sm *SearchManager) replaceCurrent(text, pattern, replacement string) (stri
 	return result, 1
 }
 
-// processRegexReplacement handles regex replacement with capture groups
-func (sm *SearchManager) processRegexReplacement(matchText, pattern, replacement string) string {
-	var flags string
-	if !sm.options.CaseSensitive {
-		flags = "(?i)"
-	}
-	
-	if sm.options.WholeWord {
-		pattern = `\b` + pattern + `\b`
-	}
-
-	regex, err := regexp.Compile(flags + pattern)
-	if err != nil {
-		return replacement
-	}
-
-	return regex.ReplaceAllString(matchText, replacement)
-}
-
 // Clear clears all search state
 func (sm *SearchManager) Clear() {
 	sm.currentPattern = ""
 	sm.matches = make([]Match, 0)
 	sm.currentIndex = -1
 	sm.lastSearchText = ""
+	sm.lastError = nil
+	sm.groups = nil
+	sm.truncated = false
 }
 
 // HasMatches returns true if there are any matches
 func (sm *SearchManager) HasMatches() bool {
 	return len(sm.matches) > 0
-}
\ No newline at end of file
+}
+
+// lineIndex holds the byte offset at which each line of a text starts and
+// converts between offsets and line/column positions
+type lineIndex []int
+
+// newLineIndex indexes the line starts of text
+func newLineIndex(text string) lineIndex {
+	starts := lineIndex{0}
+	for i := 0; i < len(text); i++ {
+		if text[i] == '\n' {
+			starts = append(starts, i+1)
+		}
+	}
+	return starts
+}
+
+// position converts a byte offset into a 1-based line and column
+func (li lineIndex) position(offset int) Position {
+	line := sort.Search(len(li), func(i int) bool { return li[i] > offset }) - 1
+	return Position{Line: line + 1, Column: offset - li[line] + 1}
+}
+
+// offset converts a 1-based line and column into a byte offset, or -1 if
+// the line does not exist
+func (li lineIndex) offset(pos Position) int {
+	if pos.Line < 1 || pos.Line > len(li) {
+		return -1
+	}
+	return li[pos.Line-1] + pos.Column - 1
+}
+
+// match builds the Match covering text[start:end]
+func (li lineIndex) match(text string, start, end int) Match {
+	return Match{
+		Start: li.position(start),
+		End:   li.position(end),
+		Text:  text[start:end],
+	}
+}
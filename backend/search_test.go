@@ -1,7 +1,10 @@
 package backend
 
 import (
+	"context"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestSearchManager_Find(t *testing.T) {
@@ -243,4 +246,186 @@ func TestSearchManager_Clear(t *testing.T) {
 	if sm.GetCurrentIndex() != -1 {
 		t.Error("Current index should be -1 after clear")
 	}
-}
\ No newline at end of file
+}
+func TestSearchManager_MultiLineRegex(t *testing.T) {
+	sm := NewSearchManager()
+	sm.SetOptions(SearchOptions{RegularExpression: true, CaseSensitive: true})
+	text := "package main\n\nfunc answer() {\n\treturn 42\n}\n"
+
+	matches := sm.Find(text, `func \w+\(\)\s*\{\n\s*return`)
+	if len(matches) != 1 {
+		t.Fatalf("Expected 1 match, got %d", len(matches))
+	}
+
+	match := matches[0]
+	if match.Start != (Position{Line: 3, Column: 1}) {
+		t.Errorf("Unexpected start: %+v", match.Start)
+	}
+	if match.End != (Position{Line: 4, Column: 8}) {
+		t.Errorf("Unexpected end: %+v", match.End)
+	}
+	if match.Text != "func answer() {\n\treturn" {
+		t.Errorf("Unexpected match text: %q", match.Text)
+	}
+}
+
+func TestSearchManager_RegexFlags(t *testing.T) {
+	sm := NewSearchManager()
+	text := "one\ntwo\nthree"
+
+	sm.SetOptions(SearchOptions{RegularExpression: true, MultiLine: true})
+	if matches := sm.Find(text, `^t\w+$`); len(matches) != 2 {
+		t.Errorf("MultiLine: expected 2 matches, got %d", len(matches))
+	}
+
+	sm.SetOptions(SearchOptions{RegularExpression: true})
+	if matches := sm.Find(text, `^t\w+$`); len(matches) != 0 {
+		t.Errorf("Without MultiLine: expected 0 matches, got %d", len(matches))
+	}
+
+	if matches := sm.Find(text, `one.two`); len(matches) != 0 {
+		t.Errorf("Without DotAll: expected 0 matches, got %d", len(matches))
+	}
+
+	sm.SetOptions(SearchOptions{RegularExpression: true, DotAll: true})
+	matches := sm.Find(text, `one.two`)
+	if len(matches) != 1 || matches[0].End != (Position{Line: 2, Column: 4}) {
+		t.Errorf("DotAll: expected 1 match ending at 2:4, got %+v", matches)
+	}
+}
+
+func TestSearchManager_LiteralNewline(t *testing.T) {
+	sm := NewSearchManager()
+	text := "alpha\nbeta\nalpha\nbeta"
+
+	matches := sm.Find(text, "ALPHA\nbeta")
+	if len(matches) != 2 {
+		t.Fatalf("Expected 2 matches, got %d", len(matches))
+	}
+	if matches[1].Start != (Position{Line: 3, Column: 1}) || matches[1].End != (Position{Line: 4, Column: 5}) {
+		t.Errorf("Unexpected second match: %+v", matches[1])
+	}
+}
+
+func TestSearchManager_MultiLineReplace(t *testing.T) {
+	sm := NewSearchManager()
+	text := "a {\n  x\n}\nb {\n  y\n}"
+
+	options := ReplaceOptions{
+		SearchOptions: SearchOptions{RegularExpression: true, CaseSensitive: true},
+		ReplaceAll:    true,
+	}
+	result, count := sm.Replace(text, `\{\n\s*(\w)\n\}`, "{ $1 }", options)
+	if count != 2 {
+		t.Errorf("Expected 2 replacements, got %d", count)
+	}
+	if expected := "a { x }\nb { y }"; result != expected {
+		t.Errorf("Expected %q, got %q", expected, result)
+	}
+
+	// Replacing the current match also handles text spanning lines
+	options.ReplaceAll = false
+	result, count = sm.Replace(text, `\{\n\s*(\w)\n\}`, "{\n\t$1\n}", options)
+	if count != 1 {
+		t.Errorf("Expected 1 replacement, got %d", count)
+	}
+	if expected := "a {\n\tx\n}\nb {\n  y\n}"; result != expected {
+		t.Errorf("Expected %q, got %q", expected, result)
+	}
+}
+
+func TestSearchManager_MatchLimit(t *testing.T) {
+	sm := NewSearchManager()
+	sm.SetMatchLimit(3)
+	text := strings.Repeat("ab ", 5)
+
+	for _, regex := range []bool{false, true} {
+		options := sm.GetOptions()
+		options.RegularExpression = regex
+		sm.SetOptions(options)
+
+		matches := sm.Find(text, "ab")
+		if len(matches) != 3 || !sm.IsTruncated() {
+			t.Errorf("regex=%v: expected 3 truncated matches, got %d (truncated %v)", regex, len(matches), sm.IsTruncated())
+		}
+	}
+
+	sm.Find("ab ab ab", "ab")
+	if sm.IsTruncated() {
+		t.Error("Expected exactly 3 matches not to be truncated")
+	}
+
+	// Replace all is not limited
+	result, count := sm.Replace(text, "ab", "x", ReplaceOptions{SearchOptions: sm.GetOptions(), ReplaceAll: true})
+	if count != 5 || result != strings.Repeat("x ", 5) {
+		t.Errorf("Expected 5 replacements, got %d: %q", count, result)
+	}
+}
+
+func TestSearchManager_FindContextCancelled(t *testing.T) {
+	sm := NewSearchManager()
+	sm.Find("one two one", "one")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	matches, err := sm.FindContext(ctx, strings.Repeat("one ", 10000), "one")
+	if err != context.Canceled || matches != nil {
+		t.Errorf("Expected cancellation, got %d matches and %v", len(matches), err)
+	}
+	if sm.GetMatchCount() != 2 || sm.GetPattern() != "one" {
+		t.Errorf("Expected previous results to be kept, got %d", sm.GetMatchCount())
+	}
+}
+
+func TestSearchManager_FindAsync(t *testing.T) {
+	sm := NewSearchManager()
+	text := strings.Repeat("word\n", 1200)
+
+	chunks := make(chan SearchChunk, 16)
+	sm.FindAsync(context.Background(), text, "word", func(chunk SearchChunk) {
+		chunks <- chunk
+	})
+
+	streamed := 0
+	for chunk := range chunks {
+		if !chunk.Done {
+			streamed += len(chunk.Matches)
+			if chunk.Found != streamed {
+				t.Errorf("Expected %d found, got %d", streamed, chunk.Found)
+			}
+			continue
+		}
+
+		if streamed != 1200 || len(chunk.Result.Matches) != 1200 {
+			t.Errorf("Expected 1200 matches, streamed %d, result %d", streamed, len(chunk.Result.Matches))
+		}
+		// Results only take effect once applied
+		if sm.HasMatches() {
+			t.Error("Expected FindAsync not to change the current search")
+		}
+		sm.ApplyResult(chunk.Result)
+		if sm.GetMatchCount() != 1200 || sm.GetPattern() != "word" {
+			t.Errorf("Expected applied result, got %d matches", sm.GetMatchCount())
+		}
+		break
+	}
+}
+
+func TestSearchManager_FindAsyncCancelled(t *testing.T) {
+	sm := NewSearchManager()
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	done := make(chan bool, 1)
+	sm.FindAsync(ctx, strings.Repeat("a", 100000), "a", func(chunk SearchChunk) {
+		if chunk.Done {
+			done <- true
+		}
+	})
+
+	select {
+	case <-done:
+		t.Error("Expected no final chunk for a cancelled search")
+	case <-time.After(200 * time.Millisecond):
+	}
+}
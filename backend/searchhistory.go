@@ -0,0 +1,228 @@
+package backend
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// DefaultSearchHistorySize is how many search and replace strings are kept
+const DefaultSearchHistorySize = 50
+
+// SearchHistoryEntry is a previously used search pattern
+type SearchHistoryEntry struct {
+	Pattern string        `json:"pattern"`
+	Options SearchOptions `json:"options"`
+}
+
+// ReplaceHistoryEntry is a previously used replacement
+type ReplaceHistoryEntry struct {
+	Replacement string         `json:"replacement"`
+	Options     ReplaceOptions `json:"options"`
+}
+
+// SavedSearch is a search stored under a name so it can be recalled
+type SavedSearch struct {
+	Name        string         `json:"name"`
+	Pattern     string         `json:"pattern"`
+	Replacement string         `json:"replacement,omitempty"`
+	Options     ReplaceOptions `json:"options"`
+}
+
+// searchHistoryData is the file format of the search history
+type searchHistoryData struct {
+	Searches     []SearchHistoryEntry  `json:"searches"`
+	Replacements []ReplaceHistoryEntry `json:"replacements"`
+	Saved        []SavedSearch         `json:"saved"`
+}
+
+// SearchHistory keeps the search and replace strings used in the find and
+// replace dialogs, most recent first, and the named saved searches. It is
+// stored next to the configuration file.
+type SearchHistory struct {
+	data    searchHistoryData
+	path    string
+	maxSize int
+	loadErr error // Why the history file could not be loaded, if it exists
+}
+
+// NewSearchHistory creates a search history stored in configDir
+func NewSearchHistory(configDir string) *SearchHistory {
+	return &SearchHistory{
+		data: searchHistoryData{
+			Searches:     make([]SearchHistoryEntry, 0),
+			Replacements: make([]ReplaceHistoryEntry, 0),
+			Saved:        make([]SavedSearch, 0),
+		},
+		path:    filepath.Join(configDir, "search_history.json"),
+		maxSize: DefaultSearchHistorySize,
+	}
+}
+
+// GetPath returns the path of the history file
+func (sh *SearchHistory) GetPath() string {
+	return sh.path
+}
+
+// Load reads the history file. A missing file leaves the history empty. A
+// file that cannot be read or parsed is not overwritten by Save, so its
+// contents are not lost.
+func (sh *SearchHistory) Load() error {
+	sh.loadErr = nil
+	data, err := os.ReadFile(sh.path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		sh.loadErr = fmt.Errorf("failed to read search history: %w", err)
+		return sh.loadErr
+	}
+
+	var loaded searchHistoryData
+	if err := json.Unmarshal(data, &loaded); err != nil {
+		sh.loadErr = fmt.Errorf("failed to parse search history: %w", err)
+		return sh.loadErr
+	}
+	if loaded.Searches != nil {
+		sh.data.Searches = loaded.Searches
+	}
+	if loaded.Replacements != nil {
+		sh.data.Replacements = loaded.Replacements
+	}
+	if loaded.Saved != nil {
+		sh.data.Saved = loaded.Saved
+	}
+	sh.trim()
+	return nil
+}
+
+// Save writes the history file. It fails without writing if the file
+// exists but could not be loaded.
+func (sh *SearchHistory) Save() error {
+	if sh.loadErr != nil {
+		return fmt.Errorf("not overwriting %s: %w", sh.path, sh.loadErr)
+	}
+	if err := os.MkdirAll(filepath.Dir(sh.path), 0755); err != nil {
+		return fmt.Errorf("failed to create config directory: %w", err)
+	}
+
+	data, err := json.MarshalIndent(sh.data, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal search history: %w", err)
+	}
+
+	if err := os.WriteFile(sh.path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write search history: %w", err)
+	}
+	return nil
+}
+
+// AddSearch records a search pattern, moving it to the front if it was
+// used before. It returns false if the history did not change.
+func (sh *SearchHistory) AddSearch(pattern string, options SearchOptions) bool {
+	if pattern == "" {
+		return false
+	}
+	entry := SearchHistoryEntry{Pattern: pattern, Options: options}
+	if len(sh.data.Searches) > 0 && sh.data.Searches[0] == entry {
+		return false
+	}
+
+	searches := []SearchHistoryEntry{entry}
+	for _, existing := range sh.data.Searches {
+		if existing.Pattern != pattern {
+			searches = append(searches, existing)
+		}
+	}
+	sh.data.Searches = searches
+	sh.trim()
+	return true
+}
+
+// AddReplacement records a replacement, moving it to the front if it was
+// used before. It returns false if the history did not change.
+func (sh *SearchHistory) AddReplacement(replacement string, options ReplaceOptions) bool {
+	entry := ReplaceHistoryEntry{Replacement: replacement, Options: options}
+	if len(sh.data.Replacements) > 0 && sh.data.Replacements[0] == entry {
+		return false
+	}
+
+	replacements := []ReplaceHistoryEntry{entry}
+	for _, existing := range sh.data.Replacements {
+		if existing.Replacement != replacement {
+			replacements = append(replacements, existing)
+		}
+	}
+	sh.data.Replacements = replacements
+	sh.trim()
+	return true
+}
+
+// GetSearches returns the search history, most recent first
+func (sh *SearchHistory) GetSearches() []SearchHistoryEntry {
+	return sh.data.Searches
+}
+
+// GetReplacements returns the replace history, most recent first
+func (sh *SearchHistory) GetReplacements() []ReplaceHistoryEntry {
+	return sh.data.Replacements
+}
+
+// SaveSearch stores a named search, replacing one with the same name
+func (sh *SearchHistory) SaveSearch(saved SavedSearch) error {
+	if saved.Name == "" {
+		return fmt.Errorf("saved search needs a name")
+	}
+	if saved.Pattern == "" {
+		return fmt.Errorf("saved search %q has no pattern", saved.Name)
+	}
+
+	for i, existing := range sh.data.Saved {
+		if existing.Name == saved.Name {
+			sh.data.Saved[i] = saved
+			return nil
+		}
+	}
+	sh.data.Saved = append(sh.data.Saved, saved)
+	sort.Slice(sh.data.Saved, func(i, j int) bool { return sh.data.Saved[i].Name < sh.data.Saved[j].Name })
+	return nil
+}
+
+// DeleteSavedSearch removes a named search. It returns false if no search
+// has that name.
+func (sh *SearchHistory) DeleteSavedSearch(name string) bool {
+	for i, existing := range sh.data.Saved {
+		if existing.Name == name {
+			sh.data.Saved = append(sh.data.Saved[:i], sh.data.Saved[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// GetSavedSearch returns the named search
+func (sh *SearchHistory) GetSavedSearch(name string) (SavedSearch, bool) {
+	for _, saved := range sh.data.Saved {
+		if saved.Name == name {
+			return saved, true
+		}
+	}
+	return SavedSearch{}, false
+}
+
+// GetSavedSearches returns the saved searches sorted by name
+func (sh *SearchHistory) GetSavedSearches() []SavedSearch {
+	return sh.data.Saved
+}
+
+// trim drops the oldest entries beyond the history size
+func (sh *SearchHistory) trim() {
+	if len(sh.data.Searches) > sh.maxSize {
+		sh.data.Searches = sh.data.Searches[:sh.maxSize]
+	}
+	if len(sh.data.Replacements) > sh.maxSize {
+		sh.data.Replacements = sh.data.Replacements[:sh.maxSize]
+	}
+}
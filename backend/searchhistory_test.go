@@ -0,0 +1,132 @@
+package backend
+
+import (
+	"fmt"
+	"os"
+	"testing"
+)
+
+func TestSearchHistory_AddSearch(t *testing.T) {
+	sh := NewSearchHistory(t.TempDir())
+	options := SearchOptions{RegularExpression: true}
+
+	sh.AddSearch("foo", SearchOptions{})
+	sh.AddSearch(`\d+`, options)
+	if sh.AddSearch(`\d+`, options) {
+		t.Error("Expected repeating the latest search not to change the history")
+	}
+	sh.AddSearch("foo", SearchOptions{CaseSensitive: true})
+
+	searches := sh.GetSearches()
+	if len(searches) != 2 {
+		t.Fatalf("Expected 2 entries, got %d", len(searches))
+	}
+	if searches[0].Pattern != "foo" || !searches[0].Options.CaseSensitive {
+		t.Errorf("Expected reused pattern first with its new options, got %+v", searches[0])
+	}
+	if searches[1].Pattern != `\d+` || !searches[1].Options.RegularExpression {
+		t.Errorf("Unexpected second entry %+v", searches[1])
+	}
+
+	if sh.AddSearch("", options) {
+		t.Error("Expected empty patterns to be ignored")
+	}
+}
+
+func TestSearchHistory_Size(t *testing.T) {
+	sh := NewSearchHistory(t.TempDir())
+	for i := 0; i < DefaultSearchHistorySize+10; i++ {
+		sh.AddSearch(fmt.Sprintf("pattern %d", i), SearchOptions{})
+		sh.AddReplacement(fmt.Sprintf("replacement %d", i), ReplaceOptions{})
+	}
+
+	if len(sh.GetSearches()) != DefaultSearchHistorySize || len(sh.GetReplacements()) != DefaultSearchHistorySize {
+		t.Errorf("Expected %d entries, got %d and %d", DefaultSearchHistorySize, len(sh.GetSearches()), len(sh.GetReplacements()))
+	}
+	if latest := fmt.Sprintf("pattern %d", DefaultSearchHistorySize+9); sh.GetSearches()[0].Pattern != latest {
+		t.Errorf("Expected %q first, got %q", latest, sh.GetSearches()[0].Pattern)
+	}
+}
+
+func TestSearchHistory_SavedSearches(t *testing.T) {
+	sh := NewSearchHistory(t.TempDir())
+
+	if err := sh.SaveSearch(SavedSearch{Pattern: "x"}); err == nil {
+		t.Error("Expected an error for a search without a name")
+	}
+	if err := sh.SaveSearch(SavedSearch{Name: "empty"}); err == nil {
+		t.Error("Expected an error for a search without a pattern")
+	}
+
+	sh.SaveSearch(SavedSearch{Name: "todo", Pattern: "TODO"})
+	sh.SaveSearch(SavedSearch{Name: "numbers", Pattern: `\d+`, Options: ReplaceOptions{SearchOptions: SearchOptions{RegularExpression: true}}})
+	sh.SaveSearch(SavedSearch{Name: "todo", Pattern: "TODO|FIXME"})
+
+	saved := sh.GetSavedSearches()
+	if len(saved) != 2 || saved[0].Name != "numbers" || saved[1].Name != "todo" {
+		t.Fatalf("Expected saved searches sorted by name, got %+v", saved)
+	}
+	if search, ok := sh.GetSavedSearch("todo"); !ok || search.Pattern != "TODO|FIXME" {
+		t.Errorf("Expected updated saved search, got %+v", search)
+	}
+
+	if !sh.DeleteSavedSearch("todo") || sh.DeleteSavedSearch("todo") {
+		t.Error("Expected delete to succeed once")
+	}
+}
+
+func TestSearchHistory_SaveLoad(t *testing.T) {
+	dir := t.TempDir()
+	sh := NewSearchHistory(dir)
+	sh.AddSearch("needle", SearchOptions{WholeWord: true, Scope: ScopeComments})
+	sh.AddReplacement("$1", ReplaceOptions{PreserveCase: true})
+	sh.SaveSearch(SavedSearch{Name: "ids", Pattern: `id(\d+)`, Replacement: "ID$1"})
+
+	if err := sh.Save(); err != nil {
+		t.Fatalf("Failed to save history: %v", err)
+	}
+
+	loaded := NewSearchHistory(dir)
+	if err := loaded.Load(); err != nil {
+		t.Fatalf("Failed to load history: %v", err)
+	}
+
+	if searches := loaded.GetSearches(); len(searches) != 1 || searches[0].Options.Scope != ScopeComments || !searches[0].Options.WholeWord {
+		t.Errorf("Unexpected searches %+v", searches)
+	}
+	if replacements := loaded.GetReplacements(); len(replacements) != 1 || !replacements[0].Options.PreserveCase {
+		t.Errorf("Unexpected replacements %+v", replacements)
+	}
+	if saved, ok := loaded.GetSavedSearch("ids"); !ok || saved.Replacement != "ID$1" {
+		t.Errorf("Unexpected saved search %+v", saved)
+	}
+}
+
+func TestSearchHistory_LoadMissing(t *testing.T) {
+	sh := NewSearchHistory(t.TempDir())
+	if err := sh.Load(); err != nil {
+		t.Errorf("Expected a missing history file to be ignored, got %v", err)
+	}
+	if len(sh.GetSearches()) != 0 {
+		t.Error("Expected an empty history")
+	}
+}
+
+func TestSearchHistory_LoadCorrupt(t *testing.T) {
+	sh := NewSearchHistory(t.TempDir())
+	if err := os.WriteFile(sh.GetPath(), []byte("{not json"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := sh.Load(); err == nil {
+		t.Fatal("Expected an error for a corrupt history file")
+	}
+
+	// The history still works in memory but is not saved over the file
+	sh.AddSearch("foo", SearchOptions{})
+	if err := sh.Save(); err == nil {
+		t.Error("Expected saving over a corrupt history file to fail")
+	}
+	if data, _ := os.ReadFile(sh.GetPath()); string(data) != "{not json" {
+		t.Errorf("Expected the corrupt file to be left alone, got %q", data)
+	}
+}
@@ -0,0 +1,260 @@
+package backend
+
+import (
+	"context"
+)
+
+const (
+	// DefaultMatchLimit caps how many matches a search materializes, so a
+	// pattern such as "e" on a large buffer stays responsive
+	DefaultMatchLimit = 10000
+
+	// searchChunkSize is how many matches FindAsync collects before it
+	// streams them to the caller
+	searchChunkSize = 500
+
+	// cancelCheckInterval is how many candidate matches are examined
+	// between checks for cancellation
+	cancelCheckInterval = 256
+)
+
+// SearchResult is the outcome of a complete search
+type SearchResult struct {
+	Pattern   string         `json:"pattern"`
+	Matches   []Match        `json:"matches"`
+	Groups    []CaptureGroup `json:"groups,omitempty"`
+	Truncated bool           `json:"truncated"` // More matches exist than the match limit
+	Err       error          `json:"-"`         // Invalid pattern or failed match, as reported by GetError
+	text      string
+}
+
+// SearchChunk is a batch of matches streamed by FindAsync
+type SearchChunk struct {
+	Matches []Match       // Matches found since the previous chunk
+	Found   int           // Matches found so far
+	Done    bool          // The search has finished
+	Result  *SearchResult // The complete result, set on the final chunk
+}
+
+// searchRun is a single search over a snapshot of the options. It does not
+// touch SearchManager state, so it can run on a background goroutine.
+type searchRun struct {
+	ctx     context.Context
+	options SearchOptions
+	limit   int         // Maximum matches to keep, 0 for no limit
+	ranges  []TextRange // Scope ranges sorted by start
+	scoped  bool        // Only matches inside ranges are kept
+	next    int         // First scope range that can contain the next match
+	checks  int         // Candidates examined since the last cancellation check
+	lines   lineIndex
+	result  SearchResult
+
+	onChunk func(SearchChunk) // Receives matches in chunks, may be nil
+	emitted int               // Matches already passed to onChunk
+}
+
+// newRun prepares a search of text with the current options
+func (sm *SearchManager) newRun(ctx context.Context, text, pattern string, limit int) *searchRun {
+	r := &searchRun{
+		ctx:     ctx,
+		options: sm.options,
+		limit:   limit,
+		lines:   newLineIndex(text),
+		result:  SearchResult{Pattern: pattern, Matches: make([]Match, 0), text: text},
+	}
+	r.ranges, r.scoped = sm.scopeRanges(text)
+	return r
+}
+
+// run performs the search and returns its result, or nil if the context
+// was cancelled
+func (r *searchRun) run(text, pattern string) *SearchResult {
+	if r.options.Structural {
+		r.findStructural(text, pattern)
+	} else if r.options.RegularExpression {
+		r.findRegex(text, pattern)
+	} else {
+		r.findLiteral(text, pattern)
+	}
+
+	if r.ctx.Err() != nil {
+		return nil
+	}
+	return &r.result
+}
+
+// add keeps match if it is inside the scope and returns false once the
+// search should stop, because the limit is reached or it was cancelled
+func (r *searchRun) add(match Match) bool {
+	r.checks++
+	if r.checks >= cancelCheckInterval {
+		r.checks = 0
+		if r.ctx.Err() != nil {
+			return false
+		}
+	}
+
+	if r.scoped && !r.inScope(match) {
+		return true
+	}
+	if r.limit > 0 && len(r.result.Matches) >= r.limit {
+		r.result.Truncated = true
+		return false
+	}
+
+	r.result.Matches = append(r.result.Matches, match)
+	if r.onChunk != nil && len(r.result.Matches)-r.emitted >= searchChunkSize {
+		r.flush()
+	}
+	return true
+}
+
+// flush streams the matches found since the last chunk
+func (r *searchRun) flush() {
+	n := len(r.result.Matches)
+	if n == r.emitted {
+		return
+	}
+	// Cap the chunk so later appends never write into memory it exposes
+	r.onChunk(SearchChunk{Matches: r.result.Matches[r.emitted:n:n], Found: n})
+	r.emitted = n
+}
+
+// inScope reports whether match lies fully inside one of the scope ranges.
+// Matches arrive in order, so ranges ending before the match are skipped
+// for good.
+func (r *searchRun) inScope(match Match) bool {
+	start := r.lines.offset(match.Start)
+	end := start + len(match.Text)
+
+	for r.next < len(r.ranges) && r.ranges[r.next].End <= start {
+		r.next++
+	}
+	for i := r.next; i < len(r.ranges) && r.ranges[i].Start <= start; i++ {
+		if end <= r.ranges[i].End {
+			return true
+		}
+	}
+	return false
+}
+
+// regexLimit is how many raw matches the regex engine needs to produce.
+// Scoped searches discard some matches, so they cannot be bounded upfront.
+func (r *searchRun) regexLimit() int {
+	if r.limit <= 0 || r.scoped {
+		return -1
+	}
+	return r.limit + 1
+}
+
+// SetMatchLimit sets how many matches a search keeps, 0 for no limit
+func (sm *SearchManager) SetMatchLimit(limit int) {
+	sm.matchLimit = limit
+}
+
+// GetMatchLimit returns how many matches a search keeps, 0 for no limit
+func (sm *SearchManager) GetMatchLimit() int {
+	return sm.matchLimit
+}
+
+// IsTruncated reports whether the last search stopped at the match limit
+func (sm *SearchManager) IsTruncated() bool {
+	return sm.truncated
+}
+
+// FindContext searches text like Find and stops early when ctx is
+// cancelled. A cancelled search returns ctx.Err() and leaves the previous
+// results in place.
+func (sm *SearchManager) FindContext(ctx context.Context, text, pattern string) ([]Match, error) {
+	return sm.find(ctx, text, pattern, sm.matchLimit)
+}
+
+// find searches text keeping at most limit matches
+func (sm *SearchManager) find(ctx context.Context, text, pattern string, limit int) ([]Match, error) {
+	if pattern == "" {
+		sm.matches = make([]Match, 0)
+		sm.currentIndex = -1
+		sm.truncated = false
+		return sm.matches, nil
+	}
+
+	result := sm.newRun(ctx, text, pattern, limit).run(text, pattern)
+	if result == nil {
+		return nil, ctx.Err()
+	}
+	return sm.ApplyResult(result), nil
+}
+
+// FindAsync searches text on a background goroutine, so large buffers do
+// not block the caller. Matches are passed to onChunk in batches as they are
+// found; the final chunk has Done set and carries the complete result,
+// which becomes the current search once passed to ApplyResult. Cancelling
+// ctx stops the search without a final chunk.
+//
+// onChunk is called on the search goroutine. The options, match limit and
+// selection scope are captured before FindAsync returns. Other scopes are
+// computed in the background when the scope provider implements
+// ScopeSnapshotter, and before FindAsync returns otherwise, so the search
+// goroutine never calls into the provider's own state.
+func (sm *SearchManager) FindAsync(ctx context.Context, text, pattern string, onChunk func(SearchChunk)) {
+	if onChunk == nil {
+		onChunk = func(SearchChunk) {}
+	}
+	r, prepare := sm.newAsyncRun(ctx, text, pattern, sm.matchLimit)
+	r.onChunk = onChunk
+
+	go func() {
+		prepare()
+
+		var result *SearchResult
+		if pattern != "" {
+			result = r.run(text, pattern)
+		} else {
+			result = &r.result
+		}
+		if result == nil || ctx.Err() != nil {
+			return
+		}
+
+		r.flush()
+		onChunk(SearchChunk{Found: len(result.Matches), Done: true, Result: result})
+	}()
+}
+
+// newAsyncRun prepares a search of text that runs on a background
+// goroutine. The options and selection scope are captured now; prepare
+// computes the remaining setup and must be called on the search goroutine
+// before the run starts.
+func (sm *SearchManager) newAsyncRun(ctx context.Context, text, pattern string, limit int) (r *searchRun, prepare func()) {
+	r = &searchRun{
+		ctx:     ctx,
+		options: sm.options,
+		limit:   limit,
+		result:  SearchResult{Pattern: pattern, Matches: make([]Match, 0), text: text},
+	}
+	var snapshot ScopeProvider
+	if snapshotter, ok := sm.scopeProvider.(ScopeSnapshotter); ok && r.options.Scope != ScopeDocument && r.options.Scope != ScopeSelection {
+		snapshot = snapshotter.ScopeSnapshot()
+	} else {
+		r.ranges, r.scoped = sm.scopeRanges(text)
+	}
+
+	return r, func() {
+		if snapshot != nil {
+			r.ranges, r.scoped = sortedScopeRanges(snapshot, text, r.options.Scope), true
+		}
+		r.lines = newLineIndex(text)
+	}
+}
+
+// ApplyResult makes result the current search and returns its matches
+func (sm *SearchManager) ApplyResult(result *SearchResult) []Match {
+	sm.currentPattern = result.Pattern
+	sm.lastSearchText = result.text
+	sm.matches = result.Matches
+	sm.currentIndex = -1
+	sm.lastError = result.Err
+	sm.groups = result.Groups
+	sm.truncated = result.Truncated
+	return sm.matches
+}
@@ -0,0 +1,58 @@
+package backend
+
+import (
+	"sort"
+)
+
+// SearchScope restricts which parts of a document a search looks at
+type SearchScope string
+
+const (
+	ScopeDocument  SearchScope = ""          // The whole document
+	ScopeSelection SearchScope = "selection" // The current selection
+	ScopeComments  SearchScope = "comments"  // Only inside comments
+	ScopeStrings   SearchScope = "strings"   // Only inside string literals
+	ScopeCode      SearchScope = "code"      // Outside comments and strings
+)
+
+// TextRange is a half-open range of byte offsets in a text
+type TextRange struct {
+	Start int `json:"start"`
+	End   int `json:"end"`
+}
+
+// ScopeProvider computes the ranges of a text that belong to a scope. The
+// editor provides selection ranges and syntax ranges from the highlighter.
+type ScopeProvider interface {
+	ScopeRanges(text string, scope SearchScope) []TextRange
+}
+
+// ScopeSnapshotter is implemented by scope providers that read state which
+// can change while a background search runs. ScopeSnapshot returns a
+// provider that shares none of that state, so FindAsync can compute the
+// scopes on its own goroutine.
+type ScopeSnapshotter interface {
+	ScopeSnapshot() ScopeProvider
+}
+
+// SetScopeProvider sets the provider used for scopes other than the whole
+// document. Without one, every scope searches the whole document.
+func (sm *SearchManager) SetScopeProvider(provider ScopeProvider) {
+	sm.scopeProvider = provider
+}
+
+// scopeRanges returns the ranges of text in the current scope, sorted by
+// start, and whether the search is restricted to them
+func (sm *SearchManager) scopeRanges(text string) ([]TextRange, bool) {
+	if sm.options.Scope == ScopeDocument || sm.scopeProvider == nil {
+		return nil, false
+	}
+	return sortedScopeRanges(sm.scopeProvider, text, sm.options.Scope), true
+}
+
+// sortedScopeRanges asks provider for the ranges of scope sorted by start
+func sortedScopeRanges(provider ScopeProvider, text string, scope SearchScope) []TextRange {
+	ranges := provider.ScopeRanges(text, scope)
+	sort.Slice(ranges, func(i, j int) bool { return ranges[i].Start < ranges[j].Start })
+	return ranges
+}
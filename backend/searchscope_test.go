@@ -0,0 +1,146 @@
+package backend
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+// mockScopeProvider returns fixed ranges for every scope except the document
+type mockScopeProvider struct {
+	ranges map[SearchScope][]TextRange
+}
+
+func (m *mockScopeProvider) ScopeRanges(text string, scope SearchScope) []TextRange {
+	return m.ranges[scope]
+}
+
+func TestSearchManager_Scope(t *testing.T) {
+	text := "// count the items\ncount := len(\"count\")\n"
+	commentEnd := strings.Index(text, "\n")
+	stringStart := strings.Index(text, "\"count\"")
+
+	provider := &mockScopeProvider{ranges: map[SearchScope][]TextRange{
+		ScopeComments:  {{Start: 0, End: commentEnd}},
+		ScopeStrings:   {{Start: stringStart, End: stringStart + len("\"count\"")}},
+		ScopeCode:      {{Start: commentEnd, End: stringStart}, {Start: stringStart + len("\"count\""), End: len(text)}},
+		ScopeSelection: {},
+	}}
+
+	tests := []struct {
+		name  string
+		scope SearchScope
+		lines []int
+	}{
+		{"document", ScopeDocument, []int{1, 2, 2}},
+		{"comments", ScopeComments, []int{1}},
+		{"strings", ScopeStrings, []int{2}},
+		{"code", ScopeCode, []int{2}},
+		{"empty selection", ScopeSelection, []int{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sm := NewSearchManager()
+			sm.SetScopeProvider(provider)
+			options := sm.GetOptions()
+			options.Scope = tt.scope
+			sm.SetOptions(options)
+
+			matches := sm.Find(text, "count")
+			if len(matches) != len(tt.lines) {
+				t.Fatalf("Expected %d matches, got %d: %+v", len(tt.lines), len(matches), matches)
+			}
+			for i, line := range tt.lines {
+				if matches[i].Start.Line != line {
+					t.Errorf("Match %d: expected line %d, got %d", i, line, matches[i].Start.Line)
+				}
+			}
+		})
+	}
+}
+
+func TestSearchManager_ScopeReplace(t *testing.T) {
+	text := "// old value\nold := 1\n"
+	sm := NewSearchManager()
+	sm.SetScopeProvider(&mockScopeProvider{ranges: map[SearchScope][]TextRange{
+		ScopeComments: {{Start: 0, End: strings.Index(text, "\n")}},
+	}})
+
+	options := ReplaceOptions{SearchOptions: sm.GetOptions(), ReplaceAll: true}
+	options.Scope = ScopeComments
+	result, count := sm.Replace(text, "old", "new", options)
+
+	if count != 1 {
+		t.Errorf("Expected 1 replacement, got %d", count)
+	}
+	if result != "// new value\nold := 1\n" {
+		t.Errorf("Unexpected result %q", result)
+	}
+}
+
+func TestSearchManager_ScopeWithoutProvider(t *testing.T) {
+	sm := NewSearchManager()
+	options := sm.GetOptions()
+	options.Scope = ScopeComments
+	sm.SetOptions(options)
+
+	if matches := sm.Find("a a a", "a"); len(matches) != 3 {
+		t.Errorf("Expected the whole document to be searched, got %d matches", len(matches))
+	}
+}
+
+// countingScopeProvider counts its calls, so tests can tell when scopes
+// were computed
+type countingScopeProvider struct {
+	mockScopeProvider
+	calls     int
+	snapshots int
+}
+
+func (c *countingScopeProvider) ScopeRanges(text string, scope SearchScope) []TextRange {
+	c.calls++
+	return c.mockScopeProvider.ScopeRanges(text, scope)
+}
+
+// snapshottingScopeProvider hands background searches a copy of itself
+type snapshottingScopeProvider struct {
+	countingScopeProvider
+}
+
+func (s *snapshottingScopeProvider) ScopeSnapshot() ScopeProvider {
+	s.snapshots++
+	return &mockScopeProvider{ranges: s.ranges}
+}
+
+func TestSearchManager_FindAsyncScope(t *testing.T) {
+	text := "// a\na\n"
+	ranges := map[SearchScope][]TextRange{ScopeComments: {{Start: 0, End: 4}}}
+
+	search := func(provider ScopeProvider) int {
+		sm := NewSearchManager()
+		sm.SetScopeProvider(provider)
+		options := sm.GetOptions()
+		options.Scope = ScopeComments
+		sm.SetOptions(options)
+
+		done := make(chan *SearchResult, 1)
+		sm.FindAsync(context.Background(), text, "a", func(chunk SearchChunk) {
+			if chunk.Done {
+				done <- chunk.Result
+			}
+		})
+		return len((<-done).Matches)
+	}
+
+	// Providers without snapshots are only called before FindAsync returns
+	plain := &countingScopeProvider{mockScopeProvider: mockScopeProvider{ranges: ranges}}
+	if count := search(plain); count != 1 || plain.calls != 1 {
+		t.Errorf("Expected 1 match from 1 synchronous call, got %d matches and %d calls", count, plain.calls)
+	}
+
+	snapshotting := &snapshottingScopeProvider{countingScopeProvider{mockScopeProvider: mockScopeProvider{ranges: ranges}}}
+	if count := search(snapshotting); count != 1 || snapshotting.snapshots != 1 || snapshotting.calls != 0 {
+		t.Errorf("Expected the snapshot to compute the scope, got %d matches, %d snapshots and %d calls", count, snapshotting.snapshots, snapshotting.calls)
+	}
+}
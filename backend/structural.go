@@ -0,0 +1,686 @@
+package backend
+
+import (
+	"context"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/scanner"
+	"go/token"
+	"reflect"
+	"strings"
+	"unicode"
+)
+
+// structuralArrow separates the pattern from the replacement of a rewrite
+// rule, as in gofmt -r
+const structuralArrow = "->"
+
+var (
+	identType    = reflect.TypeOf((*ast.Ident)(nil))
+	callExprType = reflect.TypeOf((*ast.CallExpr)(nil))
+	objectType   = reflect.TypeOf((*ast.Object)(nil))
+	positionType = reflect.TypeOf(token.NoPos)
+)
+
+// StructuralRule is a search or rewrite of Go source in the style of
+// gofmt -r. The pattern and the replacement are Go expressions matched
+// against the syntax tree, so spacing, line breaks and comments inside an
+// expression do not matter.
+//
+// Single lowercase letters are wildcards that match any expression; a
+// wildcard used twice must match the same expression both times. A wildcard
+// spread as the last argument of a call, as in f(a, b...), matches all the
+// remaining arguments, including none, and passes them on when it is spread
+// the same way in the replacement.
+type StructuralRule struct {
+	pattern     ast.Expr
+	replacement ast.Expr // nil for a search without replacement
+
+	replacementSource string
+	replacementFset   *token.FileSet
+
+	wildcards []string        // Wildcards of the pattern in order of appearance
+	spread    map[string]bool // Wildcards of the pattern that match argument lists
+}
+
+// structuralBinding is what a wildcard matched
+type structuralBinding struct {
+	exprs    []ast.Expr // The expression, or the arguments for a spread wildcard
+	spread   bool       // Bound by a spread wildcard
+	ellipsis bool       // The arguments end with a spread, as in f(xs...)
+}
+
+// structuralMatch is an expression of the source matched by the pattern
+type structuralMatch struct {
+	node     ast.Expr
+	parent   ast.Node // Node containing the match, nil at the top level
+	bindings map[string]*structuralBinding
+}
+
+// structuralSource is a parsed Go source file
+type structuralSource struct {
+	text string
+	fset *token.FileSet
+	file *ast.File
+	err  error // Syntax errors; the tree covers what could be parsed
+}
+
+// ParseStructuralRule parses a rule written as "pattern -> replacement",
+// or as a bare pattern to search for. An arrow inside a string, rune
+// literal or comment is part of the expression.
+func ParseStructuralRule(rule string) (*StructuralRule, error) {
+	parts := splitStructuralRule(rule)
+	switch len(parts) {
+	case 1:
+		return NewStructuralRule(parts[0], "")
+	case 2:
+		if strings.TrimSpace(parts[1]) == "" {
+			return nil, fmt.Errorf("rewrite rule has no replacement after %q", structuralArrow)
+		}
+		return NewStructuralRule(parts[0], parts[1])
+	default:
+		return nil, fmt.Errorf("rewrite rule must be of the form 'pattern %s replacement'", structuralArrow)
+	}
+}
+
+// splitStructuralRule splits a rule at the arrows between Go tokens. Go has
+// no "->" operator, so an arrow is a "-" token directly followed by ">".
+func splitStructuralRule(rule string) []string {
+	fset := token.NewFileSet()
+	file := fset.AddFile("", fset.Base(), len(rule))
+	var s scanner.Scanner
+	s.Init(file, []byte(rule), nil, scanner.ScanComments)
+
+	var parts []string
+	start, minus := 0, -1
+	for {
+		pos, tok, _ := s.Scan()
+		if tok == token.EOF {
+			break
+		}
+		offset := file.Offset(pos)
+		if tok == token.GTR && minus >= 0 && offset == minus+1 {
+			parts = append(parts, rule[start:minus])
+			start = offset + len(">")
+		}
+		minus = -1
+		if tok == token.SUB {
+			minus = offset
+		}
+	}
+	return append(parts, rule[start:])
+}
+
+// NewStructuralRule parses a pattern and a replacement, which may be empty
+// for a search
+func NewStructuralRule(pattern, replacement string) (*StructuralRule, error) {
+	pattern = strings.TrimSpace(pattern)
+	if pattern == "" {
+		return nil, fmt.Errorf("structural pattern is empty")
+	}
+	expr, err := parser.ParseExpr(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid structural pattern: %w", err)
+	}
+
+	rule := &StructuralRule{pattern: expr, spread: make(map[string]bool)}
+	seen := make(map[string]bool)
+	ast.Inspect(expr, func(n ast.Node) bool {
+		if call, ok := n.(*ast.CallExpr); ok {
+			if name, ok := spreadWildcard(call); ok {
+				rule.spread[name] = true
+			}
+		}
+		if ident, ok := n.(*ast.Ident); ok && isWildcard(ident.Name) && !seen[ident.Name] {
+			seen[ident.Name] = true
+			rule.wildcards = append(rule.wildcards, ident.Name)
+		}
+		return true
+	})
+
+	replacement = strings.TrimSpace(replacement)
+	if replacement == "" {
+		return rule, nil
+	}
+	rule.replacementFset = token.NewFileSet()
+	repl, err := parser.ParseExprFrom(rule.replacementFset, "", replacement, 0)
+	if err != nil {
+		return nil, fmt.Errorf("invalid structural replacement: %w", err)
+	}
+	rule.replacement = repl
+	rule.replacementSource = replacement
+
+	// Every wildcard of the replacement must be bound, and argument lists
+	// can only be passed on as arguments
+	var invalid error
+	walkWithParent(repl, func(n, parent ast.Node) bool {
+		ident, ok := n.(*ast.Ident)
+		if !ok || !isWildcard(ident.Name) || invalid != nil {
+			return invalid == nil
+		}
+		switch {
+		case !seen[ident.Name]:
+			invalid = fmt.Errorf("replacement uses wildcard %q that the pattern does not match", ident.Name)
+		case rule.spread[ident.Name] && !isSpreadArgument(ident, parent):
+			invalid = fmt.Errorf("wildcard %q matches a list of arguments and can only be passed on as f(%s...)", ident.Name, ident.Name)
+		}
+		return false
+	})
+	if invalid != nil {
+		return nil, invalid
+	}
+	return rule, nil
+}
+
+// HasReplacement reports whether the rule rewrites what it matches
+func (r *StructuralRule) HasReplacement() bool {
+	return r.replacement != nil
+}
+
+// Wildcards returns the wildcards of the pattern in order of appearance
+func (r *StructuralRule) Wildcards() []string {
+	return r.wildcards
+}
+
+// Find returns the expressions of the Go source that match the pattern.
+// Matches do not overlap: inside a matched expression no further matches
+// are reported.
+func (r *StructuralRule) Find(source string) ([]Match, error) {
+	src := parseStructuralSource(source)
+	found, err := r.find(context.Background(), src)
+	if err != nil {
+		return nil, err
+	}
+
+	lines := newLineIndex(source)
+	matches := make([]Match, 0, len(found))
+	for _, m := range found {
+		matches = append(matches, r.match(src, lines, m))
+	}
+	return matches, nil
+}
+
+// Rewrite replaces every match in the Go source with the replacement and
+// returns the new source with the number of rewrites. Text outside the
+// matches is left as it was. If the source parsed, the result must parse
+// as well.
+func (r *StructuralRule) Rewrite(source string) (string, int, error) {
+	if !r.HasReplacement() {
+		return source, 0, fmt.Errorf("structural rule has no replacement")
+	}
+	src := parseStructuralSource(source)
+	found, err := r.find(context.Background(), src)
+	if err != nil {
+		return source, 0, err
+	}
+
+	var sb strings.Builder
+	last := 0
+	for _, m := range found {
+		start, end := src.offset(m.node.Pos()), src.offset(m.node.End())
+		expanded, err := r.expand(src, m)
+		if err != nil {
+			return source, 0, err
+		}
+		sb.WriteString(source[last:start])
+		sb.WriteString(expanded)
+		last = end
+	}
+	sb.WriteString(source[last:])
+
+	result := sb.String()
+	if err := checkRewrittenSource(src, result); err != nil {
+		return source, 0, err
+	}
+	return result, len(found), nil
+}
+
+// expansions returns the replacement of every match in source by the
+// offset at which the match starts
+func (r *StructuralRule) expansions(source string) (map[int]string, error) {
+	src := parseStructuralSource(source)
+	found, err := r.find(context.Background(), src)
+	if err != nil {
+		return nil, err
+	}
+
+	expanded := make(map[int]string, len(found))
+	for _, m := range found {
+		text, err := r.expand(src, m)
+		if err != nil {
+			return nil, err
+		}
+		expanded[src.offset(m.node.Pos())] = text
+	}
+	return expanded, nil
+}
+
+// parseStructuralSource parses source as a Go file
+func parseStructuralSource(source string) *structuralSource {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "", source, parser.ParseComments|parser.SkipObjectResolution)
+	return &structuralSource{text: source, fset: fset, file: file, err: err}
+}
+
+// offset converts a position of the source to a byte offset
+func (s *structuralSource) offset(pos token.Pos) int {
+	return s.fset.Position(pos).Offset
+}
+
+// nodeText returns the source text of node
+func (s *structuralSource) nodeText(node ast.Node) string {
+	return s.text[s.offset(node.Pos()):s.offset(node.End())]
+}
+
+// find walks the source and collects the outermost expressions matching the
+// pattern in source order
+func (r *StructuralRule) find(ctx context.Context, src *structuralSource) ([]structuralMatch, error) {
+	if src.file == nil {
+		return nil, fmt.Errorf("cannot parse Go source: %w", src.err)
+	}
+
+	found := make([]structuralMatch, 0)
+	checks := 0
+	cancelled := false
+	pattern := reflect.ValueOf(r.pattern)
+	walkWithParent(src.file, func(n, parent ast.Node) bool {
+		checks++
+		if checks >= cancelCheckInterval {
+			checks = 0
+			cancelled = ctx.Err() != nil
+		}
+		if cancelled {
+			return false
+		}
+
+		expr, ok := n.(ast.Expr)
+		if !ok {
+			return true
+		}
+		bindings := make(map[string]*structuralBinding)
+		if !matchStructure(bindings, pattern, reflect.ValueOf(expr)) {
+			return true
+		}
+		found = append(found, structuralMatch{node: expr, parent: parent, bindings: bindings})
+		return false
+	})
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return found, nil
+}
+
+// match converts a structural match to a Match whose groups hold the whole
+// match followed by the text bound to each wildcard
+func (r *StructuralRule) match(src *structuralSource, lines lineIndex, m structuralMatch) Match {
+	match := lines.match(src.text, src.offset(m.node.Pos()), src.offset(m.node.End()))
+	match.Groups = make([]string, 0, len(r.wildcards)+1)
+	match.Groups = append(match.Groups, match.Text)
+	for _, name := range r.wildcards {
+		text := ""
+		if binding, ok := m.bindings[name]; ok {
+			text = src.bindingText(binding)
+		}
+		match.Groups = append(match.Groups, text)
+	}
+	return match
+}
+
+// captureGroups describes the groups of the matches, named after the
+// wildcards
+func (r *StructuralRule) captureGroups() []CaptureGroup {
+	groups := make([]CaptureGroup, 0, len(r.wildcards)+1)
+	groups = append(groups, CaptureGroup{Number: 0})
+	for i, name := range r.wildcards {
+		groups = append(groups, CaptureGroup{Number: i + 1, Name: name})
+	}
+	return groups
+}
+
+// bindingText returns the source text a wildcard matched
+func (s *structuralSource) bindingText(binding *structuralBinding) string {
+	texts := make([]string, len(binding.exprs))
+	for i, expr := range binding.exprs {
+		texts[i] = s.nodeText(expr)
+	}
+	text := strings.Join(texts, ", ")
+	if binding.ellipsis {
+		text += "..."
+	}
+	return text
+}
+
+// structuralEdit replaces a span of the replacement source
+type structuralEdit struct {
+	start, end int
+	text       string
+}
+
+// expand renders the replacement for a match, filling in the source text
+// of the wildcards and adding parentheses where operator precedence needs
+// them
+func (r *StructuralRule) expand(src *structuralSource, m structuralMatch) (string, error) {
+	offset := func(pos token.Pos) int {
+		return r.replacementFset.Position(pos).Offset
+	}
+
+	edits := make([]structuralEdit, 0)
+	var failed error
+	walkWithParent(r.replacement, func(n, parent ast.Node) bool {
+		ident, ok := n.(*ast.Ident)
+		if !ok || !isWildcard(ident.Name) {
+			return failed == nil
+		}
+		binding, ok := m.bindings[ident.Name]
+		if !ok {
+			failed = fmt.Errorf("wildcard %q is not bound", ident.Name)
+			return false
+		}
+
+		if !binding.spread {
+			text := src.nodeText(binding.exprs[0])
+			if needsParens(binding.exprs[0], parent, ident) {
+				text = "(" + text + ")"
+			}
+			edits = append(edits, structuralEdit{offset(ident.Pos()), offset(ident.End()), text})
+			return false
+		}
+
+		// A spread wildcard replaces "name..." with the arguments it matched
+		call := parent.(*ast.CallExpr)
+		start, end := offset(ident.Pos()), offset(call.Ellipsis)+len(token.ELLIPSIS.String())
+		if len(binding.exprs) == 0 && len(call.Args) > 1 {
+			// Drop the comma before the missing arguments
+			start = offset(call.Args[len(call.Args)-2].End())
+		}
+		edits = append(edits, structuralEdit{start, end, src.bindingText(binding)})
+		return false
+	})
+	if failed != nil {
+		return "", failed
+	}
+
+	var sb strings.Builder
+	last := 0
+	for _, edit := range edits {
+		sb.WriteString(r.replacementSource[last:edit.start])
+		sb.WriteString(edit.text)
+		last = edit.end
+	}
+	sb.WriteString(r.replacementSource[last:])
+	expanded := sb.String()
+
+	// The replacement takes the place of the match in its parent
+	root := r.replacement
+	if ident, ok := root.(*ast.Ident); ok && isWildcard(ident.Name) {
+		if binding := m.bindings[ident.Name]; !binding.spread {
+			root = binding.exprs[0]
+		}
+	}
+	if needsParens(root, m.parent, m.node) {
+		expanded = "(" + expanded + ")"
+	}
+	return expanded, nil
+}
+
+// checkRewrittenSource makes sure a rewrite of a valid source is valid Go
+func checkRewrittenSource(original *structuralSource, result string) error {
+	if original.err != nil {
+		return nil
+	}
+	if _, err := parser.ParseFile(token.NewFileSet(), "", result, parser.SkipObjectResolution); err != nil {
+		return fmt.Errorf("rewrite produces invalid Go: %w", err)
+	}
+	return nil
+}
+
+// isWildcard reports whether an identifier is a wildcard: a single
+// lowercase letter
+func isWildcard(name string) bool {
+	return len(name) == 1 && unicode.IsLower(rune(name[0]))
+}
+
+// spreadWildcard returns the wildcard spread as the last argument of call
+func spreadWildcard(call *ast.CallExpr) (string, bool) {
+	if !call.Ellipsis.IsValid() || len(call.Args) == 0 {
+		return "", false
+	}
+	ident, ok := call.Args[len(call.Args)-1].(*ast.Ident)
+	if !ok || !isWildcard(ident.Name) {
+		return "", false
+	}
+	return ident.Name, true
+}
+
+// isSpreadArgument reports whether ident is spread as the last argument of
+// parent
+func isSpreadArgument(ident *ast.Ident, parent ast.Node) bool {
+	call, ok := parent.(*ast.CallExpr)
+	if !ok {
+		return false
+	}
+	name, ok := spreadWildcard(call)
+	return ok && name == ident.Name && call.Args[len(call.Args)-1] == ident
+}
+
+// walkWithParent visits the nodes under root in depth-first order together
+// with their parent. Children are skipped when fn returns false.
+func walkWithParent(root ast.Node, fn func(n, parent ast.Node) bool) {
+	stack := make([]ast.Node, 0)
+	ast.Inspect(root, func(n ast.Node) bool {
+		if n == nil {
+			stack = stack[:len(stack)-1]
+			return false
+		}
+		var parent ast.Node
+		if len(stack) > 0 {
+			parent = stack[len(stack)-1]
+		}
+		if !fn(n, parent) {
+			return false
+		}
+		stack = append(stack, n)
+		return true
+	})
+}
+
+// matchStructure reports whether val matches pattern, binding wildcards in
+// bindings. With nil bindings wildcards are compared literally, which is
+// how a wildcard used again is checked against what it matched before.
+func matchStructure(bindings map[string]*structuralBinding, pattern, val reflect.Value) bool {
+	// A wildcard matches any expression, and the same one each time
+	if bindings != nil && pattern.IsValid() && pattern.Type() == identType {
+		name := pattern.Interface().(*ast.Ident).Name
+		if isWildcard(name) && val.IsValid() {
+			if expr, ok := val.Interface().(ast.Expr); ok && !val.IsNil() {
+				if old, ok := bindings[name]; ok {
+					return !old.spread && matchStructure(nil, reflect.ValueOf(old.exprs[0]), val)
+				}
+				bindings[name] = &structuralBinding{exprs: []ast.Expr{expr}}
+				return true
+			}
+		}
+	}
+
+	if !pattern.IsValid() || !val.IsValid() {
+		return !pattern.IsValid() && !val.IsValid()
+	}
+	if pattern.Type() != val.Type() {
+		return false
+	}
+
+	switch pattern.Type() {
+	case identType:
+		p, v := pattern.Interface().(*ast.Ident), val.Interface().(*ast.Ident)
+		return p == nil && v == nil || p != nil && v != nil && p.Name == v.Name
+	case objectType, positionType:
+		return true
+	case callExprType:
+		p, v := pattern.Interface().(*ast.CallExpr), val.Interface().(*ast.CallExpr)
+		if p != nil && v != nil {
+			if name, ok := spreadWildcard(p); ok && bindings != nil {
+				return matchSpreadCall(bindings, name, p, v)
+			}
+			// f(x) and f(x...) are different calls
+			if p.Ellipsis.IsValid() != v.Ellipsis.IsValid() {
+				return false
+			}
+		}
+	}
+
+	p, v := reflect.Indirect(pattern), reflect.Indirect(val)
+	if !p.IsValid() || !v.IsValid() {
+		return !p.IsValid() && !v.IsValid()
+	}
+
+	switch p.Kind() {
+	case reflect.Slice:
+		if p.Len() != v.Len() {
+			return false
+		}
+		for i := 0; i < p.Len(); i++ {
+			if !matchStructure(bindings, p.Index(i), v.Index(i)) {
+				return false
+			}
+		}
+		return true
+	case reflect.Struct:
+		for i := 0; i < p.NumField(); i++ {
+			if !matchStructure(bindings, p.Field(i), v.Field(i)) {
+				return false
+			}
+		}
+		return true
+	case reflect.Interface:
+		return matchStructure(bindings, p.Elem(), v.Elem())
+	}
+
+	// Tokens, literal values and other plain fields
+	return p.Interface() == v.Interface()
+}
+
+// matchSpreadCall matches a call whose pattern ends with a spread wildcard:
+// the leading arguments match one to one and the wildcard takes the rest
+func matchSpreadCall(bindings map[string]*structuralBinding, name string, p, v *ast.CallExpr) bool {
+	fixed := len(p.Args) - 1
+	if len(v.Args) < fixed || !matchStructure(bindings, reflect.ValueOf(p.Fun), reflect.ValueOf(v.Fun)) {
+		return false
+	}
+	for i := 0; i < fixed; i++ {
+		if !matchStructure(bindings, reflect.ValueOf(p.Args[i]), reflect.ValueOf(v.Args[i])) {
+			return false
+		}
+	}
+
+	rest := v.Args[fixed:]
+	ellipsis := v.Ellipsis.IsValid()
+	if ellipsis && len(rest) == 0 {
+		// The spread argument was taken by a leading wildcard
+		return false
+	}
+
+	if old, ok := bindings[name]; ok {
+		if !old.spread || old.ellipsis != ellipsis || len(old.exprs) != len(rest) {
+			return false
+		}
+		for i := range rest {
+			if !matchStructure(nil, reflect.ValueOf(old.exprs[i]), reflect.ValueOf(rest[i])) {
+				return false
+			}
+		}
+		return true
+	}
+	bindings[name] = &structuralBinding{exprs: rest, spread: true, ellipsis: ellipsis}
+	return true
+}
+
+// needsParens reports whether expr needs parentheses to take the place of
+// child in parent without changing how the code parses
+func needsParens(expr ast.Expr, parent ast.Node, child ast.Expr) bool {
+	// Operands of selectors, calls, indexing and conversions bind tightest
+	tight := false
+	switch p := parent.(type) {
+	case *ast.SelectorExpr, *ast.TypeAssertExpr, *ast.StarExpr, *ast.UnaryExpr:
+		tight = true
+	case *ast.CallExpr:
+		tight = p.Fun == child
+	case *ast.IndexExpr:
+		tight = p.X == child
+	case *ast.IndexListExpr:
+		tight = p.X == child
+	case *ast.SliceExpr:
+		tight = p.X == child
+	}
+
+	switch e := expr.(type) {
+	case *ast.BinaryExpr:
+		if p, ok := parent.(*ast.BinaryExpr); ok {
+			if p.X == child {
+				return p.Op.Precedence() > e.Op.Precedence()
+			}
+			return p.Op.Precedence() >= e.Op.Precedence()
+		}
+		return tight
+	case *ast.UnaryExpr, *ast.StarExpr:
+		return tight
+	}
+	return false
+}
+
+// findStructural matches pattern against the Go syntax tree of text. The
+// groups of each match are the text bound to the wildcards.
+func (r *searchRun) findStructural(text, pattern string) {
+	rule, err := NewStructuralRule(pattern, "")
+	if err != nil {
+		r.result.Err = err
+		return
+	}
+
+	src := parseStructuralSource(text)
+	found, err := rule.find(r.ctx, src)
+	if err != nil {
+		if r.ctx.Err() == nil {
+			r.result.Err = err
+		}
+		return
+	}
+
+	r.result.Groups = rule.captureGroups()
+	for _, m := range found {
+		if !r.add(rule.match(src, r.lines, m)) {
+			break
+		}
+	}
+}
+
+// structuralReplacer rewrites structural matches of the last searched text.
+// The replacements are computed on first use from the syntax tree, so each
+// one knows the context of its match.
+func (sm *SearchManager) structuralReplacer(pattern, replacement string) (replacer, error) {
+	rule, err := NewStructuralRule(pattern, replacement)
+	if err != nil {
+		return nil, err
+	}
+	if !rule.HasReplacement() {
+		return nil, fmt.Errorf("structural replacement must be a Go expression")
+	}
+
+	source := sm.lastSearchText
+	var expansions map[int]string
+	var lines lineIndex
+	return func(index int, match Match) (string, error) {
+		if expansions == nil {
+			if expansions, err = rule.expansions(source); err != nil {
+				return "", err
+			}
+			lines = newLineIndex(source)
+		}
+		expanded, ok := expansions[lines.offset(match.Start)]
+		if !ok {
+			return "", fmt.Errorf("line %d no longer matches the pattern", match.Start.Line)
+		}
+		return expanded, nil
+	}, nil
+}
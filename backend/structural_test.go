@@ -0,0 +1,188 @@
+package backend
+
+import (
+	"strings"
+	"testing"
+)
+
+const goSource = `package main
+
+import (
+	"errors"
+	"fmt"
+)
+
+func check(name string, n int) error {
+	if n < 0 {
+		return errors.New(fmt.Sprintf("%s: negative %d", name, n))
+	}
+	if n == 0 {
+		return errors.New(fmt.Sprintf("empty"))
+	}
+	return errors.New("ok")
+}
+`
+
+func TestStructuralRule_Find(t *testing.T) {
+	rule, err := NewStructuralRule("errors.New(fmt.Sprintf(a, b...))", "")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	matches, err := rule.Find(goSource)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(matches) != 2 {
+		t.Fatalf("Expected 2 matches, got %+v", matches)
+	}
+	if matches[0].Start.Line != 10 || matches[0].Text != `errors.New(fmt.Sprintf("%s: negative %d", name, n))` {
+		t.Errorf("Unexpected first match %+v", matches[0])
+	}
+	if matches[0].Groups[1] != `"%s: negative %d"` || matches[0].Groups[2] != "name, n" {
+		t.Errorf("Unexpected wildcard groups %q", matches[0].Groups)
+	}
+	if matches[1].Groups[2] != "" {
+		t.Errorf("Expected the spread wildcard to match no arguments, got %q", matches[1].Groups[2])
+	}
+}
+
+func TestStructuralRule_RepeatedWildcard(t *testing.T) {
+	source := "package p\n\nvar _ = pair(x, x) + pair(x, y) + pair(fn(1), fn( 1 ))\n"
+	rule, _ := NewStructuralRule("pair(a, a)", "")
+	matches, err := rule.Find(source)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(matches) != 2 || matches[0].Text != "pair(x, x)" || matches[1].Text != "pair(fn(1), fn( 1 ))" {
+		t.Errorf("Expected calls with equal arguments, got %+v", matches)
+	}
+}
+
+func TestStructuralRule_Rewrite(t *testing.T) {
+	rule, err := ParseStructuralRule("errors.New(fmt.Sprintf(a, b...)) -> fmt.Errorf(a, b...)")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	result, count, err := rule.Rewrite(goSource)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if count != 2 {
+		t.Errorf("Expected 2 rewrites, got %d", count)
+	}
+	if !strings.Contains(result, `return fmt.Errorf("%s: negative %d", name, n)`) {
+		t.Errorf("Expected the arguments to be passed on, got\n%s", result)
+	}
+	if !strings.Contains(result, `return fmt.Errorf("empty")`) {
+		t.Errorf("Expected the comma to be dropped without further arguments, got\n%s", result)
+	}
+	if !strings.Contains(result, `return errors.New("ok")`) {
+		t.Errorf("Expected other calls to be left alone, got\n%s", result)
+	}
+}
+
+func TestStructuralRule_Parentheses(t *testing.T) {
+	source := "package p\n\nvar v = double(a+b) * 2\nvar w = -neg(c)\n"
+
+	rule, _ := ParseStructuralRule("double(x) -> x * 2")
+	result, _, err := rule.Rewrite(source)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !strings.Contains(result, "var v = (a+b) * 2 * 2") {
+		t.Errorf("Expected parentheses around the sum only, got\n%s", result)
+	}
+
+	rule, _ = ParseStructuralRule("neg(x) -> -x")
+	result, _, err = rule.Rewrite(source)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !strings.Contains(result, "var w = -(-c)") {
+		t.Errorf("Expected parentheses around the negation, got\n%s", result)
+	}
+}
+
+func TestStructuralRule_ArrowInLiterals(t *testing.T) {
+	source := "package p\n\nvar v = strings.Split(s, \"->\")\nvar r = '-'\n"
+
+	rule, err := ParseStructuralRule(`strings.Split(x, "->") -> strings.Split(x, "=>" /* -> */)`)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	result, count, err := rule.Rewrite(source)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if count != 1 || !strings.Contains(result, `var v = strings.Split(s, "=>"`) {
+		t.Errorf("Expected the arrow in the string to be part of the pattern, got %d rewrites in\n%s", count, result)
+	}
+
+	rule, err = ParseStructuralRule("'-'->'+'")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if result, _, _ := rule.Rewrite(source); !strings.Contains(result, "var r = '+'") {
+		t.Errorf("Expected an arrow between rune literals to split the rule, got\n%s", result)
+	}
+}
+
+func TestStructuralRule_Errors(t *testing.T) {
+	tests := []struct {
+		rule string
+		want string
+	}{
+		{"", "empty"},
+		{"foo(", "invalid structural pattern"},
+		{"foo(a) -> ", "no replacement"},
+		{"foo(a) -> bar(b)", `wildcard "b"`},
+		{"foo(a, b...) -> bar(b)", "list of arguments"},
+		{"a -> b -> c", "of the form"},
+		{`f("->") -> g("->") -> h`, "of the form"},
+	}
+	for _, tt := range tests {
+		_, err := ParseStructuralRule(tt.rule)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("ParseStructuralRule(%q): expected error containing %q, got %v", tt.rule, tt.want, err)
+		}
+	}
+
+	// A rewrite that breaks valid code is refused
+	rule, _ := ParseStructuralRule("fmt -> 1")
+	if _, _, err := rule.Rewrite(goSource); err == nil {
+		t.Error("Expected an error for a rewrite producing invalid Go")
+	}
+}
+
+func TestSearchManager_Structural(t *testing.T) {
+	sm := NewSearchManager()
+	sm.SetOptions(SearchOptions{Structural: true, WrapAround: true})
+
+	matches := sm.Find(goSource, "errors.New(a)")
+	if len(matches) != 3 {
+		t.Fatalf("Expected 3 matches, got %+v", matches)
+	}
+	groups := sm.GetCaptureGroups()
+	if len(groups) != 2 || groups[1].Name != "a" {
+		t.Errorf("Expected a group named after the wildcard, got %+v", groups)
+	}
+
+	if err := sm.ValidatePattern("errors.New("); err == nil {
+		t.Error("Expected an invalid structural pattern to be reported")
+	}
+
+	options := ReplaceOptions{SearchOptions: sm.GetOptions(), ReplaceAll: true}
+	result, count := sm.Replace(goSource, "errors.New(fmt.Sprintf(a, b...))", "fmt.Errorf(a, b...)", options)
+	if count != 2 || !strings.Contains(result, `fmt.Errorf("empty")`) {
+		t.Errorf("Expected 2 structural replacements, got %d:\n%s", count, result)
+	}
+
+	// Templates see the wildcards by name
+	sm.Find(goSource, "errors.New(a)")
+	expanded, err := sm.ExpandReplacement(matches[2], 2, "errors.New(a)", `{{index .Named "a"}}`, ReplaceOptions{SearchOptions: sm.GetOptions(), Template: true})
+	if err != nil || expanded != `"ok"` {
+		t.Errorf("Expected the wildcard value, got %q (%v)", expanded, err)
+	}
+}
@@ -0,0 +1,138 @@
+package backend
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"text/template"
+)
+
+// TemplateMatchData is the data a template replacement is evaluated with
+type TemplateMatchData struct {
+	Text   string            // The matched text
+	Groups []string          // Capture group values, starting with the whole match
+	Named  map[string]string // Named capture group values
+	Index  int               // 0-based index of the match
+	Line   int               // 1-based line of the match start
+	Column int               // 1-based column of the match start
+}
+
+// Group returns capture group n, or "" if it does not exist
+func (d TemplateMatchData) Group(n int) string {
+	if n < 0 || n >= len(d.Groups) {
+		return ""
+	}
+	return d.Groups[n]
+}
+
+// TemplateReplacement is a replacement written as a Go text/template and
+// evaluated once per match, for replacements that have to be computed:
+//
+//	{{.Index}}                      0-based match index
+//	{{.Line}} {{.Column}}           position of the match
+//	{{.Text}} {{.Group 1}}          matched text and capture groups
+//	{{.Named.name}}                 named capture group
+//	{{upper .Text}} {{lower .Text}} change case
+//	{{add .Index 1}}                integer arithmetic, also on numeric groups
+//	{{pad 3 (add .Index 1)}}        zero-pad numbers (space-pad other text)
+//	{{printf "%02d" .Line}}         formatted output
+type TemplateReplacement struct {
+	source string
+	tmpl   *template.Template
+}
+
+// templateFuncs are the functions available to template replacements
+var templateFuncs = template.FuncMap{
+	"upper": strings.ToUpper,
+	"lower": strings.ToLower,
+	"add":   templateAdd,
+	"pad":   templatePad,
+}
+
+// NewTemplateReplacement parses a template replacement
+func NewTemplateReplacement(source string) (*TemplateReplacement, error) {
+	tmpl, err := template.New("replacement").Funcs(templateFuncs).Option("missingkey=error").Parse(source)
+	if err != nil {
+		return nil, fmt.Errorf("invalid replacement template: %w", err)
+	}
+	return &TemplateReplacement{source: source, tmpl: tmpl}, nil
+}
+
+// String returns the template source
+func (t *TemplateReplacement) String() string {
+	return t.source
+}
+
+// Execute evaluates the template for a match
+func (t *TemplateReplacement) Execute(data TemplateMatchData) (string, error) {
+	var sb strings.Builder
+	if err := t.tmpl.Execute(&sb, data); err != nil {
+		return "", fmt.Errorf("replacement template failed for match %d: %w", data.Index+1, err)
+	}
+	return sb.String(), nil
+}
+
+// newTemplateMatchData builds the template data for the match at index
+func newTemplateMatchData(match Match, index int, groups []CaptureGroup) TemplateMatchData {
+	data := TemplateMatchData{
+		Text:   match.Text,
+		Groups: match.Groups,
+		Named:  make(map[string]string),
+		Index:  index,
+		Line:   match.Start.Line,
+		Column: match.Start.Column,
+	}
+	if len(data.Groups) == 0 {
+		data.Groups = []string{match.Text}
+	}
+	for i, group := range groups {
+		if group.Name != "" && i < len(data.Groups) {
+			data.Named[group.Name] = data.Groups[i]
+		}
+	}
+	return data
+}
+
+// templateAdd sums integers, accepting numeric strings such as captured groups
+func templateAdd(values ...interface{}) (int, error) {
+	sum := 0
+	for _, value := range values {
+		n, err := templateInt(value)
+		if err != nil {
+			return 0, err
+		}
+		sum += n
+	}
+	return sum, nil
+}
+
+// templatePad left-pads value to width, with zeros for numbers and spaces
+// for other text
+func templatePad(width int, value interface{}) (string, error) {
+	if n, err := templateInt(value); err == nil {
+		if n < 0 {
+			return "-" + fmt.Sprintf("%0*d", width-1, -n), nil
+		}
+		return fmt.Sprintf("%0*d", width, n), nil
+	}
+	return fmt.Sprintf("%*v", width, value), nil
+}
+
+// templateInt converts a template value to an integer
+func templateInt(value interface{}) (int, error) {
+	switch v := value.(type) {
+	case int:
+		return v, nil
+	case int64:
+		return int(v), nil
+	case float64:
+		return int(v), nil
+	case string:
+		n, err := strconv.Atoi(strings.TrimSpace(v))
+		if err != nil {
+			return 0, fmt.Errorf("%q is not a number", v)
+		}
+		return n, nil
+	}
+	return 0, fmt.Errorf("%v is not a number", value)
+}
@@ -0,0 +1,91 @@
+package backend
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestTemplateReplacement_Execute(t *testing.T) {
+	match := Match{
+		Start:  Position{Line: 7, Column: 3},
+		End:    Position{Line: 7, Column: 11},
+		Text:   "item_42",
+		Groups: []string{"item_42", "item", "42"},
+	}
+	groups := []CaptureGroup{{Number: 0}, {Number: 1, Name: "name"}, {Number: 2, Name: "num"}}
+	data := newTemplateMatchData(match, 4, groups)
+
+	tests := []struct {
+		template string
+		expected string
+	}{
+		{"{{.Text}}", "item_42"},
+		{"{{upper (.Group 1)}}", "ITEM"},
+		{"{{.Named.name}}-{{add .Named.num 8}}", "item-50"},
+		{"{{pad 3 (add .Index 1)}}", "005"},
+		{"{{pad 6 .Named.name}}", "  item"},
+		{`{{printf "%s@%d:%d" (lower .Text) .Line .Column}}`, "item_42@7:3"},
+		{"{{index .Groups 2}}", "42"},
+	}
+
+	for _, tt := range tests {
+		tmpl, err := NewTemplateReplacement(tt.template)
+		if err != nil {
+			t.Errorf("%q: unexpected parse error: %v", tt.template, err)
+			continue
+		}
+		result, err := tmpl.Execute(data)
+		if err != nil {
+			t.Errorf("%q: unexpected error: %v", tt.template, err)
+			continue
+		}
+		if result != tt.expected {
+			t.Errorf("%q: expected %q, got %q", tt.template, tt.expected, result)
+		}
+	}
+}
+
+func TestTemplateReplacement_Errors(t *testing.T) {
+	if _, err := NewTemplateReplacement("{{upper .Text"); err == nil {
+		t.Error("Expected a parse error for an unclosed action")
+	}
+	if _, err := NewTemplateReplacement("{{shout .Text}}"); err == nil {
+		t.Error("Expected a parse error for an unknown function")
+	}
+
+	tmpl, err := NewTemplateReplacement("{{add .Text 1}}")
+	if err != nil {
+		t.Fatalf("Unexpected parse error: %v", err)
+	}
+	_, err = tmpl.Execute(TemplateMatchData{Text: "abc", Index: 2})
+	if err == nil || !strings.Contains(err.Error(), "match 3") {
+		t.Errorf("Expected an execution error naming match 3, got %v", err)
+	}
+}
+
+func TestSearchManager_ReplaceTemplate(t *testing.T) {
+	sm := NewSearchManager()
+	text := "step one\nstep two\nstep three"
+	options := ReplaceOptions{
+		SearchOptions: SearchOptions{RegularExpression: true},
+		ReplaceAll:    true,
+		Template:      true,
+	}
+
+	result, count := sm.Replace(text, `step (\w+)`, `{{pad 2 (add .Index 1)}}. {{upper (.Group 1)}} (line {{.Line}})`, options)
+	if count != 3 {
+		t.Errorf("Expected 3 replacements, got %d", count)
+	}
+	if expected := "01. ONE (line 1)\n02. TWO (line 2)\n03. THREE (line 3)"; result != expected {
+		t.Errorf("Expected %q, got %q", expected, result)
+	}
+
+	// An error on any match leaves the text untouched
+	result, count = sm.Replace("v1 v2 vx", `v(\w)`, "{{add (.Group 1) 1}}", options)
+	if count != 0 || result != "v1 v2 vx" {
+		t.Errorf("Expected no replacement, got %d giving %q", count, result)
+	}
+	if sm.GetError() == nil || !strings.Contains(sm.GetError().Error(), "match 3") {
+		t.Errorf("Expected an error for match 3, got %v", sm.GetError())
+	}
+}
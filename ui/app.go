@@ -18,6 +18,12 @@ func StartApp() {
 	// Initialize dialogs after window is created
 	editor.InitializeDialogs(w)
 	
+	// Load custom syntax themes, reloading them as they change
+	editor.LoadCustomThemes(w)
+	
+	// Load user language definitions and lexers into the language registry
+	editor.LoadLanguageDefinitions(w)
+	
 	menu := NewMenu(w, editor)
 
 	// Set up editor callbacks
@@ -32,6 +38,7 @@ func StartApp() {
 
 	// Apply configuration
 	editor.ApplyConfiguration()
+	editor.ApplyAppTheme(a)
 	
 	// Set up window from configuration
 	config := editor.ConfigManager.GetUIConfig()
@@ -143,6 +150,21 @@ func setupShortcuts(w fyne.Window, editor *Editor) {
 		editor.ShowReplaceDialog()
 	})
 
+	// Find in Files
+	w.Canvas().AddShortcut(&desktop.CustomShortcut{KeyName: fyne.KeyF, Modifier: fyne.KeyModifierControl | fyne.KeyModifierShift}, func(sc fyne.Shortcut) {
+		editor.ShowFindInFilesPanel()
+	})
+
+	// Occurrences
+	w.Canvas().AddShortcut(&desktop.CustomShortcut{KeyName: fyne.KeyO, Modifier: fyne.KeyModifierControl | fyne.KeyModifierShift}, func(sc fyne.Shortcut) {
+		editor.ShowOccurrencesPanel()
+	})
+
+	// Filter Lines
+	w.Canvas().AddShortcut(&desktop.CustomShortcut{KeyName: fyne.KeyL, Modifier: fyne.KeyModifierControl}, func(sc fyne.Shortcut) {
+		editor.ShowFilterLinesPanel()
+	})
+
 	// Find Next
 	w.Canvas().AddShortcut(&desktop.CustomShortcut{KeyName: fyne.KeyF3}, func(sc fyne.Shortcut) {
 		editor.FindNext()
@@ -153,6 +175,11 @@ func setupShortcuts(w fyne.Window, editor *Editor) {
 		editor.FindPrevious()
 	})
 
+	// Quick Open
+	w.Canvas().AddShortcut(&desktop.CustomShortcut{KeyName: fyne.KeyP, Modifier: fyne.KeyModifierControl}, func(sc fyne.Shortcut) {
+		editor.ShowQuickOpenDialog()
+	})
+
 	// Go to Line
 	w.Canvas().AddShortcut(&desktop.CustomShortcut{KeyName: fyne.KeyG, Modifier: fyne.KeyModifierControl}, func(sc fyne.Shortcut) {
 		editor.ShowGoToLineDialog()
@@ -168,6 +195,11 @@ func setupShortcuts(w fyne.Window, editor *Editor) {
 		editor.UnindentSelectedLines()
 	})
 
+	// Inspect Token at Cursor
+	w.Canvas().AddShortcut(&desktop.CustomShortcut{KeyName: fyne.KeyI, Modifier: fyne.KeyModifierControl | fyne.KeyModifierShift}, func(sc fyne.Shortcut) {
+		showTokenInspector(w, editor)
+	})
+
 	// Quit
 	w.Canvas().AddShortcut(&desktop.CustomShortcut{KeyName: fyne.KeyQ, Modifier: fyne.KeyModifierControl}, func(sc fyne.Shortcut) {
 		w.Close()
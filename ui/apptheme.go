@@ -0,0 +1,154 @@
+package ui
+
+import (
+	"fmt"
+	"image/color"
+	"log"
+
+	fyne "fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/theme"
+	"github.com/kenelite/goeditor/backend"
+	"github.com/kenelite/goeditor/ui/syntax"
+)
+
+// colorNameLineNumber is the color of line numbers in the editor theme
+const colorNameLineNumber fyne.ThemeColorName = "lineNumber"
+
+// editorTheme is the app-wide Fyne theme, colored after the syntax theme so
+// that the window chrome and the highlighted text match
+type editorTheme struct {
+	base    fyne.Theme
+	variant fyne.ThemeVariant
+	colors  map[fyne.ThemeColorName]color.Color
+}
+
+// newEditorTheme builds a Fyne theme of variant from the current syntax
+// theme of themes
+func newEditorTheme(themes *syntax.ThemeManager, variant fyne.ThemeVariant) *editorTheme {
+	t := &editorTheme{
+		base:    theme.DefaultTheme(),
+		variant: variant,
+		colors:  make(map[fyne.ThemeColorName]color.Color),
+	}
+
+	background := themes.GetBackgroundColor()
+	t.colors[theme.ColorNameBackground] = background
+	t.colors[theme.ColorNameInputBackground] = background
+	t.colors[theme.ColorNameForeground] = themes.GetForegroundColor()
+	if selection, ok := themes.GetSelectionColor(); ok {
+		t.colors[theme.ColorNameSelection] = selection
+	}
+	if lineNumber, ok := themes.GetLineNumberColor(); ok {
+		t.colors[colorNameLineNumber] = lineNumber
+	} else {
+		t.colors[colorNameLineNumber] = t.colors[theme.ColorNameForeground]
+	}
+	if cursor, ok := themes.GetCursorColor(); ok {
+		// Entries draw their cursor in the primary color
+		t.colors[theme.ColorNamePrimary] = cursor
+	}
+	return t
+}
+
+// Color returns the syntax theme's color for name, or the default theme's
+// color of the editor's variant
+func (t *editorTheme) Color(name fyne.ThemeColorName, _ fyne.ThemeVariant) color.Color {
+	if c, exists := t.colors[name]; exists {
+		return c
+	}
+	return t.base.Color(name, t.variant)
+}
+
+// Font returns the default theme's font
+func (t *editorTheme) Font(style fyne.TextStyle) fyne.Resource {
+	return t.base.Font(style)
+}
+
+// Icon returns the default theme's icon
+func (t *editorTheme) Icon(name fyne.ThemeIconName) fyne.Resource {
+	return t.base.Icon(name)
+}
+
+// Size returns the default theme's size
+func (t *editorTheme) Size(name fyne.ThemeSizeName) float32 {
+	return t.base.Size(name)
+}
+
+// lineNumberColor returns the color line numbers are drawn in
+func lineNumberColor() color.Color {
+	if app := fyne.CurrentApp(); app != nil {
+		if t, ok := app.Settings().Theme().(*editorTheme); ok {
+			return t.Color(colorNameLineNumber, t.variant)
+		}
+	}
+	return theme.ForegroundColor()
+}
+
+// resolveThemeVariant maps UIConfig.Theme onto a theme variant. "auto"
+// follows the system's preference.
+func resolveThemeVariant(mode string, system fyne.ThemeVariant) fyne.ThemeVariant {
+	switch mode {
+	case "dark":
+		return theme.VariantDark
+	case "light":
+		return theme.VariantLight
+	}
+	return system
+}
+
+// syntaxThemeFor picks the syntax theme for the UI mode. "light" and
+// "dark" use the configured light and dark themes, "auto" switches between
+// them with the system, and any other mode uses the default theme.
+func syntaxThemeFor(mode string, config backend.SyntaxConfig, variant fyne.ThemeVariant) string {
+	switch mode {
+	case "light", "dark", "auto":
+	default:
+		return config.DefaultTheme
+	}
+	if resolveThemeVariant(mode, variant) == theme.VariantDark {
+		return config.DarkTheme
+	}
+	return config.LightTheme
+}
+
+// ApplyAppTheme colors the whole app after UIConfig.Theme and the syntax
+// theme, and keeps following the system's dark mode preference
+func (e *Editor) ApplyAppTheme(app fyne.App) {
+	first := e.themeApp == nil
+	e.themeApp = app
+	e.refreshAppTheme(true)
+
+	if first {
+		// Called when the system switches between light and dark, and when
+		// the theme is set
+		app.Settings().AddListener(func(fyne.Settings) {
+			e.refreshAppTheme(false)
+		})
+	}
+}
+
+// refreshAppTheme selects the syntax theme and applies a matching Fyne
+// theme. Unless forced, nothing is done when neither changed.
+func (e *Editor) refreshAppTheme(force bool) {
+	if e.themeApp == nil {
+		e.repaintHighlighting()
+		return
+	}
+	settings := e.themeApp.Settings()
+	mode := e.ConfigManager.GetUIConfig().Theme
+	variant := resolveThemeVariant(mode, settings.ThemeVariant())
+	name := syntaxThemeFor(mode, e.ConfigManager.GetSyntaxConfig(), variant)
+
+	key := fmt.Sprintf("%s/%d", name, variant)
+	if !force && key == e.appThemeKey {
+		return
+	}
+	e.appThemeKey = key
+
+	themes := syntax.GetThemeManager()
+	if err := themes.SetTheme(name); err != nil {
+		log.Printf("Failed to set syntax theme %s: %v", name, err)
+	}
+	settings.SetTheme(newEditorTheme(themes, variant))
+	e.repaintHighlighting()
+}
@@ -0,0 +1,118 @@
+package ui
+
+import (
+	"image/color"
+	"testing"
+
+	"fyne.io/fyne/v2/test"
+	"fyne.io/fyne/v2/theme"
+	"github.com/kenelite/goeditor/backend"
+	"github.com/kenelite/goeditor/ui/syntax"
+)
+
+func TestSyntaxThemeFor(t *testing.T) {
+	config := backend.SyntaxConfig{DefaultTheme: "vs", LightTheme: "github", DarkTheme: "monokai"}
+
+	if variant := resolveThemeVariant("dark", theme.VariantLight); variant != theme.VariantDark {
+		t.Error("Expected the dark variant to be forced")
+	}
+	if variant := resolveThemeVariant("auto", theme.VariantDark); variant != theme.VariantDark {
+		t.Error("Expected auto to follow the system")
+	}
+	if name := syntaxThemeFor("light", config, theme.VariantDark); name != "github" {
+		t.Errorf("Expected the light theme, got %s", name)
+	}
+	if name := syntaxThemeFor("dark", config, theme.VariantLight); name != "monokai" {
+		t.Errorf("Expected the dark theme, got %s", name)
+	}
+	if name := syntaxThemeFor("", config, theme.VariantDark); name != "vs" {
+		t.Errorf("Expected the default theme, got %s", name)
+	}
+	if name := syntaxThemeFor("auto", config, theme.VariantDark); name != "monokai" {
+		t.Errorf("Expected the dark theme of the pair, got %s", name)
+	}
+	if name := syntaxThemeFor("auto", config, theme.VariantLight); name != "github" {
+		t.Errorf("Expected the light theme of the pair, got %s", name)
+	}
+}
+
+func TestApplyAppTheme(t *testing.T) {
+	testApp := test.NewApp()
+	defer testApp.Quit()
+
+	themes := syntax.GetThemeManager()
+	err := themes.RegisterCustomTheme(&syntax.ThemeConfig{
+		Name:       "app-theme-test",
+		Background: "#102030",
+		Foreground: "#e0e0e0",
+		Selection:  "#405060",
+		LineNumber: "#708090",
+		Cursor:     "#ff8800",
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	editor := NewEditor()
+	editor.ConfigManager.UpdateUIConfig(backend.UIConfig{Theme: "dark"})
+	syntaxConfig := editor.ConfigManager.GetSyntaxConfig()
+	syntaxConfig.DarkTheme = "app-theme-test"
+	editor.ConfigManager.UpdateSyntaxConfig(syntaxConfig)
+	editor.ApplyAppTheme(testApp)
+
+	current, ok := testApp.Settings().Theme().(*editorTheme)
+	if !ok {
+		t.Fatal("Expected the editor theme to be applied to the app")
+	}
+	if themes.GetCurrentThemeName() != "app-theme-test" {
+		t.Errorf("Expected the dark syntax theme, got %s", themes.GetCurrentThemeName())
+	}
+	expected := map[string]color.Color{
+		"background": color.RGBA{R: 0x10, G: 0x20, B: 0x30, A: 0xff},
+		"selection":  color.RGBA{R: 0x40, G: 0x50, B: 0x60, A: 0xff},
+		"cursor":     color.RGBA{R: 0xff, G: 0x88, B: 0x00, A: 0xff},
+		"lineNumber": color.RGBA{R: 0x70, G: 0x80, B: 0x90, A: 0xff},
+	}
+	actual := map[string]color.Color{
+		"background": current.Color(theme.ColorNameBackground, theme.VariantLight),
+		"selection":  current.Color(theme.ColorNameSelection, theme.VariantLight),
+		"cursor":     current.Color(theme.ColorNamePrimary, theme.VariantLight),
+		"lineNumber": lineNumberColor(),
+	}
+	for name, want := range expected {
+		if actual[name] != want {
+			t.Errorf("Expected %s %v, got %v", name, want, actual[name])
+		}
+	}
+	if current.Color(theme.ColorNameButton, theme.VariantLight) != theme.DefaultTheme().Color(theme.ColorNameButton, theme.VariantDark) {
+		t.Error("Expected other colors to come from the dark default theme")
+	}
+}
+
+func TestUseSyntaxTheme(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	testApp := test.NewApp()
+	defer testApp.Quit()
+
+	editor := NewEditor()
+	editor.ConfigManager.UpdateUIConfig(backend.UIConfig{Theme: "light"})
+	editor.ApplyAppTheme(testApp)
+
+	// An explicit mode shows the chosen theme, dark or not
+	themes := syntax.GetThemeManager()
+	for _, name := range []string{"dracula", "github"} {
+		if err := editor.UseSyntaxTheme(name); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if themes.GetCurrentThemeName() != name {
+			t.Errorf("Expected %s in light mode, got %s", name, themes.GetCurrentThemeName())
+		}
+	}
+	if config := editor.ConfigManager.GetSyntaxConfig(); config.LightTheme != "github" {
+		t.Errorf("Expected the light theme to be saved, got %s", config.LightTheme)
+	}
+
+	if err := editor.UseSyntaxTheme("no-such-theme"); err == nil {
+		t.Error("Expected an error for an unknown theme")
+	}
+}
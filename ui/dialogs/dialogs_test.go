@@ -1,7 +1,13 @@
 package dialogs
 
 import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+	"time"
+	"fyne.io/fyne/v2"
 	"fyne.io/fyne/v2/test"
 	"github.com/kenelite/goeditor/backend"
 )
@@ -173,4 +179,444 @@ func TestFindDialog_Options(t *testing.T) {
 	if len(matches) != 1 {
 		t.Errorf("Case sensitive: Expected 1 match, got %d", len(matches))
 	}
-}
\ No newline at end of file
+}
+
+// MockQuickOpenEditor implements QuickOpenEditorInterface for testing
+type MockQuickOpenEditor struct {
+	loadedPath string
+	line       int
+}
+
+func (m *MockQuickOpenEditor) LoadFile(path string) error {
+	m.loadedPath = path
+	return nil
+}
+
+func (m *MockQuickOpenEditor) GoToLine(lineNumber int) bool {
+	m.line = lineNumber
+	return true
+}
+
+func TestQuickOpenDialog_OpenWithLine(t *testing.T) {
+	app := test.NewApp()
+	window := test.NewWindow(nil)
+	defer app.Quit()
+
+	root := t.TempDir()
+	for _, name := range []string{"editor.go", "search.go"} {
+		if err := os.WriteFile(filepath.Join(root, name), []byte("package main\n"), 0644); err != nil {
+			t.Fatalf("Failed to write file: %v", err)
+		}
+	}
+
+	index := backend.NewFileIndex(root)
+	index.Refresh()
+
+	editor := &MockQuickOpenEditor{}
+	dialog := NewQuickOpenDialog(editor, index, window)
+	dialog.Show()
+
+	dialog.SetQuery("srch:12")
+	results := dialog.GetResults()
+	if len(results) != 1 || results[0].Path != "search.go" {
+		t.Fatalf("Expected search.go as the only result, got %v", results)
+	}
+
+	dialog.openSelected()
+
+	if editor.loadedPath != filepath.Join(root, "search.go") {
+		t.Errorf("Expected search.go to be loaded, got '%s'", editor.loadedPath)
+	}
+	if editor.line != 12 {
+		t.Errorf("Expected jump to line 12, got %d", editor.line)
+	}
+	if dialog.IsVisible() {
+		t.Error("Dialog should be hidden after opening a file")
+	}
+}
+
+func TestQuickOpenDialog_Refresh(t *testing.T) {
+	app := test.NewApp()
+	window := test.NewWindow(nil)
+	defer app.Quit()
+
+	index := backend.NewFileIndex("")
+	dialog := NewQuickOpenDialog(&MockQuickOpenEditor{}, index, window)
+	dialog.Show()
+	if dialog.statusLabel.Text != "Open a file or folder to index its files" {
+		t.Errorf("Expected a prompt to open a workspace, got %q", dialog.statusLabel.Text)
+	}
+
+	root := t.TempDir()
+	if err := os.WriteFile(filepath.Join(root, "main.go"), []byte("package main\n"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	index.SetRoot(root)
+	index.Refresh()
+	dialog.Refresh()
+
+	if results := dialog.GetResults(); len(results) != 1 || results[0].Path != "main.go" {
+		t.Errorf("Expected the indexed file after a refresh, got %v", results)
+	}
+	if dialog.statusLabel.Text != "1 of 1 files" {
+		t.Errorf("Expected the file count, got %q", dialog.statusLabel.Text)
+	}
+}
+
+// MockHighlightEditor records the search highlights painted by the dialogs
+type MockHighlightEditor struct {
+	MockEditor
+	highlights []backend.Match
+	current    int
+}
+
+func (m *MockHighlightEditor) SetSearchHighlights(matches []backend.Match, current int) {
+	m.highlights = matches
+	m.current = current
+}
+
+func (m *MockHighlightEditor) SetCurrentSearchHighlight(current int) {
+	m.current = current
+}
+
+func (m *MockHighlightEditor) ClearSearchHighlights() {
+	m.highlights = nil
+	m.current = -1
+}
+
+func TestFindDialog_Highlights(t *testing.T) {
+	app := test.NewApp()
+	window := test.NewWindow(nil)
+	defer app.Quit()
+
+	editor := &MockHighlightEditor{MockEditor: MockEditor{content: "Hello World\nHello Universe"}}
+	searchManager := backend.NewSearchManager()
+
+	dialog := NewFindDialog(editor, searchManager, window)
+	dialog.Show()
+
+	// Highlights follow the search text as it is typed
+	dialog.SetSearchText("Hel")
+	if len(editor.highlights) != 2 || editor.current != 0 {
+		t.Fatalf("Expected 2 highlights with current 0, got %d with current %d", len(editor.highlights), editor.current)
+	}
+	dialog.SetSearchText("Hello U")
+	if len(editor.highlights) != 1 {
+		t.Fatalf("Expected 1 highlight, got %d", len(editor.highlights))
+	}
+
+	dialog.SetSearchText("Hello")
+	dialog.FindNext()
+	if editor.current != 1 {
+		t.Errorf("Expected current highlight 1 after FindNext, got %d", editor.current)
+	}
+
+	dialog.Hide()
+	if editor.highlights != nil || editor.current != -1 {
+		t.Error("Highlights should be cleared when the dialog is hidden")
+	}
+}
+
+func TestFindDialog_PatternError(t *testing.T) {
+	app := test.NewApp()
+	window := test.NewWindow(nil)
+	defer app.Quit()
+
+	editor := &MockEditor{content: "foo(bar)"}
+	searchManager := backend.NewSearchManager()
+
+	dialog := NewFindDialog(editor, searchManager, window)
+	dialog.optionsCheck["regex"].SetChecked(true)
+
+	dialog.SetSearchText("foo(")
+	if expected := "Invalid pattern at position 4: missing closing )"; dialog.resultLabel.Text != expected {
+		t.Errorf("Expected result label %q, got %q", expected, dialog.resultLabel.Text)
+	}
+
+	// PCRE syntax allows lookarounds
+	dialog.optionsCheck["pcre"].SetChecked(true)
+	dialog.SetSearchText(`\w+(?=\()`)
+	if searchManager.GetMatchCount() != 1 || searchManager.GetError() != nil {
+		t.Errorf("Expected 1 match without error, got %d (%v)", searchManager.GetMatchCount(), searchManager.GetError())
+	}
+}
+
+func TestFindDialog_Scope(t *testing.T) {
+	app := test.NewApp()
+	window := test.NewWindow(nil)
+	defer app.Quit()
+
+	editor := &MockEditor{content: "a a a"}
+	searchManager := backend.NewSearchManager()
+
+	dialog := NewFindDialog(editor, searchManager, window)
+	if dialog.scopeSelect.Selected != "Whole document" {
+		t.Errorf("Expected whole document scope by default, got %q", dialog.scopeSelect.Selected)
+	}
+
+	dialog.scopeSelect.SetSelected("Comments only")
+	if scope := searchManager.GetOptions().Scope; scope != backend.ScopeComments {
+		t.Errorf("Expected comments scope, got %q", scope)
+	}
+}
+
+func TestFindDialog_BackgroundSearch(t *testing.T) {
+	app := test.NewApp()
+	window := test.NewWindow(nil)
+	defer app.Quit()
+
+	editor := &MockEditor{content: strings.Repeat("needle in a haystack\n", asyncSearchThreshold/20)}
+	searchManager := backend.NewSearchManager()
+
+	dialog := NewFindDialog(editor, searchManager, window)
+	finished := make(chan string, 2)
+	dialog.onSearchFinished = func() {
+		finished <- searchManager.GetPattern()
+	}
+	dialog.searchEntry.Text = "needle"
+	dialog.performSearch("needle")
+	if !dialog.IsSearching() {
+		t.Fatal("Expected a large buffer to be searched in the background")
+	}
+
+	// A newer query cancels the running search
+	dialog.searchEntry.Text = "hay"
+	dialog.performSearch("hay")
+
+	select {
+	case pattern := <-finished:
+		if pattern != "hay" {
+			t.Errorf("Expected only the latest query to finish, got %q", pattern)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Background search did not finish")
+	}
+	if dialog.IsSearching() {
+		t.Error("Expected the search to be done once its results are shown")
+	}
+
+	if searchManager.GetPattern() != "hay" {
+		t.Errorf("Expected results for the latest query, got %q", searchManager.GetPattern())
+	}
+	if expected := fmt.Sprintf("Match 1 of %d+", backend.DefaultMatchLimit); dialog.resultLabel.Text != expected {
+		t.Errorf("Expected result label %q, got %q", expected, dialog.resultLabel.Text)
+	}
+}
+
+func TestReplaceDialog_Preview(t *testing.T) {
+	app := test.NewApp()
+	window := test.NewWindow(nil)
+	defer app.Quit()
+
+	editor := &MockEditor{content: "first_name = 1\nlast_name = 2"}
+	searchManager := backend.NewSearchManager()
+
+	dialog := NewReplaceDialog(editor, searchManager, window)
+	dialog.optionsCheck["regex"].SetChecked(true)
+	dialog.SetSearchText(`(\w+)_name`)
+	dialog.SetReplaceText(`\u${1}Name`)
+
+	if expected := `"first_name" → "FirstName"`; dialog.previewLabel.Text != expected {
+		t.Errorf("Expected preview %q, got %q", expected, dialog.previewLabel.Text)
+	}
+
+	// The preview follows the current match
+	dialog.FindNext()
+	if expected := `"last_name" → "LastName"`; dialog.previewLabel.Text != expected {
+		t.Errorf("Expected preview %q, got %q", expected, dialog.previewLabel.Text)
+	}
+
+	// Invalid templates are reported without touching the content
+	dialog.SetReplaceText("${1")
+	dialog.ReplaceAll()
+	if editor.GetContent() != "first_name = 1\nlast_name = 2" {
+		t.Errorf("Content should be unchanged, got %q", editor.GetContent())
+	}
+	if expected := `Invalid replacement: invalid replacement at position 1: unterminated ${`; dialog.resultLabel.Text != expected {
+		t.Errorf("Expected result label %q, got %q", expected, dialog.resultLabel.Text)
+	}
+}
+
+func TestReplaceDialog_PreserveCase(t *testing.T) {
+	app := test.NewApp()
+	window := test.NewWindow(nil)
+	defer app.Quit()
+
+	editor := &MockEditor{content: "UserId userId"}
+	searchManager := backend.NewSearchManager()
+
+	dialog := NewReplaceDialog(editor, searchManager, window)
+	dialog.optionsCheck["preserveCase"].SetChecked(true)
+	dialog.SetSearchText("userid")
+	dialog.SetReplaceText("accountId")
+
+	if expected := `"UserId" → "AccountId"`; dialog.previewLabel.Text != expected {
+		t.Errorf("Expected preview %q, got %q", expected, dialog.previewLabel.Text)
+	}
+
+	dialog.ReplaceAll()
+	if expected := "AccountId accountId"; editor.GetContent() != expected {
+		t.Errorf("Expected content %q, got %q", expected, editor.GetContent())
+	}
+}
+
+func TestReplaceDialog_TemplateReplacement(t *testing.T) {
+	app := test.NewApp()
+	window := test.NewWindow(nil)
+	defer app.Quit()
+
+	editor := &MockEditor{content: "item item item"}
+	searchManager := backend.NewSearchManager()
+
+	dialog := NewReplaceDialog(editor, searchManager, window)
+	dialog.optionsCheck["template"].SetChecked(true)
+	dialog.SetSearchText("item")
+	dialog.SetReplaceText("{{.Text}}{{pad 2 (add .Index 1)}}")
+
+	if expected := `"item" → "item01"`; dialog.previewLabel.Text != expected {
+		t.Errorf("Expected preview %q, got %q", expected, dialog.previewLabel.Text)
+	}
+
+	// Template errors are reported before anything is replaced
+	dialog.SetReplaceText("{{.Text")
+	dialog.ReplaceAll()
+	if editor.GetContent() != "item item item" {
+		t.Errorf("Content should be unchanged, got %q", editor.GetContent())
+	}
+
+	dialog.SetReplaceText("{{.Text}}{{pad 2 (add .Index 1)}}")
+	dialog.ReplaceAll()
+	if expected := "item01 item02 item03"; editor.GetContent() != expected {
+		t.Errorf("Expected content %q, got %q", expected, editor.GetContent())
+	}
+}
+
+func TestFindDialog_History(t *testing.T) {
+	app := test.NewApp()
+	window := test.NewWindow(nil)
+	defer app.Quit()
+
+	editor := &MockEditor{content: "alpha beta 42"}
+	searchManager := backend.NewSearchManager()
+	history := backend.NewSearchHistory(t.TempDir())
+	history.AddSearch("alpha", backend.SearchOptions{MultiLine: true, WrapAround: true})
+	history.AddSearch(`\d+`, backend.SearchOptions{RegularExpression: true, MultiLine: true, WrapAround: true})
+
+	dialog := NewFindDialog(editor, searchManager, window)
+	dialog.SetHistory(history)
+	dialog.SetSearchText("bet")
+
+	// Up shows older entries with their options
+	dialog.searchEntry.TypedKey(&fyne.KeyEvent{Name: fyne.KeyUp})
+	if dialog.searchEntry.Text != `\d+` || !dialog.optionsCheck["regex"].Checked {
+		t.Errorf("Expected the latest regex search, got %q (regex %v)", dialog.searchEntry.Text, dialog.optionsCheck["regex"].Checked)
+	}
+	if searchManager.GetMatchCount() != 1 || searchManager.GetMatches()[0].Text != "42" {
+		t.Errorf("Expected the history search to run, got %d matches", searchManager.GetMatchCount())
+	}
+
+	dialog.searchEntry.TypedKey(&fyne.KeyEvent{Name: fyne.KeyUp})
+	dialog.searchEntry.TypedKey(&fyne.KeyEvent{Name: fyne.KeyUp})
+	if dialog.searchEntry.Text != "alpha" || dialog.optionsCheck["regex"].Checked {
+		t.Errorf("Expected the oldest search, got %q", dialog.searchEntry.Text)
+	}
+
+	// Down past the newest entry restores the typed text
+	dialog.searchEntry.TypedKey(&fyne.KeyEvent{Name: fyne.KeyDown})
+	dialog.searchEntry.TypedKey(&fyne.KeyEvent{Name: fyne.KeyDown})
+	if dialog.searchEntry.Text != "bet" {
+		t.Errorf("Expected the typed text back, got %q", dialog.searchEntry.Text)
+	}
+
+	// Navigating matches records the search
+	dialog.FindNext()
+	if searches := history.GetSearches(); searches[0].Pattern != "bet" {
+		t.Errorf("Expected the search to be recorded, got %+v", searches[0])
+	}
+}
+
+func TestReplaceDialog_SavedSearches(t *testing.T) {
+	app := test.NewApp()
+	window := test.NewWindow(nil)
+	defer app.Quit()
+
+	editor := &MockEditor{content: "id1 id22"}
+	searchManager := backend.NewSearchManager()
+	history := backend.NewSearchHistory(t.TempDir())
+
+	dialog := NewReplaceDialog(editor, searchManager, window)
+	dialog.SetHistory(history)
+	dialog.optionsCheck["regex"].SetChecked(true)
+	dialog.SetSearchText(`id(\d+)`)
+	dialog.SetReplaceText("ID-$1")
+
+	if err := dialog.SaveSearch("ids"); err != nil {
+		t.Fatalf("Failed to save search: %v", err)
+	}
+	if len(dialog.savedSelect.Options) != 1 || dialog.savedSelect.Options[0] != "ids" {
+		t.Errorf("Expected the saved search in the dropdown, got %v", dialog.savedSelect.Options)
+	}
+
+	// Reset the dialog, then recall the saved search
+	dialog.optionsCheck["regex"].SetChecked(false)
+	dialog.SetSearchText("other")
+	dialog.SetReplaceText("")
+	dialog.savedSelect.SetSelected("ids")
+
+	if dialog.searchEntry.Text != `id(\d+)` || dialog.replaceEntry.Text != "ID-$1" || !dialog.optionsCheck["regex"].Checked {
+		t.Errorf("Expected the saved search to be loaded, got %q -> %q", dialog.searchEntry.Text, dialog.replaceEntry.Text)
+	}
+
+	dialog.ReplaceAll()
+	if editor.content != "ID-1 ID-22" {
+		t.Errorf("Unexpected content %q", editor.content)
+	}
+	if replacements := history.GetReplacements(); len(replacements) != 1 || replacements[0].Replacement != "ID-$1" {
+		t.Errorf("Expected the replacement to be recorded, got %+v", replacements)
+	}
+
+	// The history is persisted
+	loaded := backend.NewSearchHistory(filepath.Dir(history.GetPath()))
+	if err := loaded.Load(); err != nil || len(loaded.GetSavedSearches()) != 1 {
+		t.Errorf("Expected the saved search on disk, got %v (%v)", loaded.GetSavedSearches(), err)
+	}
+}
+
+// MockEditApplier records edits applied through EditApplier
+type MockEditApplier struct {
+	MockEditor
+	edits int
+}
+
+func (m *MockEditApplier) ApplyEdit(content string) {
+	m.edits++
+	m.content = content
+}
+
+func TestReplaceDialog_Structural(t *testing.T) {
+	app := test.NewApp()
+	window := test.NewWindow(nil)
+	defer app.Quit()
+
+	source := "package p\n\nfunc f() {\n\tprintln(len(xs) == 0, len( ys )== 0)\n}\n"
+	editor := &MockEditApplier{MockEditor: MockEditor{content: source}}
+	searchManager := backend.NewSearchManager()
+
+	dialog := NewReplaceDialog(editor, searchManager, window)
+	dialog.optionsCheck["structural"].SetChecked(true)
+	dialog.SetSearchText("len(a) == 0")
+	if searchManager.GetMatchCount() != 2 {
+		t.Fatalf("Expected 2 structural matches, got %d", searchManager.GetMatchCount())
+	}
+
+	dialog.SetReplaceText("a == nil")
+	dialog.ReplaceAll()
+
+	expected := "package p\n\nfunc f() {\n\tprintln(xs == nil, ys == nil)\n}\n"
+	if editor.content != expected {
+		t.Errorf("Expected content %q, got %q", expected, editor.content)
+	}
+	if editor.edits != 1 {
+		t.Errorf("Expected a single edit, got %d", editor.edits)
+	}
+}
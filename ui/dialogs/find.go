@@ -1,6 +1,8 @@
 package dialogs
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"fyne.io/fyne/v2"
 	"fyne.io/fyne/v2/container"
@@ -12,12 +14,15 @@ import (
 // FindDialog represents the find dialog
 type FindDialog struct {
 	dialog        dialog.Dialog
-	searchEntry   *widget.Entry
+	searchEntry   *historyEntry
 	optionsCheck  map[string]*widget.Check
 	resultLabel   *widget.Label
 	nextButton    *widget.Button
 	prevButton    *widget.Button
 	closeButton   *widget.Button
+	scopeSelect   *widget.Select
+	savedSelect   *widget.Select
+	saveButton    *widget.Button
 	
 	// References
 	editor        EditorInterface
@@ -27,6 +32,25 @@ type FindDialog struct {
 	// State
 	isVisible     bool
 	lastPattern   string
+	cancelSearch  context.CancelFunc
+	
+	// Search history and saved searches, nil if not persisted
+	history         *backend.SearchHistory
+	searchBrowser   *historyNavigator
+	applyingOptions bool
+	
+	// savedSearch builds the saved search stored under name
+	savedSearch func(name string) backend.SavedSearch
+	
+	// onSavedSearchLoaded is called after a saved search was loaded
+	onSavedSearchLoaded func(saved backend.SavedSearch)
+	
+	// onSearchFinished is called when a search has completed and its
+	// results are shown
+	onSearchFinished func()
+	
+	// onCurrentMatchChanged is called after the matches or current match change
+	onCurrentMatchChanged func()
 }
 
 // EditorInterface defines the interface that the editor must implement
@@ -38,6 +62,73 @@ type EditorInterface interface {
 	// SelectText(start, end backend.Position)
 }
 
+// EditApplier is implemented by editors that can record a change of the
+// whole content as one undoable edit. Other editors just get the new content.
+type EditApplier interface {
+	ApplyEdit(content string)
+}
+
+// applyEdit replaces the editor content, as an undoable edit if possible
+func applyEdit(editor EditorInterface, content string) {
+	if applier, ok := editor.(EditApplier); ok {
+		applier.ApplyEdit(content)
+		return
+	}
+	editor.SetContent(content)
+}
+
+// SearchHighlighter is implemented by editors that can paint search matches.
+// Editors that do not implement it simply show no highlights.
+type SearchHighlighter interface {
+	SetSearchHighlights(matches []backend.Match, current int)
+	SetCurrentSearchHighlight(current int)
+	ClearSearchHighlights()
+}
+
+// InlineHighlighter is implemented by editors that paint matches in the text
+// only in some layouts
+type InlineHighlighter interface {
+	// HighlightsMatchesInline reports whether matches are painted in the
+	// text rather than only marked beside the scrollbar
+	HighlightsMatchesInline() bool
+}
+
+// asyncSearchThreshold is the buffer size in bytes from which searches run
+// in the background; smaller buffers are searched immediately
+const asyncSearchThreshold = 256 * 1024
+
+// searchScopes are the scopes offered in the scope selector, in display order
+var searchScopes = []struct {
+	label string
+	scope backend.SearchScope
+}{
+	{"Whole document", backend.ScopeDocument},
+	{"Selection", backend.ScopeSelection},
+	{"Comments only", backend.ScopeComments},
+	{"Strings only", backend.ScopeStrings},
+	{"Code only", backend.ScopeCode},
+}
+
+// scopeLabel returns the selector label for scope
+func scopeLabel(scope backend.SearchScope) string {
+	for _, s := range searchScopes {
+		if s.scope == scope {
+			return s.label
+		}
+	}
+	return searchScopes[0].label
+}
+
+// selectedScope returns the scope for a selector label
+func selectedScope(label string) backend.SearchScope {
+	for _, s := range searchScopes {
+		if s.label == label {
+			return s.scope
+		}
+	}
+	return backend.ScopeDocument
+}
+
 // NewFindDialog creates a new find dialog
 func NewFindDialog(editor EditorInterface, searchManager *backend.SearchManager, window fyne.Window) *FindDialog {
 	fd := &FindDialog{
@@ -54,8 +145,10 @@ func NewFindDialog(editor EditorInterface, searchManager *backend.SearchManager,
 // createDialog creates the dialog UI
 func (fd *FindDialog) createDialog() {
 	// Search entry
-	fd.searchEntry = widget.NewEntry()
+	fd.searchEntry = newHistoryEntry()
 	fd.searchEntry.SetPlaceHolder("Enter search text...")
+	fd.searchEntry.OnBrowse = fd.browseSearchHistory
+	fd.searchBrowser = newHistoryNavigator()
 	fd.searchEntry.OnChanged = func(text string) {
 		fd.performSearch(text)
 	}
@@ -82,17 +175,65 @@ func (fd *FindDialog) createDialog() {
 		fd.updateSearchOptions()
 	})
 	
+	fd.optionsCheck["multiLine"] = widget.NewCheck("^ $ match lines", func(checked bool) {
+		fd.updateSearchOptions()
+	})
+	
+	fd.optionsCheck["dotAll"] = widget.NewCheck(". matches newline", func(checked bool) {
+		fd.updateSearchOptions()
+	})
+	
+	fd.optionsCheck["pcre"] = widget.NewCheck("PCRE syntax", func(checked bool) {
+		fd.updateSearchOptions()
+	})
+	
+	fd.optionsCheck["structural"] = widget.NewCheck("Go syntax", func(checked bool) {
+		fd.updateSearchOptions()
+	})
+	
+	// Scope selector
+	scopeLabels := make([]string, len(searchScopes))
+	for i, s := range searchScopes {
+		scopeLabels[i] = s.label
+	}
+	fd.scopeSelect = widget.NewSelect(scopeLabels, nil)
+	
 	// Set default values
 	options := fd.searchManager.GetOptions()
 	fd.optionsCheck["caseSensitive"].SetChecked(options.CaseSensitive)
 	fd.optionsCheck["wholeWord"].SetChecked(options.WholeWord)
 	fd.optionsCheck["regex"].SetChecked(options.RegularExpression)
 	fd.optionsCheck["wrapAround"].SetChecked(options.WrapAround)
+	fd.optionsCheck["multiLine"].SetChecked(options.MultiLine)
+	fd.optionsCheck["dotAll"].SetChecked(options.DotAll)
+	fd.optionsCheck["pcre"].SetChecked(options.Engine == backend.RegexEnginePCRE)
+	fd.optionsCheck["structural"].SetChecked(options.Structural)
+	fd.scopeSelect.SetSelected(scopeLabel(options.Scope))
+	fd.scopeSelect.OnChanged = func(string) {
+		fd.updateSearchOptions()
+	}
 
 	// Result label
 	fd.resultLabel = widget.NewLabel("Enter text to search")
 	fd.resultLabel.Wrapping = fyne.TextWrapWord
 
+	// Saved searches
+	fd.savedSelect = widget.NewSelect([]string{}, func(name string) {
+		fd.loadSavedSearch(name)
+	})
+	fd.savedSelect.PlaceHolder = "(no saved searches)"
+	fd.saveButton = widget.NewButton("Save...", func() {
+		fd.showSaveSearchForm()
+	})
+	fd.savedSearch = func(name string) backend.SavedSearch {
+		return backend.SavedSearch{
+			Name:    name,
+			Pattern: fd.searchEntry.Text,
+			Options: backend.ReplaceOptions{SearchOptions: fd.searchManager.GetOptions()},
+		}
+	}
+	fd.updateSavedSearches()
+
 	// Buttons
 	fd.nextButton = widget.NewButton("Next", func() {
 		fd.FindNext()
@@ -119,8 +260,18 @@ func (fd *FindDialog) createDialog() {
 	optionsRow2 := container.NewHBox(
 		fd.optionsCheck["regex"],
 		fd.optionsCheck["wrapAround"],
+		fd.optionsCheck["structural"],
+	)
+	
+	optionsRow3 := container.NewHBox(
+		fd.optionsCheck["multiLine"],
+		fd.optionsCheck["dotAll"],
+		fd.optionsCheck["pcre"],
 	)
 	
+	scopeRow := container.NewBorder(nil, nil, widget.NewLabel("Search in:"), nil, fd.scopeSelect)
+	savedRow := container.NewBorder(nil, nil, widget.NewLabel("Saved:"), fd.saveButton, fd.savedSelect)
+	
 	buttonRow := container.NewHBox(
 		fd.prevButton,
 		fd.nextButton,
@@ -134,6 +285,9 @@ func (fd *FindDialog) createDialog() {
 		widget.NewLabel("Options:"),
 		optionsRow1,
 		optionsRow2,
+		optionsRow3,
+		scopeRow,
+		savedRow,
 		widget.NewSeparator(),
 		fd.resultLabel,
 		widget.NewSeparator(),
@@ -169,6 +323,8 @@ func (fd *FindDialog) Hide() {
 	
 	fd.isVisible = false
 	fd.dialog.Hide()
+	fd.cancelPendingSearch()
+	fd.recordSearch()
 	
 	// Clear highlights
 	fd.clearHighlights()
@@ -181,15 +337,15 @@ func (fd *FindDialog) IsVisible() bool {
 
 // FindNext finds the next occurrence
 func (fd *FindDialog) FindNext() bool {
-	if !fd.searchManager.HasMatches() {
+	if fd.IsSearching() || !fd.searchManager.HasMatches() {
 		return false
 	}
 
 	match := fd.searchManager.NextMatch()
 	if match != nil {
+		fd.recordSearch()
 		fd.updateResultLabel()
 		fd.highlightCurrentMatch()
-		// TODO: Scroll to match position when editor supports it
 		return true
 	}
 	
@@ -198,15 +354,15 @@ func (fd *FindDialog) FindNext() bool {
 
 // FindPrevious finds the previous occurrence
 func (fd *FindDialog) FindPrevious() bool {
-	if !fd.searchManager.HasMatches() {
+	if fd.IsSearching() || !fd.searchManager.HasMatches() {
 		return false
 	}
 
 	match := fd.searchManager.PreviousMatch()
 	if match != nil {
+		fd.recordSearch()
 		fd.updateResultLabel()
 		fd.highlightCurrentMatch()
-		// TODO: Scroll to match position when editor supports it
 		return true
 	}
 	
@@ -224,7 +380,8 @@ func (fd *FindDialog) GetSearchText() string {
 	return fd.searchEntry.Text
 }
 
-// performSearch performs the actual search
+// performSearch performs the actual search. Large buffers are searched in
+// the background; a search still running for an older pattern is cancelled.
 func (fd *FindDialog) performSearch(pattern string) {
 	if pattern == "" {
 		fd.clearSearch()
@@ -237,10 +394,46 @@ func (fd *FindDialog) performSearch(pattern string) {
 	}
 	
 	fd.lastPattern = pattern
+	fd.cancelPendingSearch()
 	
 	// Get editor content and perform search
 	content := fd.editor.GetContent()
-	matches := fd.searchManager.Find(content, pattern)
+	if len(content) < asyncSearchThreshold {
+		fd.searchManager.Find(content, pattern)
+		fd.finishSearch()
+		return
+	}
+	
+	ctx, cancel := context.WithCancel(context.Background())
+	fd.cancelSearch = cancel
+	fd.resultLabel.SetText("Searching...")
+	fd.nextButton.Disable()
+	fd.prevButton.Disable()
+	
+	fd.searchManager.FindAsync(ctx, content, pattern, func(chunk backend.SearchChunk) {
+		fyne.Do(func() {
+			// Drop results of searches superseded in the meantime
+			if ctx.Err() != nil {
+				return
+			}
+			if !chunk.Done {
+				fd.resultLabel.SetText(fmt.Sprintf("Searching... %d matches", chunk.Found))
+				return
+			}
+			
+			fd.cancelPendingSearch()
+			fd.searchManager.ApplyResult(chunk.Result)
+			fd.finishSearch()
+		})
+	})
+}
+
+// finishSearch shows the results of a completed search
+func (fd *FindDialog) finishSearch() {
+	// If we have matches, go to first one
+	if fd.searchManager.HasMatches() {
+		fd.searchManager.SetCurrentMatch(0)
+	}
 	
 	// Update UI
 	fd.updateResultLabel()
@@ -249,23 +442,30 @@ func (fd *FindDialog) performSearch(pattern string) {
 	// Highlight matches
 	fd.highlightMatches()
 	
-	// If we have matches, go to first one
-	if len(matches) > 0 {
-		fd.searchManager.SetCurrentMatch(0)
-		fd.highlightCurrentMatch()
+	if fd.onSearchFinished != nil {
+		fd.onSearchFinished()
+	}
+}
+
+// cancelPendingSearch stops a background search that is still running
+func (fd *FindDialog) cancelPendingSearch() {
+	if fd.cancelSearch != nil {
+		fd.cancelSearch()
+		fd.cancelSearch = nil
 	}
 }
 
+// IsSearching reports whether a background search is still running
+func (fd *FindDialog) IsSearching() bool {
+	return fd.cancelSearch != nil
+}
+
 // updateSearchOptions updates search options based on checkboxes
 func (fd *FindDialog) updateSearchOptions() {
-	options := backend.SearchOptions{
-		CaseSensitive:     fd.optionsCheck["caseSensitive"].Checked,
-		WholeWord:         fd.optionsCheck["wholeWord"].Checked,
-		RegularExpression: fd.optionsCheck["regex"].Checked,
-		WrapAround:        fd.optionsCheck["wrapAround"].Checked,
+	if fd.applyingOptions {
+		return
 	}
-	
-	fd.searchManager.SetOptions(options)
+	fd.searchManager.SetOptions(fd.selectedOptions())
 	
 	// Re-search with new options if we have a pattern
 	if fd.searchEntry.Text != "" {
@@ -274,22 +474,64 @@ func (fd *FindDialog) updateSearchOptions() {
 	}
 }
 
+// selectedOptions returns the search options selected in the dialog
+func (fd *FindDialog) selectedOptions() backend.SearchOptions {
+	options := backend.SearchOptions{
+		CaseSensitive:     fd.optionsCheck["caseSensitive"].Checked,
+		WholeWord:         fd.optionsCheck["wholeWord"].Checked,
+		RegularExpression: fd.optionsCheck["regex"].Checked,
+		WrapAround:        fd.optionsCheck["wrapAround"].Checked,
+		MultiLine:         fd.optionsCheck["multiLine"].Checked,
+		DotAll:            fd.optionsCheck["dotAll"].Checked,
+		Engine:            backend.RegexEngineRE2,
+		Scope:             selectedScope(fd.scopeSelect.Selected),
+		Structural:        fd.optionsCheck["structural"].Checked,
+	}
+	if fd.optionsCheck["pcre"].Checked {
+		options.Engine = backend.RegexEnginePCRE
+	}
+	return options
+}
+
 // updateResultLabel updates the result label with match information
 func (fd *FindDialog) updateResultLabel() {
 	matchCount := fd.searchManager.GetMatchCount()
 	currentIndex := fd.searchManager.GetCurrentIndex()
 	
-	if matchCount == 0 {
+	if err := fd.searchManager.GetError(); err != nil {
+		fd.resultLabel.SetText(formatSearchError(err))
+	} else if matchCount == 0 {
 		if fd.searchEntry.Text == "" {
 			fd.resultLabel.SetText("Enter text to search")
 		} else {
 			fd.resultLabel.SetText("No matches found")
 		}
+	} else if fd.searchManager.IsTruncated() {
+		fd.resultLabel.SetText(fmt.Sprintf("Match %d of %d+", currentIndex+1, matchCount) + fd.highlightNote())
 	} else {
-		fd.resultLabel.SetText(fmt.Sprintf("Match %d of %d", currentIndex+1, matchCount))
+		fd.resultLabel.SetText(fmt.Sprintf("Match %d of %d", currentIndex+1, matchCount) + fd.highlightNote())
 	}
 }
 
+// highlightNote explains where matches are shown when the editor cannot
+// paint them in the text
+func (fd *FindDialog) highlightNote() string {
+	if highlighter, ok := fd.editor.(InlineHighlighter); ok && !highlighter.HighlightsMatchesInline() {
+		return " (marked beside the scrollbar only while lines wrap)"
+	}
+	return ""
+}
+
+// formatSearchError describes a search error for the result label,
+// pointing at the position of an invalid pattern
+func formatSearchError(err error) string {
+	var patternErr *backend.PatternError
+	if errors.As(err, &patternErr) {
+		return fmt.Sprintf("Invalid pattern at position %d: %s", patternErr.Position, patternErr.Message)
+	}
+	return fmt.Sprintf("Search failed: %v", err)
+}
+
 // updateButtons updates button states based on search results
 func (fd *FindDialog) updateButtons() {
 	hasMatches := fd.searchManager.HasMatches()
@@ -305,6 +547,7 @@ func (fd *FindDialog) updateButtons() {
 
 // clearSearch clears the search state
 func (fd *FindDialog) clearSearch() {
+	fd.cancelPendingSearch()
 	fd.searchManager.Clear()
 	fd.lastPattern = ""
 	fd.updateResultLabel()
@@ -314,19 +557,175 @@ func (fd *FindDialog) clearSearch() {
 
 // highlightMatches highlights all matches in the editor
 func (fd *FindDialog) highlightMatches() {
-	// TODO: Implement text highlighting when editor supports it
-	// This would involve creating a rich text representation with highlighted segments
+	if highlighter, ok := fd.editor.(SearchHighlighter); ok {
+		highlighter.SetSearchHighlights(fd.searchManager.GetMatches(), fd.searchManager.GetCurrentIndex())
+	}
+	if fd.onCurrentMatchChanged != nil {
+		fd.onCurrentMatchChanged()
+	}
 }
 
 // highlightCurrentMatch highlights the current match
 func (fd *FindDialog) highlightCurrentMatch() {
-	// TODO: Implement current match highlighting when editor supports it
-	// This would involve selecting the text or using a different highlight color
+	if highlighter, ok := fd.editor.(SearchHighlighter); ok {
+		highlighter.SetCurrentSearchHighlight(fd.searchManager.GetCurrentIndex())
+	}
+	if fd.onCurrentMatchChanged != nil {
+		fd.onCurrentMatchChanged()
+	}
 }
 
 // clearHighlights clears all highlights from the editor
 func (fd *FindDialog) clearHighlights() {
-	// TODO: Implement highlight clearing when editor supports it
+	if highlighter, ok := fd.editor.(SearchHighlighter); ok {
+		highlighter.ClearSearchHighlights()
+	}
+	if fd.onCurrentMatchChanged != nil {
+		fd.onCurrentMatchChanged()
+	}
+}
+
+// SetHistory sets where search strings and saved searches are kept. The
+// dialog records a search once it is used to navigate matches or closed.
+func (fd *FindDialog) SetHistory(history *backend.SearchHistory) {
+	fd.history = history
+	fd.searchBrowser.reset()
+	fd.updateSavedSearches()
+}
+
+// recordSearch adds the current pattern and options to the history
+func (fd *FindDialog) recordSearch() {
+	if fd.history == nil || fd.searchEntry.Text == "" {
+		return
+	}
+	fd.searchBrowser.reset()
+	if fd.history.AddSearch(fd.searchEntry.Text, fd.searchManager.GetOptions()) {
+		fd.saveHistory()
+	}
+}
+
+// saveHistory writes the history, reporting failures in the result label
+func (fd *FindDialog) saveHistory() {
+	if err := fd.history.Save(); err != nil {
+		fd.resultLabel.SetText(fmt.Sprintf("Could not save search history: %v", err))
+	}
+}
+
+// browseSearchHistory shows an older (step 1) or newer (step -1) search
+// from the history together with its options
+func (fd *FindDialog) browseSearchHistory(step int) {
+	if fd.history == nil {
+		return
+	}
+	searches := fd.history.GetSearches()
+	position, moved := fd.searchBrowser.step(step, len(searches), fd.searchEntry.Text)
+	if !moved {
+		return
+	}
+
+	if position == -1 {
+		fd.showSearch(fd.searchBrowser.draft, fd.searchManager.GetOptions())
+		return
+	}
+	fd.showSearch(searches[position].Pattern, searches[position].Options)
+}
+
+// showSearch puts pattern and options into the dialog and searches
+func (fd *FindDialog) showSearch(pattern string, options backend.SearchOptions) {
+	fd.applyOptions(options)
+	fd.lastPattern = "" // Search even if only the options changed
+	fd.searchEntry.SetText(pattern)
+	fd.searchEntry.CursorColumn = len([]rune(pattern))
+	fd.searchEntry.Refresh()
+}
+
+// applyOptions shows options in the dialog and makes them current without
+// searching once per changed checkbox
+func (fd *FindDialog) applyOptions(options backend.SearchOptions) {
+	fd.applyingOptions = true
+	fd.optionsCheck["caseSensitive"].SetChecked(options.CaseSensitive)
+	fd.optionsCheck["wholeWord"].SetChecked(options.WholeWord)
+	fd.optionsCheck["regex"].SetChecked(options.RegularExpression)
+	fd.optionsCheck["wrapAround"].SetChecked(options.WrapAround)
+	fd.optionsCheck["multiLine"].SetChecked(options.MultiLine)
+	fd.optionsCheck["dotAll"].SetChecked(options.DotAll)
+	fd.optionsCheck["pcre"].SetChecked(options.Engine == backend.RegexEnginePCRE)
+	fd.optionsCheck["structural"].SetChecked(options.Structural)
+	fd.scopeSelect.SetSelected(scopeLabel(options.Scope))
+	fd.applyingOptions = false
+	
+	fd.searchManager.SetOptions(fd.selectedOptions())
+}
+
+// updateSavedSearches refreshes the saved search dropdown
+func (fd *FindDialog) updateSavedSearches() {
+	names := make([]string, 0)
+	if fd.history != nil {
+		for _, saved := range fd.history.GetSavedSearches() {
+			names = append(names, saved.Name)
+		}
+	}
+	fd.savedSelect.Options = names
+	if len(names) == 0 {
+		fd.savedSelect.PlaceHolder = "(no saved searches)"
+	} else {
+		fd.savedSelect.PlaceHolder = "(select a saved search)"
+	}
+	fd.savedSelect.Refresh()
+}
+
+// loadSavedSearch puts the named search into the dialog
+func (fd *FindDialog) loadSavedSearch(name string) {
+	if fd.history == nil || name == "" {
+		return
+	}
+	saved, ok := fd.history.GetSavedSearch(name)
+	if !ok {
+		return
+	}
+	
+	fd.showSearch(saved.Pattern, saved.Options.SearchOptions)
+	if fd.onSavedSearchLoaded != nil {
+		fd.onSavedSearchLoaded(saved)
+	}
+}
+
+// SaveSearch stores the current search under name
+func (fd *FindDialog) SaveSearch(name string) error {
+	if fd.history == nil {
+		return fmt.Errorf("search history is not available")
+	}
+	if err := fd.history.SaveSearch(fd.savedSearch(name)); err != nil {
+		return err
+	}
+	if err := fd.history.Save(); err != nil {
+		return err
+	}
+	
+	fd.updateSavedSearches()
+	fd.savedSelect.Selected = name
+	fd.savedSelect.Refresh()
+	return nil
+}
+
+// showSaveSearchForm asks for a name and saves the current search
+func (fd *FindDialog) showSaveSearchForm() {
+	if fd.searchEntry.Text == "" {
+		fd.resultLabel.SetText("Enter a search to save")
+		return
+	}
+	
+	nameEntry := widget.NewEntry()
+	nameEntry.SetText(fd.savedSelect.Selected)
+	items := []*widget.FormItem{widget.NewFormItem("Name", nameEntry)}
+	dialog.ShowForm("Save Search", "Save", "Cancel", items, func(confirmed bool) {
+		if !confirmed {
+			return
+		}
+		if err := fd.SaveSearch(nameEntry.Text); err != nil {
+			fd.resultLabel.SetText(fmt.Sprintf("Could not save search: %v", err))
+		}
+	}, fd.window)
 }
 
 // HandleKeyEvent handles keyboard events for the find dialog
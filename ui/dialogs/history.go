@@ -0,0 +1,77 @@
+package dialogs
+
+import (
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/widget"
+)
+
+// historyEntry is a single-line entry whose up and down keys browse a
+// history instead of moving the cursor
+type historyEntry struct {
+	widget.Entry
+
+	// OnBrowse is called with +1 for an older entry and -1 for a newer one
+	OnBrowse func(step int)
+}
+
+// newHistoryEntry creates a history entry
+func newHistoryEntry() *historyEntry {
+	entry := &historyEntry{}
+	entry.ExtendBaseWidget(entry)
+	return entry
+}
+
+// TypedKey handles the up and down keys and passes other keys to the entry
+func (e *historyEntry) TypedKey(key *fyne.KeyEvent) {
+	if e.OnBrowse != nil {
+		switch key.Name {
+		case fyne.KeyUp:
+			e.OnBrowse(1)
+			return
+		case fyne.KeyDown:
+			e.OnBrowse(-1)
+			return
+		}
+	}
+	e.Entry.TypedKey(key)
+}
+
+// historyNavigator tracks the position while browsing a history list that
+// is ordered most recent first. Position -1 is the text being typed, which
+// is kept as a draft so browsing back down restores it.
+type historyNavigator struct {
+	position int
+	draft    string
+}
+
+// newHistoryNavigator creates a navigator at the text being typed
+func newHistoryNavigator() *historyNavigator {
+	return &historyNavigator{position: -1}
+}
+
+// step moves by step entries in a history of count entries. It returns the
+// new position, -1 for the draft, and false if the position did not change.
+func (n *historyNavigator) step(step, count int, current string) (int, bool) {
+	position := n.position + step
+	if position < -1 {
+		position = -1
+	}
+	if position >= count {
+		position = count - 1
+	}
+	if position == n.position {
+		return n.position, false
+	}
+
+	if n.position == -1 {
+		n.draft = current
+	}
+	n.position = position
+	return position, true
+}
+
+// reset returns to the text being typed
+func (n *historyNavigator) reset() {
+	n.position = -1
+	n.draft = ""
+}
@@ -0,0 +1,257 @@
+package dialogs
+
+import (
+	"fmt"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+	"github.com/kenelite/goeditor/backend"
+)
+
+// maxQuickOpenResults limits how many results the palette shows
+const maxQuickOpenResults = 50
+
+// QuickOpenEditorInterface defines the editor methods used by quick-open
+type QuickOpenEditorInterface interface {
+	LoadFile(path string) error
+	GoToLine(lineNumber int) bool
+}
+
+// QuickOpenDialog represents the Ctrl+P quick-open palette
+type QuickOpenDialog struct {
+	dialog      dialog.Dialog
+	queryEntry  *widget.Entry
+	resultList  *widget.List
+	statusLabel *widget.Label
+
+	// References
+	editor    QuickOpenEditorInterface
+	fileIndex *backend.FileIndex
+	window    fyne.Window
+
+	// State
+	isVisible bool
+	results   []backend.FileMatch
+	selected  int
+}
+
+// NewQuickOpenDialog creates a new quick-open dialog
+func NewQuickOpenDialog(editor QuickOpenEditorInterface, fileIndex *backend.FileIndex, window fyne.Window) *QuickOpenDialog {
+	qd := &QuickOpenDialog{
+		editor:    editor,
+		fileIndex: fileIndex,
+		window:    window,
+		isVisible: false,
+		results:   make([]backend.FileMatch, 0),
+		selected:  -1,
+	}
+
+	qd.createDialog()
+	return qd
+}
+
+// createDialog creates the dialog UI
+func (qd *QuickOpenDialog) createDialog() {
+	// Query entry
+	qd.queryEntry = widget.NewEntry()
+	qd.queryEntry.SetPlaceHolder("Type a file name, or file:line...")
+	qd.queryEntry.OnChanged = func(text string) {
+		qd.updateResults(text)
+	}
+	qd.queryEntry.OnSubmitted = func(text string) {
+		qd.openSelected()
+	}
+
+	// Result list
+	qd.resultList = widget.NewList(
+		func() int {
+			return len(qd.results)
+		},
+		func() fyne.CanvasObject {
+			return widget.NewLabel("")
+		},
+		func(id widget.ListItemID, obj fyne.CanvasObject) {
+			if id < len(qd.results) {
+				obj.(*widget.Label).SetText(qd.results[id].Path)
+			}
+		},
+	)
+	qd.resultList.OnSelected = func(id widget.ListItemID) {
+		qd.selected = id
+		qd.openSelected()
+	}
+
+	// Status label
+	qd.statusLabel = widget.NewLabel("")
+
+	content := container.NewBorder(
+		qd.queryEntry,
+		qd.statusLabel,
+		nil, nil,
+		qd.resultList,
+	)
+
+	// Create dialog
+	qd.dialog = dialog.NewCustom("Quick Open", "Close", content, qd.window)
+	qd.dialog.SetOnClosed(func() {
+		qd.isVisible = false
+	})
+	qd.dialog.Resize(fyne.NewSize(520, 400))
+}
+
+// Show displays the quick-open dialog
+func (qd *QuickOpenDialog) Show() {
+	if qd.isVisible {
+		return
+	}
+
+	qd.isVisible = true
+	qd.queryEntry.SetText("")
+	qd.updateResults("")
+	qd.dialog.Show()
+
+	// Focus on query entry
+	qd.window.Canvas().Focus(qd.queryEntry)
+}
+
+// Hide hides the quick-open dialog
+func (qd *QuickOpenDialog) Hide() {
+	if !qd.isVisible {
+		return
+	}
+
+	qd.isVisible = false
+	qd.dialog.Hide()
+}
+
+// IsVisible returns whether the dialog is currently visible
+func (qd *QuickOpenDialog) IsVisible() bool {
+	return qd.isVisible
+}
+
+// SetQuery sets the query text programmatically
+func (qd *QuickOpenDialog) SetQuery(query string) {
+	qd.queryEntry.SetText(query)
+	qd.updateResults(query)
+}
+
+// GetResults returns the current ranked results
+func (qd *QuickOpenDialog) GetResults() []backend.FileMatch {
+	return qd.results
+}
+
+// Refresh re-ranks the results of a visible dialog, for example after the
+// file index changed
+func (qd *QuickOpenDialog) Refresh() {
+	if qd.isVisible {
+		qd.updateResults(qd.queryEntry.Text)
+	}
+}
+
+// updateResults re-ranks the indexed files for the given query
+func (qd *QuickOpenDialog) updateResults(text string) {
+	query := backend.ParseQuickOpenQuery(text)
+
+	qd.results = qd.fileIndex.Search(query.Path, maxQuickOpenResults)
+	qd.selected = -1
+	if len(qd.results) > 0 {
+		qd.selected = 0
+	}
+
+	qd.resultList.UnselectAll()
+	qd.resultList.Refresh()
+	qd.updateStatusLabel(query)
+}
+
+// updateStatusLabel shows indexing progress and result counts
+func (qd *QuickOpenDialog) updateStatusLabel(query backend.QuickOpenQuery) {
+	switch {
+	case qd.fileIndex.GetRoot() == "":
+		qd.statusLabel.SetText("Open a file or folder to index its files")
+	case qd.fileIndex.IsIndexing():
+		qd.statusLabel.SetText(fmt.Sprintf("Indexing... %d files", qd.fileIndex.GetFileCount()))
+	case query.Path == "" && query.Line > 0:
+		qd.statusLabel.SetText(fmt.Sprintf("Go to line %d in the current file", query.Line))
+	case len(qd.results) == 0:
+		qd.statusLabel.SetText("No matching files")
+	default:
+		qd.statusLabel.SetText(fmt.Sprintf("%d of %d files", len(qd.results), qd.fileIndex.GetFileCount()))
+	}
+}
+
+// openSelected opens the selected result, jumping to a line if one was given
+func (qd *QuickOpenDialog) openSelected() {
+	query := backend.ParseQuickOpenQuery(qd.queryEntry.Text)
+
+	// ":42" jumps within the current file
+	if query.Path == "" && query.Line > 0 {
+		if qd.editor.GoToLine(query.Line) {
+			qd.Hide()
+		} else {
+			qd.statusLabel.SetText(fmt.Sprintf("Line %d is out of range", query.Line))
+		}
+		return
+	}
+
+	if qd.selected < 0 || qd.selected >= len(qd.results) {
+		return
+	}
+
+	path := qd.fileIndex.AbsPath(qd.results[qd.selected].Path)
+	if err := qd.editor.LoadFile(path); err != nil {
+		dialog.ShowError(err, qd.window)
+		return
+	}
+	if query.Line > 0 {
+		qd.editor.GoToLine(query.Line)
+	}
+
+	qd.Hide()
+}
+
+// moveSelection moves the highlighted result by delta
+func (qd *QuickOpenDialog) moveSelection(delta int) {
+	if len(qd.results) == 0 {
+		return
+	}
+
+	qd.selected += delta
+	if qd.selected < 0 {
+		qd.selected = len(qd.results) - 1
+	} else if qd.selected >= len(qd.results) {
+		qd.selected = 0
+	}
+
+	// Show the highlighted row without triggering OnSelected
+	onSelected := qd.resultList.OnSelected
+	qd.resultList.OnSelected = nil
+	qd.resultList.Select(qd.selected)
+	qd.resultList.OnSelected = onSelected
+	qd.statusLabel.SetText(qd.results[qd.selected].Path)
+}
+
+// HandleKeyEvent handles keyboard events for the quick-open dialog
+func (qd *QuickOpenDialog) HandleKeyEvent(event *fyne.KeyEvent) bool {
+	if !qd.isVisible {
+		return false
+	}
+
+	switch event.Name {
+	case fyne.KeyEscape:
+		qd.Hide()
+		return true
+	case fyne.KeyReturn, fyne.KeyEnter:
+		qd.openSelected()
+		return true
+	case fyne.KeyDown:
+		qd.moveSelection(1)
+		return true
+	case fyne.KeyUp:
+		qd.moveSelection(-1)
+		return true
+	}
+
+	return false
+}
@@ -14,12 +14,14 @@ type ReplaceDialog struct {
 	*FindDialog
 	
 	// Additional UI elements for replace
-	replaceEntry     *widget.Entry
+	replaceEntry     *historyEntry
 	replaceButton    *widget.Button
 	replaceAllButton *widget.Button
+	previewLabel     *widget.Label
 	
 	// State
 	lastReplaceText  string
+	replaceBrowser   *historyNavigator
 }
 
 // NewReplaceDialog creates a new find and replace dialog
@@ -46,12 +48,43 @@ func (rd *ReplaceDialog) createReplaceDialog() {
 	}
 
 	// Replace entry
-	rd.replaceEntry = widget.NewEntry()
+	rd.replaceEntry = newHistoryEntry()
 	rd.replaceEntry.SetPlaceHolder("Enter replacement text...")
+	rd.replaceEntry.OnBrowse = rd.browseReplaceHistory
+	rd.replaceBrowser = newHistoryNavigator()
 	rd.replaceEntry.OnChanged = func(text string) {
 		rd.lastReplaceText = text
 		rd.updateReplaceButtons()
+		rd.updatePreview()
 	}
+	
+	// Preview of the replacement for the current match
+	rd.previewLabel = widget.NewLabel("")
+	rd.previewLabel.Truncation = fyne.TextTruncateEllipsis
+	rd.onCurrentMatchChanged = rd.updatePreview
+	rd.onSearchFinished = rd.updateReplaceButtons
+	rd.onSavedSearchLoaded = rd.showSavedReplacement
+	rd.savedSearch = func(name string) backend.SavedSearch {
+		return backend.SavedSearch{
+			Name:        name,
+			Pattern:     rd.searchEntry.Text,
+			Replacement: rd.replaceEntry.Text,
+			Options:     rd.replaceOptions(false),
+		}
+	}
+	
+	// Replace-only options
+	rd.optionsCheck["preserveCase"] = widget.NewCheck("Preserve case", func(checked bool) {
+		rd.updatePreview()
+	})
+	rd.optionsCheck["template"] = widget.NewCheck("Template replacement", func(checked bool) {
+		if checked {
+			rd.replaceEntry.SetPlaceHolder("{{upper .Text}}, {{pad 3 (add .Index 1)}}...")
+		} else {
+			rd.replaceEntry.SetPlaceHolder("Enter replacement text...")
+		}
+		rd.updatePreview()
+	})
 
 	// Replace buttons
 	rd.replaceButton = widget.NewButton("Replace", func() {
@@ -76,13 +109,28 @@ func (rd *ReplaceDialog) createReplaceDialog() {
 	optionsRow1 := container.NewHBox(
 		rd.optionsCheck["caseSensitive"],
 		rd.optionsCheck["wholeWord"],
+		rd.optionsCheck["preserveCase"],
 	)
 	
 	optionsRow2 := container.NewHBox(
 		rd.optionsCheck["regex"],
 		rd.optionsCheck["wrapAround"],
+		rd.optionsCheck["structural"],
 	)
 	
+	optionsRow3 := container.NewHBox(
+		rd.optionsCheck["multiLine"],
+		rd.optionsCheck["dotAll"],
+		rd.optionsCheck["pcre"],
+	)
+	
+	optionsRow4 := container.NewHBox(
+		rd.optionsCheck["template"],
+	)
+	
+	scopeRow := container.NewBorder(nil, nil, widget.NewLabel("Search in:"), nil, rd.scopeSelect)
+	savedRow := container.NewBorder(nil, nil, widget.NewLabel("Saved:"), rd.saveButton, rd.savedSelect)
+	
 	navigationRow := container.NewHBox(
 		rd.prevButton,
 		rd.nextButton,
@@ -101,10 +149,15 @@ func (rd *ReplaceDialog) createReplaceDialog() {
 	content := container.NewVBox(
 		searchRow,
 		replaceRow,
+		rd.previewLabel,
 		widget.NewSeparator(),
 		widget.NewLabel("Options:"),
 		optionsRow1,
 		optionsRow2,
+		optionsRow3,
+		optionsRow4,
+		scopeRow,
+		savedRow,
 		widget.NewSeparator(),
 		rd.resultLabel,
 		widget.NewSeparator(),
@@ -134,17 +187,21 @@ func (rd *ReplaceDialog) ReplaceCurrent() {
 	// Get current editor content
 	content := rd.editor.GetContent()
 	
-	// Perform replacement
-	options := backend.ReplaceOptions{
-		SearchOptions: rd.searchManager.GetOptions(),
-		ReplaceAll:    false,
+	// Check the replacement before changing anything
+	options := rd.replaceOptions(false)
+	if err := rd.searchManager.ValidateReplacement(searchText, replaceText, options); err != nil {
+		rd.resultLabel.SetText(fmt.Sprintf("Invalid replacement: %v", err))
+		return
 	}
 	
+	// Perform replacement
 	newContent, count := rd.searchManager.Replace(content, searchText, replaceText, options)
 	
 	if count > 0 {
 		// Update editor content
-		rd.editor.SetContent(newContent)
+		applyEdit(rd.editor, newContent)
+		
+		rd.recordReplace(options)
 		
 		// Update result label
 		rd.resultLabel.SetText(fmt.Sprintf("Replaced 1 occurrence"))
@@ -156,6 +213,8 @@ func (rd *ReplaceDialog) ReplaceCurrent() {
 		if rd.searchManager.HasMatches() {
 			rd.FindNext()
 		}
+	} else if err := rd.searchManager.GetError(); err != nil {
+		rd.resultLabel.SetText(fmt.Sprintf("Replace failed: %v", err))
 	}
 }
 
@@ -171,17 +230,21 @@ func (rd *ReplaceDialog) ReplaceAll() {
 	// Get current editor content
 	content := rd.editor.GetContent()
 	
-	// Perform replacement
-	options := backend.ReplaceOptions{
-		SearchOptions: rd.searchManager.GetOptions(),
-		ReplaceAll:    true,
+	// Check the replacement before changing anything
+	options := rd.replaceOptions(true)
+	if err := rd.searchManager.ValidateReplacement(searchText, replaceText, options); err != nil {
+		rd.resultLabel.SetText(fmt.Sprintf("Invalid replacement: %v", err))
+		return
 	}
 	
+	// Perform replacement
 	newContent, count := rd.searchManager.Replace(content, searchText, replaceText, options)
 	
 	if count > 0 {
 		// Update editor content
-		rd.editor.SetContent(newContent)
+		applyEdit(rd.editor, newContent)
+		
+		rd.recordReplace(options)
 		
 		// Update result label
 		rd.resultLabel.SetText(fmt.Sprintf("Replaced %d occurrences", count))
@@ -190,6 +253,8 @@ func (rd *ReplaceDialog) ReplaceAll() {
 		rd.searchManager.Clear()
 		rd.updateButtons()
 		rd.updateReplaceButtons()
+	} else if err := rd.searchManager.GetError(); err != nil {
+		rd.resultLabel.SetText(fmt.Sprintf("Replace failed: %v", err))
 	} else {
 		rd.resultLabel.SetText("No matches found to replace")
 	}
@@ -200,6 +265,7 @@ func (rd *ReplaceDialog) SetReplaceText(text string) {
 	rd.replaceEntry.SetText(text)
 	rd.lastReplaceText = text
 	rd.updateReplaceButtons()
+	rd.updatePreview()
 }
 
 // GetReplaceText returns the current replace text
@@ -207,6 +273,53 @@ func (rd *ReplaceDialog) GetReplaceText() string {
 	return rd.replaceEntry.Text
 }
 
+// recordReplace adds the search and the replacement to the history
+func (rd *ReplaceDialog) recordReplace(options backend.ReplaceOptions) {
+	if rd.history == nil {
+		return
+	}
+	rd.replaceBrowser.reset()
+	searchChanged := rd.history.AddSearch(rd.searchEntry.Text, options.SearchOptions)
+	options.ReplaceAll = false
+	if rd.history.AddReplacement(rd.replaceEntry.Text, options) || searchChanged {
+		rd.saveHistory()
+	}
+}
+
+// browseReplaceHistory shows an older (step 1) or newer (step -1)
+// replacement from the history together with its options
+func (rd *ReplaceDialog) browseReplaceHistory(step int) {
+	if rd.history == nil {
+		return
+	}
+	replacements := rd.history.GetReplacements()
+	position, moved := rd.replaceBrowser.step(step, len(replacements), rd.replaceEntry.Text)
+	if !moved {
+		return
+	}
+
+	if position == -1 {
+		rd.SetReplaceText(rd.replaceBrowser.draft)
+		return
+	}
+	entry := replacements[position]
+	rd.applyOptions(entry.Options.SearchOptions)
+	if rd.searchEntry.Text != "" {
+		rd.lastPattern = "" // Force re-search
+		rd.performSearch(rd.searchEntry.Text)
+	}
+	rd.optionsCheck["preserveCase"].SetChecked(entry.Options.PreserveCase)
+	rd.optionsCheck["template"].SetChecked(entry.Options.Template)
+	rd.SetReplaceText(entry.Replacement)
+}
+
+// showSavedReplacement puts the replacement of a saved search into the dialog
+func (rd *ReplaceDialog) showSavedReplacement(saved backend.SavedSearch) {
+	rd.optionsCheck["preserveCase"].SetChecked(saved.Options.PreserveCase)
+	rd.optionsCheck["template"].SetChecked(saved.Options.Template)
+	rd.SetReplaceText(saved.Replacement)
+}
+
 // Show displays the replace dialog
 func (rd *ReplaceDialog) Show() {
 	if rd.isVisible {
@@ -225,7 +338,8 @@ func (rd *ReplaceDialog) Show() {
 
 // updateReplaceButtons updates replace button states
 func (rd *ReplaceDialog) updateReplaceButtons() {
-	hasMatches := rd.searchManager.HasMatches()
+	// Matches of the previous pattern stay around until a search finishes
+	hasMatches := rd.searchManager.HasMatches() && !rd.IsSearching()
 	hasSearchText := rd.searchEntry.Text != ""
 	
 	// Replace button is enabled when we have a current match
@@ -243,7 +357,35 @@ func (rd *ReplaceDialog) updateReplaceButtons() {
 	}
 }
 
-// Override performSearch to update replace buttons
+// replaceOptions returns the replace options selected in the dialog
+func (rd *ReplaceDialog) replaceOptions(replaceAll bool) backend.ReplaceOptions {
+	return backend.ReplaceOptions{
+		SearchOptions: rd.searchManager.GetOptions(),
+		ReplaceAll:    replaceAll,
+		PreserveCase:  rd.optionsCheck["preserveCase"].Checked,
+		Template:      rd.optionsCheck["template"].Checked,
+	}
+}
+
+// updatePreview shows what the replacement expands to for the current match
+func (rd *ReplaceDialog) updatePreview() {
+	match := rd.searchManager.GetCurrentMatch()
+	if match == nil || rd.replaceEntry == nil {
+		rd.previewLabel.SetText("")
+		return
+	}
+	
+	expanded, err := rd.searchManager.ExpandReplacement(*match, rd.searchManager.GetCurrentIndex(),
+		rd.searchEntry.Text, rd.replaceEntry.Text, rd.replaceOptions(false))
+	if err != nil {
+		rd.previewLabel.SetText(fmt.Sprintf("Invalid replacement: %v", err))
+		return
+	}
+	rd.previewLabel.SetText(fmt.Sprintf("%q → %q", match.Text, expanded))
+}
+
+// Override performSearch to update replace buttons, which are refreshed
+// again once a background search finishes
 func (rd *ReplaceDialog) performSearch(pattern string) {
 	// Call parent method
 	rd.FindDialog.performSearch(pattern)
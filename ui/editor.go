@@ -1,40 +1,114 @@
 package ui
 
 import (
+	"context"
 	fyne "fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/canvas"
 	"fyne.io/fyne/v2/container"
 	"fyne.io/fyne/v2/widget"
 	"github.com/kenelite/goeditor/backend"
 	"github.com/kenelite/goeditor/ui/syntax"
 	"github.com/kenelite/goeditor/ui/dialogs"
+	"log"
 	"os"
 	"strings"
+	"time"
+	"unicode/utf8"
 )
 
+// searchRefreshDelay is how long typing must pause before the active search
+// runs again to refresh the highlights
+const searchRefreshDelay = 150 * time.Millisecond
+
 // Editor represents the main text editor component
 type Editor struct {
 	TextWidget         *widget.Entry
 	LineNumberWidget   *LineNumberWidget
 	ScrollContainer    *container.Scroll
 	EditorContainer    *fyne.Container
+	SyntaxHighlights   *SyntaxHighlightLayer
+	SearchHighlights   *SearchHighlightOverlay
+	FilterView         *filterView
+	MatchMarkers       *MatchMarkerBar
+	BottomPanel        *fyne.Container
 	StatusBar          *StatusBar
 	State              *backend.EditorState
 	FileManager        *backend.FileManager
 	ConfigManager      *backend.ConfigManager
 	History            *backend.History
 	SearchManager      *backend.SearchManager
+	SearchHistory      *backend.SearchHistory
 	IndentationManager *IndentationManager
+	FileIndex          *backend.FileIndex
+	ProjectSearcher    *backend.ProjectSearcher
+	ProjectReplacer    *backend.ProjectReplacer
+	Highlighter        *syntax.HighlightService
+	
+	// Panels
+	FindInFilesPanel *FindInFilesPanel
+	OccurrencesPanel *OccurrencesPanel
+	FilterLinesPanel *FilterLinesPanel
 	
 	// Dialogs
-	FindDialog     *dialogs.FindDialog
-	ReplaceDialog  *dialogs.ReplaceDialog
-	GoToLineDialog *dialogs.GoToLineDialog
+	FindDialog      *dialogs.FindDialog
+	ReplaceDialog   *dialogs.ReplaceDialog
+	GoToLineDialog  *dialogs.GoToLineDialog
+	QuickOpenDialog *dialogs.QuickOpenDialog
 	
 	// Callbacks for state changes
 	OnFileChanged      func(path string)
 	OnModified         func(modified bool)
 	OnCursorChanged    func(line, col int)
 	OnSelectionChanged func(hasSelection bool)
+	OnHighlightChanged func(changed []syntax.LineRange)
+	
+	// Latest background highlighting and the version of the content last
+	// submitted for it
+	highlighting     *syntax.Highlighting
+	highlightVersion int
+	
+	// Stops reloading custom themes when their files change
+	stopThemeWatch func()
+	
+	// App whose theme follows the syntax theme, and the syntax theme and
+	// variant last applied to it
+	themeApp    fyne.App
+	appThemeKey string
+	
+	// Theme editor window while it is open
+	themeEditorWindow fyne.Window
+	
+	// Language detected for the document, the modeline region it was
+	// detected with, and the language chosen in its place
+	detectedFileType backend.FileType
+	detectedRegion   string
+	languageOverride *backend.FileType
+	
+	// The pending detection after an edit of the modeline region, and a
+	// counter that discards outdated detections
+	detectTimer   *time.Timer
+	detectVersion int
+	
+	// Search that refreshes the highlights after an edit, a counter that
+	// discards outdated refreshes, and the cancel function of the one running
+	searchRefreshTimer   *time.Timer
+	searchRefreshVersion int
+	searchRefreshCancel  context.CancelFunc
+	
+	// Line filter applied to the view, nil when all lines are shown, and
+	// the filter running in the background with its cancel function
+	lineFilter        *lineFilter
+	pendingLineFilter *lineFilter
+	lineFilterCancel  context.CancelFunc
+	
+	// Text entry themed so the syntax layer can draw its text
+	textView fyne.CanvasObject
+	
+	// The filter view with a gutter of original line numbers, and the
+	// spacer aligning the numbers with its first row
+	filterPane   *fyne.Container
+	filterGutter *fyne.Container
+	gutterSpacer *canvas.Rectangle
 }
 
 // NewEditor creates a new editor instance
@@ -47,6 +121,17 @@ func NewEditor() *Editor {
 		History:            backend.NewHistory(),
 		SearchManager:      backend.NewSearchManager(),
 		IndentationManager: NewIndentationManager(),
+		FileIndex:          backend.NewFileIndex(""),
+		ProjectSearcher:    backend.NewProjectSearcher(),
+		detectedFileType:   plainTextFileType,
+	}
+	e.ProjectReplacer = backend.NewProjectReplacer(e.FileManager)
+	e.SearchManager.SetScopeProvider(e)
+	e.Highlighter = syntax.NewHighlightService(e.applyHighlighting)
+	e.SearchHistory = backend.NewSearchHistory(e.ConfigManager.GetConfigDir())
+	if err := e.SearchHistory.Load(); err != nil {
+		// Start with an empty history, which is not saved over the file
+		log.Printf("Failed to load search history %s: %v", e.SearchHistory.GetPath(), err)
 	}
 	
 	// Create line number widget (but don't add to UI yet to avoid crashes)
@@ -74,10 +159,42 @@ func NewEditor() *Editor {
 func (e *Editor) createEditorContainer() {
 	// For now, create a simple container with just the text widget
 	// Line numbers will be added in a future iteration when Fyne supports it better
-	e.EditorContainer = container.NewMax(e.TextWidget)
+	e.SearchHighlights = NewSearchHighlightOverlay(e.TextWidget)
+	
+	// The syntax layer draws the text in syntax colors over the entry,
+	// which keeps drawing the cursor and selection
+	e.SyntaxHighlights = NewSyntaxHighlightLayer(e.TextWidget)
+	e.textView = container.NewThemeOverride(e.TextWidget, &syntaxEntryTheme{layer: e.SyntaxHighlights})
 	
-	// Wrap in scroll container
+	// Filtered lines are shown in place of the text widget while a line
+	// filter is applied, next to the line number widget showing their
+	// original numbers
+	e.FilterView = newFilterView()
+	e.FilterView.OnActivate = e.goToFilteredLine
+	e.gutterSpacer = canvas.NewRectangle(nil)
+	e.filterGutter = container.NewBorder(e.gutterSpacer, nil, nil, widget.NewSeparator(), e.LineNumberWidget)
+	e.filterPane = container.NewBorder(nil, nil, e.filterGutter, nil, e.FilterView)
+	e.filterPane.Hide()
+	
+	e.EditorContainer = container.NewMax(e.textView, e.SyntaxHighlights, e.SearchHighlights, e.filterPane)
+	
+	// Wrap in scroll container. The entry itself does not scroll so that
+	// search highlights stacked over it move together with the text.
+	e.TextWidget.Scroll = container.ScrollNone
 	e.ScrollContainer = container.NewScroll(e.EditorContainer)
+	e.ScrollContainer.OnScrolled = func(offset fyne.Position) {
+		e.updateViewport(offset, e.ScrollContainer.Size())
+	}
+	
+	// Search match markers shown beside the scrollbar
+	e.MatchMarkers = NewMatchMarkerBar()
+	e.MatchMarkers.OnLineTapped = func(line int) {
+		e.scrollToLine(line)
+	}
+	
+	// Bottom panel area for tool panels such as search results
+	e.BottomPanel = container.NewStack()
+	e.BottomPanel.Hide()
 }
 
 // GetEditorContainer returns the main editor container for embedding in the UI
@@ -88,12 +205,34 @@ func (e *Editor) GetEditorContainer() *container.Scroll {
 // GetCompleteLayout returns the complete editor layout including status bar
 func (e *Editor) GetCompleteLayout() *fyne.Container {
 	return container.NewBorder(
-		nil, e.StatusBar.GetContainer(), // top, bottom
-		nil, nil, // left, right
+		nil, container.NewVBox(e.BottomPanel, e.StatusBar.GetContainer()), // top, bottom
+		nil, e.MatchMarkers, // left, right
 		e.ScrollContainer, // center
 	)
 }
 
+// ShowBottomPanel shows a tool panel below the editor, replacing any other panel
+func (e *Editor) ShowBottomPanel(panel fyne.CanvasObject) {
+	e.BottomPanel.Objects = []fyne.CanvasObject{panel}
+	e.BottomPanel.Show()
+	e.BottomPanel.Refresh()
+}
+
+// IsBottomPanelShowing reports whether the bottom panel is showing panel
+func (e *Editor) IsBottomPanelShowing(panel fyne.CanvasObject) bool {
+	return e.BottomPanel.Visible() && len(e.BottomPanel.Objects) > 0 && e.BottomPanel.Objects[0] == panel
+}
+
+// HideBottomPanel hides the bottom panel if it is currently showing panel
+func (e *Editor) HideBottomPanel(panel fyne.CanvasObject) {
+	if len(e.BottomPanel.Objects) == 0 || e.BottomPanel.Objects[0] != panel {
+		return
+	}
+	e.BottomPanel.Objects = nil
+	e.BottomPanel.Hide()
+	e.BottomPanel.Refresh()
+}
+
 // GetStatusBar returns the status bar component
 func (e *Editor) GetStatusBar() *StatusBar {
 	return e.StatusBar
@@ -102,12 +241,17 @@ func (e *Editor) GetStatusBar() *StatusBar {
 // EnableLineNumbers enables line number display (call after UI is initialized)
 func (e *Editor) EnableLineNumbers() {
 	if e.LineNumberWidget != nil && e.EditorContainer != nil {
+		// The widget moves out of the filter view's gutter, it numbers the
+		// filtered lines where it is now
+		e.filterGutter.Objects = nil
+		e.filterPane.Objects = []fyne.CanvasObject{e.FilterView}
+		
 		// Recreate container with line numbers
 		e.EditorContainer.Objects = []fyne.CanvasObject{
 			container.NewHBox(
 				e.LineNumberWidget,
 				widget.NewSeparator(),
-				e.TextWidget,
+				container.NewStack(e.textView, e.SyntaxHighlights, e.SearchHighlights, e.filterPane),
 			),
 		}
 		e.EditorContainer.Refresh()
@@ -117,8 +261,20 @@ func (e *Editor) EnableLineNumbers() {
 // InitializeDialogs initializes the search dialogs (call this after window is available)
 func (e *Editor) InitializeDialogs(window fyne.Window) {
 	e.FindDialog = dialogs.NewFindDialog(e, e.SearchManager, window)
+	e.FindDialog.SetHistory(e.SearchHistory)
 	e.ReplaceDialog = dialogs.NewReplaceDialog(e, e.SearchManager, window)
+	e.ReplaceDialog.SetHistory(e.SearchHistory)
 	e.GoToLineDialog = dialogs.NewGoToLineDialog(e, window)
+	e.QuickOpenDialog = dialogs.NewQuickOpenDialog(e, e.FileIndex, window)
+	e.FindInFilesPanel = NewFindInFilesPanel(e, e.ProjectSearcher, e.ProjectReplacer, window)
+	e.OccurrencesPanel = NewOccurrencesPanel(e, window)
+	e.FilterLinesPanel = NewFilterLinesPanel(e, window)
+	
+	// Show the files of the workspace as they are indexed. The workspace
+	// root is set when a file or folder is opened.
+	e.FileIndex.OnIndexed = func(count int) {
+		fyne.Do(e.QuickOpenDialog.Refresh)
+	}
 }
 
 // setupTextWidgetCallbacks sets up callbacks for the text widget
@@ -132,9 +288,21 @@ func (e *Editor) setupTextWidgetCallbacks() {
 			}
 		}
 		
+		// Keep a filtered view in step with the buffer
+		e.refreshLineFilter()
+		
 		// Update line number widget
 		e.updateLineNumbers()
 		
+		// Keep search highlights in step with the edited text
+		e.refreshSearchHighlights()
+		
+		// A new shebang or modeline may change the language
+		e.redetectFileType(content)
+		
+		// Re-highlight the syntax in the background
+		e.requestHighlighting()
+		
 		// Simulate cursor position update
 		e.SimulateCursorMovement()
 		
@@ -204,19 +372,29 @@ func (e *Editor) LoadFile(path string) error {
 	}
 	
 	// Update editor content
+	e.ClearLineFilter()
 	e.TextWidget.SetText(content)
 	
 	// Clear history when loading a new file
 	e.History.Clear()
 	
-	// Update state
-	fileType := e.FileManager.GetFileType(path)
+	// Update state, detecting the language of the new document
+	e.languageOverride = nil
+	fileType := e.detectFileType(path, content)
 	e.State.SetCurrentFile(path, fileInfo.Size, fileType.Name)
 	e.State.SetModified(false)
 	
+	// Index the project of files opened outside the workspace
+	if !e.FileIndex.Contains(path) {
+		e.SetWorkspaceRoot(backend.FindWorkspaceRoot(path))
+	}
+	
 	// Reset cursor position
 	e.State.SetCursorPosition(1, 1)
 	
+	// Highlight with the lexer of the new file type
+	e.requestHighlighting()
+	
 	// Notify callbacks
 	if e.OnFileChanged != nil {
 		e.OnFileChanged(path)
@@ -247,11 +425,17 @@ func (e *Editor) SaveFile(path string) error {
 	// Update state
 	fileInfo, _ := e.FileManager.GetFileInfo(path)
 	if fileInfo != nil {
-		fileType := e.FileManager.GetFileType(path)
+		fileType := e.detectFileType(path, content)
 		e.State.SetCurrentFile(path, fileInfo.Size, fileType.Name)
+		if e.languageOverride != nil {
+			e.State.Language = e.languageOverride.Name
+		}
 	}
 	e.State.SetModified(false)
 	
+	// Saving under a new name may change the file type
+	e.requestHighlighting()
+	
 	// Notify callbacks
 	if e.OnModified != nil {
 		e.OnModified(false)
@@ -270,8 +454,12 @@ func (e *Editor) SaveFile(path string) error {
 
 // NewFile creates a new empty file
 func (e *Editor) NewFile() {
+	e.ClearLineFilter()
 	e.TextWidget.SetText("")
 	e.State = backend.NewEditorState()
+	e.languageOverride = nil
+	e.detectFileType("", "")
+	e.requestHighlighting()
 	
 	// Clear history when creating a new file
 	e.History.Clear()
@@ -303,6 +491,17 @@ func (e *Editor) SetContent(content string) {
 	e.TextWidget.SetText(content)
 }
 
+// ApplyEdit replaces the content as a single edit that Undo reverts in one
+// step
+func (e *Editor) ApplyEdit(content string) {
+	old := e.GetContent()
+	if content == old {
+		return
+	}
+	e.History.RecordEdit(old, content)
+	e.SetContent(content)
+}
+
 // IsModified returns whether the file has been modified
 func (e *Editor) IsModified() bool {
 	return e.State.IsModified
@@ -315,10 +514,10 @@ func (e *Editor) GetCurrentFile() string {
 
 // GetFileType returns the current file type
 func (e *Editor) GetFileType() backend.FileType {
-	if e.State.CurrentFile == "" {
-		return backend.FileType{Name: "Plain Text", LexerName: "text"}
+	if e.languageOverride != nil {
+		return *e.languageOverride
 	}
-	return e.FileManager.GetFileType(e.State.CurrentFile)
+	return e.detectedFileType
 }
 
 // ApplyConfiguration applies configuration settings to the editor
@@ -333,6 +532,9 @@ func (e *Editor) ApplyConfiguration() {
 	if !config.WordWrap {
 		e.TextWidget.Wrapping = fyne.TextWrapOff
 	}
+	// The syntax layer only draws the text of unwrapped lines
+	e.TextWidget.Refresh()
+	e.SyntaxHighlights.Refresh()
 	
 	// TODO: Apply other configuration settings as Fyne supports them
 }
@@ -473,6 +675,10 @@ func (e *Editor) applyOperation(op backend.Operation) {
 	case backend.Replace:
 		// Replace with new text
 		content := e.TextWidget.Text
+		if replaced, ok := op.Apply(content); ok {
+			e.TextWidget.SetText(replaced)
+			return
+		}
 		e.TextWidget.SetText(content + op.NewText) // Simplified implementation
 	}
 }
@@ -498,6 +704,10 @@ func (e *Editor) applyReverseOperation(op backend.Operation) {
 	case backend.Replace:
 		// Replace with old text
 		content := e.TextWidget.Text
+		if reverted, ok := op.Revert(content); ok {
+			e.TextWidget.SetText(reverted)
+			return
+		}
 		e.TextWidget.SetText(content + op.OldText) // Simplified implementation
 	}
 }
@@ -571,7 +781,7 @@ func (e *Editor) Replace(pattern, replacement string, options backend.ReplaceOpt
 	newContent, count := e.SearchManager.Replace(content, pattern, replacement, options)
 	
 	if count > 0 {
-		e.SetContent(newContent)
+		e.ApplyEdit(newContent)
 		// Mark as modified
 		e.State.SetModified(true)
 		if e.OnModified != nil {
@@ -582,6 +792,239 @@ func (e *Editor) Replace(pattern, replacement string, options backend.ReplaceOpt
 	return count
 }
 
+// SetSearchHighlights paints every match in the editor, with the match at
+// current in a distinct color, and marks them beside the scrollbar
+func (e *Editor) SetSearchHighlights(matches []backend.Match, current int) {
+	content := e.GetContent()
+	if e.HighlightsMatchesInline() {
+		e.SearchHighlights.SetMatches(matches, current, content)
+	}
+	e.MatchMarkers.SetMatches(matches, current, strings.Count(content, "\n")+1)
+	e.scrollToMatch(matches, current)
+	e.refreshOccurrences(content, matches)
+}
+
+// SetCurrentSearchHighlight changes which highlighted match is the current one
+// and scrolls it into view
+func (e *Editor) SetCurrentSearchHighlight(current int) {
+	e.SearchHighlights.SetCurrent(current)
+	e.MatchMarkers.SetCurrent(current)
+	e.scrollToMatch(e.SearchManager.GetMatches(), current)
+}
+
+// ClearSearchHighlights removes all search highlights and markers
+func (e *Editor) ClearSearchHighlights() {
+	e.SearchHighlights.Clear()
+	e.MatchMarkers.Clear()
+}
+
+// refreshSearchHighlights re-runs the active search after the text changes
+// and updates the highlights and the occurrences panel. The search waits
+// until typing pauses and runs in the background, superseding any refresh
+// still pending.
+func (e *Editor) refreshSearchHighlights() {
+	highlighted := e.SearchHighlights.HasMatches() || e.MatchMarkers.HasMatches()
+	listed := e.OccurrencesPanel != nil && e.OccurrencesPanel.IsVisible()
+	if !highlighted && !listed {
+		return
+	}
+	e.cancelSearchRefresh()
+	if e.SearchManager.GetPattern() == "" {
+		e.ClearSearchHighlights()
+		e.refreshOccurrences(e.GetContent(), nil)
+		return
+	}
+	
+	version := e.searchRefreshVersion
+	e.searchRefreshTimer = time.AfterFunc(searchRefreshDelay, func() {
+		fyne.Do(func() {
+			if version == e.searchRefreshVersion {
+				e.searchRefreshTimer = nil
+				e.startSearchRefresh()
+			}
+		})
+	})
+}
+
+// cancelSearchRefresh stops the pending or running search refresh, if any
+func (e *Editor) cancelSearchRefresh() {
+	e.searchRefreshVersion++
+	if e.searchRefreshTimer != nil {
+		e.searchRefreshTimer.Stop()
+		e.searchRefreshTimer = nil
+	}
+	if e.searchRefreshCancel != nil {
+		e.searchRefreshCancel()
+		e.searchRefreshCancel = nil
+	}
+}
+
+// startSearchRefresh searches the current text for the active pattern in
+// the background and shows the matches once it completes
+func (e *Editor) startSearchRefresh() {
+	ctx, cancel := context.WithCancel(context.Background())
+	e.searchRefreshCancel = cancel
+	version := e.searchRefreshVersion
+	content := e.GetContent()
+	
+	e.SearchManager.FindAsync(ctx, content, e.SearchManager.GetPattern(), func(chunk backend.SearchChunk) {
+		if !chunk.Done {
+			return
+		}
+		fyne.Do(func() {
+			if ctx.Err() != nil || version != e.searchRefreshVersion {
+				return
+			}
+			e.searchRefreshCancel = nil
+			cancel()
+			e.applySearchRefresh(chunk.Result, content)
+		})
+	})
+}
+
+// applySearchRefresh makes result the current search, keeping the current
+// match where it was, and shows its matches
+func (e *Editor) applySearchRefresh(result *backend.SearchResult, content string) {
+	highlighted := e.SearchHighlights.HasMatches() || e.MatchMarkers.HasMatches()
+	current := e.SearchManager.GetCurrentIndex()
+	matches := e.SearchManager.ApplyResult(result)
+	if current >= len(matches) {
+		current = len(matches) - 1
+	}
+	if current >= 0 {
+		e.SearchManager.SetCurrentMatch(current)
+	}
+	
+	if highlighted {
+		if e.HighlightsMatchesInline() {
+			e.SearchHighlights.SetMatches(matches, current, content)
+		}
+		e.MatchMarkers.SetMatches(matches, current, strings.Count(content, "\n")+1)
+	}
+	e.refreshOccurrences(content, matches)
+}
+
+// HighlightsMatchesInline reports whether search matches are painted in
+// the text. The overlay cannot follow wrapped lines, so while lines wrap
+// matches are only marked beside the scrollbar.
+func (e *Editor) HighlightsMatchesInline() bool {
+	return e.TextWidget.Wrapping == fyne.TextWrapOff
+}
+
+// refreshOccurrences lists matches in the occurrences panel if it is showing
+func (e *Editor) refreshOccurrences(content string, matches []backend.Match) {
+	if e.OccurrencesPanel != nil && e.OccurrencesPanel.IsVisible() {
+		e.OccurrencesPanel.SetMatches(e.SearchManager.GetPattern(), content, matches)
+	}
+}
+
+// GoToMatch moves the cursor to the start of match, makes it the current
+// search match if it is one, and scrolls it into view
+func (e *Editor) GoToMatch(match backend.Match) {
+	if !e.GoToLine(match.Start.Line) {
+		return
+	}
+	
+	lines := strings.Split(e.GetContent(), "\n")
+	line := lines[match.Start.Line-1]
+	column := match.Start.Column - 1
+	if column > len(line) {
+		column = len(line)
+	}
+	if column < 0 {
+		column = 0
+	}
+	e.TextWidget.CursorRow = match.Start.Line - 1
+	e.TextWidget.CursorColumn = utf8.RuneCountInString(line[:column])
+	e.TextWidget.Refresh()
+	
+	for i, m := range e.SearchManager.GetMatches() {
+		if m.Start == match.Start {
+			e.SearchManager.SetCurrentMatch(i)
+			e.SetCurrentSearchHighlight(i)
+			return
+		}
+	}
+	e.scrollToLine(match.Start.Line)
+}
+
+// ShowOccurrencesPanel lists the matches of the current search in a panel
+func (e *Editor) ShowOccurrencesPanel() {
+	if e.OccurrencesPanel != nil {
+		e.OccurrencesPanel.Show()
+	}
+}
+
+// HideOccurrencesPanel hides the occurrences panel
+func (e *Editor) HideOccurrencesPanel() {
+	if e.OccurrencesPanel != nil {
+		e.OccurrencesPanel.Hide()
+	}
+}
+
+// scrollToMatch scrolls the editor so the match at index is visible
+func (e *Editor) scrollToMatch(matches []backend.Match, index int) {
+	if index < 0 || index >= len(matches) {
+		return
+	}
+	pos, size := e.SearchHighlights.MatchBounds(matches[index])
+	e.scrollToY(pos.Y, size.Height)
+}
+
+// scrollToLine scrolls the editor so the given line is visible
+func (e *Editor) scrollToLine(lineNumber int) {
+	line := backend.Position{Line: lineNumber, Column: 1}
+	pos, size := e.SearchHighlights.MatchBounds(backend.Match{Start: line, End: line})
+	e.scrollToY(pos.Y, size.Height)
+}
+
+// scrollToY scrolls vertically so the range [y, y+height] is in view
+func (e *Editor) scrollToY(y, height float32) {
+	viewHeight := e.ScrollContainer.Size().Height
+	offset := e.ScrollContainer.Offset
+	if viewHeight == 0 || (y >= offset.Y && y+height <= offset.Y+viewHeight) {
+		return
+	}
+	
+	offset.Y = y - viewHeight/3
+	if offset.Y < 0 {
+		offset.Y = 0
+	}
+	e.ScrollContainer.ScrollToOffset(offset)
+	e.updateViewport(e.ScrollContainer.Offset, e.ScrollContainer.Size())
+}
+
+// ShowFindInFilesPanel shows the Find in Files panel
+func (e *Editor) ShowFindInFilesPanel() {
+	if e.FindInFilesPanel != nil {
+		e.FindInFilesPanel.Show()
+	}
+}
+
+// HideFindInFilesPanel hides the Find in Files panel
+func (e *Editor) HideFindInFilesPanel() {
+	if e.FindInFilesPanel != nil {
+		e.FindInFilesPanel.Hide()
+	}
+}
+
+// OpenFileAtLine opens a file, unless it is already the current file, and
+// moves the cursor to the given line
+func (e *Editor) OpenFileAtLine(path string, lineNumber int) error {
+	if !samePath(path, e.GetCurrentFile()) {
+		if err := e.LoadFile(path); err != nil {
+			return err
+		}
+	}
+	
+	if e.GoToLine(lineNumber) {
+		e.TextWidget.CursorRow = lineNumber - 1
+		e.TextWidget.CursorColumn = 0
+		e.TextWidget.Refresh()
+	}
+	return nil
+}
+
 // GetSearchManager returns the search manager
 func (e *Editor) GetSearchManager() *backend.SearchManager {
 	return e.SearchManager
@@ -628,6 +1071,36 @@ func (e *Editor) GoToLine(lineNumber int) bool {
 	return true
 }
 
+// Quick Open Methods
+
+// ShowQuickOpenDialog shows the quick-open palette
+func (e *Editor) ShowQuickOpenDialog() {
+	if e.QuickOpenDialog != nil {
+		e.QuickOpenDialog.Show()
+	}
+}
+
+// HideQuickOpenDialog hides the quick-open palette
+func (e *Editor) HideQuickOpenDialog() {
+	if e.QuickOpenDialog != nil {
+		e.QuickOpenDialog.Hide()
+	}
+}
+
+// SetWorkspaceRoot changes the workspace root and re-indexes its files
+func (e *Editor) SetWorkspaceRoot(root string) {
+	e.FileIndex.SetRoot(root)
+	e.FileIndex.Start()
+	if e.QuickOpenDialog != nil {
+		e.QuickOpenDialog.Refresh()
+	}
+}
+
+// GetWorkspaceRoot returns the current workspace root
+func (e *Editor) GetWorkspaceRoot() string {
+	return e.FileIndex.GetRoot()
+}
+
 // Indentation Methods
 
 // HandleTabKey handles Tab key press for indentation
@@ -715,6 +1188,9 @@ func (e *Editor) HandleKeyEvent(event *fyne.KeyEvent) bool {
 	if e.GoToLineDialog != nil && e.GoToLineDialog.HandleKeyEvent(event) {
 		return true
 	}
+	if e.QuickOpenDialog != nil && e.QuickOpenDialog.HandleKeyEvent(event) {
+		return true
+	}
 	
 	// Handle editor-specific key events
 	switch event.Name {
@@ -0,0 +1,505 @@
+package ui
+
+import (
+	"context"
+	"fmt"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+	"github.com/kenelite/goeditor/backend"
+)
+
+// asyncFilterThreshold is the buffer size in bytes from which lines are
+// filtered in the background, as the find dialog does for searches
+const asyncFilterThreshold = 256 * 1024
+
+// filterModeOptions are the filter modes offered in the panel, in the order
+// of backend.FilterMode
+var filterModeOptions = []string{"Keep matching lines", "Hide matching lines"}
+
+// lineFilter is the filter currently applied to the editor view
+type lineFilter struct {
+	pattern string
+	options backend.SearchOptions
+	mode    backend.FilterMode
+	result  *backend.FilteredText
+}
+
+// filterView shows the filtered lines in place of the text widget. It can
+// be scrolled, selected and copied but not edited, so the buffer stays
+// untouched; Enter jumps to the original of the line under the cursor.
+type filterView struct {
+	widget.Entry
+
+	// OnActivate is called with the row of the cursor when Enter is pressed
+	OnActivate func(row int)
+}
+
+// newFilterView creates a filter view
+func newFilterView() *filterView {
+	v := &filterView{}
+	v.MultiLine = true
+	v.Wrapping = fyne.TextWrapOff
+	v.Scroll = container.ScrollNone
+	v.ExtendBaseWidget(v)
+	return v
+}
+
+// TypedRune ignores typed text
+func (v *filterView) TypedRune(r rune) {
+}
+
+// TypedKey passes navigation keys to the entry and ignores editing keys
+func (v *filterView) TypedKey(key *fyne.KeyEvent) {
+	switch key.Name {
+	case fyne.KeyUp, fyne.KeyDown, fyne.KeyLeft, fyne.KeyRight,
+		fyne.KeyHome, fyne.KeyEnd, fyne.KeyPageUp, fyne.KeyPageDown:
+		v.Entry.TypedKey(key)
+	case fyne.KeyReturn, fyne.KeyEnter:
+		if v.OnActivate != nil {
+			v.OnActivate(v.CursorRow)
+		}
+	}
+}
+
+// TypedShortcut allows copying and selecting but not cutting or pasting
+func (v *filterView) TypedShortcut(shortcut fyne.Shortcut) {
+	switch shortcut.(type) {
+	case *fyne.ShortcutCopy, *fyne.ShortcutSelectAll:
+		v.Entry.TypedShortcut(shortcut)
+	}
+}
+
+// FilterLinesPanel keeps or hides the lines matching a pattern, like grep
+// and grep -v. The editor shows the remaining lines with their original
+// line numbers until the filter is cleared; the buffer is not modified.
+type FilterLinesPanel struct {
+	container *fyne.Container
+
+	patternEntry  *widget.Entry
+	modeSelect    *widget.Select
+	caseCheck     *widget.Check
+	wordCheck     *widget.Check
+	regexCheck    *widget.Check
+	applyButton   *widget.Button
+	showAllButton *widget.Button
+	newDocButton  *widget.Button
+	closeButton   *widget.Button
+	statusLabel   *widget.Label
+
+	// References
+	editor *Editor
+	window fyne.Window
+
+	// State
+	mode      backend.FilterMode
+	isVisible bool
+}
+
+// NewFilterLinesPanel creates a new filter lines panel
+func NewFilterLinesPanel(editor *Editor, window fyne.Window) *FilterLinesPanel {
+	fp := &FilterLinesPanel{
+		editor: editor,
+		window: window,
+	}
+
+	fp.createLayout()
+	return fp
+}
+
+// createLayout creates the panel UI
+func (fp *FilterLinesPanel) createLayout() {
+	fp.patternEntry = widget.NewEntry()
+	fp.patternEntry.SetPlaceHolder("Filter lines...")
+	fp.patternEntry.OnSubmitted = func(text string) {
+		fp.Apply()
+	}
+
+	fp.modeSelect = widget.NewSelect(filterModeOptions, func(selected string) {
+		for i, option := range filterModeOptions {
+			if option == selected {
+				fp.mode = backend.FilterMode(i)
+			}
+		}
+		fp.reapply()
+	})
+	fp.modeSelect.SetSelected(filterModeOptions[0])
+
+	// Options checkboxes; the remaining options follow the find dialog
+	fp.caseCheck = widget.NewCheck("Case sensitive", func(bool) { fp.reapply() })
+	fp.wordCheck = widget.NewCheck("Whole word", func(bool) { fp.reapply() })
+	fp.regexCheck = widget.NewCheck("Regular expression", func(bool) { fp.reapply() })
+
+	// Buttons
+	fp.applyButton = widget.NewButton("Filter", func() {
+		fp.Apply()
+	})
+	fp.applyButton.Importance = widget.HighImportance
+
+	fp.showAllButton = widget.NewButton("Show All", func() {
+		fp.editor.ClearLineFilter()
+		fp.updateStatus()
+	})
+
+	fp.newDocButton = widget.NewButton("Open as New Document", func() {
+		fp.OpenAsNewDocument()
+	})
+
+	fp.closeButton = widget.NewButton("Close", func() {
+		fp.Hide()
+	})
+
+	fp.statusLabel = widget.NewLabel("")
+
+	// Layout
+	patternRow := container.NewBorder(nil, nil, widget.NewLabel("Filter:"), container.NewHBox(fp.modeSelect, fp.applyButton), fp.patternEntry)
+	optionsRow := container.NewHBox(fp.caseCheck, fp.wordCheck, fp.regexCheck)
+	statusRow := container.NewBorder(nil, nil, nil, container.NewHBox(fp.showAllButton, fp.newDocButton, fp.closeButton), fp.statusLabel)
+
+	fp.container = container.NewVBox(patternRow, optionsRow, statusRow)
+}
+
+// GetContainer returns the panel container for embedding in the editor layout
+func (fp *FilterLinesPanel) GetContainer() fyne.CanvasObject {
+	return fp.container
+}
+
+// Show displays the panel, taking the search options from the search manager
+func (fp *FilterLinesPanel) Show() {
+	if !fp.editor.IsLineFilterActive() {
+		options := fp.editor.SearchManager.GetOptions()
+		fp.caseCheck.SetChecked(options.CaseSensitive)
+		fp.wordCheck.SetChecked(options.WholeWord)
+		fp.regexCheck.SetChecked(options.RegularExpression)
+		if fp.patternEntry.Text == "" {
+			fp.patternEntry.SetText(fp.editor.SearchManager.GetPattern())
+		}
+	}
+
+	fp.isVisible = true
+	fp.editor.ShowBottomPanel(fp.container)
+	fp.updateStatus()
+	if fp.window != nil {
+		fp.window.Canvas().Focus(fp.patternEntry)
+	}
+}
+
+// Hide hides the panel and shows all lines again
+func (fp *FilterLinesPanel) Hide() {
+	fp.isVisible = false
+	fp.editor.ClearLineFilter()
+	fp.editor.HideBottomPanel(fp.container)
+}
+
+// IsVisible returns whether the panel is currently visible
+func (fp *FilterLinesPanel) IsVisible() bool {
+	return fp.isVisible && fp.editor.IsBottomPanelShowing(fp.container)
+}
+
+// SetPattern sets the pattern to filter by
+func (fp *FilterLinesPanel) SetPattern(pattern string) {
+	fp.patternEntry.SetText(pattern)
+}
+
+// SetMode sets whether matching lines are kept or hidden
+func (fp *FilterLinesPanel) SetMode(mode backend.FilterMode) {
+	fp.modeSelect.SetSelected(filterModeOptions[mode])
+}
+
+// options returns the search options of the panel
+func (fp *FilterLinesPanel) options() backend.SearchOptions {
+	options := fp.editor.SearchManager.GetOptions()
+	options.CaseSensitive = fp.caseCheck.Checked
+	options.WholeWord = fp.wordCheck.Checked
+	options.RegularExpression = fp.regexCheck.Checked
+	return options
+}
+
+// Apply filters the editor view by the pattern. An empty pattern shows all
+// lines.
+func (fp *FilterLinesPanel) Apply() {
+	pattern := fp.patternEntry.Text
+	if pattern == "" {
+		fp.editor.ClearLineFilter()
+		fp.updateStatus()
+		return
+	}
+
+	result, err := fp.editor.ApplyLineFilter(pattern, fp.options(), fp.mode)
+	if err != nil {
+		fp.statusLabel.SetText(fmt.Sprintf("Invalid pattern: %v", err))
+		return
+	}
+	if result == nil {
+		fp.statusLabel.SetText("Filtering...")
+		return
+	}
+	fp.updateStatus()
+}
+
+// reapply filters again after an option changed, if a filter is applied
+// or running
+func (fp *FilterLinesPanel) reapply() {
+	if fp.editor != nil && (fp.editor.IsLineFilterActive() || fp.editor.IsLineFilterPending()) {
+		fp.Apply()
+	}
+}
+
+// filterFinished shows the outcome of a filter that ran in the background
+func (fp *FilterLinesPanel) filterFinished(err error) {
+	if err != nil {
+		fp.statusLabel.SetText(fmt.Sprintf("Invalid pattern: %v", err))
+		return
+	}
+	fp.updateStatus()
+}
+
+// OpenAsNewDocument replaces the editor with a new document holding the
+// filtered lines
+func (fp *FilterLinesPanel) OpenAsNewDocument() {
+	if !fp.editor.IsLineFilterActive() {
+		fp.Apply()
+		if fp.editor.IsLineFilterPending() {
+			// The lines can be opened once the filter is done
+			return
+		}
+	}
+	if err := fp.editor.MaterializeLineFilter(); err != nil {
+		fp.statusLabel.SetText(fmt.Sprintf("Cannot open a new document: %v", err))
+		return
+	}
+	fp.updateStatus()
+	fp.statusLabel.SetText("Filtered lines opened as a new document")
+}
+
+// updateStatus shows how many lines the filter keeps
+func (fp *FilterLinesPanel) updateStatus() {
+	result := fp.editor.GetLineFilterResult()
+	if result == nil {
+		fp.statusLabel.SetText("Showing all lines. Enter a pattern and press Filter.")
+		fp.newDocButton.Disable()
+		fp.showAllButton.Disable()
+		return
+	}
+
+	fp.statusLabel.SetText(fmt.Sprintf("Showing %d of %d lines. Press Enter on a line to go to it.", result.Lines(), result.TotalLines))
+	fp.newDocButton.Enable()
+	fp.showAllButton.Enable()
+}
+
+// ApplyLineFilter shows only the lines of the buffer that pattern keeps or
+// hides, searching with options. The buffer is not modified; the view keeps
+// following it until ClearLineFilter is called. Large buffers are filtered
+// in the background, so the window stays responsive: the result is nil
+// then, and the view changes once IsLineFilterPending reports false.
+func (e *Editor) ApplyLineFilter(pattern string, options backend.SearchOptions, mode backend.FilterMode) (*backend.FilteredText, error) {
+	e.cancelLineFilter()
+	filter := &lineFilter{pattern: pattern, options: options, mode: mode}
+	content := e.GetContent()
+	if len(content) >= asyncFilterThreshold {
+		if err := backend.ValidateSearchPattern(pattern, options); err != nil {
+			return nil, err
+		}
+		e.startLineFilter(filter, content)
+		return nil, nil
+	}
+
+	if err := e.runLineFilter(filter, content); err != nil {
+		return nil, err
+	}
+	e.lineFilter = filter
+	e.showFilterView()
+	return filter.result, nil
+}
+
+// lineFilterSearch returns a search manager set up for filter
+func (e *Editor) lineFilterSearch(filter *lineFilter) *backend.SearchManager {
+	sm := backend.NewSearchManager()
+	sm.SetScopeProvider(e)
+	sm.SetOptions(filter.options)
+	return sm
+}
+
+// runLineFilter filters content with the settings of filter
+func (e *Editor) runLineFilter(filter *lineFilter, content string) error {
+	result, err := e.lineFilterSearch(filter).FilterLines(content, filter.pattern, filter.mode)
+	if err != nil {
+		return err
+	}
+	filter.result = result
+	return nil
+}
+
+// startLineFilter filters content with the settings of filter in the
+// background and shows the result once it completes, unless the filter was
+// cancelled in the meantime
+func (e *Editor) startLineFilter(filter *lineFilter, content string) {
+	ctx, cancel := context.WithCancel(context.Background())
+	e.pendingLineFilter = filter
+	e.lineFilterCancel = cancel
+
+	e.lineFilterSearch(filter).FilterLinesAsync(ctx, content, filter.pattern, filter.mode, func(result *backend.FilteredText, err error) {
+		fyne.Do(func() {
+			if ctx.Err() != nil {
+				return
+			}
+			e.cancelLineFilter()
+			if err != nil {
+				e.ClearLineFilter()
+			} else {
+				filter.result = result
+				e.lineFilter = filter
+				e.showFilterView()
+			}
+			if e.FilterLinesPanel != nil {
+				e.FilterLinesPanel.filterFinished(err)
+			}
+		})
+	})
+}
+
+// cancelLineFilter stops the filter running in the background, if any
+func (e *Editor) cancelLineFilter() {
+	if e.lineFilterCancel != nil {
+		e.lineFilterCancel()
+		e.lineFilterCancel = nil
+	}
+	e.pendingLineFilter = nil
+}
+
+// refreshLineFilter filters the buffer again after it changed, restarting
+// a filter still running on the previous text
+func (e *Editor) refreshLineFilter() {
+	filter := e.lineFilter
+	if e.pendingLineFilter != nil {
+		filter = e.pendingLineFilter
+	}
+	if filter == nil {
+		return
+	}
+	e.cancelLineFilter()
+
+	content := e.GetContent()
+	if len(content) >= asyncFilterThreshold {
+		e.startLineFilter(filter, content)
+		return
+	}
+	if err := e.runLineFilter(filter, content); err != nil {
+		e.ClearLineFilter()
+		return
+	}
+	e.lineFilter = filter
+	e.showFilterView()
+}
+
+// showFilterView shows the filtered lines in place of the text widget,
+// each next to its original line number
+func (e *Editor) showFilterView() {
+	result := e.lineFilter.result
+	e.FilterView.TextStyle = e.TextWidget.TextStyle
+	e.FilterView.SetText(result.Text)
+	e.FilterView.CursorRow = 0
+	e.FilterView.CursorColumn = 0
+	e.TextWidget.Hide()
+	e.SyntaxHighlights.Hide()
+	e.SearchHighlights.Hide()
+	e.filterPane.Show()
+
+	// Number the rows of the filter view where the entry draws them
+	th := e.FilterView.Theme()
+	textSize := th.Size(theme.SizeNameText)
+	e.gutterSpacer.SetMinSize(fyne.NewSize(0, th.Size(theme.SizeNameInnerPadding)))
+	e.LineNumberWidget.SetFontHeight(textSize)
+	e.LineNumberWidget.SetLineHeight(fyne.MeasureText("M", textSize, e.FilterView.TextStyle).Height)
+	e.LineNumberWidget.SetLineNumbers(result.LineNumbers)
+	e.EditorContainer.Refresh()
+}
+
+// ClearLineFilter shows all lines of the buffer again and stops a filter
+// still running
+func (e *Editor) ClearLineFilter() {
+	e.cancelLineFilter()
+	if e.lineFilter == nil {
+		return
+	}
+	e.lineFilter = nil
+	e.filterPane.Hide()
+	e.FilterView.SetText("")
+	e.TextWidget.Show()
+	e.SyntaxHighlights.Show()
+	e.SearchHighlights.Show()
+	e.LineNumberWidget.SetLineNumbers(nil)
+	e.updateLineNumbers()
+	e.EditorContainer.Refresh()
+}
+
+// IsLineFilterActive reports whether the view is filtered
+func (e *Editor) IsLineFilterActive() bool {
+	return e.lineFilter != nil
+}
+
+// IsLineFilterPending reports whether a filter is running in the background
+func (e *Editor) IsLineFilterPending() bool {
+	return e.lineFilterCancel != nil
+}
+
+// GetLineFilterResult returns the lines currently shown by the filter, or
+// nil if the view is not filtered
+func (e *Editor) GetLineFilterResult() *backend.FilteredText {
+	if e.lineFilter == nil {
+		return nil
+	}
+	return e.lineFilter.result
+}
+
+// MaterializeLineFilter replaces the editor with a new, unsaved document
+// holding the filtered lines. It fails if the view is not filtered or the
+// current document has unsaved changes, which the new document would
+// discard.
+func (e *Editor) MaterializeLineFilter() error {
+	if e.lineFilter == nil {
+		return fmt.Errorf("no line filter is applied")
+	}
+	if e.IsModified() {
+		return fmt.Errorf("save the current document before opening the filtered lines as a new document")
+	}
+	text := e.lineFilter.result.Text
+	e.ClearLineFilter()
+	e.NewFile()
+	e.SetContent(text)
+	return nil
+}
+
+// goToFilteredLine leaves the filtered view at the original of the filtered
+// line at row
+func (e *Editor) goToFilteredLine(row int) {
+	if e.lineFilter == nil {
+		return
+	}
+	numbers := e.lineFilter.result.LineNumbers
+	if row < 0 || row >= len(numbers) {
+		return
+	}
+	line := numbers[row]
+	e.ClearLineFilter()
+	if e.FilterLinesPanel != nil {
+		e.FilterLinesPanel.updateStatus()
+	}
+	e.GoToMatch(backend.Match{Start: backend.Position{Line: line, Column: 1}})
+}
+
+// ShowFilterLinesPanel shows the filter lines panel
+func (e *Editor) ShowFilterLinesPanel() {
+	if e.FilterLinesPanel != nil {
+		e.FilterLinesPanel.Show()
+	}
+}
+
+// HideFilterLinesPanel hides the filter lines panel and shows all lines
+func (e *Editor) HideFilterLinesPanel() {
+	if e.FilterLinesPanel != nil {
+		e.FilterLinesPanel.Hide()
+	}
+}
@@ -0,0 +1,202 @@
+package ui
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/canvas"
+	"fyne.io/fyne/v2/test"
+	"github.com/kenelite/goeditor/backend"
+)
+
+func TestFilterLinesPanel(t *testing.T) {
+	testApp := test.NewApp()
+	defer testApp.Quit()
+
+	window := testApp.NewWindow("Test")
+	editor := NewEditor()
+	editor.InitializeDialogs(window)
+	content := "INFO start\nERROR disk full\nINFO retry\nERROR again\n"
+	editor.SetContent(content)
+	editor.State.SetModified(false)
+
+	editor.ShowFilterLinesPanel()
+	panel := editor.FilterLinesPanel
+	if !panel.IsVisible() {
+		t.Fatal("Expected the filter lines panel to be visible")
+	}
+
+	panel.SetPattern("error")
+	panel.Apply()
+	if !editor.IsLineFilterActive() {
+		t.Fatal("Expected the view to be filtered")
+	}
+	if editor.FilterView.Text != "ERROR disk full\nERROR again\n" {
+		t.Errorf("Unexpected filtered view %q", editor.FilterView.Text)
+	}
+	if numbers := editor.LineNumberWidget.GetLineNumbers(); !reflect.DeepEqual(numbers, []int{2, 4}) {
+		t.Errorf("Expected original line numbers 2 and 4, got %v", numbers)
+	}
+	window.SetContent(editor.GetCompleteLayout())
+	window.Resize(fyne.NewSize(600, 400))
+	if shown := visibleTexts(window.Canvas().Content()); !containsAll(shown, "2", "4") || containsAll(shown, "1") {
+		t.Errorf("Expected the original line numbers next to the filtered lines, got %v", shown)
+	}
+	if editor.GetContent() != content || editor.IsModified() {
+		t.Error("Expected the buffer to be left untouched")
+	}
+
+	// Hiding the matching lines instead
+	panel.SetMode(backend.FilterHide)
+	if editor.FilterView.Text != "INFO start\nINFO retry\n" {
+		t.Errorf("Unexpected filtered view %q", editor.FilterView.Text)
+	}
+
+	// The view follows changes to the buffer
+	editor.SetContent("INFO start\nERROR disk full\nWARN low memory\n")
+	if numbers := editor.LineNumberWidget.GetLineNumbers(); !reflect.DeepEqual(numbers, []int{1, 3}) {
+		t.Errorf("Expected line numbers 1 and 3, got %v", numbers)
+	}
+
+	// Enter on a filtered line goes to its original line
+	editor.FilterView.CursorRow = 1
+	editor.goToFilteredLine(editor.FilterView.CursorRow)
+	if editor.IsLineFilterActive() {
+		t.Error("Expected the filter to be cleared")
+	}
+	if editor.TextWidget.CursorRow != 2 {
+		t.Errorf("Expected the cursor on row 2, got %d", editor.TextWidget.CursorRow)
+	}
+	if editor.LineNumberWidget.GetLineNumbers() != nil {
+		t.Error("Expected consecutive line numbers again")
+	}
+	if containsAll(visibleTexts(window.Canvas().Content()), "1") {
+		t.Error("Expected the line numbers to be hidden with the filter view")
+	}
+
+	// A modified buffer is never discarded for the new document
+	panel.SetMode(backend.FilterKeep)
+	editor.State.SetModified(true)
+	panel.OpenAsNewDocument()
+	if editor.GetContent() != "INFO start\nERROR disk full\nWARN low memory\n" || !editor.IsModified() {
+		t.Errorf("Expected the modified buffer to survive, got %q", editor.GetContent())
+	}
+	if !editor.IsLineFilterActive() {
+		t.Error("Expected the filter to stay applied")
+	}
+
+	// Materializing opens the filtered lines as a new document
+	editor.State.SetModified(false)
+	panel.OpenAsNewDocument()
+	if editor.GetContent() != "ERROR disk full\n" || editor.GetCurrentFile() != "" {
+		t.Errorf("Expected a new document with the filtered lines, got %q", editor.GetContent())
+	}
+	if editor.IsLineFilterActive() {
+		t.Error("Expected the new document to be unfiltered")
+	}
+
+	editor.HideFilterLinesPanel()
+	if panel.IsVisible() {
+		t.Error("Expected the panel to be hidden")
+	}
+}
+
+func TestFilterLinesLargeBuffer(t *testing.T) {
+	testApp := test.NewApp()
+	defer testApp.Quit()
+
+	window := testApp.NewWindow("Test")
+	editor := NewEditor()
+	editor.InitializeDialogs(window)
+	// The entry takes long to lay out this much text, so it is set directly
+	content := "INFO start\n" + strings.Repeat("ERROR disk full\n", asyncFilterThreshold/16) + "INFO done\n"
+	editor.TextWidget.Text = content
+
+	editor.ShowFilterLinesPanel()
+	panel := editor.FilterLinesPanel
+	panel.SetPattern("info")
+	panel.Apply()
+	if !editor.IsLineFilterPending() || editor.IsLineFilterActive() {
+		t.Fatal("Expected a large buffer to be filtered in the background")
+	}
+	if panel.statusLabel.Text != "Filtering..." {
+		t.Errorf("Expected the panel to show the filter is running, got %q", panel.statusLabel.Text)
+	}
+
+	// Applying again replaces the running filter
+	panel.caseCheck.SetChecked(true)
+	waitForLineFilter(t, editor)
+	if result := editor.GetLineFilterResult(); result == nil || result.Lines() != 0 {
+		t.Errorf("Expected no line to match case-sensitively, got %v", result)
+	}
+
+	panel.caseCheck.SetChecked(false)
+	waitForLineFilter(t, editor)
+	if editor.FilterView.Text != "INFO start\nINFO done\n" {
+		t.Errorf("Unexpected filtered view %q", editor.FilterView.Text)
+	}
+
+	// An edit filters the buffer again in the background
+	editor.TextWidget.Text = content + "INFO again\n"
+	editor.refreshLineFilter()
+	if !editor.IsLineFilterPending() {
+		t.Error("Expected the edited buffer to be filtered in the background")
+	}
+	waitForLineFilter(t, editor)
+	if result := editor.GetLineFilterResult(); result == nil || result.Lines() != 3 {
+		t.Errorf("Expected the new line to be kept, got %v", result)
+	}
+}
+
+// waitForLineFilter waits for the filter running in the background
+func waitForLineFilter(t *testing.T, editor *Editor) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for editor.IsLineFilterPending() && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if editor.IsLineFilterPending() {
+		t.Fatal("Line filter did not finish")
+	}
+}
+
+// visibleTexts returns the text of every visible canvas text under object
+func visibleTexts(object fyne.CanvasObject) []string {
+	if !object.Visible() {
+		return nil
+	}
+	var texts []string
+	switch o := object.(type) {
+	case *canvas.Text:
+		texts = append(texts, o.Text)
+	case *fyne.Container:
+		for _, child := range o.Objects {
+			texts = append(texts, visibleTexts(child)...)
+		}
+	case fyne.Widget:
+		for _, child := range test.WidgetRenderer(o).Objects() {
+			texts = append(texts, visibleTexts(child)...)
+		}
+	}
+	return texts
+}
+
+// containsAll reports whether texts holds every one of wanted
+func containsAll(texts []string, wanted ...string) bool {
+	for _, want := range wanted {
+		found := false
+		for _, text := range texts {
+			if text == want {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,552 @@
+package ui
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/canvas"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+	"github.com/kenelite/goeditor/backend"
+)
+
+// findInFilesPanelHeight is the minimum height of the results panel
+const findInFilesPanelHeight = 260
+
+// maxPreviewLength truncates long line previews in the results tree
+const maxPreviewLength = 160
+
+// FindInFilesPanel searches a directory and lists the results grouped by file
+type FindInFilesPanel struct {
+	container *fyne.Container
+
+	patternEntry   *widget.Entry
+	replaceEntry   *widget.Entry
+	directoryEntry *widget.Entry
+	includeEntry   *widget.Entry
+	excludeEntry   *widget.Entry
+	optionsCheck   map[string]*widget.Check
+	searchButton   *widget.Button
+	stopButton     *widget.Button
+	replaceButton  *widget.Button
+	undoButton     *widget.Button
+	closeButton    *widget.Button
+	statusLabel    *widget.Label
+	resultTree     *widget.Tree
+
+	// References
+	editor   *Editor
+	searcher *backend.ProjectSearcher
+	replacer *backend.ProjectReplacer
+	window   fyne.Window
+
+	// State
+	results       []backend.FileSearchResult
+	searchPattern string
+	cancel        context.CancelFunc
+	generation    int
+	isVisible     bool
+}
+
+// NewFindInFilesPanel creates a new Find in Files panel
+func NewFindInFilesPanel(editor *Editor, searcher *backend.ProjectSearcher, replacer *backend.ProjectReplacer, window fyne.Window) *FindInFilesPanel {
+	fp := &FindInFilesPanel{
+		editor:   editor,
+		searcher: searcher,
+		replacer: replacer,
+		window:   window,
+		results:  make([]backend.FileSearchResult, 0),
+	}
+
+	fp.createLayout()
+	return fp
+}
+
+// createLayout creates the panel UI
+func (fp *FindInFilesPanel) createLayout() {
+	fp.patternEntry = widget.NewEntry()
+	fp.patternEntry.SetPlaceHolder("Search in files...")
+	fp.patternEntry.OnSubmitted = func(text string) {
+		fp.StartSearch()
+	}
+
+	fp.replaceEntry = widget.NewEntry()
+	fp.replaceEntry.SetPlaceHolder("Replace with...")
+
+	fp.directoryEntry = widget.NewEntry()
+	fp.directoryEntry.SetPlaceHolder("Directory")
+	fp.directoryEntry.OnSubmitted = func(text string) {
+		fp.StartSearch()
+	}
+
+	fp.includeEntry = widget.NewEntry()
+	fp.includeEntry.SetPlaceHolder("Include, e.g. *.go, ui/**")
+
+	fp.excludeEntry = widget.NewEntry()
+	fp.excludeEntry.SetPlaceHolder("Exclude, e.g. *_test.go")
+
+	// Options checkboxes
+	options := fp.searcher.GetOptions()
+	fp.optionsCheck = make(map[string]*widget.Check)
+	fp.optionsCheck["caseSensitive"] = widget.NewCheck("Case sensitive", nil)
+	fp.optionsCheck["wholeWord"] = widget.NewCheck("Whole word", nil)
+	fp.optionsCheck["regex"] = widget.NewCheck("Regular expression", nil)
+	fp.optionsCheck["multiLine"] = widget.NewCheck("^ $ match lines", nil)
+	fp.optionsCheck["dotAll"] = widget.NewCheck(". matches newline", nil)
+	fp.optionsCheck["pcre"] = widget.NewCheck("PCRE syntax", nil)
+	fp.optionsCheck["gitignore"] = widget.NewCheck("Use .gitignore", nil)
+	fp.optionsCheck["caseSensitive"].SetChecked(options.CaseSensitive)
+	fp.optionsCheck["wholeWord"].SetChecked(options.WholeWord)
+	fp.optionsCheck["regex"].SetChecked(options.RegularExpression)
+	fp.optionsCheck["multiLine"].SetChecked(options.MultiLine)
+	fp.optionsCheck["dotAll"].SetChecked(options.DotAll)
+	fp.optionsCheck["pcre"].SetChecked(options.Engine == backend.RegexEnginePCRE)
+	fp.optionsCheck["gitignore"].SetChecked(options.RespectGitignore)
+	fp.excludeEntry.SetText(strings.Join(options.Exclude, ", "))
+
+	// Buttons
+	fp.searchButton = widget.NewButton("Search", func() {
+		fp.StartSearch()
+	})
+	fp.searchButton.Importance = widget.HighImportance
+
+	fp.stopButton = widget.NewButton("Stop", func() {
+		fp.CancelSearch()
+	})
+	fp.stopButton.Disable()
+
+	fp.replaceButton = widget.NewButton("Replace...", func() {
+		fp.PreviewReplace()
+	})
+	fp.replaceButton.Disable()
+
+	fp.undoButton = widget.NewButton("Undo Replace", func() {
+		fp.UndoReplace()
+	})
+	fp.undoButton.Disable()
+
+	fp.closeButton = widget.NewButton("Close", func() {
+		fp.Hide()
+	})
+
+	fp.statusLabel = widget.NewLabel("")
+
+	// Results grouped by file: "<file>" nodes with "<file>:<match>" children
+	fp.resultTree = widget.NewTree(
+		fp.childUIDs,
+		fp.isBranch,
+		func(branch bool) fyne.CanvasObject {
+			return widget.NewLabel("")
+		},
+		fp.updateNode,
+	)
+	fp.resultTree.OnSelected = func(uid widget.TreeNodeID) {
+		fp.openResult(uid)
+	}
+
+	// Layout
+	searchRow := container.NewBorder(nil, nil, widget.NewLabel("Find:"), container.NewHBox(fp.searchButton, fp.stopButton), fp.patternEntry)
+	replaceRow := container.NewBorder(nil, nil, widget.NewLabel("Replace:"), container.NewHBox(fp.replaceButton, fp.undoButton), fp.replaceEntry)
+	directoryRow := container.NewBorder(nil, nil, widget.NewLabel("In:"), nil, fp.directoryEntry)
+	filterRow := container.NewGridWithColumns(2, fp.includeEntry, fp.excludeEntry)
+	optionsRow := container.NewHBox(
+		fp.optionsCheck["caseSensitive"],
+		fp.optionsCheck["wholeWord"],
+		fp.optionsCheck["regex"],
+		fp.optionsCheck["multiLine"],
+		fp.optionsCheck["dotAll"],
+		fp.optionsCheck["pcre"],
+		fp.optionsCheck["gitignore"],
+	)
+	statusRow := container.NewBorder(nil, nil, nil, fp.closeButton, fp.statusLabel)
+
+	form := container.NewVBox(searchRow, replaceRow, directoryRow, filterRow, optionsRow, statusRow)
+
+	// Keep the panel tall enough to show a useful number of results
+	spacer := canvas.NewRectangle(nil)
+	spacer.SetMinSize(fyne.NewSize(0, findInFilesPanelHeight))
+
+	fp.container = container.NewStack(
+		spacer,
+		container.NewBorder(form, nil, nil, nil, fp.resultTree),
+	)
+}
+
+// GetContainer returns the panel container for embedding in the editor layout
+func (fp *FindInFilesPanel) GetContainer() fyne.CanvasObject {
+	return fp.container
+}
+
+// Show displays the panel and focuses the pattern entry
+func (fp *FindInFilesPanel) Show() {
+	if fp.directoryEntry.Text == "" {
+		fp.directoryEntry.SetText(fp.editor.GetWorkspaceRoot())
+	}
+
+	fp.isVisible = true
+	fp.editor.ShowBottomPanel(fp.container)
+
+	if fp.window != nil {
+		fp.window.Canvas().Focus(fp.patternEntry)
+	}
+}
+
+// Hide hides the panel and cancels any running search
+func (fp *FindInFilesPanel) Hide() {
+	fp.CancelSearch()
+	fp.isVisible = false
+	fp.editor.HideBottomPanel(fp.container)
+}
+
+// IsVisible returns whether the panel is currently visible
+func (fp *FindInFilesPanel) IsVisible() bool {
+	return fp.isVisible
+}
+
+// SetSearchText sets the search pattern programmatically
+func (fp *FindInFilesPanel) SetSearchText(text string) {
+	fp.patternEntry.SetText(text)
+}
+
+// SetDirectory sets the directory to search
+func (fp *FindInFilesPanel) SetDirectory(dir string) {
+	fp.directoryEntry.SetText(dir)
+}
+
+// GetResults returns the results collected so far
+func (fp *FindInFilesPanel) GetResults() []backend.FileSearchResult {
+	return fp.results
+}
+
+// collectOptions builds project search options from the panel inputs
+func (fp *FindInFilesPanel) collectOptions() backend.ProjectSearchOptions {
+	options := fp.searcher.GetOptions()
+	options.CaseSensitive = fp.optionsCheck["caseSensitive"].Checked
+	options.WholeWord = fp.optionsCheck["wholeWord"].Checked
+	options.RegularExpression = fp.optionsCheck["regex"].Checked
+	options.MultiLine = fp.optionsCheck["multiLine"].Checked
+	options.DotAll = fp.optionsCheck["dotAll"].Checked
+	options.Engine = backend.RegexEngineRE2
+	if fp.optionsCheck["pcre"].Checked {
+		options.Engine = backend.RegexEnginePCRE
+	}
+	options.RespectGitignore = fp.optionsCheck["gitignore"].Checked
+	options.Include = backend.ParseGlobList(fp.includeEntry.Text)
+	options.Exclude = backend.ParseGlobList(fp.excludeEntry.Text)
+	return options
+}
+
+// StartSearch cancels any running search and starts a new one
+func (fp *FindInFilesPanel) StartSearch() {
+	pattern := fp.patternEntry.Text
+	dir := strings.TrimSpace(fp.directoryEntry.Text)
+	if pattern == "" {
+		fp.statusLabel.SetText("Enter text to search")
+		return
+	}
+	if dir == "" {
+		dir = fp.editor.GetWorkspaceRoot()
+	}
+	if dir == "" {
+		fp.statusLabel.SetText("Enter a directory to search, or open a file or folder")
+		return
+	}
+
+	fp.CancelSearch()
+	fp.searcher.SetOptions(fp.collectOptions())
+
+	fp.results = make([]backend.FileSearchResult, 0)
+	fp.searchPattern = pattern
+	fp.resultTree.UnselectAll()
+	fp.resultTree.Refresh()
+	fp.replaceButton.Disable()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	fp.cancel = cancel
+	fp.generation++
+	generation := fp.generation
+
+	fp.statusLabel.SetText("Searching...")
+	fp.searchButton.Disable()
+	fp.stopButton.Enable()
+
+	go func() {
+		summary, err := fp.searcher.Search(ctx, dir, pattern, func(result backend.FileSearchResult) {
+			fyne.Do(func() {
+				if generation != fp.generation {
+					return
+				}
+				fp.addResult(result)
+			})
+		})
+
+		fyne.Do(func() {
+			if generation != fp.generation {
+				return
+			}
+			fp.finishSearch(summary, err)
+		})
+	}()
+}
+
+// CancelSearch stops the running search, if any
+func (fp *FindInFilesPanel) CancelSearch() {
+	if fp.cancel != nil {
+		fp.cancel()
+		fp.cancel = nil
+	}
+}
+
+// addResult appends a file's matches to the tree
+func (fp *FindInFilesPanel) addResult(result backend.FileSearchResult) {
+	fp.results = append(fp.results, result)
+	fp.resultTree.Refresh()
+	fp.statusLabel.SetText(fmt.Sprintf("Searching... %d files with matches", len(fp.results)))
+}
+
+// finishSearch updates the panel once a search completes
+func (fp *FindInFilesPanel) finishSearch(summary backend.ProjectSearchSummary, err error) {
+	fp.cancel = nil
+	fp.searchButton.Enable()
+	fp.stopButton.Disable()
+	if err == nil && len(fp.results) > 0 {
+		fp.replaceButton.Enable()
+	}
+
+	switch {
+	case err == context.Canceled:
+		fp.statusLabel.SetText(fmt.Sprintf("Search stopped: %d matches in %d files", summary.MatchCount, summary.FilesMatched))
+	case err != nil:
+		fp.statusLabel.SetText(err.Error())
+	case summary.MatchCount == 0:
+		fp.statusLabel.SetText(fmt.Sprintf("No matches found in %d files", summary.FilesSearched))
+	default:
+		fp.statusLabel.SetText(fmt.Sprintf("%d matches in %d of %d files", summary.MatchCount, summary.FilesMatched, summary.FilesSearched))
+	}
+}
+
+// PreviewReplace computes the replacements for the current results and
+// shows them in a preview dialog
+func (fp *FindInFilesPanel) PreviewReplace() {
+	if len(fp.results) == 0 || fp.searchPattern == "" {
+		return
+	}
+
+	// Files with unsaved changes in the editor would be overwritten on reload
+	results := make([]backend.FileSearchResult, 0, len(fp.results))
+	skipped := make([]string, 0)
+	for _, result := range fp.results {
+		if fp.editor.IsModified() && samePath(result.Path, fp.editor.GetCurrentFile()) {
+			skipped = append(skipped, result.RelPath)
+			continue
+		}
+		results = append(results, result)
+	}
+
+	pattern := fp.searchPattern
+	replacement := fp.replaceEntry.Text
+	options := fp.searcher.GetOptions().SearchOptions
+
+	replacements, err := fp.replacer.Preview(results, pattern, replacement, options)
+	if err != nil {
+		fp.showError(err)
+		return
+	}
+	if len(replacements) == 0 {
+		fp.statusLabel.SetText("Nothing to replace")
+		fp.reportSkipped(skipped)
+		return
+	}
+
+	preview := NewReplacePreviewDialog(replacements, fp.window)
+	preview.OnApply = func(reviewed []backend.FileReplacement) {
+		fp.applyReplace(reviewed, pattern, replacement)
+	}
+	preview.Show()
+	fp.reportSkipped(skipped)
+}
+
+// reportSkipped lists the files left out of a replacement for their unsaved
+// changes
+func (fp *FindInFilesPanel) reportSkipped(skipped []string) {
+	switch len(skipped) {
+	case 0:
+	case 1:
+		fp.statusLabel.SetText(fmt.Sprintf("Skipped %s: save it before replacing", skipped[0]))
+	default:
+		fp.statusLabel.SetText(fmt.Sprintf("Skipped %s: save them before replacing", strings.Join(skipped, ", ")))
+	}
+}
+
+// applyReplace writes the reviewed replacements and refreshes the results
+func (fp *FindInFilesPanel) applyReplace(replacements []backend.FileReplacement, pattern, replacement string) {
+	batch, err := fp.replacer.Apply(replacements, pattern, replacement)
+	if err != nil {
+		fp.showError(err)
+		return
+	}
+
+	edits := 0
+	for _, change := range batch.Files {
+		edits += change.EditCount
+	}
+	fp.reloadChangedFiles(batch)
+	fp.undoButton.Enable()
+
+	// The old results no longer match the file contents
+	fp.results = make([]backend.FileSearchResult, 0)
+	fp.resultTree.UnselectAll()
+	fp.resultTree.Refresh()
+	fp.replaceButton.Disable()
+	fp.statusLabel.SetText(fmt.Sprintf("Replaced %d occurrences in %d files", edits, len(batch.Files)))
+}
+
+// UndoReplace restores all files changed by the last Replace in Files
+func (fp *FindInFilesPanel) UndoReplace() {
+	batch, err := fp.replacer.UndoLastBatch()
+	if err != nil {
+		fp.showError(err)
+		return
+	}
+
+	fp.reloadChangedFiles(batch)
+	if !fp.replacer.CanUndo() {
+		fp.undoButton.Disable()
+	}
+	fp.statusLabel.SetText(fmt.Sprintf("Restored %d files", len(batch.Files)))
+}
+
+// reloadChangedFiles reloads the open file if the batch touched it
+func (fp *FindInFilesPanel) reloadChangedFiles(batch *backend.ReplaceBatch) {
+	current := fp.editor.GetCurrentFile()
+	for _, change := range batch.Files {
+		if samePath(change.Path, current) && !fp.editor.IsModified() {
+			if err := fp.editor.LoadFile(current); err != nil {
+				fp.showError(err)
+			}
+			return
+		}
+	}
+}
+
+// showError reports an error in a dialog, or the status label without a window
+func (fp *FindInFilesPanel) showError(err error) {
+	if fp.window != nil {
+		dialog.ShowError(err, fp.window)
+		return
+	}
+	fp.statusLabel.SetText(err.Error())
+}
+
+// childUIDs returns the tree children of uid
+func (fp *FindInFilesPanel) childUIDs(uid widget.TreeNodeID) []widget.TreeNodeID {
+	if uid == "" {
+		ids := make([]widget.TreeNodeID, len(fp.results))
+		for i := range fp.results {
+			ids[i] = strconv.Itoa(i)
+		}
+		return ids
+	}
+
+	fileIndex, matchIndex := parseResultUID(uid)
+	if matchIndex >= 0 || fileIndex < 0 || fileIndex >= len(fp.results) {
+		return nil
+	}
+
+	matches := fp.results[fileIndex].Matches
+	ids := make([]widget.TreeNodeID, len(matches))
+	for i := range matches {
+		ids[i] = fmt.Sprintf("%d:%d", fileIndex, i)
+	}
+	return ids
+}
+
+// isBranch reports whether uid is a file node
+func (fp *FindInFilesPanel) isBranch(uid widget.TreeNodeID) bool {
+	if uid == "" {
+		return true
+	}
+	_, matchIndex := parseResultUID(uid)
+	return matchIndex < 0
+}
+
+// updateNode renders a file or match node
+func (fp *FindInFilesPanel) updateNode(uid widget.TreeNodeID, branch bool, obj fyne.CanvasObject) {
+	label := obj.(*widget.Label)
+	fileIndex, matchIndex := parseResultUID(uid)
+	if fileIndex < 0 || fileIndex >= len(fp.results) {
+		label.SetText("")
+		return
+	}
+
+	result := fp.results[fileIndex]
+	if matchIndex < 0 {
+		label.TextStyle = fyne.TextStyle{Bold: true}
+		label.SetText(fmt.Sprintf("%s (%d)", result.RelPath, len(result.Matches)))
+		return
+	}
+	if matchIndex >= len(result.Matches) {
+		label.SetText("")
+		return
+	}
+
+	match := result.Matches[matchIndex]
+	label.TextStyle = fyne.TextStyle{}
+	label.SetText(fmt.Sprintf("%d: %s", match.Start.Line, truncatePreview(strings.TrimSpace(match.LineText))))
+}
+
+// openResult opens the file of the selected node at its match
+func (fp *FindInFilesPanel) openResult(uid widget.TreeNodeID) {
+	fileIndex, matchIndex := parseResultUID(uid)
+	if fileIndex < 0 || fileIndex >= len(fp.results) {
+		return
+	}
+
+	result := fp.results[fileIndex]
+	line := 1
+	if matchIndex >= 0 && matchIndex < len(result.Matches) {
+		line = result.Matches[matchIndex].Start.Line
+	} else if len(result.Matches) > 0 {
+		line = result.Matches[0].Start.Line
+	}
+
+	if err := fp.editor.OpenFileAtLine(result.Path, line); err != nil {
+		fp.showError(err)
+	}
+}
+
+// parseResultUID splits a tree node ID into file and match indexes.
+// The match index is -1 for file nodes; both are -1 for invalid IDs.
+func parseResultUID(uid widget.TreeNodeID) (int, int) {
+	parts := strings.SplitN(uid, ":", 2)
+	fileIndex, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return -1, -1
+	}
+	if len(parts) == 1 {
+		return fileIndex, -1
+	}
+	matchIndex, err := strconv.Atoi(parts[1])
+	if err != nil {
+		return -1, -1
+	}
+	return fileIndex, matchIndex
+}
+
+// samePath reports whether two paths refer to the same file
+func samePath(a, b string) bool {
+	if a == "" || b == "" {
+		return false
+	}
+	absA, errA := filepath.Abs(a)
+	absB, errB := filepath.Abs(b)
+	if errA != nil || errB != nil {
+		return filepath.Clean(a) == filepath.Clean(b)
+	}
+	return absA == absB
+}
@@ -0,0 +1,89 @@
+package ui
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"fyne.io/fyne/v2/test"
+	"fyne.io/fyne/v2/widget"
+	"github.com/kenelite/goeditor/backend"
+)
+
+// waitForSearch waits for the panel's search to finish
+func waitForSearch(t *testing.T, panel *FindInFilesPanel) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for panel.cancel != nil {
+		if time.Now().After(deadline) {
+			t.Fatal("Timed out waiting for the search")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestFindInFilesPanel(t *testing.T) {
+	testApp := test.NewApp()
+	defer testApp.Quit()
+	window := test.NewWindow(nil)
+
+	dir := t.TempDir()
+	files := map[string]string{
+		"a.txt": "needle\nneedle " + strings.Repeat("ü", 2*maxPreviewLength) + "\n",
+		"b.txt": "nothing here\n",
+		"c.txt": "needle\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write file: %v", err)
+		}
+	}
+
+	editor := NewEditor()
+	panel := NewFindInFilesPanel(editor, backend.NewProjectSearcher(), backend.NewProjectReplacer(editor.FileManager), window)
+	panel.SetDirectory(dir)
+	panel.SetSearchText("needle")
+	panel.StartSearch()
+	waitForSearch(t, panel)
+
+	if len(panel.GetResults()) != 2 {
+		t.Fatalf("Expected matches in 2 files, got %+v", panel.GetResults())
+	}
+	if panel.statusLabel.Text != "3 matches in 2 of 3 files" {
+		t.Errorf("Unexpected status %q", panel.statusLabel.Text)
+	}
+
+	// Long lines are shortened without splitting characters
+	fileIndex := 0
+	if panel.GetResults()[1].RelPath == "a.txt" {
+		fileIndex = 1
+	}
+	label := widget.NewLabel("")
+	panel.updateNode(fmt.Sprintf("%d:1", fileIndex), false, label)
+	if !utf8.ValidString(label.Text) || !strings.HasSuffix(label.Text, "…") {
+		t.Errorf("Expected a truncated preview, got %q", label.Text)
+	}
+	if count := utf8.RuneCountInString(label.Text); count != len("2: ")+maxPreviewLength+1 {
+		t.Errorf("Expected %d characters in the preview, got %d", maxPreviewLength, count)
+	}
+
+	// The open file with unsaved changes is left out of the replacement
+	if err := editor.LoadFile(filepath.Join(dir, "a.txt")); err != nil {
+		t.Fatalf("Failed to load file: %v", err)
+	}
+	editor.State.SetModified(true)
+	panel.replaceEntry.SetText("pin")
+	panel.PreviewReplace()
+	if panel.statusLabel.Text != "Skipped a.txt: save it before replacing" {
+		t.Errorf("Expected the open file to be skipped, got %q", panel.statusLabel.Text)
+	}
+
+	panel.reportSkipped([]string{"a.txt", "c.txt"})
+	if panel.statusLabel.Text != "Skipped a.txt, c.txt: save them before replacing" {
+		t.Errorf("Expected all skipped files to be listed, got %q", panel.statusLabel.Text)
+	}
+}
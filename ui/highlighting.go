@@ -0,0 +1,82 @@
+package ui
+
+import (
+	fyne "fyne.io/fyne/v2"
+	"github.com/kenelite/goeditor/backend"
+	"github.com/kenelite/goeditor/ui/syntax"
+)
+
+// requestHighlighting submits the current content for highlighting in the
+// background. Each change gets a new version so stale jobs are dropped.
+func (e *Editor) requestHighlighting() {
+	if e.Highlighter == nil {
+		return
+	}
+	e.highlightVersion++
+	// Draws the edited text at once and colors the visible lines first
+	e.updateViewport(e.ScrollContainer.Offset, e.ScrollContainer.Size())
+	e.Highlighter.Submit(e.highlightVersion, e.highlightLanguage(), e.GetContent())
+}
+
+// highlightLanguage returns the language the current document is
+// highlighted as
+func (e *Editor) highlightLanguage() string {
+	language := e.GetFileType().LexerName
+	if language == "" {
+		return "text"
+	}
+	return language
+}
+
+// applyHighlighting takes a highlighting result on the UI goroutine and
+// repaints the lines it changed
+func (e *Editor) applyHighlighting(result syntax.HighlightResult) {
+	e.highlighting = result.Highlighting
+	e.SyntaxHighlights.SetHighlighting(result.Highlighting)
+	if e.OnHighlightChanged != nil {
+		e.OnHighlightChanged(result.Changed)
+	}
+}
+
+// repaintHighlighting repaints every line, as after a change of theme
+// colors
+func (e *Editor) repaintHighlighting() {
+	e.SyntaxHighlights.Repaint()
+	if e.highlighting == nil || e.OnHighlightChanged == nil {
+		return
+	}
+	e.OnHighlightChanged([]syntax.LineRange{{Start: 0, End: e.highlighting.LineCount()}})
+}
+
+// GetHighlighting returns the latest highlighting of the content, which may
+// lag behind the content while the highlighter catches up
+func (e *Editor) GetHighlighting() *syntax.Highlighting {
+	return e.highlighting
+}
+
+// updateViewport tells the layers over the text and the highlighter which
+// part of the text is visible
+func (e *Editor) updateViewport(offset fyne.Position, size fyne.Size) {
+	e.SyntaxHighlights.SetViewport(offset, size)
+	e.SearchHighlights.SetViewport(offset, size)
+	e.updateHighlightViewport(offset, size)
+}
+
+// updateHighlightViewport tells the highlighter which lines are visible so
+// they are colored first
+func (e *Editor) updateHighlightViewport(offset fyne.Position, size fyne.Size) {
+	if e.Highlighter == nil {
+		return
+	}
+	first := backend.Position{Line: 1, Column: 1}
+	origin, line := e.SearchHighlights.MatchBounds(backend.Match{Start: first, End: first})
+	if line.Height <= 0 {
+		return
+	}
+	start := int((offset.Y - origin.Y) / line.Height)
+	if start < 0 {
+		start = 0
+	}
+	end := start + int(size.Height/line.Height) + 2
+	e.Highlighter.SetViewport(syntax.LineRange{Start: start, End: end})
+}
@@ -0,0 +1,213 @@
+package ui
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	fyne "fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/dialog"
+	"github.com/kenelite/goeditor/backend"
+	"github.com/kenelite/goeditor/ui/syntax"
+)
+
+// analysisLimit is how much of a document is scored by the lexers'
+// analysers when nothing else identifies its language
+const analysisLimit = 64 * 1024
+
+// languageDetectDelay is how long the first line must stay unchanged before
+// the language is detected again
+const languageDetectDelay = 300 * time.Millisecond
+
+// plainTextFileType is the file type of documents whose language is unknown
+var plainTextFileType = backend.FileType{Name: "Plain Text", LexerName: "text", MimeType: "text/plain"}
+
+// detectFileType detects the language of the document from its modeline,
+// path and shebang line, falling back to analysing its content, and
+// remembers it as the document's file type
+func (e *Editor) detectFileType(path, content string) backend.FileType {
+	fileType, ok := e.FileManager.DetectFileType(path, content)
+	if !ok {
+		fileType = plainTextFileType
+		sample := content
+		if len(sample) > analysisLimit {
+			sample = sample[:analysisLimit]
+		}
+		if language := syntax.GetLanguageManager().AnalyseLanguage(sample); language != "" {
+			fileType = e.fileTypeForLanguage(language)
+		}
+	}
+
+	e.setDetectedFileType(fileType, backend.ModelineRegion(content))
+	return fileType
+}
+
+// setDetectedFileType remembers the detected language of the document and
+// discards any detection still pending
+func (e *Editor) setDetectedFileType(fileType backend.FileType, region string) {
+	e.detectVersion++
+	if e.detectTimer != nil {
+		e.detectTimer.Stop()
+		e.detectTimer = nil
+	}
+
+	changed := fileType.Name != e.detectedFileType.Name
+	e.detectedFileType = fileType
+	e.detectedRegion = region
+	if changed && e.languageOverride == nil {
+		e.applyLanguageIndentation()
+	}
+}
+
+// redetectFileType detects the language again after an edit of the lines
+// at the start or end of the document, which hold shebangs and modelines.
+// Detection waits until those lines stop changing and runs in the
+// background. Only a modeline, the file name or a shebang change the
+// language, the content is not analysed again while typing, and a language
+// chosen by the user is kept.
+func (e *Editor) redetectFileType(content string) {
+	region := backend.ModelineRegion(content)
+	if region == e.detectedRegion || e.languageOverride != nil {
+		return
+	}
+	e.detectedRegion = region
+
+	e.detectVersion++
+	version := e.detectVersion
+	path := e.State.CurrentFile
+	if e.detectTimer != nil {
+		e.detectTimer.Stop()
+	}
+	e.detectTimer = time.AfterFunc(languageDetectDelay, func() {
+		fileType, ok := e.FileManager.DetectFileType(path, content)
+		if !ok {
+			return
+		}
+		fyne.Do(func() {
+			if version != e.detectVersion || e.languageOverride != nil {
+				return
+			}
+			e.setDetectedFileType(fileType, region)
+			e.State.Language = fileType.Name
+			e.requestHighlighting()
+			if e.StatusBar != nil {
+				e.StatusBar.Refresh()
+			}
+		})
+	})
+}
+
+// fileTypeForLanguage returns the file type of a lexer name, describing
+// languages without a registered file type by their lexer
+func (e *Editor) fileTypeForLanguage(language string) backend.FileType {
+	if fileType, exists := e.FileManager.GetFileTypeByName(language); exists {
+		return fileType
+	}
+	fileType := backend.FileType{Name: language, LexerName: language}
+	if info := syntax.GetLanguageManager().GetLanguageInfo(language); info != nil {
+		fileType.Name = info.Name
+		if len(info.MimeTypes) > 0 {
+			fileType.MimeType = info.MimeTypes[0]
+		}
+	}
+	return fileType
+}
+
+// SetLanguageMode overrides the detected language of the document with a
+// file type's lexer, or detects it again when language is empty. The
+// override lasts until another document is loaded.
+func (e *Editor) SetLanguageMode(language string) error {
+	if language == "" {
+		e.languageOverride = nil
+		e.State.Language = e.detectedFileType.Name
+	} else {
+		if language != "text" && !syntax.GetLanguageManager().IsLanguageSupported(language) {
+			return fmt.Errorf("unknown language '%s'", language)
+		}
+		fileType := e.fileTypeForLanguage(language)
+		e.languageOverride = &fileType
+		e.State.Language = fileType.Name
+	}
+
+	e.applyLanguageIndentation()
+	e.requestHighlighting()
+	if e.StatusBar != nil {
+		e.StatusBar.Refresh()
+	}
+	return nil
+}
+
+// IsLanguageOverridden reports whether the language was chosen rather than
+// detected
+func (e *Editor) IsLanguageOverridden() bool {
+	return e.languageOverride != nil
+}
+
+// GetDetectedFileType returns the file type detected for the document,
+// whether or not it is overridden
+func (e *Editor) GetDetectedFileType() backend.FileType {
+	return e.detectedFileType
+}
+
+// LanguageModes returns the file types a document can be switched to,
+// sorted by name
+func (e *Editor) LanguageModes() []backend.FileType {
+	modes := e.FileManager.GetAllFileTypes()
+	sort.Slice(modes, func(i, j int) bool {
+		return strings.ToLower(modes[i].Name) < strings.ToLower(modes[j].Name)
+	})
+	return modes
+}
+
+// applyLanguageIndentation indents with the defaults of the document's
+// language, or the editor settings where the language has none
+func (e *Editor) applyLanguageIndentation() {
+	config := e.ConfigManager.GetEditorConfig()
+	fileType := e.GetFileType()
+
+	tabSize := config.TabSize
+	if fileType.TabSize > 0 {
+		tabSize = fileType.TabSize
+	}
+	useSpaces := config.InsertSpaces
+	switch fileType.Indentation {
+	case "tabs":
+		useSpaces = false
+	case "spaces":
+		useSpaces = true
+	}
+	e.IndentationManager.SetTabSize(tabSize)
+	e.IndentationManager.SetUseSpaces(useSpaces)
+}
+
+// LoadLanguageDefinitions loads the user language definitions in the
+// languages folder of the configuration directory into the language
+// registry, compiles the user lexers in its lexers folder, sets up the
+// embedded language heuristics of the syntax settings and detects the
+// document's language again. Invalid files and settings are reported in
+// window.
+func (e *Editor) LoadLanguageDefinitions(window fyne.Window) {
+	dir := e.ConfigManager.GetLanguagesDir()
+	if err := backend.GetLanguageRegistry().LoadLanguageDefinitions(dir); err != nil {
+		dialog.ShowError(fmt.Errorf("failed to load language definitions from %s:\n%w", dir, err), window)
+	}
+	languages := syntax.GetLanguageManager()
+	lexersDir := e.ConfigManager.GetLexersDir()
+	if err := languages.LoadUserLexers(lexersDir); err != nil {
+		dialog.ShowError(fmt.Errorf("failed to load lexers from %s:\n%w", lexersDir, err), window)
+	}
+	// Embedded languages may be highlighted by user lexers
+	if err := languages.SetEmbeddedLanguages(e.ConfigManager.GetSyntaxConfig().Embedded); err != nil {
+		dialog.ShowError(fmt.Errorf("failed to set up embedded languages:\n%w", err), window)
+	}
+
+	fileType := e.detectFileType(e.State.CurrentFile, e.GetContent())
+	if e.languageOverride == nil {
+		e.State.Language = fileType.Name
+	}
+	e.requestHighlighting()
+	if e.StatusBar != nil {
+		e.StatusBar.Refresh()
+	}
+}
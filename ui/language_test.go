@@ -0,0 +1,141 @@
+package ui
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"fyne.io/fyne/v2/test"
+	"github.com/kenelite/goeditor/backend"
+)
+
+func TestLanguageDetectionAndOverride(t *testing.T) {
+	testApp := test.NewApp()
+	defer testApp.Quit()
+
+	editor := NewEditor()
+	path := filepath.Join(t.TempDir(), "deploy")
+	if err := os.WriteFile(path, []byte("#!/usr/bin/env python3\nprint('hi')\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := editor.LoadFile(path); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if fileType := editor.GetFileType(); fileType.LexerName != "python" {
+		t.Errorf("Expected the shebang to be detected, got %s", fileType.LexerName)
+	}
+	if editor.StatusBar.languageLabel.Text != "Python" {
+		t.Errorf("Expected the status bar to show Python, got %s", editor.StatusBar.languageLabel.Text)
+	}
+
+	if err := editor.SetLanguageMode("bash"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !editor.IsLanguageOverridden() || editor.GetFileType().LexerName != "bash" || editor.highlightLanguage() != "bash" {
+		t.Errorf("Expected the chosen language to be used, got %s", editor.GetFileType().LexerName)
+	}
+	if editor.StatusBar.languageLabel.Text != "Shell" {
+		t.Errorf("Expected the status bar to show the chosen language, got %s", editor.StatusBar.languageLabel.Text)
+	}
+	if err := editor.SetLanguageMode("klingon"); err == nil {
+		t.Error("Expected an error for an unknown language")
+	}
+
+	if err := editor.SetLanguageMode(""); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if editor.IsLanguageOverridden() || editor.GetFileType().LexerName != "python" {
+		t.Errorf("Expected detection to be restored, got %s", editor.GetFileType().LexerName)
+	}
+
+	// Typing a shebang into a new document changes its language once the
+	// line stops changing
+	editor.NewFile()
+	editor.SetContent("#!/bin/bash\necho hi\n")
+	if fileType := editor.GetFileType(); fileType.LexerName == "bash" {
+		t.Error("Expected the language to be detected after a delay")
+	}
+	if fileType := waitForFileType(editor, "bash"); fileType.LexerName != "bash" {
+		t.Errorf("Expected the typed shebang to be detected, got %s", fileType.LexerName)
+	}
+
+	// Changing the shebang changes the language again
+	editor.SetContent("#!/usr/bin/env python3\nprint('hi')\n")
+	if fileType := waitForFileType(editor, "python"); fileType.LexerName != "python" {
+		t.Errorf("Expected the changed shebang to be detected, got %s", fileType.LexerName)
+	}
+
+	// A modeline added to a file names its language over the extension
+	textFile := filepath.Join(t.TempDir(), "notes.txt")
+	if err := os.WriteFile(textFile, []byte("package main\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := editor.LoadFile(textFile); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	editor.SetContent("// vim: ft=go\npackage main\n")
+	if fileType := waitForFileType(editor, "go"); fileType.LexerName != "go" {
+		t.Errorf("Expected the modeline to be detected, got %s", fileType.LexerName)
+	}
+
+	// So does one at the end of the file, when only that line changes
+	body := strings.Repeat("package main\n", 10)
+	editor.SetContent(body + "# vim: ft=python\n")
+	if fileType := waitForFileType(editor, "python"); fileType.LexerName != "python" {
+		t.Errorf("Expected the trailing modeline to be detected, got %s", fileType.LexerName)
+	}
+	editor.SetContent(body + "// vim: ft=go\n")
+	if fileType := waitForFileType(editor, "go"); fileType.LexerName != "go" {
+		t.Errorf("Expected the changed trailing modeline to be detected, got %s", fileType.LexerName)
+	}
+
+	// A language chosen by the user is kept
+	if err := editor.SetLanguageMode("bash"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	editor.SetContent("#!/usr/bin/env python3\nprint('hi')\n")
+	if fileType := waitForFileType(editor, "python"); fileType.LexerName != "bash" {
+		t.Errorf("Expected the chosen language to be kept, got %s", fileType.LexerName)
+	}
+}
+
+// waitForFileType waits for the background language detection to settle
+// on a lexer, giving up after a few detection delays
+func waitForFileType(editor *Editor, lexer string) backend.FileType {
+	deadline := time.Now().Add(5 * languageDetectDelay)
+	for editor.GetFileType().LexerName != lexer && time.Now().Before(deadline) {
+		time.Sleep(languageDetectDelay / 10)
+	}
+	return editor.GetFileType()
+}
+
+func TestLanguageIndentationDefaults(t *testing.T) {
+	testApp := test.NewApp()
+	defer testApp.Quit()
+
+	editor := NewEditor()
+	dir := t.TempDir()
+	goFile := filepath.Join(dir, "main.go")
+	yamlFile := filepath.Join(dir, "config.yml")
+	os.WriteFile(goFile, []byte("package main\n"), 0644)
+	os.WriteFile(yamlFile, []byte("key: value\n"), 0644)
+
+	if err := editor.LoadFile(goFile); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if editor.IndentationManager.GetUseSpaces() {
+		t.Error("Expected Go files to be indented with tabs")
+	}
+	if fileType := editor.GetFileType(); fileType.LineComment != "//" || len(fileType.Brackets) == 0 {
+		t.Errorf("Expected the comment tokens and brackets of Go, got %+v", fileType)
+	}
+
+	if err := editor.LoadFile(yamlFile); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !editor.IndentationManager.GetUseSpaces() || editor.IndentationManager.GetTabSize() != 2 {
+		t.Errorf("Expected YAML files to be indented with two spaces, got %d", editor.IndentationManager.GetTabSize())
+	}
+}
@@ -6,7 +6,6 @@ import (
 	
 	"fyne.io/fyne/v2"
 	"fyne.io/fyne/v2/canvas"
-	"fyne.io/fyne/v2/theme"
 	"fyne.io/fyne/v2/widget"
 )
 
@@ -15,6 +14,7 @@ type LineNumberWidget struct {
 	widget.BaseWidget
 	editor     *Editor
 	lineCount  int
+	numbers    []int // Line numbers to show instead of 1..lineCount, e.g. for a filtered view
 	lineHeight float32
 	fontHeight float32
 	padding    float32
@@ -58,6 +58,36 @@ func (ln *LineNumberWidget) UpdateLineCount(count int) {
 	}
 }
 
+// SetLineNumbers shows numbers instead of consecutive line numbers, one per
+// displayed line, so a filtered view keeps the original numbering. Passing
+// nil returns to numbering lines from 1.
+func (ln *LineNumberWidget) SetLineNumbers(numbers []int) {
+	ln.numbers = numbers
+	if numbers != nil {
+		ln.lineCount = len(numbers)
+		if ln.lineCount < 1 {
+			ln.lineCount = 1
+		}
+	}
+	ln.Refresh()
+}
+
+// GetLineNumbers returns the numbers set by SetLineNumbers, or nil
+func (ln *LineNumberWidget) GetLineNumbers() []int {
+	return ln.numbers
+}
+
+// lineNumberAt returns the number shown for the displayed line at index
+func (ln *LineNumberWidget) lineNumberAt(index int) int {
+	if ln.numbers == nil {
+		return index + 1
+	}
+	if index < 0 || index >= len(ln.numbers) {
+		return 0
+	}
+	return ln.numbers[index]
+}
+
 // SetLineHeight sets the height of each line
 func (ln *LineNumberWidget) SetLineHeight(height float32) {
 	if height > 0 && ln.lineHeight != height {
@@ -87,9 +117,14 @@ func (ln *LineNumberWidget) Tapped(ev *fyne.PointEvent) {
 	
 	// Calculate which line was clicked
 	lineIndex := int(ev.Position.Y / ln.lineHeight)
+	if ln.numbers != nil && ln.editor.IsLineFilterActive() {
+		ln.editor.goToFilteredLine(lineIndex)
+		return
+	}
 	if lineIndex >= 0 && lineIndex < ln.lineCount {
-		lineNumber := lineIndex + 1
-		ln.selectLine(lineNumber)
+		if lineNumber := ln.lineNumberAt(lineIndex); lineNumber > 0 {
+			ln.selectLine(lineNumber)
+		}
 	}
 }
 
@@ -131,7 +166,11 @@ func (ln *LineNumberWidget) selectLine(lineNumber int) {
 // GetPreferredWidth calculates the preferred width for the line number widget
 func (ln *LineNumberWidget) GetPreferredWidth() float32 {
 	// Calculate width based on the number of digits needed for the highest line number
-	digits := len(fmt.Sprintf("%d", ln.lineCount))
+	highest := ln.lineCount
+	if len(ln.numbers) > 0 {
+		highest = ln.numbers[len(ln.numbers)-1]
+	}
+	digits := len(fmt.Sprintf("%d", highest))
 	charWidth := ln.fontHeight * 0.6 // Approximate character width
 	return float32(digits)*charWidth + ln.padding*2
 }
@@ -193,15 +232,18 @@ func (r *lineNumberRenderer) updateTexts() {
 	
 	// Create or update text objects
 	for i := 0; i < r.widget.lineCount; i++ {
-		lineNumber := i + 1
+		label := ""
+		if lineNumber := r.widget.lineNumberAt(i); lineNumber > 0 {
+			label = fmt.Sprintf("%d", lineNumber)
+		}
 		
 		if r.texts[i] == nil {
-			r.texts[i] = canvas.NewText(fmt.Sprintf("%d", lineNumber), theme.ForegroundColor())
+			r.texts[i] = canvas.NewText(label, lineNumberColor())
 			r.texts[i].TextSize = r.widget.fontHeight
 			r.texts[i].Alignment = fyne.TextAlignTrailing // Right-align numbers
 		} else {
-			r.texts[i].Text = fmt.Sprintf("%d", lineNumber)
-			r.texts[i].Color = theme.ForegroundColor()
+			r.texts[i].Text = label
+			r.texts[i].Color = lineNumberColor()
 			r.texts[i].TextSize = r.widget.fontHeight
 		}
 	}
@@ -233,6 +275,11 @@ func (ln *LineNumberWidget) UpdateFromEditor() {
 		return
 	}
 	
+	// A filtered view sets its own numbers
+	if ln.numbers != nil {
+		return
+	}
+	
 	content := ln.editor.GetContent()
 	lines := strings.Split(content, "\n")
 	ln.UpdateLineCount(len(lines))
@@ -25,6 +25,20 @@ func NewMenu(win fyne.Window, editor *Editor) *fyne.MainMenu {
 	})
 	// Shortcuts are handled by the setupShortcuts function
 
+	openFolderItem := fyne.NewMenuItem("Open Folder...", func() {
+		dialog.ShowFolderOpen(func(uri fyne.ListableURI, err error) {
+			if err != nil || uri == nil {
+				return
+			}
+			editor.SetWorkspaceRoot(uri.Path())
+		}, win)
+	})
+
+	quickOpenItem := fyne.NewMenuItem("Quick Open...", func() {
+		editor.ShowQuickOpenDialog()
+	})
+	// Shortcuts are handled by the setupShortcuts function
+
 	saveItem := fyne.NewMenuItem("Save", func() {
 		if editor.GetCurrentFile() != "" {
 			if err := editor.SaveFile(editor.GetCurrentFile()); err != nil {
@@ -81,6 +95,31 @@ func NewMenu(win fyne.Window, editor *Editor) *fyne.MainMenu {
 	})
 	// Shortcuts are handled by the setupShortcuts function
 
+	findInFilesItem := fyne.NewMenuItem("Find in Files...", func() {
+		editor.ShowFindInFilesPanel()
+	})
+	// Shortcuts are handled by the setupShortcuts function
+
+	occurrencesItem := fyne.NewMenuItem("Show Occurrences", func() {
+		editor.ShowOccurrencesPanel()
+	})
+	// Shortcuts are handled by the setupShortcuts function
+
+	filterLinesItem := fyne.NewMenuItem("Filter Lines...", func() {
+		editor.ShowFilterLinesPanel()
+	})
+	// Shortcuts are handled by the setupShortcuts function
+
+	openFilteredItem := fyne.NewMenuItem("Open Filtered Lines as New Document", func() {
+		if err := editor.MaterializeLineFilter(); err != nil {
+			dialog.ShowError(err, win)
+		}
+	})
+
+	structuralRewriteItem := fyne.NewMenuItem("Structural Rewrite (Go)...", func() {
+		showStructuralRewriteDialog(win, editor)
+	})
+
 	findNextItem := fyne.NewMenuItem("Find Next", func() {
 		editor.FindNext()
 	})
@@ -108,15 +147,30 @@ func NewMenu(win fyne.Window, editor *Editor) *fyne.MainMenu {
 	})
 	// Shortcuts are handled by the setupShortcuts function
 
+	// Preferences menu items
+	importThemeItem := fyne.NewMenuItem("Import Color Theme...", func() {
+		showImportThemeDialog(win, editor)
+	})
+
+	themeEditorItem := fyne.NewMenuItem("Theme Editor...", func() {
+		showThemeEditor(editor)
+	})
+
+	inspectTokenItem := fyne.NewMenuItem("Inspect Token at Cursor", func() {
+		showTokenInspector(win, editor)
+	})
+	// Shortcuts are handled by the setupShortcuts function
+
 	// Enable/disable menu items based on state
 	saveItem.Disabled = !editor.IsModified()
 	undoItem.Disabled = !editor.CanUndo()
 	redoItem.Disabled = !editor.CanRedo()
 
 	// Create menus - simplified to avoid crashes
-	fileMenu := fyne.NewMenu("File", newItem, openItem, saveItem, saveAsItem, quitItem)
-	editMenu := fyne.NewMenu("Edit", undoItem, redoItem, findItem, replaceItem, findNextItem, findPrevItem, goToLineItem)
+	fileMenu := fyne.NewMenu("File", newItem, openItem, openFolderItem, quickOpenItem, saveItem, saveAsItem, quitItem)
+	editMenu := fyne.NewMenu("Edit", undoItem, redoItem, findItem, replaceItem, findInFilesItem, occurrencesItem, filterLinesItem, openFilteredItem, structuralRewriteItem, findNextItem, findPrevItem, goToLineItem)
 	formatMenu := fyne.NewMenu("Format", indentItem, unindentItem)
+	preferencesMenu := fyne.NewMenu("Preferences", themeEditorItem, importThemeItem, inspectTokenItem)
 	
-	return fyne.NewMainMenu(fileMenu, editMenu, formatMenu)
+	return fyne.NewMainMenu(fileMenu, editMenu, formatMenu, preferencesMenu)
 }
@@ -0,0 +1,302 @@
+package ui
+
+import (
+	"fmt"
+	"strconv"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/canvas"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/widget"
+	"github.com/kenelite/goeditor/backend"
+)
+
+// occurrencesPanelHeight is the minimum height of the occurrences panel
+const occurrencesPanelHeight = 220
+
+// occurrenceContextOptions are the context sizes offered in the panel
+var occurrenceContextOptions = []string{"No context", "1 line", "2 lines", "3 lines"}
+
+// OccurrencesPanel lists every match of the current search in the buffer,
+// one line per row with optional context lines. Selecting a row moves the
+// cursor to its first match; editing a row and pressing Enter writes the
+// line back to the buffer.
+type OccurrencesPanel struct {
+	container *fyne.Container
+
+	titleLabel    *widget.Label
+	contextSelect *widget.Select
+	closeButton   *widget.Button
+	statusLabel   *widget.Label
+	list          *widget.List
+
+	// References
+	editor *Editor
+	window fyne.Window
+
+	// State
+	rows      []backend.OccurrenceLine
+	pending   map[int]pendingOccurrenceEdit
+	context   int
+	isVisible bool
+}
+
+// pendingOccurrenceEdit is the text typed into the row of a line that was
+// not written back yet, kept while the list recycles the row
+type pendingOccurrenceEdit struct {
+	original string
+	text     string
+}
+
+// occurrenceRow is a list row: a line number button and the editable line
+type occurrenceRow struct {
+	*fyne.Container
+	lineButton *widget.Button
+	entry      *widget.Entry
+	index      int
+}
+
+// NewOccurrencesPanel creates a new occurrences panel
+func NewOccurrencesPanel(editor *Editor, window fyne.Window) *OccurrencesPanel {
+	op := &OccurrencesPanel{
+		editor:  editor,
+		window:  window,
+		rows:    make([]backend.OccurrenceLine, 0),
+		pending: make(map[int]pendingOccurrenceEdit),
+	}
+
+	op.createLayout()
+	return op
+}
+
+// createLayout creates the panel UI
+func (op *OccurrencesPanel) createLayout() {
+	op.titleLabel = widget.NewLabel("Occurrences")
+	op.titleLabel.TextStyle = fyne.TextStyle{Bold: true}
+	op.titleLabel.Truncation = fyne.TextTruncateEllipsis
+
+	op.contextSelect = widget.NewSelect(occurrenceContextOptions, func(selected string) {
+		for i, option := range occurrenceContextOptions {
+			if option == selected {
+				op.context = i
+			}
+		}
+		op.Update()
+	})
+
+	op.closeButton = widget.NewButton("Close", func() {
+		op.Hide()
+	})
+
+	op.statusLabel = widget.NewLabel("")
+
+	op.list = widget.NewList(
+		func() int {
+			return len(op.rows)
+		},
+		op.createRow,
+		op.updateRow,
+	)
+	op.list.OnSelected = func(id widget.ListItemID) {
+		op.activate(id)
+		op.list.UnselectAll()
+	}
+
+	// Selecting lists the matches, so the labels and list must exist
+	op.contextSelect.SetSelected(occurrenceContextOptions[0])
+
+	header := container.NewBorder(nil, nil, nil, container.NewHBox(op.contextSelect, op.closeButton), op.titleLabel)
+
+	// Keep the panel tall enough to show a useful number of lines
+	spacer := canvas.NewRectangle(nil)
+	spacer.SetMinSize(fyne.NewSize(0, occurrencesPanelHeight))
+
+	op.container = container.NewStack(
+		spacer,
+		container.NewBorder(header, op.statusLabel, nil, nil, op.list),
+	)
+}
+
+// createRow creates the template object for a list row
+func (op *OccurrencesPanel) createRow() fyne.CanvasObject {
+	row := &occurrenceRow{index: -1}
+	row.lineButton = widget.NewButton("0000", func() {
+		op.activate(row.index)
+	})
+	row.lineButton.Importance = widget.LowImportance
+	row.entry = widget.NewEntry()
+	row.entry.TextStyle = fyne.TextStyle{Monospace: true}
+	row.entry.OnChanged = func(text string) {
+		op.keepEdit(row.index, text)
+	}
+	row.entry.OnSubmitted = func(text string) {
+		op.writeBack(row.index, text)
+	}
+	row.Container = container.NewBorder(nil, nil, row.lineButton, nil, row.entry)
+	return row
+}
+
+// updateRow shows the row at id in a list row object
+func (op *OccurrencesPanel) updateRow(id widget.ListItemID, obj fyne.CanvasObject) {
+	row, ok := obj.(*occurrenceRow)
+	if !ok || id >= len(op.rows) {
+		return
+	}
+	row.index = id
+	line := op.rows[id]
+
+	if line.IsSeparator() {
+		row.lineButton.SetText("--")
+		row.lineButton.Disable()
+		row.entry.Hide()
+		return
+	}
+
+	// grep style: "12:" for lines with matches, "11-" for context
+	marker := "-"
+	if line.IsMatch() {
+		marker = ":"
+	}
+	row.lineButton.SetText(strconv.Itoa(line.Line) + marker)
+	row.lineButton.Enable()
+	row.entry.Show()
+	text := line.Text
+	if edit, ok := op.pending[line.Line]; ok && edit.original == line.Text {
+		text = edit.text
+	}
+	row.entry.SetText(text)
+	if line.IsMatch() {
+		row.entry.Enable()
+	} else {
+		row.entry.Disable()
+	}
+}
+
+// GetContainer returns the panel container for embedding in the editor layout
+func (op *OccurrencesPanel) GetContainer() fyne.CanvasObject {
+	return op.container
+}
+
+// Show displays the panel with the matches of the current search
+func (op *OccurrencesPanel) Show() {
+	op.isVisible = true
+	op.editor.ShowBottomPanel(op.container)
+	op.Update()
+}
+
+// Hide hides the panel
+func (op *OccurrencesPanel) Hide() {
+	op.isVisible = false
+	op.editor.HideBottomPanel(op.container)
+}
+
+// IsVisible returns whether the panel is currently visible. Another panel
+// may have taken its place at the bottom of the editor.
+func (op *OccurrencesPanel) IsVisible() bool {
+	return op.isVisible && op.editor.IsBottomPanelShowing(op.container)
+}
+
+// GetRows returns the rows currently listed
+func (op *OccurrencesPanel) GetRows() []backend.OccurrenceLine {
+	return op.rows
+}
+
+// Update lists the current matches of the editor's search manager
+func (op *OccurrencesPanel) Update() {
+	sm := op.editor.SearchManager
+	op.SetMatches(sm.GetPattern(), op.editor.GetContent(), sm.GetMatches())
+}
+
+// SetMatches lists matches of pattern in content
+func (op *OccurrencesPanel) SetMatches(pattern, content string, matches []backend.Match) {
+	op.rows = backend.CollectOccurrences(content, matches, op.context)
+
+	// Drop typed text of lines that are no longer listed as they were
+	listed := make(map[int]string, len(op.rows))
+	for _, row := range op.rows {
+		if row.IsMatch() {
+			listed[row.Line] = row.Text
+		}
+	}
+	for line, edit := range op.pending {
+		if text, ok := listed[line]; !ok || text != edit.original {
+			delete(op.pending, line)
+		}
+	}
+
+	lines := 0
+	for _, row := range op.rows {
+		if row.IsMatch() {
+			lines++
+		}
+	}
+
+	switch {
+	case pattern == "":
+		op.titleLabel.SetText("Occurrences")
+		op.statusLabel.SetText("Search with Find (Ctrl+F) to list its matches here")
+	case len(matches) == 0:
+		op.titleLabel.SetText(fmt.Sprintf("Occurrences of %q", pattern))
+		op.statusLabel.SetText("No matches found")
+	default:
+		op.titleLabel.SetText(fmt.Sprintf("Occurrences of %q", pattern))
+		status := fmt.Sprintf("%d matches on %d lines. Edit a line and press Enter to write it back.", len(matches), lines)
+		if op.editor.SearchManager.IsTruncated() {
+			status = fmt.Sprintf("First %d matches on %d lines. Edit a line and press Enter to write it back.", len(matches), lines)
+		}
+		op.statusLabel.SetText(status)
+	}
+	op.list.Refresh()
+}
+
+// activate moves the cursor to the first match of the row at index, or to
+// the start of a context line
+func (op *OccurrencesPanel) activate(index int) {
+	if index < 0 || index >= len(op.rows) || op.rows[index].IsSeparator() {
+		return
+	}
+	row := op.rows[index]
+	if row.IsMatch() {
+		op.editor.GoToMatch(row.Matches[0])
+	} else {
+		op.editor.GoToMatch(backend.Match{Start: backend.Position{Line: row.Line, Column: 1}})
+	}
+}
+
+// keepEdit remembers text typed into the row at index until it is written
+// back, so scrolling the row out of view does not lose it
+func (op *OccurrencesPanel) keepEdit(index int, text string) {
+	if index < 0 || index >= len(op.rows) || !op.rows[index].IsMatch() {
+		return
+	}
+	row := op.rows[index]
+	if text == row.Text {
+		delete(op.pending, row.Line)
+		return
+	}
+	op.pending[row.Line] = pendingOccurrenceEdit{original: row.Text, text: text}
+}
+
+// writeBack replaces the line of the row at index in the buffer with text
+func (op *OccurrencesPanel) writeBack(index int, text string) {
+	if index < 0 || index >= len(op.rows) || !op.rows[index].IsMatch() {
+		return
+	}
+	row := op.rows[index]
+	if text == row.Text {
+		return
+	}
+
+	content, err := backend.ReplaceLine(op.editor.GetContent(), row.Line, row.Text, text)
+	if err != nil {
+		op.Update()
+		op.statusLabel.SetText(fmt.Sprintf("Could not write line %d back: %v", row.Line, err))
+		return
+	}
+
+	// The edit is one undoable step; it refreshes the matches and this
+	// panel once the search runs again
+	delete(op.pending, row.Line)
+	op.editor.ApplyEdit(content)
+	op.statusLabel.SetText(fmt.Sprintf("Line %d updated", row.Line))
+}
@@ -0,0 +1,93 @@
+package ui
+
+import (
+	"testing"
+	"time"
+
+	"fyne.io/fyne/v2/test"
+)
+
+func TestOccurrencesPanel(t *testing.T) {
+	testApp := test.NewApp()
+	defer testApp.Quit()
+
+	window := testApp.NewWindow("Test")
+	editor := NewEditor()
+	editor.InitializeDialogs(window)
+	editor.SetContent("alpha\nbeta TODO\ngamma\nTODO: delta TODO\n")
+	editor.Find("TODO", editor.SearchManager.GetOptions())
+
+	editor.ShowOccurrencesPanel()
+	panel := editor.OccurrencesPanel
+	if !panel.IsVisible() {
+		t.Fatal("Expected the occurrences panel to be visible")
+	}
+	rows := panel.GetRows()
+	if len(rows) != 3 || rows[0].Line != 2 || !rows[1].IsSeparator() || rows[2].Line != 4 || len(rows[2].Matches) != 2 {
+		t.Fatalf("Unexpected rows %+v", rows)
+	}
+
+	// The list follows edits to the buffer once typing pauses
+	editor.SetContent("TODO\nalpha\nbeta TODO\n")
+	waitForSearchRefresh(t, editor)
+	rows = panel.GetRows()
+	if len(rows) != 3 || rows[0].Line != 1 || rows[2].Line != 3 {
+		t.Fatalf("Expected the list to update, got %+v", rows)
+	}
+
+	// Activating a row moves the cursor to its match
+	panel.activate(2)
+	if editor.TextWidget.CursorRow != 2 || editor.TextWidget.CursorColumn != 5 {
+		t.Errorf("Expected cursor at row 2 column 5, got %d:%d", editor.TextWidget.CursorRow, editor.TextWidget.CursorColumn)
+	}
+	if editor.SearchManager.GetCurrentIndex() != 1 {
+		t.Errorf("Expected the second match to be current, got %d", editor.SearchManager.GetCurrentIndex())
+	}
+
+	// Edited lines are written back
+	panel.writeBack(2, "beta DONE")
+	waitForSearchRefresh(t, editor)
+	if content := editor.GetContent(); content != "TODO\nalpha\nbeta DONE\n" {
+		t.Errorf("Unexpected content %q", content)
+	}
+	if rows = panel.GetRows(); len(rows) != 1 {
+		t.Errorf("Expected one remaining occurrence, got %+v", rows)
+	}
+
+	// Writing back is one undoable step
+	if !editor.Undo() || editor.GetContent() != "TODO\nalpha\nbeta TODO\n" {
+		t.Errorf("Expected the write back to be undone, got %q", editor.GetContent())
+	}
+	waitForSearchRefresh(t, editor)
+
+	// Text typed into a row survives the row being recycled for another
+	row := panel.createRow().(*occurrenceRow)
+	panel.updateRow(2, row)
+	row.entry.SetText("beta half typed")
+	panel.updateRow(0, row)
+	if row.entry.Text != "TODO" {
+		t.Errorf("Expected the recycled row to show its own line, got %q", row.entry.Text)
+	}
+	panel.updateRow(2, row)
+	if row.entry.Text != "beta half typed" {
+		t.Errorf("Expected the typed text to be kept, got %q", row.entry.Text)
+	}
+
+	editor.HideOccurrencesPanel()
+	if panel.IsVisible() {
+		t.Error("Expected the panel to be hidden")
+	}
+}
+
+// waitForSearchRefresh waits for the search that refreshes the matches
+// after an edit
+func waitForSearchRefresh(t *testing.T, editor *Editor) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for editor.searchRefreshTimer != nil || editor.searchRefreshCancel != nil {
+		if time.Now().After(deadline) {
+			t.Fatal("Timed out waiting for the search to refresh")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
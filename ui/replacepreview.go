@@ -0,0 +1,255 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+	"github.com/kenelite/goeditor/backend"
+)
+
+// ReplacePreviewDialog shows the planned replacements of a Replace in Files
+// operation and lets the user pick which files and matches to change
+type ReplacePreviewDialog struct {
+	dialog       dialog.Dialog
+	fileTree     *widget.Tree
+	diffText     *widget.RichText
+	summaryLabel *widget.Label
+	applyButton  *widget.Button
+
+	// State
+	replacements []backend.FileReplacement
+	currentFile  int
+
+	// OnApply is called with the reviewed replacements when Apply is pressed
+	OnApply func(replacements []backend.FileReplacement)
+}
+
+// NewReplacePreviewDialog creates a preview dialog for the given replacements
+func NewReplacePreviewDialog(replacements []backend.FileReplacement, window fyne.Window) *ReplacePreviewDialog {
+	rp := &ReplacePreviewDialog{
+		replacements: replacements,
+		currentFile:  -1,
+	}
+
+	rp.createDialog(window)
+	return rp
+}
+
+// createDialog creates the dialog UI
+func (rp *ReplacePreviewDialog) createDialog(window fyne.Window) {
+	rp.fileTree = widget.NewTree(
+		rp.childUIDs,
+		rp.isBranch,
+		func(branch bool) fyne.CanvasObject {
+			return container.NewHBox(widget.NewCheck("", nil), widget.NewLabel(""))
+		},
+		rp.updateNode,
+	)
+	rp.fileTree.OnSelected = func(uid widget.TreeNodeID) {
+		fileIndex, _ := parseResultUID(uid)
+		rp.showDiff(fileIndex)
+	}
+
+	rp.diffText = widget.NewRichText()
+	rp.summaryLabel = widget.NewLabel("")
+
+	rp.applyButton = widget.NewButton("Apply", func() {
+		rp.dialog.Hide()
+		if rp.OnApply != nil {
+			rp.OnApply(rp.replacements)
+		}
+	})
+	rp.applyButton.Importance = widget.HighImportance
+
+	cancelButton := widget.NewButton("Cancel", func() {
+		rp.dialog.Hide()
+	})
+
+	split := container.NewHSplit(rp.fileTree, container.NewScroll(rp.diffText))
+	split.SetOffset(0.4)
+
+	content := container.NewBorder(
+		nil,
+		container.NewBorder(nil, nil, nil, container.NewHBox(cancelButton, rp.applyButton), rp.summaryLabel),
+		nil, nil,
+		split,
+	)
+
+	rp.dialog = dialog.NewCustomWithoutButtons("Replace in Files - Preview", content, window)
+	rp.dialog.Resize(fyne.NewSize(900, 600))
+
+	rp.updateSummary()
+	if len(rp.replacements) > 0 {
+		rp.showDiff(0)
+	}
+}
+
+// Show displays the preview dialog
+func (rp *ReplacePreviewDialog) Show() {
+	rp.dialog.Show()
+}
+
+// Hide hides the preview dialog
+func (rp *ReplacePreviewDialog) Hide() {
+	rp.dialog.Hide()
+}
+
+// GetReplacements returns the replacements with the current selection state
+func (rp *ReplacePreviewDialog) GetReplacements() []backend.FileReplacement {
+	return rp.replacements
+}
+
+// childUIDs returns the tree children of uid
+func (rp *ReplacePreviewDialog) childUIDs(uid widget.TreeNodeID) []widget.TreeNodeID {
+	if uid == "" {
+		ids := make([]widget.TreeNodeID, len(rp.replacements))
+		for i := range rp.replacements {
+			ids[i] = fmt.Sprintf("%d", i)
+		}
+		return ids
+	}
+
+	fileIndex, matchIndex := parseResultUID(uid)
+	if matchIndex >= 0 || fileIndex < 0 || fileIndex >= len(rp.replacements) {
+		return nil
+	}
+
+	edits := rp.replacements[fileIndex].Edits
+	ids := make([]widget.TreeNodeID, len(edits))
+	for i := range edits {
+		ids[i] = fmt.Sprintf("%d:%d", fileIndex, i)
+	}
+	return ids
+}
+
+// isBranch reports whether uid is a file node
+func (rp *ReplacePreviewDialog) isBranch(uid widget.TreeNodeID) bool {
+	if uid == "" {
+		return true
+	}
+	_, matchIndex := parseResultUID(uid)
+	return matchIndex < 0
+}
+
+// updateNode renders a file or edit node with its checkbox
+func (rp *ReplacePreviewDialog) updateNode(uid widget.TreeNodeID, branch bool, obj fyne.CanvasObject) {
+	row := obj.(*fyne.Container)
+	check := row.Objects[0].(*widget.Check)
+	label := row.Objects[1].(*widget.Label)
+
+	fileIndex, matchIndex := parseResultUID(uid)
+	if fileIndex < 0 || fileIndex >= len(rp.replacements) {
+		return
+	}
+	fr := &rp.replacements[fileIndex]
+
+	// Detach the handler while syncing the checkbox with the model
+	check.OnChanged = nil
+	if matchIndex < 0 {
+		check.SetChecked(fr.Enabled)
+		label.TextStyle = fyne.TextStyle{Bold: true}
+		label.SetText(fmt.Sprintf("%s (%d/%d)", fr.RelPath, fr.EnabledEditCount(), len(fr.Edits)))
+		check.OnChanged = func(checked bool) {
+			fr.Enabled = checked
+			if checked && fr.EnabledEditCount() == 0 {
+				fr.SetAllEdits(true)
+			}
+			rp.selectionChanged(fileIndex)
+		}
+		return
+	}
+
+	if matchIndex >= len(fr.Edits) {
+		return
+	}
+	edit := &fr.Edits[matchIndex]
+	check.SetChecked(edit.Enabled)
+	label.TextStyle = fyne.TextStyle{}
+	label.SetText(fmt.Sprintf("%d: %s", edit.Start.Line, truncatePreview(strings.TrimSpace(edit.LineText))))
+	check.OnChanged = func(checked bool) {
+		edit.Enabled = checked
+		if checked {
+			fr.Enabled = true
+		}
+		rp.selectionChanged(fileIndex)
+	}
+}
+
+// selectionChanged refreshes the tree, diff and summary after a checkbox toggles
+func (rp *ReplacePreviewDialog) selectionChanged(fileIndex int) {
+	rp.fileTree.Refresh()
+	rp.showDiff(fileIndex)
+	rp.updateSummary()
+}
+
+// showDiff displays the diff of the file at fileIndex
+func (rp *ReplacePreviewDialog) showDiff(fileIndex int) {
+	if fileIndex < 0 || fileIndex >= len(rp.replacements) {
+		return
+	}
+	rp.currentFile = fileIndex
+
+	fr := &rp.replacements[fileIndex]
+	segments := []widget.RichTextSegment{
+		&widget.TextSegment{
+			Text:  fr.RelPath,
+			Style: widget.RichTextStyle{TextStyle: fyne.TextStyle{Bold: true}},
+		},
+	}
+
+	diff := fr.FormatDiff()
+	if diff == "" {
+		segments = append(segments, &widget.TextSegment{Text: "No changes selected", Style: widget.RichTextStyleParagraph})
+	}
+	for _, line := range strings.Split(strings.TrimSuffix(diff, "\n"), "\n") {
+		if line == "" {
+			continue
+		}
+		style := widget.RichTextStyleCodeBlock
+		switch line[0] {
+		case '-':
+			style.ColorName = theme.ColorNameError
+		case '+':
+			style.ColorName = theme.ColorNameSuccess
+		case '@':
+			style.ColorName = theme.ColorNamePlaceHolder
+		}
+		segments = append(segments, &widget.TextSegment{Text: line, Style: style})
+	}
+
+	rp.diffText.Segments = segments
+	rp.diffText.Refresh()
+}
+
+// updateSummary shows how many edits will be applied
+func (rp *ReplacePreviewDialog) updateSummary() {
+	edits, files := 0, 0
+	for i := range rp.replacements {
+		if count := rp.replacements[i].EnabledEditCount(); count > 0 {
+			edits += count
+			files++
+		}
+	}
+
+	rp.summaryLabel.SetText(fmt.Sprintf("%d replacements in %d files", edits, files))
+	if edits == 0 {
+		rp.applyButton.Disable()
+	} else {
+		rp.applyButton.Enable()
+	}
+}
+
+// truncatePreview shortens a line preview for display to maxPreviewLength
+// characters, never splitting one
+func truncatePreview(text string) string {
+	if utf8.RuneCountInString(text) > maxPreviewLength {
+		return string([]rune(text)[:maxPreviewLength]) + "…"
+	}
+	return text
+}
@@ -0,0 +1,22 @@
+package ui
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestTruncatePreview(t *testing.T) {
+	if preview := truncatePreview("short"); preview != "short" {
+		t.Errorf("Expected short previews to be kept, got %q", preview)
+	}
+
+	long := strings.Repeat("é", maxPreviewLength+10)
+	preview := truncatePreview(long)
+	if !utf8.ValidString(preview) {
+		t.Errorf("Expected valid UTF-8, got %q", preview)
+	}
+	if count := utf8.RuneCountInString(preview); count != maxPreviewLength+1 {
+		t.Errorf("Expected %d characters and an ellipsis, got %d", maxPreviewLength, count)
+	}
+}
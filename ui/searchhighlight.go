@@ -0,0 +1,422 @@
+package ui
+
+import (
+	"image/color"
+	"strings"
+	"unicode/utf8"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/canvas"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+	"github.com/kenelite/goeditor/backend"
+)
+
+var (
+	// matchHighlightColor paints every search match
+	matchHighlightColor = color.NRGBA{R: 0xff, G: 0xd7, B: 0x00, A: 0x60}
+	// currentMatchHighlightColor paints the current search match
+	currentMatchHighlightColor = color.NRGBA{R: 0xff, G: 0x8c, B: 0x00, A: 0xa0}
+)
+
+// matchMarkerBarWidth is the width of the scrollbar match marker strip
+const matchMarkerBarWidth = 10
+
+// overlayViewportMargin is how far outside the viewport matches are still drawn
+const overlayViewportMargin = 200
+
+// SearchHighlightOverlay paints search matches on top of the editor text.
+// It is stacked over the text entry and does not handle any input, so
+// clicks and typing still reach the entry below.
+type SearchHighlightOverlay struct {
+	widget.BaseWidget
+	entry *widget.Entry
+
+	matches []backend.Match
+	current int
+	lines   []string
+
+	// Visible region of the overlay, used to draw only nearby matches
+	viewportOffset fyne.Position
+	viewportSize   fyne.Size
+}
+
+// NewSearchHighlightOverlay creates an overlay for the given entry
+func NewSearchHighlightOverlay(entry *widget.Entry) *SearchHighlightOverlay {
+	o := &SearchHighlightOverlay{
+		entry:   entry,
+		current: -1,
+	}
+	o.ExtendBaseWidget(o)
+	return o
+}
+
+// SetMatches replaces the highlighted matches
+func (o *SearchHighlightOverlay) SetMatches(matches []backend.Match, current int, text string) {
+	o.matches = matches
+	o.current = current
+	o.lines = strings.Split(text, "\n")
+	o.Refresh()
+}
+
+// SetCurrent changes which match is painted as the current one
+func (o *SearchHighlightOverlay) SetCurrent(current int) {
+	if o.current == current {
+		return
+	}
+	o.current = current
+	o.Refresh()
+}
+
+// Clear removes all highlights
+func (o *SearchHighlightOverlay) Clear() {
+	o.matches = nil
+	o.current = -1
+	o.lines = nil
+	o.Refresh()
+}
+
+// HasMatches returns true if any matches are highlighted
+func (o *SearchHighlightOverlay) HasMatches() bool {
+	return len(o.matches) > 0
+}
+
+// SetViewport records the visible region so only nearby matches are drawn
+func (o *SearchHighlightOverlay) SetViewport(offset fyne.Position, size fyne.Size) {
+	o.viewportOffset = offset
+	o.viewportSize = size
+	o.Refresh()
+}
+
+// MatchBounds returns the position and size of a match relative to the
+// entry, following the layout of a non-wrapping multi-line entry
+func (o *SearchHighlightOverlay) MatchBounds(match backend.Match) (fyne.Position, fyne.Size) {
+	th := o.entry.Theme()
+	textSize := th.Size(theme.SizeNameText)
+	innerPad := th.Size(theme.SizeNameInnerPadding)
+	style := o.entry.TextStyle
+	rowHeight := fyne.MeasureText("M", textSize, style).Height
+
+	startX := innerPad + o.columnOffset(match.Start.Line, match.Start.Column, textSize, style)
+	y := innerPad + rowHeight*float32(match.Start.Line-1)
+
+	// Only the first line of a match spanning lines is measured here; see
+	// lineSegments for splitting such matches
+	var endX float32
+	if match.End.Line == match.Start.Line {
+		endX = innerPad + o.columnOffset(match.End.Line, match.End.Column, textSize, style)
+	} else {
+		endX = innerPad + o.columnOffset(match.Start.Line, len(o.line(match.Start.Line))+1, textSize, style)
+	}
+
+	width := endX - startX
+	if width < 2 {
+		width = 2
+	}
+	return fyne.NewPos(startX, y), fyne.NewSize(width, rowHeight)
+}
+
+// lineSegments splits a match spanning several lines into one single-line
+// match per line so each line can be highlighted separately. Only the
+// segments on lines first to last are returned.
+func (o *SearchHighlightOverlay) lineSegments(match backend.Match, first, last int) []backend.Match {
+	if match.End.Line <= match.Start.Line {
+		return []backend.Match{match}
+	}
+
+	from, to := match.Start.Line, match.End.Line
+	if from < first {
+		from = first
+	}
+	if to > last {
+		to = last
+	}
+	if from > to {
+		return nil
+	}
+
+	segments := make([]backend.Match, 0, to-from+1)
+	for line := from; line <= to; line++ {
+		start := backend.Position{Line: line, Column: 1}
+		end := backend.Position{Line: line, Column: len(o.line(line)) + 1}
+		if line == match.Start.Line {
+			start = match.Start
+		}
+		if line == match.End.Line {
+			end = match.End
+		}
+		segments = append(segments, backend.Match{Start: start, End: end})
+	}
+	return segments
+}
+
+// line returns the 1-based line of the highlighted text
+func (o *SearchHighlightOverlay) line(lineNumber int) string {
+	if lineNumber < 1 || lineNumber > len(o.lines) {
+		return ""
+	}
+	return o.lines[lineNumber-1]
+}
+
+// columnOffset measures the width of a line up to a 1-based byte column
+func (o *SearchHighlightOverlay) columnOffset(lineNumber, column int, textSize float32, style fyne.TextStyle) float32 {
+	line := o.line(lineNumber)
+	end := column - 1
+	if end <= 0 {
+		return 0
+	}
+	if end > len(line) {
+		end = len(line)
+	}
+	for end > 0 && end < len(line) && !utf8.RuneStart(line[end]) {
+		end--
+	}
+	return fyne.MeasureText(line[:end], textSize, style).Width
+}
+
+// CreateRenderer creates the renderer for the overlay
+func (o *SearchHighlightOverlay) CreateRenderer() fyne.WidgetRenderer {
+	return &searchHighlightRenderer{overlay: o}
+}
+
+// searchHighlightRenderer draws the overlay using a pool of rectangles
+type searchHighlightRenderer struct {
+	overlay *SearchHighlightOverlay
+	rects   []*canvas.Rectangle
+	objects []fyne.CanvasObject
+	size    fyne.Size
+}
+
+func (r *searchHighlightRenderer) Layout(size fyne.Size) {
+	r.size = size
+	r.update()
+}
+
+func (r *searchHighlightRenderer) MinSize() fyne.Size {
+	return fyne.NewSize(0, 0)
+}
+
+func (r *searchHighlightRenderer) Refresh() {
+	r.update()
+	canvas.Refresh(r.overlay)
+}
+
+func (r *searchHighlightRenderer) Objects() []fyne.CanvasObject {
+	return r.objects
+}
+
+func (r *searchHighlightRenderer) Destroy() {}
+
+// update positions one rectangle per visible match, reusing rectangles
+// from earlier updates so typing in the search box stays cheap
+func (r *searchHighlightRenderer) update() {
+	o := r.overlay
+	top := o.viewportOffset.Y - overlayViewportMargin
+	bottom := o.viewportOffset.Y + o.viewportSize.Height + overlayViewportMargin
+	if o.viewportSize.Height == 0 {
+		bottom = r.size.Height + overlayViewportMargin
+	}
+
+	// Find the visible lines from the row height alone, so matches out of
+	// view are skipped before any text is measured
+	th := o.entry.Theme()
+	innerPad := th.Size(theme.SizeNameInnerPadding)
+	rowHeight := fyne.MeasureText("M", th.Size(theme.SizeNameText), o.entry.TextStyle).Height
+	first := int((top-innerPad)/rowHeight) + 1
+	last := int((bottom-innerPad)/rowHeight) + 1
+
+	used := 0
+	for i, match := range o.matches {
+		// Matches are sorted, so none after this one are visible either
+		if match.Start.Line > last {
+			break
+		}
+		if match.End.Line < first {
+			continue
+		}
+
+		col := color.Color(matchHighlightColor)
+		if i == o.current {
+			col = currentMatchHighlightColor
+		}
+
+		for _, segment := range o.lineSegments(match, first, last) {
+			pos, size := o.MatchBounds(segment)
+
+			if used == len(r.rects) {
+				r.rects = append(r.rects, canvas.NewRectangle(matchHighlightColor))
+			}
+			rect := r.rects[used]
+			used++
+
+			if rect.FillColor != col {
+				rect.FillColor = col
+				rect.Refresh()
+			}
+			rect.Move(pos)
+			rect.Resize(size)
+			rect.Show()
+		}
+	}
+
+	for i := used; i < len(r.rects); i++ {
+		r.rects[i].Hide()
+	}
+
+	if len(r.objects) != len(r.rects) {
+		r.objects = make([]fyne.CanvasObject, len(r.rects))
+		for i, rect := range r.rects {
+			r.objects[i] = rect
+		}
+	}
+}
+
+// MatchMarkerBar shows where search matches are in the document as marks
+// alongside the editor scrollbar
+type MatchMarkerBar struct {
+	widget.BaseWidget
+
+	matchLines []int
+	current    int
+	lineCount  int
+
+	// OnLineTapped is called with the line under a tap on the bar
+	OnLineTapped func(line int)
+}
+
+// NewMatchMarkerBar creates a new match marker bar
+func NewMatchMarkerBar() *MatchMarkerBar {
+	b := &MatchMarkerBar{current: -1, lineCount: 1}
+	b.ExtendBaseWidget(b)
+	return b
+}
+
+// SetMatches updates the marked lines
+func (b *MatchMarkerBar) SetMatches(matches []backend.Match, current int, lineCount int) {
+	b.matchLines = make([]int, len(matches))
+	for i, match := range matches {
+		b.matchLines[i] = match.Start.Line
+	}
+	b.current = current
+	if lineCount < 1 {
+		lineCount = 1
+	}
+	b.lineCount = lineCount
+	b.Refresh()
+}
+
+// SetCurrent changes which mark is shown as the current match
+func (b *MatchMarkerBar) SetCurrent(current int) {
+	if b.current == current {
+		return
+	}
+	b.current = current
+	b.Refresh()
+}
+
+// Clear removes all marks
+func (b *MatchMarkerBar) Clear() {
+	b.matchLines = nil
+	b.current = -1
+	b.Refresh()
+}
+
+// HasMatches returns true if any marks are shown
+func (b *MatchMarkerBar) HasMatches() bool {
+	return len(b.matchLines) > 0
+}
+
+// MarkerY returns the vertical position of a line's marker for a bar height
+func (b *MatchMarkerBar) MarkerY(line int, height float32) float32 {
+	if b.lineCount <= 1 {
+		return 0
+	}
+	return float32(line-1) / float32(b.lineCount) * height
+}
+
+// Tapped jumps to the line under the tap
+func (b *MatchMarkerBar) Tapped(ev *fyne.PointEvent) {
+	if b.OnLineTapped == nil || b.Size().Height == 0 {
+		return
+	}
+	line := int(ev.Position.Y/b.Size().Height*float32(b.lineCount)) + 1
+	if line > b.lineCount {
+		line = b.lineCount
+	}
+	b.OnLineTapped(line)
+}
+
+// CreateRenderer creates the renderer for the marker bar
+func (b *MatchMarkerBar) CreateRenderer() fyne.WidgetRenderer {
+	return &matchMarkerRenderer{bar: b}
+}
+
+// matchMarkerRenderer draws one mark per matched line
+type matchMarkerRenderer struct {
+	bar     *MatchMarkerBar
+	marks   []*canvas.Rectangle
+	objects []fyne.CanvasObject
+	size    fyne.Size
+}
+
+func (r *matchMarkerRenderer) Layout(size fyne.Size) {
+	r.size = size
+	r.update()
+}
+
+func (r *matchMarkerRenderer) MinSize() fyne.Size {
+	return fyne.NewSize(matchMarkerBarWidth, 0)
+}
+
+func (r *matchMarkerRenderer) Refresh() {
+	r.update()
+	canvas.Refresh(r.bar)
+}
+
+func (r *matchMarkerRenderer) Objects() []fyne.CanvasObject {
+	return r.objects
+}
+
+func (r *matchMarkerRenderer) Destroy() {}
+
+// update places the marks, merging matches that fall on the same pixel row
+func (r *matchMarkerRenderer) update() {
+	b := r.bar
+	used := 0
+	lastY := float32(-1)
+	for i, line := range b.matchLines {
+		y := b.MarkerY(line, r.size.Height)
+		isCurrent := i == b.current
+		if !isCurrent && used > 0 && y-lastY < 2 {
+			continue
+		}
+		lastY = y
+
+		if used == len(r.marks) {
+			r.marks = append(r.marks, canvas.NewRectangle(matchHighlightColor))
+		}
+		mark := r.marks[used]
+		used++
+
+		col := color.Color(color.NRGBA{R: 0xff, G: 0xc1, B: 0x07, A: 0xff})
+		if isCurrent {
+			col = currentMatchHighlightColor
+		}
+		mark.FillColor = col
+		mark.Move(fyne.NewPos(1, y))
+		mark.Resize(fyne.NewSize(r.size.Width-2, 3))
+		mark.Show()
+		mark.Refresh()
+	}
+
+	for i := used; i < len(r.marks); i++ {
+		r.marks[i].Hide()
+	}
+
+	if len(r.objects) != len(r.marks) {
+		r.objects = make([]fyne.CanvasObject, len(r.marks))
+		for i, mark := range r.marks {
+			r.objects[i] = mark
+		}
+	}
+}
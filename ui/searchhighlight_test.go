@@ -0,0 +1,91 @@
+package ui
+
+import (
+	"strings"
+	"testing"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/test"
+	"fyne.io/fyne/v2/widget"
+	"github.com/kenelite/goeditor/backend"
+)
+
+func TestSearchHighlightRefresh(t *testing.T) {
+	testApp := test.NewApp()
+	defer testApp.Quit()
+
+	editor := NewEditor()
+	editor.ApplyConfiguration()
+	editor.SetContent("one\ntwo one\n")
+	matches := editor.Find("one", editor.SearchManager.GetOptions())
+	editor.SetSearchHighlights(matches, 0)
+
+	// Typing only searches again once it pauses
+	editor.SetContent("one\ntwo one\none\n")
+	editor.SetContent("one\ntwo one\none one\n")
+	if count := editor.SearchManager.GetMatchCount(); count != 2 {
+		t.Errorf("Expected the search to wait for typing to pause, got %d matches", count)
+	}
+	waitForSearchRefresh(t, editor)
+	if count := editor.SearchManager.GetMatchCount(); count != 4 {
+		t.Errorf("Expected 4 matches after the edits, got %d", count)
+	}
+	if !editor.SearchHighlights.HasMatches() || !editor.MatchMarkers.HasMatches() {
+		t.Error("Expected the matches to be highlighted and marked")
+	}
+
+	// Wrapped lines only get markers beside the scrollbar
+	config := editor.ConfigManager.GetEditorConfig()
+	config.WordWrap = true
+	editor.ConfigManager.UpdateEditorConfig(config)
+	editor.ApplyConfiguration()
+	editor.ClearSearchHighlights()
+	editor.SetSearchHighlights(editor.SearchManager.GetMatches(), 0)
+	if editor.HighlightsMatchesInline() || editor.SearchHighlights.HasMatches() || !editor.MatchMarkers.HasMatches() {
+		t.Error("Expected only the scrollbar markers while lines wrap")
+	}
+}
+
+func TestSearchHighlightViewport(t *testing.T) {
+	testApp := test.NewApp()
+	defer testApp.Quit()
+
+	entry := widget.NewMultiLineEntry()
+	overlay := NewSearchHighlightOverlay(entry)
+	lines := make([]string, 1000)
+	matches := make([]backend.Match, 0, len(lines)+1)
+	for i := range lines {
+		lines[i] = "match"
+		matches = append(matches, backend.Match{
+			Start: backend.Position{Line: i + 1, Column: 1},
+			End:   backend.Position{Line: i + 1, Column: 6},
+		})
+	}
+	// One match spans the whole document
+	matches = append(matches, backend.Match{
+		Start: backend.Position{Line: 1, Column: 1},
+		End:   backend.Position{Line: len(lines), Column: 6},
+	})
+	overlay.SetMatches(matches, -1, strings.Join(lines, "\n"))
+	overlay.Resize(fyne.NewSize(400, 20000))
+	overlay.SetViewport(fyne.NewPos(0, 5000), fyne.NewSize(400, 300))
+
+	visible := 0
+	for _, object := range test.WidgetRenderer(overlay).Objects() {
+		if !object.Visible() {
+			continue
+		}
+		visible++
+		if y := object.Position().Y; y < 5000-overlayViewportMargin-50 || y > 5300+overlayViewportMargin {
+			t.Errorf("Expected only highlights near the viewport, got one at %v", y)
+		}
+	}
+	if visible == 0 || visible > 200 {
+		t.Errorf("Expected highlights for the visible lines only, got %d", visible)
+	}
+
+	segments := overlay.lineSegments(matches[len(matches)-1], 10, 12)
+	if len(segments) != 3 || segments[0].Start.Line != 10 || segments[2].End.Line != 12 {
+		t.Errorf("Expected segments for lines 10 to 12, got %v", segments)
+	}
+}
@@ -0,0 +1,102 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/kenelite/goeditor/backend"
+	"github.com/kenelite/goeditor/ui/syntax"
+)
+
+// ScopeRanges implements backend.ScopeProvider. Selection ranges come from
+// the text widget; comment, string and code ranges come from the token
+// stream of the syntax highlighter for the current file type.
+func (e *Editor) ScopeRanges(text string, scope backend.SearchScope) []backend.TextRange {
+	if scope == backend.ScopeSelection {
+		// Without a selection that can be located nothing is searched
+		ranges := make([]backend.TextRange, 0)
+		if start, end, err := e.selectionRange(text); err == nil {
+			ranges = append(ranges, backend.TextRange{Start: start, End: end})
+		}
+		return ranges
+	}
+	return e.ScopeSnapshot().ScopeRanges(text, scope)
+}
+
+// ScopeSnapshot implements backend.ScopeSnapshotter. The returned provider
+// holds the current language, so background searches do not read the
+// editor while the language changes.
+func (e *Editor) ScopeSnapshot() backend.ScopeProvider {
+	language := e.GetFileType().LexerName
+	if language == "" {
+		language = "text"
+	}
+	return syntaxScopes(language)
+}
+
+// syntaxScopes computes comment, string and code ranges by lexing a text
+// as a language
+type syntaxScopes string
+
+// ScopeRanges implements backend.ScopeProvider
+func (language syntaxScopes) ScopeRanges(text string, scope backend.SearchScope) []backend.TextRange {
+	ranges := make([]backend.TextRange, 0)
+
+	var kind syntax.TokenKind
+	switch scope {
+	case backend.ScopeComments:
+		kind = syntax.TokenComment
+	case backend.ScopeStrings:
+		kind = syntax.TokenString
+	case backend.ScopeCode:
+		kind = syntax.TokenCode
+	default:
+		return append(ranges, backend.TextRange{Start: 0, End: len(text)})
+	}
+
+	for _, token := range syntax.ClassifyTokens(text, string(language)) {
+		if token.Kind == kind {
+			ranges = append(ranges, backend.TextRange{Start: token.Start, End: token.End})
+		}
+	}
+	return ranges
+}
+
+// selectionRange returns the byte range of the selected text. The entry only
+// reports the selected text and the cursor, which sits at one end of the
+// selection, so both directions are tried. When the text at neither side of
+// the cursor is the selected text, the selection cannot be located and an
+// error is returned rather than guessing another occurrence.
+func (e *Editor) selectionRange(text string) (int, int, error) {
+	selected := e.TextWidget.SelectedText()
+	if selected == "" {
+		return 0, 0, fmt.Errorf("no text is selected")
+	}
+
+	cursor := cursorOffset(text, e.TextWidget.CursorRow, e.TextWidget.CursorColumn)
+	if start := cursor - len(selected); start >= 0 && text[start:cursor] == selected {
+		return start, cursor, nil
+	}
+	if end := cursor + len(selected); end <= len(text) && text[cursor:end] == selected {
+		return cursor, end, nil
+	}
+	return 0, 0, fmt.Errorf("the selection is not at the cursor")
+}
+
+// cursorOffset converts a 0-based row and rune column into a byte offset
+func cursorOffset(text string, row, column int) int {
+	offset := 0
+	for i := 0; i < row; i++ {
+		next := strings.IndexByte(text[offset:], '\n')
+		if next == -1 {
+			return len(text)
+		}
+		offset += next + 1
+	}
+	for i := 0; i < column && offset < len(text) && text[offset] != '\n'; i++ {
+		_, size := utf8.DecodeRuneInString(text[offset:])
+		offset += size
+	}
+	return offset
+}
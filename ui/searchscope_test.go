@@ -0,0 +1,31 @@
+package ui
+
+import (
+	"testing"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/test"
+	"github.com/kenelite/goeditor/backend"
+)
+
+func TestSelectionScope(t *testing.T) {
+	testApp := test.NewApp()
+	defer testApp.Quit()
+
+	editor := NewEditor()
+	text := "one two\none two\n"
+	editor.SetContent(text)
+
+	if _, _, err := editor.selectionRange(text); err == nil {
+		t.Error("Expected an error without a selection")
+	}
+	if ranges := editor.ScopeRanges(text, backend.ScopeSelection); len(ranges) != 0 {
+		t.Errorf("Expected nothing to be searched without a selection, got %v", ranges)
+	}
+
+	editor.TextWidget.TypedShortcut(&fyne.ShortcutSelectAll{})
+	start, end, err := editor.selectionRange(text)
+	if err != nil || start != 0 || end != len(text) {
+		t.Errorf("Expected the whole text to be selected, got %d-%d: %v", start, end, err)
+	}
+}
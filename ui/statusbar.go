@@ -7,6 +7,7 @@ import (
 	
 	"fyne.io/fyne/v2"
 	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/driver/desktop"
 	"fyne.io/fyne/v2/widget"
 )
 
@@ -22,6 +23,9 @@ type StatusBar struct {
 	languageLabel  *widget.Label  // Shows file language/type
 	selectionLabel *widget.Label  // Shows selection statistics
 	
+	// Opens the language mode picker when the language is tapped
+	languageButton *statusBarButton
+	
 	// Reference to editor for state access
 	editor *Editor
 }
@@ -102,12 +106,13 @@ func (sb *StatusBar) createLayout() {
 	)
 	
 	// Create right section (position, encoding, language)
+	sb.languageButton = newStatusBarButton(sb.languageLabel, sb.showLanguagePicker)
 	rightSection := container.NewHBox(
 		sb.positionLabel,
 		separator1,
 		sb.encodingLabel,
 		separator2,
-		sb.languageLabel,
+		sb.languageButton,
 	)
 	
 	// Create the main container using border layout
@@ -197,6 +202,36 @@ func (sb *StatusBar) SetLanguage(language string) {
 	sb.languageLabel.SetText(language)
 }
 
+// showLanguagePicker lists the language modes above the language label.
+// Choosing one overrides the detected language of the document.
+func (sb *StatusBar) showLanguagePicker() {
+	if sb.editor == nil {
+		return
+	}
+	canvas := fyne.CurrentApp().Driver().CanvasForObject(sb.languageLabel)
+	if canvas == nil {
+		return
+	}
+	
+	choose := func(language string) func() {
+		return func() {
+			sb.editor.SetLanguageMode(language)
+		}
+	}
+	current := sb.editor.GetFileType()
+	auto := fyne.NewMenuItem(fmt.Sprintf("Auto Detect (%s)", sb.editor.GetDetectedFileType().Name), choose(""))
+	auto.Checked = !sb.editor.IsLanguageOverridden()
+	items := []*fyne.MenuItem{auto, fyne.NewMenuItemSeparator()}
+	for _, fileType := range sb.editor.LanguageModes() {
+		item := fyne.NewMenuItem(fileType.Name, choose(fileType.LexerName))
+		item.Checked = sb.editor.IsLanguageOverridden() && fileType.LexerName == current.LexerName
+		items = append(items, item)
+	}
+	
+	position := fyne.CurrentApp().Driver().AbsolutePositionForObject(sb.languageLabel)
+	widget.ShowPopUpMenuAtPosition(fyne.NewMenu("Language Mode", items...), canvas, position)
+}
+
 // SetEncoding updates the encoding display
 func (sb *StatusBar) SetEncoding(encoding string) {
 	if encoding == "" {
@@ -343,4 +378,35 @@ func (sb *StatusBar) Show() {
 // IsVisible returns whether the status bar is currently visible
 func (sb *StatusBar) IsVisible() bool {
 	return sb.Container.Visible()
-}
\ No newline at end of file
+}
+
+// statusBarButton makes a status bar item tappable, keeping its look
+type statusBarButton struct {
+	widget.BaseWidget
+	content  fyne.CanvasObject
+	onTapped func()
+}
+
+// newStatusBarButton creates a status bar item that calls onTapped
+func newStatusBarButton(content fyne.CanvasObject, onTapped func()) *statusBarButton {
+	b := &statusBarButton{content: content, onTapped: onTapped}
+	b.ExtendBaseWidget(b)
+	return b
+}
+
+// CreateRenderer renders the item's content
+func (b *statusBarButton) CreateRenderer() fyne.WidgetRenderer {
+	return widget.NewSimpleRenderer(b.content)
+}
+
+// Tapped calls the item's action
+func (b *statusBarButton) Tapped(*fyne.PointEvent) {
+	if b.onTapped != nil {
+		b.onTapped()
+	}
+}
+
+// Cursor shows that the item can be clicked
+func (b *statusBarButton) Cursor() desktop.Cursor {
+	return desktop.PointerCursor
+}
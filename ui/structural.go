@@ -0,0 +1,56 @@
+package ui
+
+import (
+	"fmt"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+	"github.com/kenelite/goeditor/backend"
+)
+
+// RewriteGo applies a structural rewrite rule written as
+// "pattern -> replacement" to the buffer, in the style of gofmt -r, and
+// returns the number of expressions rewritten. All rewrites are a single
+// edit that Undo reverts in one step.
+func (e *Editor) RewriteGo(rule string) (int, error) {
+	parsed, err := backend.ParseStructuralRule(rule)
+	if err != nil {
+		return 0, err
+	}
+	if !parsed.HasReplacement() {
+		return 0, fmt.Errorf("rewrite rule needs a replacement: pattern -> replacement")
+	}
+
+	content, count, err := parsed.Rewrite(e.GetContent())
+	if err != nil {
+		return 0, err
+	}
+	if count > 0 {
+		e.ApplyEdit(content)
+	}
+	return count, nil
+}
+
+// showStructuralRewriteDialog asks for a rewrite rule and applies it
+func showStructuralRewriteDialog(window fyne.Window, editor *Editor) {
+	ruleEntry := widget.NewEntry()
+	ruleEntry.SetPlaceHolder("errors.New(fmt.Sprintf(a, b...)) -> fmt.Errorf(a, b...)")
+
+	items := []*widget.FormItem{
+		widget.NewFormItem("Rule", ruleEntry),
+	}
+	form := dialog.NewForm("Structural Rewrite (Go)", "Rewrite", "Cancel", items, func(confirmed bool) {
+		if !confirmed || ruleEntry.Text == "" {
+			return
+		}
+		count, err := editor.RewriteGo(ruleEntry.Text)
+		if err != nil {
+			dialog.ShowError(err, window)
+			return
+		}
+		dialog.ShowInformation("Structural Rewrite", fmt.Sprintf("Rewrote %d expressions", count), window)
+	}, window)
+	form.Resize(fyne.NewSize(560, 0))
+	form.Show()
+}
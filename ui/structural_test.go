@@ -0,0 +1,41 @@
+package ui
+
+import (
+	"testing"
+
+	"fyne.io/fyne/v2/test"
+)
+
+func TestEditorRewriteGo(t *testing.T) {
+	testApp := test.NewApp()
+	defer testApp.Quit()
+
+	editor := NewEditor()
+	source := "package p\n\nimport \"errors\"\n\nvar err = errors.New(fmt.Sprintf(\"%d items\", n))\n"
+	editor.SetContent(source)
+	editor.History.Clear()
+
+	count, err := editor.RewriteGo("errors.New(fmt.Sprintf(a, b...)) -> fmt.Errorf(a, b...)")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if count != 1 {
+		t.Errorf("Expected one rewrite, got %d", count)
+	}
+	expected := "package p\n\nimport \"errors\"\n\nvar err = fmt.Errorf(\"%d items\", n)\n"
+	if editor.GetContent() != expected {
+		t.Errorf("Unexpected content %q", editor.GetContent())
+	}
+
+	// The rewrite is undone in one step
+	if !editor.Undo() || editor.GetContent() != source {
+		t.Errorf("Expected undo to restore the source, got %q", editor.GetContent())
+	}
+	if !editor.Redo() || editor.GetContent() != expected {
+		t.Errorf("Expected redo to apply the rewrite again, got %q", editor.GetContent())
+	}
+
+	if _, err := editor.RewriteGo("errors.New(a)"); err == nil {
+		t.Error("Expected an error for a rule without replacement")
+	}
+}
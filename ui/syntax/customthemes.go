@@ -0,0 +1,348 @@
+package syntax
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/alecthomas/chroma"
+)
+
+// tokenAliases are short names for common token types in theme files
+var tokenAliases = map[string]chroma.TokenType{
+	"background":  chroma.Background,
+	"text":        chroma.Text,
+	"keyword":     chroma.Keyword,
+	"type":        chroma.KeywordType,
+	"name":        chroma.Name,
+	"function":    chroma.NameFunction,
+	"class":       chroma.NameClass,
+	"variable":    chroma.NameVariable,
+	"constant":    chroma.NameConstant,
+	"builtin":     chroma.NameBuiltin,
+	"tag":         chroma.NameTag,
+	"attribute":   chroma.NameAttribute,
+	"decorator":   chroma.NameDecorator,
+	"string":      chroma.LiteralString,
+	"number":      chroma.LiteralNumber,
+	"literal":     chroma.Literal,
+	"operator":    chroma.Operator,
+	"punctuation": chroma.Punctuation,
+	"comment":     chroma.Comment,
+	"preproc":     chroma.CommentPreproc,
+	"error":       chroma.Error,
+}
+
+var (
+	tokenNamesOnce sync.Once
+	tokenNames     map[string]chroma.TokenType
+)
+
+// TokenTypeByName looks up a chroma token type by its name, such as
+// "NameFunction" or "Name.Function", or by a short alias such as
+// "function". Names are case-insensitive.
+func TokenTypeByName(name string) (chroma.TokenType, bool) {
+	tokenNamesOnce.Do(func() {
+		tokenNames = make(map[string]chroma.TokenType)
+		for tokenType := range chroma.StandardTypes {
+			tokenNames[strings.ToLower(tokenType.String())] = tokenType
+		}
+	})
+
+	key := strings.ToLower(strings.NewReplacer(".", "", "-", "", "_", "", " ", "").Replace(name))
+	if tokenType, exists := tokenAliases[key]; exists {
+		return tokenType, true
+	}
+	tokenType, exists := tokenNames[key]
+	return tokenType, exists
+}
+
+// ToStyle converts the theme configuration into a chroma style. Token
+// colors are keyed by token type name and use chroma's style entry syntax:
+// a color such as "#0000ff" followed by any of "bold", "italic" and
+// "underline", or "bg:#rrggbb" for a background.
+func (config *ThemeConfig) ToStyle() (*chroma.Style, error) {
+	if config.Name == "" {
+		return nil, fmt.Errorf("theme name cannot be empty")
+	}
+
+	builder := chroma.NewStyleBuilder(config.Name)
+
+	background := ""
+	if config.Background != "" {
+		if err := checkColor("background", config.Background); err != nil {
+			return nil, err
+		}
+		background = "bg:" + config.Background
+	}
+	if config.Foreground != "" {
+		if err := checkColor("foreground", config.Foreground); err != nil {
+			return nil, err
+		}
+		builder.Add(chroma.Text, config.Foreground)
+		background = strings.TrimSpace(background + " " + config.Foreground)
+	}
+	if background != "" {
+		builder.Add(chroma.Background, background)
+	}
+	if config.Selection != "" {
+		if err := checkColor("selection", config.Selection); err != nil {
+			return nil, err
+		}
+		builder.Add(chroma.LineHighlight, "bg:"+config.Selection)
+	}
+	if config.LineNumber != "" {
+		if err := checkColor("lineNumber", config.LineNumber); err != nil {
+			return nil, err
+		}
+		builder.Add(chroma.LineNumbers, config.LineNumber)
+	}
+
+	// Sort the names so that the first error reported is always the same
+	names := make([]string, 0, len(config.TokenColors))
+	for name := range config.TokenColors {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		tokenType, exists := TokenTypeByName(name)
+		if !exists {
+			return nil, fmt.Errorf("unknown token type %q", name)
+		}
+		entry := config.TokenColors[name]
+		if _, err := chroma.ParseStyleEntry(entry); err != nil {
+			return nil, fmt.Errorf("invalid style for token %q: %w", name, err)
+		}
+		if tokenType == chroma.Background && background != "" {
+			entry = background + " " + entry
+		}
+		builder.Add(tokenType, entry)
+	}
+
+	style, err := builder.Build()
+	if err != nil {
+		return nil, fmt.Errorf("failed to build style: %w", err)
+	}
+	return style, nil
+}
+
+// checkColor reports an error if value is not a hex color
+func checkColor(field, value string) error {
+	if !strings.HasPrefix(value, "#") || !chroma.ParseColour(value).IsSet() {
+		return fmt.Errorf("invalid %s color %q, expected #rrggbb", field, value)
+	}
+	return nil
+}
+
+// LoadThemeFile reads and validates a theme configuration from a JSON
+// file. A theme without a name is named after its file.
+func LoadThemeFile(path string) (*ThemeConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read theme file: %w", err)
+	}
+
+	var config ThemeConfig
+	if err := json.Unmarshal(data, &config); err != nil {
+		var syntaxErr *json.SyntaxError
+		if errors.As(err, &syntaxErr) {
+			line, col := lineAndColumn(data, syntaxErr.Offset)
+			return nil, fmt.Errorf("failed to parse theme file at line %d, column %d: %w", line, col, err)
+		}
+		return nil, fmt.Errorf("failed to parse theme file: %w", err)
+	}
+	if config.Name == "" {
+		config.Name = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	}
+
+	if _, err := config.ToStyle(); err != nil {
+		return nil, err
+	}
+	return &config, nil
+}
+
+// ErrThemeExists is returned by SaveCustomTheme for a theme that would
+// replace another
+var ErrThemeExists = errors.New("theme already exists")
+
+// SaveCustomTheme registers a theme and writes it to a JSON file named
+// after the theme in dir, returning the file's path. Unless overwrite is
+// set, a custom theme of the same name or an existing file is not replaced
+// and the error wraps ErrThemeExists.
+func (tm *ThemeManager) SaveCustomTheme(dir string, config *ThemeConfig, overwrite bool) (string, error) {
+	if err := checkThemeName(config.Name); err != nil {
+		return "", err
+	}
+	if _, err := config.ToStyle(); err != nil {
+		return "", fmt.Errorf("invalid theme %q: %w", config.Name, err)
+	}
+	path := filepath.Join(dir, themeFileName(config.Name))
+	if !overwrite {
+		_, registered := tm.GetCustomThemes()[config.Name]
+		if _, err := os.Stat(path); err == nil || registered {
+			return "", fmt.Errorf("%w: %q", ErrThemeExists, config.Name)
+		}
+	}
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create themes directory: %w", err)
+	}
+	data, err := json.MarshalIndent(config, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal theme: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return "", fmt.Errorf("failed to write theme file: %w", err)
+	}
+	if err := tm.RegisterCustomTheme(config); err != nil {
+		return "", err
+	}
+
+	tm.mu.Lock()
+	tm.themeFiles[path] = config.Name
+	tm.mu.Unlock()
+	return path, nil
+}
+
+// themeFileName turns a theme name into a file name
+func themeFileName(name string) string {
+	name = strings.Map(func(r rune) rune {
+		if strings.ContainsRune(`/\:*?"<>|`, r) || r < ' ' {
+			return '-'
+		}
+		return r
+	}, strings.TrimSpace(name))
+	return name + ".json"
+}
+
+// lineAndColumn converts a byte offset into a 1-based line and column
+func lineAndColumn(data []byte, offset int64) (int, int) {
+	if offset > int64(len(data)) {
+		offset = int64(len(data))
+	}
+	before := data[:offset]
+	line := strings.Count(string(before), "\n") + 1
+	col := len(before) - strings.LastIndex(string(before), "\n")
+	return line, col
+}
+
+// LoadCustomThemes registers every *.json theme in dir, replacing themes
+// loaded from there before and dropping those no file defines any more.
+// Invalid files are reported together in the error, one per file, and keep
+// the theme last loaded from them.
+func (tm *ThemeManager) LoadCustomThemes(dir string) error {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return fmt.Errorf("failed to list themes: %w", err)
+	}
+	sort.Strings(paths)
+
+	var errs []error
+	loaded := make(map[string]string)
+	present := make(map[string]bool)
+	for _, path := range paths {
+		present[path] = true
+		config, err := LoadThemeFile(path)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", filepath.Base(path), err))
+			continue
+		}
+		if err := tm.RegisterCustomTheme(config); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", filepath.Base(path), err))
+			continue
+		}
+		loaded[path] = config.Name
+	}
+
+	tm.mu.Lock()
+	var dropped []string
+	for path, name := range tm.themeFiles {
+		if !present[path] || (loaded[path] != "" && loaded[path] != name) {
+			// The file is gone or now defines a theme of another name
+			dropped = append(dropped, name)
+			delete(tm.themeFiles, path)
+		}
+	}
+	for path, name := range loaded {
+		tm.themeFiles[path] = name
+	}
+	for _, name := range dropped {
+		if !tm.hasThemeFile(name) {
+			tm.removeCustomTheme(name)
+		}
+	}
+	tm.mu.Unlock()
+
+	return errors.Join(errs...)
+}
+
+// hasThemeFile reports whether a loaded theme file defines the named theme
+func (tm *ThemeManager) hasThemeFile(name string) bool {
+	for _, fileName := range tm.themeFiles {
+		if fileName == name {
+			return true
+		}
+	}
+	return false
+}
+
+// WatchCustomThemes reloads the themes in dir whenever a theme file is
+// added, changed or removed, checking every interval. onReload is called
+// from the watching goroutine after each reload. The returned function
+// stops watching.
+func (tm *ThemeManager) WatchCustomThemes(dir string, interval time.Duration, onReload func(error)) (stop func()) {
+	quit := make(chan struct{})
+	var once sync.Once
+	last := themeDirSignature(dir)
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-quit:
+				return
+			case <-ticker.C:
+			}
+
+			current := themeDirSignature(dir)
+			if current == last {
+				continue
+			}
+			last = current
+			err := tm.LoadCustomThemes(dir)
+			if onReload != nil {
+				onReload(err)
+			}
+		}
+	}()
+
+	return func() {
+		once.Do(func() { close(quit) })
+	}
+}
+
+// themeDirSignature summarizes the names, sizes and modification times of
+// the theme files in dir
+func themeDirSignature(dir string) string {
+	paths, _ := filepath.Glob(filepath.Join(dir, "*.json"))
+	sort.Strings(paths)
+
+	var signature strings.Builder
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			continue
+		}
+		fmt.Fprintf(&signature, "%s:%d:%d\n", path, info.Size(), info.ModTime().UnixNano())
+	}
+	return signature.String()
+}
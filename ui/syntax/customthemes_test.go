@@ -0,0 +1,239 @@
+package syntax
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alecthomas/chroma"
+	"github.com/alecthomas/chroma/styles"
+)
+
+func TestThemeConfig_ToStyle(t *testing.T) {
+	config := &ThemeConfig{
+		Name:       "paper",
+		Background: "#fdf6e3",
+		Foreground: "#333333",
+		LineNumber: "#999999",
+		TokenColors: map[string]string{
+			"keyword":       "#0000ff bold",
+			"Name.Function": "italic #00aa00",
+			"CommentSingle": "underline #808080",
+		},
+	}
+
+	style, err := config.ToStyle()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	keyword := style.Get(chroma.KeywordType)
+	if keyword.Colour.String() != "#0000ff" || keyword.Bold != chroma.Yes {
+		t.Errorf("Expected bold blue keywords, got %s", keyword)
+	}
+	if function := style.Get(chroma.NameFunction); function.Italic != chroma.Yes {
+		t.Errorf("Expected italic functions, got %s", function)
+	}
+	if comment := style.Get(chroma.CommentSingle); comment.Underline != chroma.Yes {
+		t.Errorf("Expected underlined comments, got %s", comment)
+	}
+	if background := style.Get(chroma.Background); background.Background.String() != "#fdf6e3" {
+		t.Errorf("Unexpected background %s", background)
+	}
+	if text := style.Get(chroma.Text); text.Colour.String() != "#333333" {
+		t.Errorf("Unexpected foreground %s", text)
+	}
+
+	invalid := []struct {
+		config *ThemeConfig
+		want   string
+	}{
+		{&ThemeConfig{Name: "x", TokenColors: map[string]string{"keywrd": "#000000"}}, `unknown token type "keywrd"`},
+		{&ThemeConfig{Name: "x", TokenColors: map[string]string{"keyword": "#00zz00"}}, `token "keyword"`},
+		{&ThemeConfig{Name: "x", TokenColors: map[string]string{"keyword": "blue"}}, "unknown style element"},
+		{&ThemeConfig{Name: "x", Background: "white"}, "invalid background color"},
+	}
+	for _, tt := range invalid {
+		if _, err := tt.config.ToStyle(); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("Expected an error containing %q, got %v", tt.want, err)
+		}
+	}
+}
+
+func TestThemeManager_CustomThemeTakesEffect(t *testing.T) {
+	tm := NewThemeManager()
+	err := tm.RegisterCustomTheme(&ThemeConfig{
+		Name:        "ocean",
+		Background:  "#002b36",
+		TokenColors: map[string]string{"keyword": "#ff0000"},
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := tm.SetTheme("ocean"); err != nil {
+		t.Fatalf("Expected the custom theme to be selectable: %v", err)
+	}
+	if got := tm.GetTokenColor(chroma.Keyword); got != chromaToRGBA(chroma.MustParseColour("#ff0000")) {
+		t.Errorf("Expected red keywords, got %v", got)
+	}
+	if !tm.IsDarkTheme() {
+		t.Error("Expected a dark theme")
+	}
+	if info := tm.GetThemeInfo("ocean"); info == nil || info.BackgroundColor != "#002b36" {
+		t.Errorf("Unexpected theme info %+v", info)
+	}
+
+	err = tm.RegisterCustomTheme(&ThemeConfig{Name: "broken", TokenColors: map[string]string{"nothing": "#ffffff"}})
+	if err == nil || !strings.Contains(err.Error(), `invalid theme "broken"`) {
+		t.Errorf("Expected an invalid theme to be refused, got %v", err)
+	}
+}
+
+func TestThemeManager_LoadCustomThemes(t *testing.T) {
+	dir := t.TempDir()
+	writeTheme := func(name, content string) {
+		t.Helper()
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	writeTheme("night.json", `{"tokenColors": {"keyword": "#112233 bold"}}`)
+	writeTheme("bad.json", "{\n  \"name\": \"bad\",\n  \"tokenColors\": {\n}")
+	writeTheme("notes.txt", "not a theme")
+
+	tm := NewThemeManager()
+	err := tm.LoadCustomThemes(dir)
+	if err == nil || !strings.Contains(err.Error(), "bad.json") || !strings.Contains(err.Error(), "line 4") {
+		t.Errorf("Expected the invalid file to be reported with its position, got %v", err)
+	}
+	if _, exists := tm.GetCustomThemes()["night"]; !exists {
+		t.Fatal("Expected the theme to be named after its file")
+	}
+
+	// Changes are picked up while watching
+	reloaded := make(chan error, 16)
+	stop := tm.WatchCustomThemes(dir, 5*time.Millisecond, func(err error) { reloaded <- err })
+	defer stop()
+	waitForReload := func(done func() bool) {
+		t.Helper()
+		for !done() {
+			select {
+			case <-reloaded:
+			case <-time.After(5 * time.Second):
+				t.Fatal("Timed out waiting for the themes to reload")
+			}
+		}
+	}
+
+	writeTheme("night.json", `{"tokenColors": {"keyword": "#445566"}}`)
+	waitForReload(func() bool {
+		config := tm.GetCustomThemes()["night"]
+		return config != nil && config.TokenColors["keyword"] == "#445566"
+	})
+	tm.SetTheme("night")
+	if got := tm.GetTheme().Get(chroma.Keyword).Colour.String(); got != "#445566" {
+		t.Errorf("Expected the reloaded keyword color, got %s", got)
+	}
+
+	// Removing the file drops the theme, and the default theme replaces it
+	if err := os.Remove(filepath.Join(dir, "night.json")); err != nil {
+		t.Fatal(err)
+	}
+	waitForReload(func() bool {
+		_, exists := tm.GetCustomThemes()["night"]
+		return !exists
+	})
+	if name := tm.GetCurrentThemeName(); name != defaultThemeName {
+		t.Errorf("Expected the default theme after removing the current one, got %s", name)
+	}
+}
+
+func TestThemeManager_LoadCustomThemesSharedName(t *testing.T) {
+	dir := t.TempDir()
+	for _, file := range []string{"night.json", "night-copy.json"} {
+		content := `{"name": "night", "tokenColors": {"keyword": "#112233"}}`
+		if err := os.WriteFile(filepath.Join(dir, file), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tm := NewThemeManager()
+	if err := tm.LoadCustomThemes(dir); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	// The theme stays while another file still defines it
+	if err := os.Remove(filepath.Join(dir, "night-copy.json")); err != nil {
+		t.Fatal(err)
+	}
+	if err := tm.LoadCustomThemes(dir); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, exists := tm.GetCustomThemes()["night"]; !exists {
+		t.Error("Expected the theme to be kept while a file defines it")
+	}
+
+	if err := os.Remove(filepath.Join(dir, "night.json")); err != nil {
+		t.Fatal(err)
+	}
+	if err := tm.LoadCustomThemes(dir); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, exists := tm.GetCustomThemes()["night"]; exists {
+		t.Error("Expected the theme to be dropped with its last file")
+	}
+}
+
+func TestThemeManager_BuiltinThemeNames(t *testing.T) {
+	tm := NewThemeManager()
+	builtin := tm.GetTheme()
+	for _, name := range []string{"monokai", "Monokai"} {
+		err := tm.RegisterCustomTheme(&ThemeConfig{Name: name, Background: "#000000"})
+		if err == nil || !strings.Contains(err.Error(), "built-in") {
+			t.Errorf("%s: expected the built-in name to be rejected, got %v", name, err)
+		}
+	}
+	if err := tm.SetTheme("monokai"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if tm.GetTheme() == builtin || tm.GetTheme() != styles.Get("monokai") {
+		t.Error("Expected the built-in theme to be used")
+	}
+	if _, exists := tm.GetCustomThemes()["monokai"]; exists {
+		t.Error("Expected no custom theme to be registered")
+	}
+}
+
+func TestThemeManager_SaveCustomThemeOverwrite(t *testing.T) {
+	dir := t.TempDir()
+	tm := NewThemeManager()
+	if _, err := tm.SaveCustomTheme(dir, &ThemeConfig{Name: "night", Background: "#000000"}, false); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	path, err := tm.SaveCustomTheme(dir, &ThemeConfig{Name: "night", Background: "#111111"}, false)
+	if !errors.Is(err, ErrThemeExists) {
+		t.Fatalf("Expected ErrThemeExists, got %v", err)
+	}
+	data, _ := os.ReadFile(filepath.Join(dir, "night.json"))
+	if !strings.Contains(string(data), "#000000") || tm.GetCustomThemes()["night"].Background != "#000000" {
+		t.Error("Expected the existing theme to be kept")
+	}
+
+	// A file left by another editor session is not replaced either
+	other := NewThemeManager()
+	if _, err := other.SaveCustomTheme(dir, &ThemeConfig{Name: "night", Background: "#111111"}, false); !errors.Is(err, ErrThemeExists) {
+		t.Errorf("Expected ErrThemeExists for an existing file, got %v", err)
+	}
+
+	path, err = tm.SaveCustomTheme(dir, &ThemeConfig{Name: "night", Background: "#111111"}, true)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	data, _ = os.ReadFile(path)
+	if !strings.Contains(string(data), "#111111") || tm.GetCustomThemes()["night"].Background != "#111111" {
+		t.Error("Expected the theme to be replaced when overwriting")
+	}
+}
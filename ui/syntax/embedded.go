@@ -0,0 +1,506 @@
+package syntax
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/alecthomas/chroma"
+	"github.com/alecthomas/chroma/lexers"
+	"github.com/kenelite/goeditor/backend"
+)
+
+// EmbeddedRegion is a part of a document written in another language, as
+// byte offsets into the document, End exclusive
+type EmbeddedRegion struct {
+	Start    int
+	End      int
+	Language string
+}
+
+// RegionDetector finds the regions of a document in embedded languages
+type RegionDetector func(source string) []EmbeddedRegion
+
+// embeddingRule is a compiled embedded language heuristic
+type embeddingRule struct {
+	backend.EmbeddedLanguage
+	pattern *regexp.Regexp
+	group   int // The group holding the embedded text
+}
+
+// region returns the embedded region of the first match of the rule in
+// text, as offsets into text
+func (rule *embeddingRule) region(text string) (EmbeddedRegion, bool) {
+	match := rule.pattern.FindStringSubmatchIndex(text)
+	if match == nil || match[2*rule.group] < 0 || match[2*rule.group] == match[2*rule.group+1] {
+		return EmbeddedRegion{}, false
+	}
+	return EmbeddedRegion{Start: match[2*rule.group], End: match[2*rule.group+1], Language: rule.Language}, true
+}
+
+// regions returns the embedded regions of every match of the rule in text
+func (rule *embeddingRule) regions(text string) []EmbeddedRegion {
+	var regions []EmbeddedRegion
+	for _, match := range rule.pattern.FindAllStringSubmatchIndex(text, -1) {
+		if start, end := match[2*rule.group], match[2*rule.group+1]; start >= 0 && start < end {
+			regions = append(regions, EmbeddedRegion{Start: start, End: end, Language: rule.Language})
+		}
+	}
+	return regions
+}
+
+// SetEmbeddedLanguages sets the heuristics that find embedded languages,
+// replacing those set before. Rules are tried in order and the first
+// region found wins where regions overlap. Invalid rules are reported
+// together in the error and skipped.
+func (lm *LanguageManager) SetEmbeddedLanguages(languages []backend.EmbeddedLanguage) error {
+	var errs []error
+	var rules []*embeddingRule
+	for i, language := range languages {
+		rule, err := lm.compileEmbeddingRule(language)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("embedded language %d (%s in %s): %w", i+1, language.Language, language.Host, err))
+			continue
+		}
+		rules = append(rules, rule)
+	}
+
+	lm.mu.Lock()
+	lm.embeddingRules = rules
+	lm.highlightCache = make(map[string]chroma.Lexer)
+	lm.mu.Unlock()
+	return errors.Join(errs...)
+}
+
+// compileEmbeddingRule checks a heuristic and compiles its pattern
+func (lm *LanguageManager) compileEmbeddingRule(language backend.EmbeddedLanguage) (*embeddingRule, error) {
+	if lm.lookupLexer(language.Host) == nil {
+		return nil, fmt.Errorf("unknown host language %q", language.Host)
+	}
+	if lm.lookupLexer(language.Language) == nil {
+		return nil, fmt.Errorf("unknown language %q", language.Language)
+	}
+	switch language.Scope {
+	case backend.ScopeDocument, backend.ScopeStrings, backend.ScopeComments:
+	default:
+		return nil, fmt.Errorf("scope must be \"strings\", \"comments\" or empty, got %q", language.Scope)
+	}
+	pattern, err := regexp.Compile(language.Pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid pattern: %w", err)
+	}
+
+	group := pattern.SubexpIndex("code")
+	if group < 0 {
+		group = 0
+		if pattern.NumSubexp() > 0 {
+			group = 1
+		}
+	}
+	return &embeddingRule{EmbeddedLanguage: language, pattern: pattern, group: group}, nil
+}
+
+// RegisterRegionDetector adds a detector of embedded regions in documents
+// of a host language, in addition to the built-in ones
+func (lm *LanguageManager) RegisterRegionDetector(host string, detector RegionDetector) {
+	lm.mu.Lock()
+	defer lm.mu.Unlock()
+	lm.regionDetectors[strings.ToLower(host)] = append(lm.regionDetectors[strings.ToLower(host)], detector)
+	lm.highlightCache = make(map[string]chroma.Lexer)
+}
+
+// defaultRegionDetectors returns the built-in detectors by host language
+func defaultRegionDetectors() map[string][]RegionDetector {
+	return map[string][]RegionDetector{
+		"markdown": {FencedCodeRegions},
+		"html":     {ScriptAndStyleRegions},
+	}
+}
+
+// GetHighlightLexer returns the lexer that highlights a language: its
+// lexer, combined with the lexers of the languages embedded in it where
+// any are detected in the document
+func (lm *LanguageManager) GetHighlightLexer(language string) chroma.Lexer {
+	lm.mu.RLock()
+	lexer, exists := lm.highlightCache[language]
+	lm.mu.RUnlock()
+	if exists {
+		return lexer
+	}
+
+	lexer = lm.GetLexer(language)
+	if lexer != lexers.Fallback {
+		lexer = lm.withEmbeddedLanguages(lexer)
+	}
+
+	lm.mu.Lock()
+	lm.highlightCache[language] = lexer
+	lm.mu.Unlock()
+	return lexer
+}
+
+// withEmbeddedLanguages combines a host lexer with the detectors and rules
+// of its embedded languages, or returns it as it is when it has none
+func (lm *LanguageManager) withEmbeddedLanguages(host chroma.Lexer) chroma.Lexer {
+	config := host.Config()
+	names := append([]string{strings.ToLower(config.Name)}, config.Aliases...)
+
+	lm.mu.RLock()
+	var detectors []RegionDetector
+	for _, name := range names {
+		detectors = append(detectors, lm.regionDetectors[name]...)
+	}
+	rules := lm.embeddingRules
+	lm.mu.RUnlock()
+
+	embedded := &embeddedLexer{host: host, languages: lm, detectors: detectors}
+	for _, rule := range rules {
+		if hostLexer := lm.lookupLexer(rule.Host); hostLexer == nil || hostLexer.Config().Name != config.Name {
+			continue
+		}
+		if rule.Scope == backend.ScopeDocument {
+			embedded.documentRules = append(embedded.documentRules, rule)
+		} else {
+			embedded.tokenRules = append(embedded.tokenRules, rule)
+		}
+	}
+	if len(embedded.detectors) == 0 && len(embedded.documentRules) == 0 && len(embedded.tokenRules) == 0 {
+		return host
+	}
+	return embedded
+}
+
+// embeddedLexer lexes a document with its host lexer and replaces the
+// tokens of embedded regions with the tokens of their own lexers
+type embeddedLexer struct {
+	host          chroma.Lexer
+	languages     *LanguageManager
+	detectors     []RegionDetector
+	documentRules []*embeddingRule // Matched against the whole document
+	tokenRules    []*embeddingRule // Matched against string or comment tokens
+}
+
+// Config returns the host lexer's configuration
+func (l *embeddedLexer) Config() *chroma.Config {
+	return l.host.Config()
+}
+
+// Tokenise lexes text, merging the token streams of the host and of the
+// embedded regions
+func (l *embeddedLexer) Tokenise(options *chroma.TokeniseOptions, text string) (chroma.Iterator, error) {
+	if options == nil {
+		options = &chroma.TokeniseOptions{State: "root", EnsureLF: true}
+	}
+	if options.EnsureLF {
+		// Normalize line breaks here, so that region offsets match the
+		// text the host lexes
+		text = strings.ReplaceAll(strings.ReplaceAll(text, "\r\n", "\n"), "\r", "\n")
+		normalized := *options
+		normalized.EnsureLF = false
+		options = &normalized
+	}
+
+	iterator, err := l.host.Tokenise(options, text)
+	if err != nil {
+		return nil, err
+	}
+	merger := &tokenMerger{
+		lexer:   l,
+		text:    text,
+		host:    iterator,
+		regions: l.regions(text),
+	}
+	return merger.next, nil
+}
+
+// regions returns the embedded regions of text found by the detectors and
+// document rules, sorted and without overlaps
+func (l *embeddedLexer) regions(text string) []EmbeddedRegion {
+	var found []EmbeddedRegion
+	for _, detector := range l.detectors {
+		found = append(found, detector(text)...)
+	}
+	for _, rule := range l.documentRules {
+		found = append(found, rule.regions(text)...)
+	}
+
+	// Regions found earlier win overlaps, so drop later overlaps before
+	// sorting; the kept regions do not overlap and have distinct starts
+	var regions []EmbeddedRegion
+	for _, region := range found {
+		if region.Start < 0 || region.End > len(text) || region.Start >= region.End {
+			continue
+		}
+		overlaps := false
+		for _, kept := range regions {
+			if region.Start < kept.End && kept.Start < region.End {
+				overlaps = true
+				break
+			}
+		}
+		if !overlaps {
+			regions = append(regions, region)
+		}
+	}
+	sort.Slice(regions, func(i, j int) bool { return regions[i].Start < regions[j].Start })
+	return regions
+}
+
+// tokenMerger walks the host tokens, emitting the tokens of the embedded
+// lexers in place of the host tokens of each region
+type tokenMerger struct {
+	lexer   *embeddedLexer
+	text    string
+	host    chroma.Iterator
+	regions []EmbeddedRegion // Regions not reached yet
+	offset  int              // Offset of the next host text
+	skipTo  int              // Host text before this offset was replaced
+	pending chroma.Token     // The rest of a host token split at a region
+	queue   []chroma.Token   // Tokens to emit before lexing on
+}
+
+// next returns the next token
+func (m *tokenMerger) next() chroma.Token {
+	for {
+		if len(m.queue) > 0 {
+			token := m.queue[0]
+			m.queue = m.queue[1:]
+			return token
+		}
+
+		token := m.pending
+		m.pending = chroma.Token{}
+		if token.Value == "" {
+			if token = m.host(); token == chroma.EOF {
+				return chroma.EOF
+			}
+			if token.Value == "" {
+				continue
+			}
+		}
+		start, end := m.offset, m.offset+len(token.Value)
+
+		// Drop the host text of a region whose tokens were emitted
+		if start < m.skipTo {
+			if end > m.skipTo {
+				m.pending = chroma.Token{Type: token.Type, Value: token.Value[m.skipTo-start:]}
+			}
+			m.offset = min(end, m.skipTo)
+			continue
+		}
+
+		if len(m.regions) > 0 && m.regions[0].Start < end {
+			region := m.regions[0]
+			if start < region.Start {
+				// Emit the host text up to the region first
+				m.pending = chroma.Token{Type: token.Type, Value: token.Value[region.Start-start:]}
+				token.Value = token.Value[:region.Start-start]
+			} else {
+				m.regions = m.regions[1:]
+				if tokens, ok := m.lexer.regionTokens(m.text, region); ok {
+					m.queue = tokens
+					m.skipTo = region.End
+				}
+				m.pending = token
+				continue
+			}
+		}
+
+		m.offset += len(token.Value)
+		m.queue = m.lexer.applyTokenRules(token)
+	}
+}
+
+// regionTokens lexes a region with the lexer of its language. ok is false
+// when the language is unknown, so that the host tokens are kept.
+func (l *embeddedLexer) regionTokens(text string, region EmbeddedRegion) (tokens []chroma.Token, ok bool) {
+	lexer := l.languages.GetHighlightLexer(region.Language)
+	if lexer == lexers.Fallback {
+		return nil, false
+	}
+	source := text[region.Start:region.End]
+	iterator, err := lexer.Tokenise(&chroma.TokeniseOptions{State: "root", Nested: true}, source)
+	if err != nil {
+		return nil, false
+	}
+
+	// The tokens must cover the region exactly: lexers may stop early
+	rest := len(source)
+	for token := iterator(); token != chroma.EOF && rest > 0; token = iterator() {
+		if len(token.Value) > rest {
+			token.Value = token.Value[:rest]
+		}
+		rest -= len(token.Value)
+		tokens = append(tokens, token)
+	}
+	if rest > 0 {
+		tokens = append(tokens, chroma.Token{Type: chroma.Text, Value: source[len(source)-rest:]})
+	}
+	return tokens, true
+}
+
+// applyTokenRules replaces the embedded region of a string or comment
+// token found by the first matching rule with the region's tokens
+func (l *embeddedLexer) applyTokenRules(token chroma.Token) []chroma.Token {
+	kind := tokenKind(token.Type)
+	for _, rule := range l.tokenRules {
+		if (rule.Scope == backend.ScopeStrings && kind != TokenString) || (rule.Scope == backend.ScopeComments && kind != TokenComment) {
+			continue
+		}
+		region, ok := rule.region(token.Value)
+		if !ok {
+			continue
+		}
+		embedded, ok := l.regionTokens(token.Value, region)
+		if !ok {
+			continue
+		}
+
+		tokens := make([]chroma.Token, 0, len(embedded)+2)
+		if region.Start > 0 {
+			tokens = append(tokens, chroma.Token{Type: token.Type, Value: token.Value[:region.Start]})
+		}
+		tokens = append(tokens, embedded...)
+		if region.End < len(token.Value) {
+			tokens = append(tokens, chroma.Token{Type: token.Type, Value: token.Value[region.End:]})
+		}
+		return tokens
+	}
+	return []chroma.Token{token}
+}
+
+var (
+	// fenceOpening matches the opening line of a fenced code block and
+	// its info string
+	fenceOpening = regexp.MustCompile("^ {0,3}(`{3,}|~{3,})[ \\t]*(.*?)[ \\t]*$")
+	// scriptOrStyle matches HTML comments and script and style start tags
+	scriptOrStyle = regexp.MustCompile(`(?is)<!--.*?-->|<(script|style)\b((?:[^>"']|"[^"]*"|'[^']*')*)>`)
+	// htmlAttribute matches an attribute of a start tag
+	htmlAttribute = regexp.MustCompile(`([\w:-]+)\s*=\s*(?:"([^"]*)"|'([^']*)'|([^\s"'>]+))`)
+	// closingTags matches the end tags of script and style elements
+	closingTags = map[string]*regexp.Regexp{
+		"script": regexp.MustCompile(`(?i)</script\s*>`),
+		"style":  regexp.MustCompile(`(?i)</style\s*>`),
+	}
+)
+
+// FencedCodeRegions finds the fenced code blocks of a Markdown document
+// whose info string names a language, such as "```go". A block without a
+// closing fence runs to the end of the document.
+func FencedCodeRegions(source string) []EmbeddedRegion {
+	var regions []EmbeddedRegion
+	lines := strings.SplitAfter(source, "\n")
+	offset := 0
+	for i := 0; i < len(lines); i++ {
+		match := fenceOpening.FindStringSubmatch(strings.TrimRight(lines[i], "\r\n"))
+		offset += len(lines[i])
+		if match == nil || (match[1][0] == '`' && strings.Contains(match[2], "`")) {
+			continue
+		}
+
+		fence := match[1]
+		start := offset
+		end := len(source)
+		for i+1 < len(lines) {
+			i++
+			line := strings.TrimRight(lines[i], " \t\r\n")
+			if closing := strings.TrimLeft(line, " "); len(line)-len(closing) <= 3 &&
+				strings.HasPrefix(closing, fence) && strings.Trim(closing, fence[:1]) == "" {
+				end = offset
+				offset += len(lines[i])
+				break
+			}
+			offset += len(lines[i])
+		}
+
+		if language := fenceLanguage(match[2]); language != "" && start < end {
+			regions = append(regions, EmbeddedRegion{Start: start, End: end, Language: language})
+		}
+	}
+	return regions
+}
+
+// fenceLanguage returns the language an info string names, such as "js"
+// for "js title=app.js", "{.python}" or "language-js"
+func fenceLanguage(info string) string {
+	fields := strings.Fields(info)
+	if len(fields) == 0 {
+		return ""
+	}
+	language := strings.Trim(fields[0], "{}.")
+	language = strings.TrimPrefix(language, "language-")
+	return strings.ToLower(language)
+}
+
+// ScriptAndStyleRegions finds the contents of the script and style
+// elements of an HTML document, in the language their type or lang
+// attribute names. Scripts of unknown types, such as templates, are left
+// to the HTML lexer.
+func ScriptAndStyleRegions(source string) []EmbeddedRegion {
+	var regions []EmbeddedRegion
+	for offset := 0; offset < len(source); {
+		match := scriptOrStyle.FindStringSubmatchIndex(source[offset:])
+		if match == nil {
+			break
+		}
+		tagEnd := offset + match[1]
+		if match[2] < 0 {
+			// A comment
+			offset = tagEnd
+			continue
+		}
+
+		tag := strings.ToLower(source[offset+match[2] : offset+match[3]])
+		attributes := htmlAttributes(source[offset+match[4] : offset+match[5]])
+		end := len(source)
+		next := len(source)
+		if closing := closingTags[tag].FindStringIndex(source[tagEnd:]); closing != nil {
+			end = tagEnd + closing[0]
+			next = tagEnd + closing[1]
+		}
+		if language := elementLanguage(tag, attributes); language != "" && tagEnd < end {
+			regions = append(regions, EmbeddedRegion{Start: tagEnd, End: end, Language: language})
+		}
+		offset = next
+	}
+	return regions
+}
+
+// htmlAttributes returns the attributes of a start tag by lowercased name
+func htmlAttributes(tag string) map[string]string {
+	attributes := make(map[string]string)
+	for _, match := range htmlAttribute.FindAllStringSubmatch(tag, -1) {
+		attributes[strings.ToLower(match[1])] = match[2] + match[3] + match[4]
+	}
+	return attributes
+}
+
+// elementLanguage returns the language of a script or style element's
+// contents, or "" when it is not code
+func elementLanguage(tag string, attributes map[string]string) string {
+	if lang := attributes["lang"]; lang != "" {
+		return strings.ToLower(lang)
+	}
+	mimeType := strings.ToLower(strings.TrimSpace(attributes["type"]))
+	if tag == "style" {
+		if mimeType == "" || mimeType == "text/css" {
+			return "css"
+		}
+		return ""
+	}
+
+	switch mimeType {
+	case "", "module", "text/javascript", "application/javascript", "text/ecmascript", "application/ecmascript":
+		return "javascript"
+	case "importmap", "speculationrules", "application/json", "application/ld+json":
+		return "json"
+	case "text/typescript", "application/typescript":
+		return "typescript"
+	}
+	if lexer := lexers.MatchMimeType(mimeType); lexer != nil {
+		return languageName(lexer)
+	}
+	return ""
+}
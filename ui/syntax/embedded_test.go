@@ -0,0 +1,211 @@
+package syntax
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/alecthomas/chroma"
+	"github.com/kenelite/goeditor/backend"
+)
+
+// highlightTokens lexes source as language with embedded languages and
+// checks that the tokens cover the source exactly
+func highlightTokens(t *testing.T, lm *LanguageManager, language, source string) []chroma.Token {
+	t.Helper()
+	iterator, err := lm.GetHighlightLexer(language).Tokenise(&chroma.TokeniseOptions{State: "root"}, source)
+	if err != nil {
+		t.Fatalf("Failed to tokenise: %v", err)
+	}
+	var tokens []chroma.Token
+	var text strings.Builder
+	for token := iterator(); token != chroma.EOF; token = iterator() {
+		tokens = append(tokens, token)
+		text.WriteString(token.Value)
+	}
+	if text.String() != source {
+		t.Fatalf("Expected the tokens to cover the source, got %q", text.String())
+	}
+	return tokens
+}
+
+// typeOf returns the type of the first token whose trimmed value is value
+func typeOf(tokens []chroma.Token, value string) (chroma.TokenType, bool) {
+	for _, token := range tokens {
+		if strings.TrimSpace(token.Value) == value {
+			return token.Type, true
+		}
+	}
+	return 0, false
+}
+
+func TestFencedCodeRegions(t *testing.T) {
+	source := "# Title\n\n```go\nfunc main() {}\n```\n\n~~~~ {.python}\nprint(1)\n~~~~\n\n```\nplain\n```\n\n````js title=app.js\nlet x\n```\nstill js\n"
+	regions := FencedCodeRegions(source)
+
+	expected := []struct{ text, language string }{
+		{"func main() {}\n", "go"},
+		{"print(1)\n", "python"},
+		{"let x\n```\nstill js\n", "js"},
+	}
+	if len(regions) != len(expected) {
+		t.Fatalf("Expected %d regions, got %+v", len(expected), regions)
+	}
+	for i, region := range regions {
+		if text := source[region.Start:region.End]; text != expected[i].text || region.Language != expected[i].language {
+			t.Errorf("Expected %s region %q, got %s region %q", expected[i].language, expected[i].text, region.Language, text)
+		}
+	}
+}
+
+func TestScriptAndStyleRegions(t *testing.T) {
+	source := `<html>
+<!-- <script>commented()</script> -->
+<script type="module">import x from "y";</script>
+<script type="application/ld+json">{"a": 1}</script>
+<script type="text/x-template"><div></div></script>
+<STYLE lang='scss'>a { b: c; }</STYLE>
+<style media="print">p {}</style>
+</html>`
+	regions := ScriptAndStyleRegions(source)
+
+	expected := []struct{ text, language string }{
+		{`import x from "y";`, "javascript"},
+		{`{"a": 1}`, "json"},
+		{`a { b: c; }`, "scss"},
+		{`p {}`, "css"},
+	}
+	if len(regions) != len(expected) {
+		t.Fatalf("Expected %d regions, got %+v", len(expected), regions)
+	}
+	for i, region := range regions {
+		if text := source[region.Start:region.End]; text != expected[i].text || region.Language != expected[i].language {
+			t.Errorf("Expected %s region %q, got %s region %q", expected[i].language, expected[i].text, region.Language, text)
+		}
+	}
+}
+
+func TestEmbeddedMarkdownHighlighting(t *testing.T) {
+	lm := NewLanguageManager()
+	tokens := highlightTokens(t, lm, "markdown", "Some *text*\n\n```python\ndef main():\n    pass\n```\n")
+
+	if tokenType, ok := typeOf(tokens, "def"); !ok || !tokenType.InCategory(chroma.Keyword) {
+		t.Errorf("Expected the fence to be highlighted as Python, got %v", tokens)
+	}
+	if tokenType, ok := typeOf(tokens, "pass"); !ok || !tokenType.InCategory(chroma.Keyword) {
+		t.Errorf("Expected the whole fence to be highlighted as Python, got %v", tokens)
+	}
+}
+
+func TestEmbeddedHTMLHighlighting(t *testing.T) {
+	lm := NewLanguageManager()
+	tokens := highlightTokens(t, lm, "html", "<p>hi</p>\n<script type=\"application/json\">{\"key\": true}</script>\n")
+
+	if tokenType, ok := typeOf(tokens, `"key"`); !ok || tokenType != chroma.NameTag {
+		t.Errorf("Expected the script to be highlighted as JSON, got %v", tokens)
+	}
+	if tokenType, ok := typeOf(tokens, "p"); !ok || tokenType != chroma.NameTag {
+		t.Errorf("Expected the HTML to keep its highlighting, got %v", tokens)
+	}
+}
+
+func TestGoHTMLTemplateHighlighting(t *testing.T) {
+	lm := NewLanguageManager()
+	language := lm.DetectLanguageFromFilename("page.gohtml")
+	tokens := highlightTokens(t, lm, language, "<p class=\"x\">{{ .Name }}</p>\n<script>let x = 1</script>\n")
+
+	if tokenType, ok := typeOf(tokens, "p"); !ok || tokenType != chroma.NameTag {
+		t.Errorf("Expected the HTML of a %s file to be highlighted, got %v", language, tokens)
+	}
+	if tokenType, ok := typeOf(tokens, "{{"); !ok || tokenType != chroma.CommentPreproc {
+		t.Errorf("Expected template actions to be highlighted, got %v", tokens)
+	}
+	if tokenType, ok := typeOf(tokens, "let"); !ok || !tokenType.InCategory(chroma.Keyword) {
+		t.Errorf("Expected the script to be highlighted as JavaScript, got %v", tokens)
+	}
+}
+
+func TestEmbeddedGoStrings(t *testing.T) {
+	lm := NewLanguageManager()
+	source := "package main\n\nconst query = `SELECT name FROM users`\nconst page = `<p>{{.Name}}</p>`\nconst plain = `hello`\n"
+	tokens := highlightTokens(t, lm, "go", source)
+
+	if tokenType, ok := typeOf(tokens, "SELECT"); !ok || !tokenType.InCategory(chroma.Keyword) {
+		t.Errorf("Expected SQL in a raw string to be highlighted, got %v", tokens)
+	}
+	if tokenType, ok := typeOf(tokens, "`"); !ok || tokenType != chroma.LiteralString {
+		t.Errorf("Expected the quotes to stay a string, got %v", tokens)
+	}
+	if tokenType, ok := typeOf(tokens, "{{"); !ok || tokenType == chroma.LiteralString {
+		t.Errorf("Expected template actions to stay highlighted, got %v", tokens)
+	}
+	if tokenType, ok := typeOf(tokens, "hello"); !ok || tokenType != chroma.LiteralString {
+		t.Errorf("Expected other strings to stay strings, got %v", tokens)
+	}
+
+	// The host lexer is unchanged
+	if lm.GetLexer("go") == lm.GetHighlightLexer("go") {
+		t.Error("Expected the highlight lexer to combine the Go lexer")
+	}
+	if lm.GetHighlightLexer("json") != lm.GetLexer("json") {
+		t.Error("Expected languages without embedded languages to use their lexer")
+	}
+}
+
+func TestSetEmbeddedLanguages(t *testing.T) {
+	lm := NewLanguageManager()
+	err := lm.SetEmbeddedLanguages([]backend.EmbeddedLanguage{
+		{Host: "go", Language: "sql", Scope: backend.ScopeComments, Pattern: `^//\s*sql:(?P<code>.*)`},
+		{Host: "go", Language: "sql", Pattern: "("},
+		{Host: "go", Language: "nonexistent", Pattern: "x"},
+		{Host: "go", Language: "sql", Scope: "code", Pattern: "x"},
+	})
+	if err == nil {
+		t.Fatal("Expected errors for the invalid rules")
+	}
+	for _, message := range []string{"embedded language 2 (sql in go): invalid pattern", `unknown language "nonexistent"`, "scope must be"} {
+		if !strings.Contains(err.Error(), message) {
+			t.Errorf("Expected %q in %q", message, err)
+		}
+	}
+
+	tokens := highlightTokens(t, lm, "go", "// sql: DELETE FROM t\nconst q = `SELECT 1`\n")
+	if tokenType, ok := typeOf(tokens, "DELETE"); !ok || !tokenType.InCategory(chroma.Keyword) {
+		t.Errorf("Expected SQL in a comment to be highlighted, got %v", tokens)
+	}
+	if _, ok := typeOf(tokens, "SELECT"); ok {
+		t.Errorf("Expected the built-in heuristics to be replaced, got %v", tokens)
+	}
+}
+
+func TestRegisterRegionDetector(t *testing.T) {
+	lm := NewLanguageManager()
+	lm.RegisterRegionDetector("yaml", func(source string) []EmbeddedRegion {
+		start := strings.Index(source, "run: ") + len("run: ")
+		return []EmbeddedRegion{{Start: start, End: strings.Index(source[start:], "\n") + start, Language: "bash"}}
+	})
+
+	tokens := highlightTokens(t, lm, "yaml", "name: build\nrun: echo $HOME\n")
+	if tokenType, ok := typeOf(tokens, "$HOME"); !ok || tokenType != chroma.NameVariable {
+		t.Errorf("Expected the detected region to be highlighted as Bash, got %v", tokens)
+	}
+}
+
+func TestIncrementalEmbeddedHighlighting(t *testing.T) {
+	text := "# Notes\n\n```go\nfunc a() {}\n```\n"
+	h := NewIncrementalHighlighter("markdown")
+	h.Update(text)
+
+	edited := strings.Replace(text, "func a", "func b", 1)
+	h.Update(edited)
+	fresh := NewIncrementalHighlighter("markdown")
+	fresh.Update(edited)
+	for i := 0; i < fresh.LineCount(); i++ {
+		if !reflect.DeepEqual(h.LineTokens(i), fresh.LineTokens(i)) {
+			t.Errorf("Line %d: expected %v, got %v", i, fresh.LineTokens(i), h.LineTokens(i))
+		}
+	}
+	if tokenType, ok := typeOf(h.LineTokens(3), "func"); !ok || !tokenType.InCategory(chroma.Keyword) {
+		t.Errorf("Expected the fence to be highlighted as Go, got %v", h.LineTokens(3))
+	}
+}
@@ -41,10 +41,7 @@ func chromaToRGBA(c chroma.Colour) color.Color {
 	if !c.IsSet() {
 		return color.Black
 	}
-	r := uint8((c >> 16) & 0xFF)
-	g := uint8((c >> 8) & 0xFF)
-	b := uint8(c & 0xFF)
-	return color.RGBA{R: r, G: g, B: b, A: 255}
+	return color.RGBA{R: c.Red(), G: c.Green(), B: c.Blue(), A: 255}
 }
 
 // HighlightCode highlights source code for any supported language
@@ -52,37 +49,45 @@ func HighlightCode(source string, language string) []widget.RichTextSegment {
 	return HighlightCodeWithTheme(source, language, "")
 }
 
-// HighlightCodeWithTheme highlights source code with a specific theme
+// HighlightCodeWithTheme highlights source code with a specific theme. The
+// current theme is used when themeName is empty or unknown; it is not
+// changed.
 func HighlightCodeWithTheme(source string, language string, themeName string) []widget.RichTextSegment {
 	initManagers()
+
+	style := themeManager.GetTheme()
+	if themeName != "" {
+		if named, exists := themeManager.GetThemeStyle(themeName); exists {
+			style = named
+		} else {
+			log.Printf("Theme '%s' not found, using the current theme", themeName)
+		}
+	}
+	return HighlightCodeWithStyle(source, language, style)
+}
+
+// HighlightCodeWithStyle highlights source code with a chroma style, such
+// as one built from a theme being edited
+func HighlightCodeWithStyle(source string, language string, style *chroma.Style) []widget.RichTextSegment {
+	initManagers()
 	
 	// Handle empty source
 	if source == "" {
 		return []widget.RichTextSegment{}
 	}
 
-	// Get lexer for the language
-	lexer := languageManager.GetLexer(language)
+	// Get lexer for the language and the languages embedded in it
+	lexer := languageManager.GetHighlightLexer(language)
 	if lexer == nil {
 		log.Printf("No lexer found for language: %s", language)
-		return createPlainTextSegments(source)
-	}
-
-	// Get theme
-	var style *chroma.Style
-	if themeName != "" {
-		// Try to use specific theme
-		if err := themeManager.SetTheme(themeName); err != nil {
-			log.Printf("Failed to set theme %s: %v", themeName, err)
-		}
+		return plainTextSegments(source, style)
 	}
-	style = themeManager.GetTheme()
 
 	// Tokenize the source code
 	iterator, err := lexer.Tokenise(nil, source)
 	if err != nil {
 		log.Printf("Tokenization error for language %s: %v", language, err)
-		return createPlainTextSegments(source)
+		return plainTextSegments(source, style)
 	}
 
 	// Convert tokens to segments
@@ -92,37 +97,47 @@ func HighlightCodeWithTheme(source string, language string, themeName string) []
 			continue
 		}
 
-		// Get color for this token type
-		tokenStyle := style.Get(token.Type)
-		var col color.Color
-		if tokenStyle.Colour.IsSet() {
-			col = chromaToRGBA(tokenStyle.Colour)
-		} else {
-			// Use default foreground color
-			col = themeManager.GetForegroundColor()
-		}
-
-		seg := NewSyntaxSegment(token.Value, col)
-		segments = append(segments, seg)
+		segments = append(segments, tokenSegment(style, token))
 	}
 
 	// If no segments were created, return plain text
 	if len(segments) == 0 {
-		return createPlainTextSegments(source)
+		return plainTextSegments(source, style)
 	}
 
 	return segments
 }
 
-// createPlainTextSegments creates plain text segments as fallback
-func createPlainTextSegments(source string) []widget.RichTextSegment {
-	initManagers()
-	col := themeManager.GetForegroundColor()
+// tokenSegment creates the segment for one token, colored by style
+func tokenSegment(style *chroma.Style, token chroma.Token) widget.RichTextSegment {
+	// Get color for this token type
+	tokenStyle := style.Get(token.Type)
+	var col color.Color
+	if tokenStyle.Colour.IsSet() {
+		col = chromaToRGBA(tokenStyle.Colour)
+	} else {
+		// Use the style's foreground color
+		col = themeManager.styleForeground(style)
+	}
+
+	return NewSyntaxSegment(token.Value, col)
+}
+
+// plainTextSegments creates a single segment in the foreground color of
+// style
+func plainTextSegments(source string, style *chroma.Style) []widget.RichTextSegment {
 	return []widget.RichTextSegment{
-		NewSyntaxSegment(source, col),
+		NewSyntaxSegment(source, themeManager.styleForeground(style)),
 	}
 }
 
+// createPlainTextSegments creates plain text segments in the current theme
+// as fallback
+func createPlainTextSegments(source string) []widget.RichTextSegment {
+	initManagers()
+	return plainTextSegments(source, themeManager.GetTheme())
+}
+
 // HighlightGoCode maintains backward compatibility
 func HighlightGoCode(source string) []widget.RichTextSegment {
 	return HighlightCode(source, "go")
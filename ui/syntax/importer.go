@@ -0,0 +1,572 @@
+package syntax
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/alecthomas/chroma"
+)
+
+// scopeMappings maps chroma token types onto TextMate scopes, most wanted
+// scope first. A theme rule applies to a token type when its selector is
+// one of the scopes or a parent of one, such as "keyword" for
+// "keyword.control".
+var scopeMappings = []struct {
+	tokenType chroma.TokenType
+	scopes    []string
+}{
+	{chroma.Comment, []string{"comment"}},
+	{chroma.CommentSingle, []string{"comment.line"}},
+	{chroma.CommentMultiline, []string{"comment.block"}},
+	{chroma.CommentPreproc, []string{"meta.preprocessor", "keyword.control.directive"}},
+	{chroma.Keyword, []string{"keyword.control", "keyword"}},
+	{chroma.KeywordConstant, []string{"constant.language"}},
+	{chroma.KeywordDeclaration, []string{"storage.type", "storage"}},
+	{chroma.KeywordNamespace, []string{"keyword.control.import", "keyword.other.import"}},
+	{chroma.KeywordReserved, []string{"storage.modifier"}},
+	{chroma.KeywordType, []string{"support.type", "entity.name.type", "storage.type"}},
+	{chroma.Name, []string{"variable"}},
+	{chroma.NameAttribute, []string{"entity.other.attribute-name"}},
+	{chroma.NameBuiltin, []string{"support.function.builtin", "support.function"}},
+	{chroma.NameBuiltinPseudo, []string{"variable.language"}},
+	{chroma.NameClass, []string{"entity.name.class", "entity.name.type"}},
+	{chroma.NameConstant, []string{"variable.other.constant", "constant.other"}},
+	{chroma.NameDecorator, []string{"entity.name.function.decorator", "meta.decorator"}},
+	{chroma.NameException, []string{"entity.name.exception", "support.class"}},
+	{chroma.NameFunction, []string{"entity.name.function", "support.function"}},
+	{chroma.NameNamespace, []string{"entity.name.namespace", "entity.name.package"}},
+	{chroma.NameTag, []string{"entity.name.tag"}},
+	{chroma.NameVariable, []string{"variable.other", "variable"}},
+	{chroma.Literal, []string{"constant"}},
+	{chroma.LiteralString, []string{"string"}},
+	{chroma.LiteralStringChar, []string{"constant.character", "string.quoted.single"}},
+	{chroma.LiteralStringDoc, []string{"comment.block.documentation", "string.quoted.docstring"}},
+	{chroma.LiteralStringEscape, []string{"constant.character.escape"}},
+	{chroma.LiteralStringRegex, []string{"string.regexp"}},
+	{chroma.LiteralNumber, []string{"constant.numeric"}},
+	{chroma.Operator, []string{"keyword.operator"}},
+	{chroma.OperatorWord, []string{"keyword.operator.word", "keyword.operator"}},
+	{chroma.Punctuation, []string{"punctuation"}},
+	{chroma.GenericDeleted, []string{"markup.deleted"}},
+	{chroma.GenericEmph, []string{"markup.italic"}},
+	{chroma.GenericHeading, []string{"markup.heading"}},
+	{chroma.GenericInserted, []string{"markup.inserted"}},
+	{chroma.GenericStrong, []string{"markup.bold"}},
+	{chroma.GenericSubheading, []string{"markup.heading"}},
+	{chroma.Error, []string{"invalid"}},
+}
+
+// scopeRule is one scoped rule of an imported theme
+type scopeRule struct {
+	scopes     []string
+	foreground string
+	fontStyle  string
+}
+
+// importedTheme is what the VS Code and TextMate formats have in common
+type importedTheme struct {
+	name       string
+	background string
+	foreground string
+	selection  string
+	lineNumber string
+	cursor     string
+	rules      []scopeRule
+}
+
+// importedThemeSuffix is added to the names of imported themes that are
+// named like a built-in theme
+const importedThemeSuffix = " (imported)"
+
+// ImportThemeFile imports a VS Code color theme (.json) or a TextMate theme
+// (.tmTheme) as a theme configuration. A theme without a name is named
+// after its file.
+func ImportThemeFile(path string) (*ThemeConfig, error) {
+	var theme *importedTheme
+	var err error
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		theme, err = readVSCodeTheme(path, 0)
+	case ".tmtheme", ".plist", ".xml":
+		var data []byte
+		if data, err = os.ReadFile(path); err != nil {
+			return nil, fmt.Errorf("failed to read theme file: %w", err)
+		}
+		theme, err = parseTextMateTheme(data)
+	default:
+		return nil, fmt.Errorf("unsupported theme file %q, expected .json or .tmTheme", filepath.Base(path))
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if theme.name == "" {
+		theme.name = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	}
+	return theme.toThemeConfig()
+}
+
+// ImportVSCodeTheme converts a VS Code color theme into a theme
+// configuration. Comments and trailing commas are allowed, as in VS Code.
+func ImportVSCodeTheme(data []byte) (*ThemeConfig, error) {
+	theme, err := parseVSCodeTheme(data)
+	if err != nil {
+		return nil, err
+	}
+	return theme.toThemeConfig()
+}
+
+// ImportTextMateTheme converts a TextMate .tmTheme property list into a
+// theme configuration
+func ImportTextMateTheme(data []byte) (*ThemeConfig, error) {
+	theme, err := parseTextMateTheme(data)
+	if err != nil {
+		return nil, err
+	}
+	return theme.toThemeConfig()
+}
+
+// vscodeTheme is the JSON layout of a VS Code color theme
+type vscodeTheme struct {
+	Name        string            `json:"name"`
+	Include     string            `json:"include"`
+	Colors      map[string]string `json:"colors"`
+	TokenColors json.RawMessage   `json:"tokenColors"`
+}
+
+// vscodeTokenColor is one rule of a VS Code color theme
+type vscodeTokenColor struct {
+	Scope    interface{} `json:"scope"`
+	Settings struct {
+		Foreground string `json:"foreground"`
+		Background string `json:"background"`
+		FontStyle  string `json:"fontStyle"`
+	} `json:"settings"`
+}
+
+// readVSCodeTheme reads a VS Code theme file, merging in the theme it
+// includes
+func readVSCodeTheme(path string, depth int) (*importedTheme, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read theme file: %w", err)
+	}
+	theme, err := parseVSCodeTheme(data)
+	if err != nil {
+		return nil, err
+	}
+	if theme.include == "" {
+		return &theme.importedTheme, nil
+	}
+	if depth >= 8 {
+		return nil, fmt.Errorf("too many nested includes in %s", filepath.Base(path))
+	}
+
+	base, err := readVSCodeTheme(filepath.Join(filepath.Dir(path), theme.include), depth+1)
+	if err != nil {
+		return nil, fmt.Errorf("failed to import included theme %s: %w", theme.include, err)
+	}
+	return base.merge(&theme.importedTheme), nil
+}
+
+// vscodeImport is a parsed VS Code theme with the file it includes
+type vscodeImport struct {
+	importedTheme
+	include string
+}
+
+// parseVSCodeTheme parses the JSON of a VS Code color theme
+func parseVSCodeTheme(data []byte) (*vscodeImport, error) {
+	var raw vscodeTheme
+	if err := json.Unmarshal(stripJSONComments(data), &raw); err != nil {
+		return nil, fmt.Errorf("failed to parse VS Code theme: %w", err)
+	}
+
+	theme := &vscodeImport{include: raw.Include}
+	theme.name = raw.Name
+	theme.background = raw.Colors["editor.background"]
+	theme.foreground = raw.Colors["editor.foreground"]
+	theme.selection = raw.Colors["editor.selectionBackground"]
+	theme.lineNumber = raw.Colors["editorLineNumber.foreground"]
+	theme.cursor = raw.Colors["editorCursor.foreground"]
+
+	if len(raw.TokenColors) == 0 {
+		return theme, nil
+	}
+	var tokenColors []vscodeTokenColor
+	if err := json.Unmarshal(raw.TokenColors, &tokenColors); err != nil {
+		// Some themes keep their token colors in a separate file
+		var path string
+		if json.Unmarshal(raw.TokenColors, &path) == nil {
+			return nil, fmt.Errorf("token colors in a separate file (%s) are not supported", path)
+		}
+		return nil, fmt.Errorf("failed to parse token colors: %w", err)
+	}
+
+	for _, tokenColor := range tokenColors {
+		settings := tokenColor.Settings
+		scopes := scopeList(tokenColor.Scope)
+		if len(scopes) == 0 {
+			// Rules without a scope hold the global colors
+			theme.foreground = firstNonEmpty(theme.foreground, settings.Foreground)
+			theme.background = firstNonEmpty(theme.background, settings.Background)
+			continue
+		}
+		theme.rules = append(theme.rules, scopeRule{scopes: scopes, foreground: settings.Foreground, fontStyle: settings.FontStyle})
+	}
+	return theme, nil
+}
+
+// scopeList reads the scope of a rule, given either as a string of comma
+// separated selectors or as a list of selectors
+func scopeList(scope interface{}) []string {
+	var selectors []string
+	switch scope := scope.(type) {
+	case string:
+		selectors = strings.Split(scope, ",")
+	case []interface{}:
+		for _, selector := range scope {
+			if selector, ok := selector.(string); ok {
+				selectors = append(selectors, strings.Split(selector, ",")...)
+			}
+		}
+	}
+
+	scopes := make([]string, 0, len(selectors))
+	for _, selector := range selectors {
+		if selector = strings.TrimSpace(selector); selector != "" {
+			scopes = append(scopes, selector)
+		}
+	}
+	return scopes
+}
+
+// jsonCommentPattern matches strings, which are kept, and comments
+var jsonCommentPattern = regexp.MustCompile(`"(?:[^"\\]|\\.)*"|//[^\n]*|/\*(?s:.*?)\*/`)
+
+// jsonStringPattern matches a JSON string
+var jsonStringPattern = regexp.MustCompile(`"(?:[^"\\]|\\.)*"`)
+
+// trailingCommaPattern matches a comma before a closing bracket
+var trailingCommaPattern = regexp.MustCompile(`,(\s*[}\]])`)
+
+// stripJSONComments removes comments and trailing commas from JSON with
+// comments, as VS Code writes it
+func stripJSONComments(data []byte) []byte {
+	data = jsonCommentPattern.ReplaceAllFunc(data, func(match []byte) []byte {
+		if match[0] == '"' {
+			return match
+		}
+		// Keep line breaks so that error offsets still point at the right line
+		return bytes.Repeat([]byte("\n"), bytes.Count(match, []byte("\n")))
+	})
+
+	// Trailing commas are only removed outside strings
+	var out bytes.Buffer
+	last := 0
+	for _, loc := range jsonStringPattern.FindAllIndex(data, -1) {
+		out.Write(trailingCommaPattern.ReplaceAll(data[last:loc[0]], []byte("$1")))
+		out.Write(data[loc[0]:loc[1]])
+		last = loc[1]
+	}
+	out.Write(trailingCommaPattern.ReplaceAll(data[last:], []byte("$1")))
+	return out.Bytes()
+}
+
+// parseTextMateTheme parses a TextMate theme property list
+func parseTextMateTheme(data []byte) (*importedTheme, error) {
+	value, err := parsePlist(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse TextMate theme: %w", err)
+	}
+	root, ok := value.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("failed to parse TextMate theme: expected a dictionary at the top level")
+	}
+	settings, ok := root["settings"].([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("failed to parse TextMate theme: missing settings array")
+	}
+
+	theme := &importedTheme{}
+	theme.name, _ = root["name"].(string)
+	for _, item := range settings {
+		entry, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		values, _ := entry["settings"].(map[string]interface{})
+		get := func(key string) string {
+			value, _ := values[key].(string)
+			return value
+		}
+
+		scope, _ := entry["scope"].(string)
+		scopes := scopeList(scope)
+		if len(scopes) == 0 {
+			// The entry without a scope holds the global colors
+			theme.background = firstNonEmpty(theme.background, get("background"))
+			theme.foreground = firstNonEmpty(theme.foreground, get("foreground"))
+			theme.selection = firstNonEmpty(theme.selection, get("selection"))
+			theme.lineNumber = firstNonEmpty(theme.lineNumber, get("gutterForeground"))
+			theme.cursor = firstNonEmpty(theme.cursor, get("caret"))
+			continue
+		}
+		theme.rules = append(theme.rules, scopeRule{scopes: scopes, foreground: get("foreground"), fontStyle: get("fontStyle")})
+	}
+	return theme, nil
+}
+
+// parsePlist decodes an XML property list into maps, slices, strings and
+// booleans
+func parsePlist(data []byte) (interface{}, error) {
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return nil, err
+		}
+		if start, ok := token.(xml.StartElement); ok && start.Name.Local != "plist" {
+			return decodePlistValue(decoder, start)
+		}
+	}
+}
+
+// decodePlistValue decodes the value starting at start
+func decodePlistValue(decoder *xml.Decoder, start xml.StartElement) (interface{}, error) {
+	switch start.Name.Local {
+	case "dict", "array":
+		dict := make(map[string]interface{})
+		var array []interface{}
+		key := ""
+		for {
+			token, err := decoder.Token()
+			if err != nil {
+				return nil, err
+			}
+			switch token := token.(type) {
+			case xml.StartElement:
+				if token.Name.Local == "key" {
+					if err := decoder.DecodeElement(&key, &token); err != nil {
+						return nil, err
+					}
+					continue
+				}
+				value, err := decodePlistValue(decoder, token)
+				if err != nil {
+					return nil, err
+				}
+				if start.Name.Local == "dict" {
+					dict[key] = value
+				} else {
+					array = append(array, value)
+				}
+			case xml.EndElement:
+				if start.Name.Local == "dict" {
+					return dict, nil
+				}
+				return array, nil
+			}
+		}
+	case "true", "false":
+		if err := decoder.Skip(); err != nil {
+			return nil, err
+		}
+		return start.Name.Local == "true", nil
+	default:
+		var text string
+		if err := decoder.DecodeElement(&text, &start); err != nil {
+			return nil, err
+		}
+		return strings.TrimSpace(text), nil
+	}
+}
+
+// merge returns the theme with the colors and rules of other laid over it
+func (theme *importedTheme) merge(other *importedTheme) *importedTheme {
+	merged := *theme
+	merged.name = firstNonEmpty(other.name, theme.name)
+	merged.background = firstNonEmpty(other.background, theme.background)
+	merged.foreground = firstNonEmpty(other.foreground, theme.foreground)
+	merged.selection = firstNonEmpty(other.selection, theme.selection)
+	merged.lineNumber = firstNonEmpty(other.lineNumber, theme.lineNumber)
+	merged.cursor = firstNonEmpty(other.cursor, theme.cursor)
+	merged.rules = append(append([]scopeRule{}, theme.rules...), other.rules...)
+	return &merged
+}
+
+// toThemeConfig maps the theme's scopes onto chroma token types. A theme
+// named like a built-in theme, as many ports of Monokai or Dracula are, is
+// renamed with importedThemeSuffix, since custom themes cannot replace
+// built-in ones.
+func (theme *importedTheme) toThemeConfig() (*ThemeConfig, error) {
+	background := normalizeColor(theme.background)
+	config := &ThemeConfig{
+		Name:        theme.name,
+		Background:  background,
+		Foreground:  blendColor(theme.foreground, background),
+		Selection:   blendColor(theme.selection, background),
+		LineNumber:  blendColor(theme.lineNumber, background),
+		Cursor:      blendColor(theme.cursor, background),
+		TokenColors: make(map[string]string),
+	}
+
+	chosen := make(map[chroma.TokenType]int)
+	for _, mapping := range scopeMappings {
+		rule := theme.bestRule(mapping.scopes)
+		if rule < 0 {
+			continue
+		}
+		chosen[mapping.tokenType] = rule
+
+		// Token types inherit from their category, so only differences
+		// need an entry
+		parents := []chroma.TokenType{mapping.tokenType.SubCategory(), mapping.tokenType.Category()}
+		inherited := false
+		for _, parent := range parents {
+			if parent == mapping.tokenType {
+				continue
+			}
+			if parentRule, exists := chosen[parent]; exists {
+				inherited = parentRule == rule
+				break
+			}
+		}
+		if inherited {
+			continue
+		}
+
+		if entry := theme.rules[rule].styleEntry(background); entry != "" {
+			config.TokenColors[mapping.tokenType.String()] = entry
+		}
+	}
+
+	if config.Name == "" {
+		return nil, fmt.Errorf("theme has no name")
+	}
+	if isBuiltinTheme(config.Name) {
+		config.Name += importedThemeSuffix
+	}
+	if _, err := config.ToStyle(); err != nil {
+		return nil, fmt.Errorf("imported theme is invalid: %w", err)
+	}
+	return config, nil
+}
+
+// bestRule returns the index of the rule with the most specific selector
+// for the first of scopes that any rule applies to, or -1. Later rules win
+// over earlier ones of the same specificity, as in TextMate.
+func (theme *importedTheme) bestRule(scopes []string) int {
+	for _, scope := range scopes {
+		best, bestLength := -1, 0
+		for i, rule := range theme.rules {
+			for _, selector := range rule.scopes {
+				if strings.Contains(selector, " ") {
+					// Descendant and exclusion selectors are not supported
+					continue
+				}
+				if (scope == selector || strings.HasPrefix(scope, selector+".")) && len(selector) >= bestLength {
+					best, bestLength = i, len(selector)
+				}
+			}
+		}
+		if best >= 0 {
+			return best
+		}
+	}
+	return -1
+}
+
+// styleEntry converts the rule into a chroma style entry, blending a
+// translucent foreground over the theme background
+func (rule scopeRule) styleEntry(background string) string {
+	var parts []string
+	if color := blendColor(rule.foreground, background); color != "" {
+		parts = append(parts, color)
+	}
+	for _, style := range strings.Fields(rule.fontStyle) {
+		switch style {
+		case "bold", "italic", "underline":
+			parts = append(parts, style)
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+// normalizeColor converts #rgb, #rgba and #rrggbbaa colors to #rrggbb,
+// dropping the alpha channel. Invalid and fully transparent colors become
+// empty.
+func normalizeColor(value string) string {
+	color, alpha, ok := parseColor(value)
+	if !ok || alpha == 0 {
+		return ""
+	}
+	return fmt.Sprintf("#%02x%02x%02x", color[0], color[1], color[2])
+}
+
+// blendColor converts value like normalizeColor, but blends a translucent
+// color over background first, so that a selection such as #ffffff20 keeps
+// the tint it has in the editor instead of turning opaque white. Without a
+// valid background the alpha channel is dropped.
+func blendColor(value, background string) string {
+	color, alpha, ok := parseColor(value)
+	if !ok || alpha == 0 {
+		return ""
+	}
+	if base, _, ok := parseColor(background); ok && alpha < 0xff {
+		for i := range color {
+			color[i] = uint8((int(color[i])*int(alpha) + int(base[i])*(0xff-int(alpha)) + 0x7f) / 0xff)
+		}
+	}
+	return fmt.Sprintf("#%02x%02x%02x", color[0], color[1], color[2])
+}
+
+// parseColor parses a #rgb, #rgba, #rrggbb or #rrggbbaa color into its
+// red, green and blue channels and its alpha
+func parseColor(value string) (color [3]uint8, alpha uint8, ok bool) {
+	value = strings.ToLower(strings.TrimSpace(value))
+	if !strings.HasPrefix(value, "#") {
+		return color, 0, false
+	}
+	hex := value[1:]
+	if len(hex) == 3 || len(hex) == 4 {
+		expanded := ""
+		for _, digit := range hex {
+			expanded += string(digit) + string(digit)
+		}
+		hex = expanded
+	}
+	if len(hex) == 6 {
+		hex += "ff"
+	}
+	if len(hex) != 8 {
+		return color, 0, false
+	}
+
+	var channels [4]uint8
+	for i := range channels {
+		n, err := strconv.ParseUint(hex[2*i:2*i+2], 16, 8)
+		if err != nil {
+			return color, 0, false
+		}
+		channels[i] = uint8(n)
+	}
+	return [3]uint8{channels[0], channels[1], channels[2]}, channels[3], true
+}
+
+// firstNonEmpty returns the first of values that is not empty
+func firstNonEmpty(values ...string) string {
+	for _, value := range values {
+		if value != "" {
+			return value
+		}
+	}
+	return ""
+}
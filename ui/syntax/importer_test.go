@@ -0,0 +1,213 @@
+package syntax
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/alecthomas/chroma"
+)
+
+const vscodeThemeSource = `{
+	// A theme as VS Code writes it
+	"name": "Harbor Dark",
+	"type": "dark",
+	"colors": {
+		"editor.background": "#1e1e1e",
+		"editor.foreground": "#d4d4d4",
+		"editor.selectionBackground": "#264f78cc",
+		"editorLineNumber.foreground": "#858585",
+		"editorCursor.foreground": "#aeafad",
+	},
+	"tokenColors": [
+		{"scope": ["comment", "punctuation.definition.comment"], "settings": {"foreground": "#6A9955", "fontStyle": "italic"}},
+		{"scope": "keyword, storage.type", "settings": {"foreground": "#569cd6"}},
+		{"scope": "keyword.control", "settings": {"foreground": "#c586c0", "fontStyle": "bold underline"}},
+		{"scope": "string", "settings": {"foreground": "#ce9178"}},
+		{"scope": "entity.name.function", "settings": {"foreground": "#dcdcaa"}},
+		{"scope": "constant.numeric", "settings": {"foreground": "#b5cea8"}},
+		{"scope": "source.js string", "settings": {"foreground": "#ff0000"}}, /* descendant selectors are skipped */
+	],
+}`
+
+const textMateThemeSource = `<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE plist PUBLIC "-//Apple//DTD PLIST 1.0//EN" "http://www.apple.com/DTDs/PropertyList-1.0.dtd">
+<plist version="1.0">
+<dict>
+	<key>name</key>
+	<string>Quiet Light</string>
+	<key>settings</key>
+	<array>
+		<dict>
+			<key>settings</key>
+			<dict>
+				<key>background</key>
+				<string>#F5F5F5</string>
+				<key>foreground</key>
+				<string>#333333</string>
+				<key>caret</key>
+				<string>#54494B</string>
+				<key>selection</key>
+				<string>#C9D0D9</string>
+				<key>gutterForeground</key>
+				<string>#AAA</string>
+			</dict>
+		</dict>
+		<dict>
+			<key>name</key>
+			<string>Comments</string>
+			<key>scope</key>
+			<string>comment, punctuation.definition.comment</string>
+			<key>settings</key>
+			<dict>
+				<key>fontStyle</key>
+				<string>italic</string>
+				<key>foreground</key>
+				<string>#AAAAAA</string>
+			</dict>
+		</dict>
+		<dict>
+			<key>scope</key>
+			<string>keyword</string>
+			<key>settings</key>
+			<dict>
+				<key>foreground</key>
+				<string>#4B83CD</string>
+			</dict>
+		</dict>
+	</array>
+</dict>
+</plist>`
+
+func TestImportVSCodeTheme(t *testing.T) {
+	config, err := ImportVSCodeTheme([]byte(vscodeThemeSource))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if config.Name != "Harbor Dark" || config.Background != "#1e1e1e" || config.Foreground != "#d4d4d4" {
+		t.Errorf("Unexpected editor colors %+v", config)
+	}
+	if config.Selection != "#244566" || config.LineNumber != "#858585" || config.Cursor != "#aeafad" {
+		t.Errorf("Unexpected UI colors %+v", config)
+	}
+
+	expected := map[string]string{
+		"Comment":            "#6a9955 italic",
+		"Keyword":            "#c586c0 bold underline",
+		"KeywordDeclaration": "#569cd6",
+		"LiteralString":      "#ce9178",
+		"NameFunction":       "#dcdcaa",
+		"LiteralNumber":      "#b5cea8",
+	}
+	for name, entry := range expected {
+		if config.TokenColors[name] != entry {
+			t.Errorf("Expected %s to be %q, got %q", name, entry, config.TokenColors[name])
+		}
+	}
+	if _, exists := config.TokenColors["CommentSingle"]; exists {
+		t.Error("Expected token types to inherit the same rule from their category")
+	}
+
+	style, err := config.ToStyle()
+	if err != nil {
+		t.Fatalf("Expected a valid style: %v", err)
+	}
+	if comment := style.Get(chroma.CommentSingle); comment.Colour.String() != "#6a9955" || comment.Italic != chroma.Yes {
+		t.Errorf("Unexpected comment style %s", comment)
+	}
+
+	// Translucent colors are blended over the background, not made opaque
+	config, err = ImportVSCodeTheme([]byte(`{
+		"name": "Faint",
+		"colors": {"editor.background": "#1e1e1e", "editor.selectionBackground": "#ffffff20", "editorCursor.foreground": "#ffffff00"},
+		"tokenColors": [{"scope": "comment", "settings": {"foreground": "#ffffff80"}}]
+	}`))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if config.Selection != "#3a3a3a" || config.Cursor != "" {
+		t.Errorf("Expected the selection to be blended and the cursor dropped, got %+v", config)
+	}
+	if config.TokenColors["Comment"] != "#8f8f8f" {
+		t.Errorf("Expected a blended comment color, got %q", config.TokenColors["Comment"])
+	}
+
+	if _, err := ImportVSCodeTheme([]byte(`{"name": "x", "tokenColors": "./tokens.json"}`)); err == nil || !strings.Contains(err.Error(), "separate file") {
+		t.Errorf("Expected token colors in another file to be reported, got %v", err)
+	}
+	if _, err := ImportVSCodeTheme([]byte(`{"name": `)); err == nil {
+		t.Error("Expected an error for invalid JSON")
+	}
+}
+
+func TestImportTextMateTheme(t *testing.T) {
+	config, err := ImportTextMateTheme([]byte(textMateThemeSource))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if config.Name != "Quiet Light" || config.Background != "#f5f5f5" || config.Cursor != "#54494b" || config.LineNumber != "#aaaaaa" {
+		t.Errorf("Unexpected editor colors %+v", config)
+	}
+	if config.TokenColors["Comment"] != "#aaaaaa italic" || config.TokenColors["Keyword"] != "#4b83cd" {
+		t.Errorf("Unexpected token colors %v", config.TokenColors)
+	}
+
+	if _, err := ImportTextMateTheme([]byte("<plist><array></array></plist>")); err == nil {
+		t.Error("Expected an error for a property list that is not a theme")
+	}
+}
+
+func TestImportThemeFile(t *testing.T) {
+	dir := t.TempDir()
+	base := `{"colors": {"editor.background": "#000000"}, "tokenColors": [{"scope": "string", "settings": {"foreground": "#00ff00"}}]}`
+	derived := `{"include": "./base.json", "tokenColors": [{"scope": "keyword", "settings": {"foreground": "#ff0000"}}]}`
+	os.WriteFile(filepath.Join(dir, "base.json"), []byte(base), 0644)
+	os.WriteFile(filepath.Join(dir, "derived-theme.json"), []byte(derived), 0644)
+
+	config, err := ImportThemeFile(filepath.Join(dir, "derived-theme.json"))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if config.Name != "derived-theme" || config.Background != "#000000" {
+		t.Errorf("Expected the name of the file and the included colors, got %+v", config)
+	}
+	if config.TokenColors["LiteralString"] != "#00ff00" || config.TokenColors["Keyword"] != "#ff0000" {
+		t.Errorf("Expected rules of both files, got %v", config.TokenColors)
+	}
+
+	if _, err := ImportThemeFile(filepath.Join(dir, "theme.css")); err == nil {
+		t.Error("Expected an error for an unsupported file type")
+	}
+
+	// Imported themes can be persisted and loaded again
+	tm := NewThemeManager()
+	themesDir := filepath.Join(dir, "themes")
+	path, err := tm.SaveCustomTheme(themesDir, config, false)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if filepath.Base(path) != "derived-theme.json" {
+		t.Errorf("Unexpected theme file %s", path)
+	}
+	reloaded := NewThemeManager()
+	if err := reloaded.LoadCustomThemes(themesDir); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := reloaded.SetTheme("derived-theme"); err != nil {
+		t.Errorf("Expected the saved theme to load: %v", err)
+	}
+
+	// A theme named like a built-in one is imported under another name
+	os.WriteFile(filepath.Join(dir, "monokai.json"), []byte(`{"name": "Monokai", "colors": {"editor.background": "#272822"}}`), 0644)
+	config, err = ImportThemeFile(filepath.Join(dir, "monokai.json"))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if config.Name != "Monokai (imported)" {
+		t.Errorf("Expected the theme to be renamed, got %q", config.Name)
+	}
+	if _, err := tm.SaveCustomTheme(themesDir, config, false); err != nil {
+		t.Errorf("Expected the renamed theme to be saved: %v", err)
+	}
+}
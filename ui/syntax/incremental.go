@@ -0,0 +1,430 @@
+package syntax
+
+import (
+	"context"
+	"reflect"
+	"strings"
+
+	"fyne.io/fyne/v2/widget"
+	"github.com/alecthomas/chroma"
+)
+
+const (
+	// syncLines is how many consecutive lines must lex exactly as before
+	// for the lexer state to count as converged
+	syncLines = 2
+	// cancelCheckLines is how many lines are lexed between checks for
+	// cancellation
+	cancelCheckLines = 256
+	// lexWindowLines is how many lines after an edit the lexer state may
+	// converge in at first. The window doubles until it does.
+	lexWindowLines = 64
+	// lexMarginLines is how many lines past the window the lexer sees, so
+	// that tokens spanning lines, such as block comments, are recognized
+	// when they end up to this far after it
+	lexMarginLines = 128
+)
+
+// relexResult tells how lexing a window of lines ended
+type relexResult int
+
+const (
+	relexDone           relexResult = iota // The highlighting is complete
+	relexRestartEarlier                    // The lexer was not in the root state at the start line
+	relexNeedsMoreLines                    // The window ended before the lexer state converged
+)
+
+// LineRange is a range of 0-based line numbers, End exclusive
+type LineRange struct {
+	Start int
+	End   int
+}
+
+// lineState is the lexer state at the start of a line. chroma does not
+// expose its state stack, so the state is described by the token carried
+// over from the previous line, and confirmed by re-lexing neighbouring
+// lines before trusting it.
+type lineState struct {
+	continued bool             // A token of the previous line goes on here
+	tokenType chroma.TokenType // The type of that token
+}
+
+// highlightedLine is the cached highlighting of one line
+type highlightedLine struct {
+	text   string         // The line, including its line break
+	state  lineState      // Lexer state at the start of the line
+	tokens []chroma.Token // Token runs, split at the line break
+}
+
+// IncrementalHighlighter keeps the highlighting of one document and,
+// after an edit, re-lexes only from the first changed line until the lexer
+// state converges with the cached lines again
+type IncrementalHighlighter struct {
+	language string
+	lexer    chroma.Lexer
+	lines    []highlightedLine
+}
+
+// NewIncrementalHighlighter creates a highlighter for an empty document
+// in language
+func NewIncrementalHighlighter(language string) *IncrementalHighlighter {
+	initManagers()
+	h := &IncrementalHighlighter{}
+	h.SetLanguage(language)
+	return h
+}
+
+// SetLanguage switches the lexer and highlights the document again from
+// scratch
+func (h *IncrementalHighlighter) SetLanguage(language string) {
+	h.language = language
+	h.lexer = languageManager.GetHighlightLexer(language)
+
+	text := h.Text()
+	h.lines = nil
+	h.Update(text)
+}
+
+// GetLanguage returns the language the document is lexed as
+func (h *IncrementalHighlighter) GetLanguage() string {
+	return h.language
+}
+
+// Text returns the highlighted text
+func (h *IncrementalHighlighter) Text() string {
+	var builder strings.Builder
+	for _, line := range h.lines {
+		builder.WriteString(line.text)
+	}
+	return builder.String()
+}
+
+// LineCount returns the number of lines, counting the one after a final
+// line break
+func (h *IncrementalHighlighter) LineCount() int {
+	return len(h.lines)
+}
+
+// LineTokens returns the token runs of a 0-based line
+func (h *IncrementalHighlighter) LineTokens(line int) []chroma.Token {
+	return h.Snapshot().LineTokens(line)
+}
+
+// LineSegments returns the segments of a 0-based line in the current
+// theme. The last segment includes the line break.
+func (h *IncrementalHighlighter) LineSegments(line int) []widget.RichTextSegment {
+	return h.Snapshot().LineSegments(line)
+}
+
+// Segments returns the segments of the whole document in the current theme
+func (h *IncrementalHighlighter) Segments() []widget.RichTextSegment {
+	return h.Snapshot().Segments()
+}
+
+// Snapshot returns the current highlighting. Updates never modify cached
+// lines in place, so the snapshot stays valid while the highlighter goes on.
+func (h *IncrementalHighlighter) Snapshot() *Highlighting {
+	return &Highlighting{lines: h.lines}
+}
+
+// Highlighting is a read-only snapshot of a document's highlighting
+type Highlighting struct {
+	lines []highlightedLine
+}
+
+// LineCount returns the number of lines
+func (hl *Highlighting) LineCount() int {
+	return len(hl.lines)
+}
+
+// LineTokens returns the token runs of a 0-based line
+func (hl *Highlighting) LineTokens(line int) []chroma.Token {
+	if line < 0 || line >= len(hl.lines) {
+		return nil
+	}
+	return hl.lines[line].tokens
+}
+
+// LineText returns the text of a 0-based line without its line break
+func (hl *Highlighting) LineText(line int) string {
+	if line < 0 || line >= len(hl.lines) {
+		return ""
+	}
+	return strings.TrimSuffix(hl.lines[line].text, "\n")
+}
+
+// LineSegments returns the segments of a 0-based line in the current
+// theme. The last segment includes the line break.
+func (hl *Highlighting) LineSegments(line int) []widget.RichTextSegment {
+	initManagers()
+	style := themeManager.GetTheme()
+	segments := make([]widget.RichTextSegment, 0)
+	for _, token := range hl.LineTokens(line) {
+		segments = append(segments, tokenSegment(style, token))
+	}
+	return segments
+}
+
+// Segments returns the segments of the whole document in the current theme
+func (hl *Highlighting) Segments() []widget.RichTextSegment {
+	segments := make([]widget.RichTextSegment, 0)
+	for i := range hl.lines {
+		segments = append(segments, hl.LineSegments(i)...)
+	}
+	return segments
+}
+
+// Update re-highlights the document for its new text and returns the
+// ranges of lines, numbered in the new text, whose text or highlighting
+// changed. Lines after an inserted or deleted line keep their highlighting
+// and are not reported, although they moved.
+func (h *IncrementalHighlighter) Update(text string) []LineRange {
+	changed, _ := h.UpdateContext(context.Background(), text)
+	return changed
+}
+
+// UpdateContext is Update with cancellation. A cancelled update returns the
+// context's error and leaves the highlighting as it was.
+func (h *IncrementalHighlighter) UpdateContext(ctx context.Context, text string) ([]LineRange, error) {
+	return h.update(ctx, text, LineRange{}, nil)
+}
+
+// update re-highlights the document for its new text. Only a window of
+// lines from the edit on is lexed, doubling until the lexer state
+// converges, so an edit costs time in proportion to the lines it recolors
+// rather than to the document. A token that would only end further than
+// lexMarginLines past where the state converged, such as a block comment
+// opened far above its end, is lexed as if the text ended there. When the
+// window has to grow after it covered the viewport, preview is called once
+// with the highlighting so far, in which lines after the window keep their
+// cached highlighting.
+func (h *IncrementalHighlighter) update(ctx context.Context, text string, viewport LineRange, preview func(*Highlighting, []LineRange)) ([]LineRange, error) {
+	newLines := splitLines(text)
+	prefix, editEnd, shift := h.editRange(newLines)
+	if prefix == len(h.lines) && prefix == len(newLines) {
+		return nil, nil
+	}
+
+	// Restart a few lines before the edit, going further back until the
+	// lines up to the edit lex as they did before
+	back, window := syncLines, lexWindowLines
+	for {
+		start := h.restartLine(prefix - back)
+		end := editEnd + window + lexMarginLines
+		if end > len(newLines) {
+			end = len(newLines)
+		}
+		lines, changed, result, err := h.relex(ctx, text, newLines, start, prefix, editEnd, shift, end)
+		if err != nil {
+			return nil, err
+		}
+
+		switch result {
+		case relexDone:
+			h.lines = lines
+			return changed, nil
+		case relexRestartEarlier:
+			back *= 2
+		case relexNeedsMoreLines:
+			if preview != nil && end >= viewport.End {
+				preview(&Highlighting{lines: append(lines, h.lines[end-shift:]...)}, changed)
+				preview = nil
+			}
+			window *= 2
+		}
+	}
+}
+
+// editRange narrows an edit down to the lines between a common prefix and
+// suffix of the cached and new lines. The edited lines of the new text are
+// prefix up to editEnd, and later lines moved by shift lines.
+func (h *IncrementalHighlighter) editRange(newLines []string) (prefix, editEnd, shift int) {
+	oldLines := h.lines
+	for prefix < len(oldLines) && prefix < len(newLines) && oldLines[prefix].text == newLines[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(oldLines)-prefix && suffix < len(newLines)-prefix &&
+		oldLines[len(oldLines)-1-suffix].text == newLines[len(newLines)-1-suffix] {
+		suffix++
+	}
+	return prefix, len(newLines) - suffix, len(newLines) - len(oldLines)
+}
+
+// restartLine returns the nearest line at or before line that does not
+// continue a token of the line before
+func (h *IncrementalHighlighter) restartLine(line int) int {
+	if line >= len(h.lines) {
+		line = len(h.lines) - 1
+	}
+	for line > 0 && h.lines[line].state.continued {
+		line--
+	}
+	if line < 0 {
+		return 0
+	}
+	return line
+}
+
+// relex lexes newLines from the start line up to the end line in the root
+// state. The lines before prefix are unchanged and must lex exactly as
+// cached, otherwise the lexer was not in the root state at start. Lexing
+// stops once the lines after editEnd match the cached ones shifted by
+// shift lines. The lexer only sees the text up to end, so the margin of
+// lines before end, which it may have lexed differently with the text
+// after them, does not count towards converging.
+func (h *IncrementalHighlighter) relex(ctx context.Context, text string, newLines []string, start, prefix, editEnd, shift, end int) (lines []highlightedLine, changed []LineRange, result relexResult, err error) {
+	oldLines := h.lines
+	lines = make([]highlightedLine, 0, len(newLines))
+	lines = append(lines, oldLines[:start]...)
+
+	offset, length := 0, 0
+	for _, line := range newLines[:start] {
+		offset += len(line)
+	}
+	for _, line := range newLines[start:end] {
+		length += len(line)
+	}
+	lexer := newLineLexer(h.lexer, text[offset:offset+length])
+	trusted := end
+	if end < len(newLines) {
+		trusted = end - lexMarginLines
+	}
+
+	matched := 0
+	for i := start; i < end; i++ {
+		if (i-start)%cancelCheckLines == cancelCheckLines-1 {
+			if err := ctx.Err(); err != nil {
+				return nil, nil, relexDone, err
+			}
+		}
+		line := lexer.next(newLines[i])
+
+		switch {
+		case i < prefix:
+			if sameHighlighting(line, oldLines[i]) {
+				// Keep the cached line, so views can tell it is unchanged
+				line = oldLines[i]
+				break
+			}
+			if start > 0 {
+				return nil, nil, relexRestartEarlier, nil
+			}
+			// The document start is always in the root state
+			changed = addLineRange(changed, i)
+		case i < editEnd:
+			changed = addLineRange(changed, i)
+		default:
+			old := oldLines[i-shift]
+			if !sameHighlighting(line, old) {
+				matched = 0
+				changed = addLineRange(changed, i)
+				break
+			}
+			line = old
+			matched++
+			if matched >= syncLines && i < trusted {
+				// Converged, the rest of the cache is still valid
+				lines = append(lines, line)
+				lines = append(lines, oldLines[i-shift+1:]...)
+				return lines, changed, relexDone, nil
+			}
+		}
+		lines = append(lines, line)
+	}
+	if end < len(newLines) {
+		return lines, changed, relexNeedsMoreLines, nil
+	}
+	return lines, changed, relexDone, nil
+}
+
+// sameHighlighting reports whether two lines have the same text, start
+// state and tokens
+func sameHighlighting(a, b highlightedLine) bool {
+	return a.text == b.text && a.state == b.state && reflect.DeepEqual(a.tokens, b.tokens)
+}
+
+// addLineRange adds a line to the ranges, extending the last range when
+// the line follows it
+func addLineRange(ranges []LineRange, line int) []LineRange {
+	if n := len(ranges); n > 0 && ranges[n-1].End == line {
+		ranges[n-1].End = line + 1
+		return ranges
+	}
+	return append(ranges, LineRange{Start: line, End: line + 1})
+}
+
+// splitLines splits text after each line break, keeping an empty last line
+// after a final line break
+func splitLines(text string) []string {
+	return strings.SplitAfter(text, "\n")
+}
+
+// lineLexer splits the token stream of a lexer into lines
+type lineLexer struct {
+	iterator chroma.Iterator
+	pending  chroma.Token // The rest of a token that spans lines
+	err      bool
+}
+
+// newLineLexer starts lexing source in the root state
+func newLineLexer(lexer chroma.Lexer, source string) *lineLexer {
+	l := &lineLexer{}
+	if lexer == nil {
+		l.err = true
+		return l
+	}
+	// Keep line endings as they are so lines match the source
+	iterator, err := lexer.Tokenise(&chroma.TokeniseOptions{State: "root", EnsureLF: false}, source)
+	if err != nil {
+		l.err = true
+		return l
+	}
+	l.iterator = iterator
+	return l
+}
+
+// next returns the highlighting of the next line, whose text is text
+func (l *lineLexer) next(text string) highlightedLine {
+	line := highlightedLine{text: text}
+	if l.pending.Value != "" {
+		line.state = lineState{continued: true, tokenType: l.pending.Type}
+	}
+
+	rest := len(text)
+	for rest > 0 {
+		token := l.pending
+		l.pending = chroma.Token{}
+		if token.Value == "" {
+			if l.err {
+				token = chroma.Token{Type: chroma.Text, Value: text[len(text)-rest:]}
+			} else if token = l.iterator(); token == chroma.EOF {
+				// Lexers may stop early, the rest is plain text
+				l.err = true
+				continue
+			}
+		}
+		if token.Value == "" {
+			continue
+		}
+
+		if len(token.Value) > rest {
+			l.pending = chroma.Token{Type: token.Type, Value: token.Value[rest:]}
+			token.Value = token.Value[:rest]
+		}
+		rest -= len(token.Value)
+		line.tokens = appendToken(line.tokens, token)
+	}
+	return line
+}
+
+// appendToken adds a token, merging it into the previous one if both are
+// of the same type, so that coalescing does not depend on where lexing
+// started
+func appendToken(tokens []chroma.Token, token chroma.Token) []chroma.Token {
+	if n := len(tokens); n > 0 && tokens[n-1].Type == token.Type {
+		tokens[n-1].Value += token.Value
+		return tokens
+	}
+	return append(tokens, token)
+}
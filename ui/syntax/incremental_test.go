@@ -0,0 +1,170 @@
+package syntax
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/alecthomas/chroma"
+)
+
+const incrementalSource = `package main
+
+func main() {
+	x := 1
+	y := 2 // */
+	z := 3
+	println(x, y, z)
+}
+`
+
+// checkFullHighlighting compares every line with a highlighter that lexed
+// the text from scratch
+func checkFullHighlighting(t *testing.T, h *IncrementalHighlighter) {
+	t.Helper()
+	full := NewIncrementalHighlighter(h.GetLanguage())
+	full.Update(h.Text())
+	if h.LineCount() != full.LineCount() {
+		t.Fatalf("Expected %d lines, got %d", full.LineCount(), h.LineCount())
+	}
+	for i := 0; i < full.LineCount(); i++ {
+		if !reflect.DeepEqual(h.LineTokens(i), full.LineTokens(i)) {
+			t.Errorf("Line %d: expected %v, got %v", i, full.LineTokens(i), h.LineTokens(i))
+		}
+	}
+}
+
+func TestIncrementalHighlighter(t *testing.T) {
+	h := NewIncrementalHighlighter("go")
+	changed := h.Update(incrementalSource)
+	if !reflect.DeepEqual(changed, []LineRange{{Start: 0, End: 8}}) {
+		t.Errorf("Expected every line to change at first, got %v", changed)
+	}
+	if h.Text() != incrementalSource {
+		t.Errorf("Unexpected text %q", h.Text())
+	}
+
+	// Editing one line repaints only that line
+	edited := strings.Replace(incrementalSource, "x := 1", "x := 10", 1)
+	changed = h.Update(edited)
+	if !reflect.DeepEqual(changed, []LineRange{{Start: 3, End: 4}}) {
+		t.Errorf("Expected line 3 to change, got %v", changed)
+	}
+	checkFullHighlighting(t, h)
+
+	if changed := h.Update(edited); changed != nil {
+		t.Errorf("Expected no change for the same text, got %v", changed)
+	}
+}
+
+func TestIncrementalHighlighter_StateChange(t *testing.T) {
+	h := NewIncrementalHighlighter("go")
+	h.Update(incrementalSource)
+
+	// Opening a comment re-lexes the lines up to where it ends
+	commented := strings.Replace(incrementalSource, "\tx := 1", "\t/* x := 1", 1)
+	changed := h.Update(commented)
+	if !reflect.DeepEqual(changed, []LineRange{{Start: 3, End: 5}}) {
+		t.Errorf("Expected lines 3 and 4 to change, got %v", changed)
+	}
+	checkFullHighlighting(t, h)
+
+	// Editing inside the comment restarts before it
+	commented = strings.Replace(commented, "y := 2", "y := 20", 1)
+	changed = h.Update(commented)
+	if !reflect.DeepEqual(changed, []LineRange{{Start: 4, End: 5}}) {
+		t.Errorf("Expected line 4 to change, got %v", changed)
+	}
+	checkFullHighlighting(t, h)
+
+	// Inserting and deleting lines
+	inserted := strings.Replace(commented, "\tz := 3\n", "\tz := 3\n\tw := 4\n\tv := 5\n", 1)
+	changed = h.Update(inserted)
+	if !reflect.DeepEqual(changed, []LineRange{{Start: 6, End: 8}}) {
+		t.Errorf("Expected the inserted lines to change, got %v", changed)
+	}
+	checkFullHighlighting(t, h)
+
+	h.Update(incrementalSource)
+	checkFullHighlighting(t, h)
+	if h.Text() != incrementalSource {
+		t.Errorf("Unexpected text %q", h.Text())
+	}
+}
+
+func TestIncrementalHighlighter_Segments(t *testing.T) {
+	h := NewIncrementalHighlighter("go")
+	h.Update("package main\r\n\r\nvar s = `raw\r\nstring`\r\n")
+
+	var text strings.Builder
+	for _, segment := range h.Segments() {
+		text.WriteString(segment.(*SyntaxSegment).Text)
+	}
+	if text.String() != h.Text() {
+		t.Errorf("Expected the segments to cover the text, got %q", text.String())
+	}
+	if len(h.LineSegments(3)) == 0 || len(h.LineSegments(10)) != 0 {
+		t.Error("Unexpected line segments")
+	}
+
+	h.SetLanguage("python")
+	if h.GetLanguage() != "python" || h.LineCount() != 5 {
+		t.Errorf("Expected the text to be kept for a new language, got %d lines", h.LineCount())
+	}
+}
+
+// recordingLexer records the length of the longest text it lexed
+type recordingLexer struct {
+	chroma.Lexer
+	longest int
+}
+
+func (l *recordingLexer) Tokenise(options *chroma.TokeniseOptions, text string) (chroma.Iterator, error) {
+	if len(text) > l.longest {
+		l.longest = len(text)
+	}
+	return l.Lexer.Tokenise(options, text)
+}
+
+func TestIncrementalHighlighter_BoundedLexing(t *testing.T) {
+	source := "package main\n\nfunc main() {\n" + strings.Repeat("\tx := 1\n", 2597) + "\tx := 1 // */\n" + strings.Repeat("\tx := 1\n", 2402) + "}\n"
+	h := NewIncrementalHighlighter("go")
+	h.Update(source)
+	lexer := &recordingLexer{Lexer: h.lexer}
+	h.lexer = lexer
+
+	// A small edit only lexes the lines around it
+	lines := strings.SplitAfter(source, "\n")
+	lines[2500] = "\ty := 2\n"
+	edited := strings.Join(lines, "")
+	if changed := h.Update(edited); !reflect.DeepEqual(changed, []LineRange{{Start: 2500, End: 2501}}) {
+		t.Errorf("Expected line 2500 to change, got %v", changed)
+	}
+	if lexer.longest > len(source)/20 {
+		t.Errorf("Expected only a window to be lexed, lexed %d of %d bytes", lexer.longest, len(source))
+	}
+	checkFullHighlighting(t, h)
+
+	// Opening a comment recolors the lines up to where it ends, the
+	// viewport first
+	lines[2500] = "\t/* y := 2\n"
+	commented := strings.Join(lines, "")
+	previews := 0
+	changed, err := h.update(context.Background(), commented, LineRange{Start: 2500, End: 2520}, func(preview *Highlighting, changed []LineRange) {
+		previews++
+		if preview.LineCount() != h.LineCount() || len(changed) != 1 || changed[0].Start != 2500 || changed[0].End < 2520 {
+			t.Errorf("Expected a preview covering the viewport, got %v", changed)
+		}
+		if tokens := preview.LineTokens(2510); len(tokens) != 1 || tokens[0].Type != chroma.CommentMultiline {
+			t.Errorf("Expected the viewport to be commented out, got %v", tokens)
+		}
+	})
+	if err != nil || previews != 1 {
+		t.Fatalf("Expected one preview, got %d (%v)", previews, err)
+	}
+	if !reflect.DeepEqual(changed, []LineRange{{Start: 2500, End: 2601}}) {
+		t.Errorf("Expected the commented lines to change, got %v", changed)
+	}
+	checkFullHighlighting(t, h)
+}
@@ -5,38 +5,53 @@ import (
 	"log"
 	"path/filepath"
 	"strings"
+	"sync"
 
 	"github.com/alecthomas/chroma"
 	"github.com/alecthomas/chroma/lexers"
+	"github.com/kenelite/goeditor/backend"
 )
 
-// LanguageManager manages syntax highlighting for different programming languages
+// LanguageManager manages syntax highlighting for different programming
+// languages. Language names are resolved through the language registry,
+// so its definitions decide which lexer highlights a language.
 type LanguageManager struct {
-	lexers map[string]chroma.Lexer
-	cache  map[string]chroma.Lexer
+	mu       sync.RWMutex            // Guards the maps, as highlighting runs in the background
+	lexers   map[string]chroma.Lexer // User lexers by lowercased name and alias
+	cache    map[string]chroma.Lexer
+	registry *backend.FileTypeManager
+
+	// Embedded language highlighting: detectors by host language, the
+	// heuristics, and the combined lexers by language
+	regionDetectors map[string][]RegionDetector
+	embeddingRules  []*embeddingRule
+	highlightCache  map[string]chroma.Lexer
 }
 
 // NewLanguageManager creates a new language manager
 func NewLanguageManager() *LanguageManager {
-	return &LanguageManager{
-		lexers: make(map[string]chroma.Lexer),
-		cache:  make(map[string]chroma.Lexer),
+	lm := &LanguageManager{
+		lexers:          make(map[string]chroma.Lexer),
+		cache:           make(map[string]chroma.Lexer),
+		registry:        backend.GetLanguageRegistry(),
+		regionDetectors: defaultRegionDetectors(),
+		highlightCache:  make(map[string]chroma.Lexer),
 	}
+	lm.SetEmbeddedLanguages(backend.DefaultEmbeddedLanguages())
+	return lm
 }
 
 // GetLexer returns the appropriate lexer for the given language
 func (lm *LanguageManager) GetLexer(language string) chroma.Lexer {
 	// Check cache first
-	if lexer, exists := lm.cache[language]; exists {
+	lm.mu.RLock()
+	lexer, exists := lm.cache[language]
+	lm.mu.RUnlock()
+	if exists {
 		return lexer
 	}
 
-	// Try to get lexer by name
-	lexer := lexers.Get(language)
-	if lexer == nil {
-		// Try common aliases
-		lexer = lm.getLexerByAlias(language)
-	}
+	lexer = lm.lookupLexer(language)
 
 	// Fall back to plain text if no lexer found
 	if lexer == nil {
@@ -45,49 +60,60 @@ func (lm *LanguageManager) GetLexer(language string) chroma.Lexer {
 	}
 
 	// Cache the result
+	lm.mu.Lock()
 	lm.cache[language] = lexer
+	lm.mu.Unlock()
 	return lexer
 }
 
-// getLexerByAlias tries to find a lexer using common aliases
-func (lm *LanguageManager) getLexerByAlias(language string) chroma.Lexer {
-	aliases := map[string]string{
-		"js":         "javascript",
-		"jsx":        "javascript",
-		"ts":         "typescript",
-		"tsx":        "typescript",
-		"py":         "python",
-		"pyw":        "python",
-		"pyi":        "python",
-		"c++":        "cpp",
-		"cxx":        "cpp",
-		"cc":         "cpp",
-		"hpp":        "cpp",
-		"hxx":        "cpp",
-		"h":          "c",
-		"htm":        "html",
-		"yml":        "yaml",
-		"sh":         "bash",
-		"zsh":        "bash",
-		"dockerfile": "docker",
-		"text":       "text",
-		"txt":        "text",
-		"plain":      "text",
-	}
-
-	if alias, exists := aliases[strings.ToLower(language)]; exists {
-		return lexers.Get(alias)
-	}
-
-	return nil
+// lookupLexer returns the lexer of a language name or alias, or nil. User
+// lexers are found by their own names first. The registry maps names such
+// as "dockerfile" or "sh" onto its lexer names; other names are looked up
+// among the user and chroma lexers.
+func (lm *LanguageManager) lookupLexer(language string) chroma.Lexer {
+	lm.mu.RLock()
+	lexer, exists := lm.lexers[strings.ToLower(language)]
+	lm.mu.RUnlock()
+	if exists {
+		return lexer
+	}
+
+	if fileType, exists := lm.registry.GetFileTypeByName(language); exists {
+		if lexer := lm.findLexer(fileType.LexerName); lexer != nil {
+			return lexer
+		}
+	}
+	return lm.findLexer(language)
+}
+
+// findLexer returns the user lexer of a name or alias, or else chroma's
+// lexer of it, so that user lexers can replace built-in ones
+func (lm *LanguageManager) findLexer(name string) chroma.Lexer {
+	lm.mu.RLock()
+	lexer, exists := lm.lexers[strings.ToLower(name)]
+	lm.mu.RUnlock()
+	if exists {
+		return lexer
+	}
+	return lexers.Get(name)
 }
 
 // GetSupportedLanguages returns a list of all supported languages
 func (lm *LanguageManager) GetSupportedLanguages() []string {
 	var languages []string
 	
-	// Get all available lexers from chroma
-	for _, lexer := range lexers.Registry.Lexers {
+	// Get all available lexers from chroma, then the user lexers
+	all := append([]chroma.Lexer{}, lexers.Registry.Lexers...)
+	seen := make(map[chroma.Lexer]bool)
+	lm.mu.RLock()
+	for _, lexer := range lm.lexers {
+		if !seen[lexer] {
+			seen[lexer] = true
+			all = append(all, lexer)
+		}
+	}
+	lm.mu.RUnlock()
+	for _, lexer := range all {
 		if lexer != nil {
 			config := lexer.Config()
 			if config != nil {
@@ -139,67 +165,87 @@ type LanguageInfo struct {
 	Description string   `json:"description"`
 }
 
-// DetectLanguageFromFilename attempts to detect the language from a filename
+// DetectLanguageFromFilename attempts to detect the language from a
+// filename, through the language registry and then the filename patterns
+// of chroma's lexers
 func (lm *LanguageManager) DetectLanguageFromFilename(filename string) string {
-	// Try to match by filename patterns first
+	if fileType, exists := lm.registry.DetectFileTypeByFilename(filename); exists {
+		return fileType.LexerName
+	}
+
+	// Patterns such as "Dockerfile" match the name without its directory
+	base := filepath.Base(filename)
 	for _, lexer := range lexers.Registry.Lexers {
 		if lexer != nil {
 			config := lexer.Config()
 			if config != nil {
 				for _, pattern := range config.Filenames {
-					if matched, _ := filepath.Match(pattern, filename); matched {
-						// Return the first alias if available, otherwise the name
-						if len(config.Aliases) > 0 {
-							return config.Aliases[0]
-						}
-						return strings.ToLower(config.Name)
+					if matched, _ := filepath.Match(pattern, base); matched {
+						return languageName(lexer)
 					}
 				}
 			}
 		}
 	}
+	return "text"
+}
 
-	// Fall back to extension-based detection
-	ext := strings.ToLower(filepath.Ext(filename))
-	switch ext {
-	case ".go":
-		return "go"
-	case ".js", ".jsx", ".mjs":
-		return "javascript"
-	case ".ts", ".tsx":
-		return "typescript"
-	case ".py", ".pyw", ".pyi":
-		return "python"
-	case ".java":
-		return "java"
-	case ".c", ".h":
-		return "c"
-	case ".cpp", ".cxx", ".cc", ".hpp", ".hxx":
-		return "cpp"
-	case ".html", ".htm":
-		return "html"
-	case ".css":
-		return "css"
-	case ".json":
-		return "json"
-	case ".xml", ".xsd", ".xsl":
-		return "xml"
-	case ".yaml", ".yml":
-		return "yaml"
-	case ".md", ".markdown":
-		return "markdown"
-	case ".sh", ".bash", ".zsh":
-		return "bash"
-	case ".sql":
-		return "sql"
-	case ".dockerfile":
-		return "docker"
-	default:
-		return "text"
+// DetectLanguage detects the language of a document from a Vim or Emacs
+// modeline, its filename or the interpreter of its shebang line, through
+// the language registry and then chroma's lexers, or failing those by
+// scoring its content with each lexer's analyser
+func (lm *LanguageManager) DetectLanguage(filename, content string) string {
+	if fileType, ok := lm.registry.DetectFileTypeFromContent(filename, content); ok {
+		return fileType.LexerName
+	}
+	if name := backend.ModelineLanguage(content); name != "" {
+		if lexer := lm.lookupLexer(strings.TrimSuffix(strings.ToLower(name), "-mode")); lexer != nil {
+			return languageName(lexer)
+		}
+	}
+	if filename != "" {
+		if language := lm.DetectLanguageFromFilename(filename); language != "text" {
+			return language
+		}
+	}
+	if interpreter := backend.ShebangInterpreter(content); interpreter != "" {
+		if lexer := lm.lookupLexer(strings.TrimRight(interpreter, "0123456789.-")); lexer != nil {
+			return languageName(lexer)
+		}
+	}
+	if language := lm.AnalyseLanguage(content); language != "" {
+		return language
+	}
+	return "text"
+}
+
+// AnalyseLanguage scores content with each lexer's analyser and returns the
+// language of the best match, or "" when no analyser recognizes it
+func (lm *LanguageManager) AnalyseLanguage(content string) string {
+	if strings.TrimSpace(content) == "" {
+		return ""
+	}
+	lexer := lexers.Analyse(content)
+	if lexer == nil {
+		return ""
+	}
+	return languageName(lexer)
+}
+
+// languageName returns the name a lexer is referred to by: its first alias
+// if it has one, otherwise its lowercased name
+func languageName(lexer chroma.Lexer) string {
+	config := lexer.Config()
+	if len(config.Aliases) > 0 {
+		return config.Aliases[0]
 	}
+	return strings.ToLower(config.Name)
 }
 
 // ClearCache clears the lexer cache
 func (lm *LanguageManager) ClearCache() {
+	lm.mu.Lock()
+	defer lm.mu.Unlock()
 	lm.cache = make(map[string]chroma.Lexer)
+	lm.highlightCache = make(map[string]chroma.Lexer)
 }
\ No newline at end of file
@@ -3,6 +3,9 @@ package syntax
 import (
 	"strings"
 	"testing"
+
+	"github.com/alecthomas/chroma/lexers"
+	"github.com/kenelite/goeditor/backend"
 )
 
 func TestLanguageManager(t *testing.T) {
@@ -110,6 +113,33 @@ func TestDetectLanguageFromFilename(t *testing.T) {
 	}
 }
 
+func TestDetectLanguage(t *testing.T) {
+	lm := NewLanguageManager()
+
+	testCases := []struct {
+		name     string
+		filename string
+		content  string
+		expected string
+	}{
+		{"exact filename in a directory", "/src/app/Dockerfile", "FROM alpine\n", "docker"},
+		{"makefile", "/src/Makefile", "all:\n\tgo build\n", "makefile"},
+		{"shell profile", "/home/user/.bashrc", "export PATH\n", "bash"},
+		{"shebang", "/usr/local/bin/tool", "#!/usr/bin/env python3\nprint('hi')\n", "python"},
+		{"modeline over extension", "notes.txt", "# vim: set ft=yaml :\nkey: value\n", "yaml"},
+		{"analysis", "", "package main\n\nfunc main() { fmt.Println() }\n", "go"},
+		{"nothing to go by", "", "just some words\n", "text"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if detected := lm.DetectLanguage(tc.filename, tc.content); detected != tc.expected {
+				t.Errorf("Expected %s, got %s", tc.expected, detected)
+			}
+		})
+	}
+}
+
 func TestLanguageManagerSupport(t *testing.T) {
 	lm := NewLanguageManager()
 
@@ -194,4 +224,18 @@ func TestGetSupportedLanguages(t *testing.T) {
 			// t.Errorf("Expected to find %s in supported languages", expected)
 		}
 	}
-}
\ No newline at end of file
+}
+
+func TestRegistryLexersExist(t *testing.T) {
+	lm := NewLanguageManager()
+	for _, fileType := range backend.GetLanguageRegistry().GetAllFileTypes() {
+		if lexers.Get(fileType.LexerName) == nil {
+			t.Errorf("No chroma lexer %q for %s", fileType.LexerName, fileType.Name)
+		}
+		for _, alias := range fileType.Aliases {
+			if lm.GetLexer(alias) != lexers.Get(fileType.LexerName) {
+				t.Errorf("Expected alias %q to highlight as %s", alias, fileType.LexerName)
+			}
+		}
+	}
+}
@@ -0,0 +1,98 @@
+package syntax
+
+import (
+	"strings"
+
+	"github.com/alecthomas/chroma"
+)
+
+// TokenKind is the coarse syntactic class of a run of source text
+type TokenKind int
+
+const (
+	TokenCode    TokenKind = iota // Anything that is not a comment or string
+	TokenComment                  // Comments, excluding preprocessor directives
+	TokenString                   // String literals, including their delimiters
+)
+
+// TokenRange is a run of source text of one kind, as byte offsets
+type TokenRange struct {
+	Start int
+	End   int
+	Kind  TokenKind
+}
+
+// ClassifyTokens splits source into comment, string and code ranges using
+// the chroma lexer for language. Adjacent tokens of the same kind are
+// merged. Without a lexer, the whole source is code.
+func ClassifyTokens(source string, language string) []TokenRange {
+	initManagers()
+
+	ranges := make([]TokenRange, 0)
+	if source == "" {
+		return ranges
+	}
+
+	lexer := languageManager.GetLexer(language)
+	if lexer == nil {
+		return append(ranges, TokenRange{Start: 0, End: len(source), Kind: TokenCode})
+	}
+
+	// Keep line endings as they are so offsets match the source
+	iterator, err := lexer.Tokenise(&chroma.TokeniseOptions{State: "root", EnsureLF: false}, source)
+	if err != nil {
+		return append(ranges, TokenRange{Start: 0, End: len(source), Kind: TokenCode})
+	}
+
+	offset := 0
+	for token := iterator(); token != chroma.EOF; token = iterator() {
+		if token.Value == "" || offset >= len(source) {
+			continue
+		}
+
+		end := offset + len(token.Value)
+		if end > len(source) {
+			// Lexers may append a trailing newline
+			end = len(source)
+		}
+
+		kind := tokenKind(token.Type)
+		if kind == TokenComment {
+			// Line comments include their line break, which belongs to the code
+			commentEnd := offset + len(strings.TrimRight(source[offset:end], "\r\n"))
+			ranges = appendTokenRange(ranges, offset, commentEnd, TokenComment)
+			ranges = appendTokenRange(ranges, commentEnd, end, TokenCode)
+		} else {
+			ranges = appendTokenRange(ranges, offset, end, kind)
+		}
+		offset = end
+	}
+
+	return appendTokenRange(ranges, offset, len(source), TokenCode)
+}
+
+// appendTokenRange adds a range, merging it into the previous one if both
+// are of the same kind
+func appendTokenRange(ranges []TokenRange, start, end int, kind TokenKind) []TokenRange {
+	if end <= start {
+		return ranges
+	}
+	if n := len(ranges); n > 0 && ranges[n-1].Kind == kind && ranges[n-1].End == start {
+		ranges[n-1].End = end
+		return ranges
+	}
+	return append(ranges, TokenRange{Start: start, End: end, Kind: kind})
+}
+
+// tokenKind maps a chroma token type to its coarse kind
+func tokenKind(tokenType chroma.TokenType) TokenKind {
+	switch {
+	case tokenType == chroma.CommentPreproc || tokenType == chroma.CommentPreprocFile:
+		return TokenCode
+	case tokenType.InCategory(chroma.Comment):
+		return TokenComment
+	case tokenType.InSubCategory(chroma.LiteralString):
+		return TokenString
+	}
+	return TokenCode
+}
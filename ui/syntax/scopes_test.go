@@ -0,0 +1,59 @@
+package syntax
+
+import (
+	"testing"
+)
+
+func TestClassifyTokens(t *testing.T) {
+	source := "package main\r\n\r\n// greet says hello\r\nfunc greet() string {\r\n\treturn \"hello\" /* inline */\r\n}\r\n"
+
+	ranges := ClassifyTokens(source, "go")
+	if len(ranges) == 0 {
+		t.Fatal("Expected token ranges")
+	}
+
+	// Ranges must tile the source exactly
+	offset := 0
+	for _, r := range ranges {
+		if r.Start != offset || r.End <= r.Start {
+			t.Fatalf("Range %+v does not continue at offset %d", r, offset)
+		}
+		offset = r.End
+	}
+	if offset != len(source) {
+		t.Fatalf("Ranges end at %d, source is %d bytes", offset, len(source))
+	}
+
+	collect := func(kind TokenKind) []string {
+		texts := make([]string, 0)
+		for _, r := range ranges {
+			if r.Kind == kind {
+				texts = append(texts, source[r.Start:r.End])
+			}
+		}
+		return texts
+	}
+
+	comments := collect(TokenComment)
+	if len(comments) != 2 || comments[0] != "// greet says hello" || comments[1] != "/* inline */" {
+		t.Errorf("Unexpected comments %q", comments)
+	}
+	strs := collect(TokenString)
+	if len(strs) != 1 || strs[0] != "\"hello\"" {
+		t.Errorf("Unexpected strings %q", strs)
+	}
+}
+
+func TestClassifyTokens_UnknownLanguage(t *testing.T) {
+	source := "just some text"
+	ranges := ClassifyTokens(source, "no-such-language")
+	if len(ranges) != 1 || ranges[0].Kind != TokenCode || ranges[0].End != len(source) {
+		t.Errorf("Expected a single code range, got %+v", ranges)
+	}
+}
+
+func TestClassifyTokens_Empty(t *testing.T) {
+	if ranges := ClassifyTokens("", "go"); len(ranges) != 0 {
+		t.Errorf("Expected no ranges, got %+v", ranges)
+	}
+}
@@ -0,0 +1,283 @@
+package syntax
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	fyne "fyne.io/fyne/v2"
+	"github.com/alecthomas/chroma"
+)
+
+const (
+	// DefaultHighlightDebounce is how long the service waits for a burst of
+	// edits to end before highlighting
+	DefaultHighlightDebounce = 50 * time.Millisecond
+	// viewportPriorityLines is how many edited lines make the service color
+	// the viewport first, before highlighting the whole document
+	viewportPriorityLines = 200
+)
+
+// HighlightResult is the highlighting of one version of a document
+type HighlightResult struct {
+	Version      int
+	Language     string
+	Changed      []LineRange   // Lines to repaint, numbered in this version
+	Highlighting *Highlighting // The highlighting of this version
+	Provisional  bool          // Only the viewport is highlighted, the full result follows
+}
+
+// highlightJob is a request to highlight one version of a document
+type highlightJob struct {
+	version  int
+	language string
+	text     string
+}
+
+// HighlightService highlights a document in a worker goroutine. Bursts of
+// edits are debounced, a job is cancelled as soon as a newer version of the
+// document is submitted, and results are posted to the UI goroutine.
+type HighlightService struct {
+	onResult func(HighlightResult)
+	post     func(func())
+	debounce time.Duration
+
+	mu       sync.Mutex
+	version  int
+	pending  *highlightJob
+	cancel   context.CancelFunc
+	viewport LineRange
+	closed   bool
+
+	wake chan struct{}
+	quit chan struct{}
+
+	// Only used by the worker
+	highlighter *IncrementalHighlighter
+}
+
+// NewHighlightService starts a highlighting service that calls onResult on
+// the UI goroutine for each highlighted version
+func NewHighlightService(onResult func(HighlightResult)) *HighlightService {
+	return newHighlightService(onResult, fyne.Do, DefaultHighlightDebounce)
+}
+
+// newHighlightService starts a service that posts results with post
+func newHighlightService(onResult func(HighlightResult), post func(func()), debounce time.Duration) *HighlightService {
+	initManagers()
+	s := &HighlightService{
+		onResult: onResult,
+		post:     post,
+		debounce: debounce,
+		wake:     make(chan struct{}, 1),
+		quit:     make(chan struct{}),
+	}
+	go s.run()
+	return s
+}
+
+// Submit asks for a version of the document to be highlighted as language.
+// Versions must increase; an older or repeated version is ignored.
+func (s *HighlightService) Submit(version int, language string, text string) {
+	s.mu.Lock()
+	if s.closed || version <= s.version {
+		s.mu.Unlock()
+		return
+	}
+	s.version = version
+	s.pending = &highlightJob{version: version, language: language, text: text}
+	if s.cancel != nil {
+		// The running job is stale now
+		s.cancel()
+		s.cancel = nil
+	}
+	s.mu.Unlock()
+
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+// SetViewport sets the visible lines, which are colored first after large
+// changes
+func (s *HighlightService) SetViewport(viewport LineRange) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.viewport = viewport
+}
+
+// Close stops the worker and cancels the running job
+func (s *HighlightService) Close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return
+	}
+	s.closed = true
+	if s.cancel != nil {
+		s.cancel()
+		s.cancel = nil
+	}
+	close(s.quit)
+}
+
+// isCurrent reports whether version is the latest submitted version
+func (s *HighlightService) isCurrent(version int) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return !s.closed && version == s.version
+}
+
+// run is the worker loop
+func (s *HighlightService) run() {
+	for {
+		select {
+		case <-s.quit:
+			return
+		case <-s.wake:
+		}
+		if !s.settle() {
+			return
+		}
+
+		ctx, job, viewport := s.takeJob()
+		if job != nil {
+			s.highlight(ctx, job, viewport)
+		}
+	}
+}
+
+// settle waits until no edit arrived for the debounce delay. It returns
+// false when the service was closed meanwhile.
+func (s *HighlightService) settle() bool {
+	timer := time.NewTimer(s.debounce)
+	defer timer.Stop()
+	for {
+		select {
+		case <-s.quit:
+			return false
+		case <-s.wake:
+			timer.Reset(s.debounce)
+		case <-timer.C:
+			return true
+		}
+	}
+}
+
+// takeJob takes the pending job, if any, with a context cancelled once a
+// newer version is submitted
+func (s *HighlightService) takeJob() (context.Context, *highlightJob, LineRange) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	job := s.pending
+	s.pending = nil
+	if job == nil {
+		return nil, nil, s.viewport
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	s.cancel = cancel
+	return ctx, job, s.viewport
+}
+
+// highlight runs one job, posting a provisional result for the viewport
+// first when much of the document needs lexing or recoloring
+func (s *HighlightService) highlight(ctx context.Context, job *highlightJob, viewport LineRange) {
+	if s.highlighter == nil || s.highlighter.GetLanguage() != job.language {
+		s.highlighter = NewIncrementalHighlighter(job.language)
+	}
+
+	if preview, changed := s.highlighter.previewViewport(job.text, viewport); preview != nil {
+		s.publish(HighlightResult{
+			Version:      job.version,
+			Language:     job.language,
+			Changed:      changed,
+			Highlighting: preview,
+			Provisional:  true,
+		})
+	}
+
+	changed, err := s.highlighter.update(ctx, job.text, viewport, func(preview *Highlighting, changed []LineRange) {
+		s.publish(HighlightResult{
+			Version:      job.version,
+			Language:     job.language,
+			Changed:      changed,
+			Highlighting: preview,
+			Provisional:  true,
+		})
+	})
+	if err != nil {
+		return
+	}
+	s.publish(HighlightResult{
+		Version:      job.version,
+		Language:     job.language,
+		Changed:      changed,
+		Highlighting: s.highlighter.Snapshot(),
+	})
+}
+
+// publish posts a result to the UI goroutine, dropping it there if a newer
+// version was submitted meanwhile
+func (s *HighlightService) publish(result HighlightResult) {
+	if !s.isCurrent(result.Version) {
+		return
+	}
+	s.post(func() {
+		if s.isCurrent(result.Version) && s.onResult != nil {
+			s.onResult(result)
+		}
+	})
+}
+
+// previewViewport quickly highlights the visible lines of text when many
+// lines were edited. The viewport is lexed on its own from the root state,
+// which may be off until the whole document has been lexed. Lines outside
+// it keep their cached highlighting when unchanged and are plain otherwise.
+func (h *IncrementalHighlighter) previewViewport(text string, viewport LineRange) (*Highlighting, []LineRange) {
+	newLines := splitLines(text)
+	prefix, editEnd, shift := h.editRange(newLines)
+	if editEnd-prefix <= viewportPriorityLines {
+		return nil, nil
+	}
+
+	start, end := viewport.Start, viewport.End
+	if start < prefix {
+		start = prefix
+	}
+	if end > editEnd {
+		end = editEnd
+	}
+	if start >= end {
+		return nil, nil
+	}
+
+	lines := make([]highlightedLine, len(newLines))
+	for i, line := range newLines {
+		switch {
+		case i < prefix:
+			lines[i] = h.lines[i]
+		case i >= editEnd:
+			lines[i] = h.lines[i-shift]
+		default:
+			lines[i] = highlightedLine{text: line}
+			if line != "" {
+				lines[i].tokens = []chroma.Token{{Type: chroma.Text, Value: line}}
+			}
+		}
+	}
+
+	offset, length := 0, 0
+	for _, line := range newLines[:start] {
+		offset += len(line)
+	}
+	for _, line := range newLines[start:end] {
+		length += len(line)
+	}
+	lexer := newLineLexer(h.lexer, text[offset:offset+length])
+	for i := start; i < end; i++ {
+		lines[i] = lexer.next(newLines[i])
+	}
+	return &Highlighting{lines: lines}, []LineRange{{Start: start, End: end}}
+}
@@ -0,0 +1,95 @@
+package syntax
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newTestService starts a service that posts results straight to a channel
+func newTestService(debounce time.Duration) (*HighlightService, chan HighlightResult) {
+	results := make(chan HighlightResult, 16)
+	post := func(fn func()) { fn() }
+	return newHighlightService(func(result HighlightResult) { results <- result }, post, debounce), results
+}
+
+// nextResult waits for the next posted result
+func nextResult(t *testing.T, results chan HighlightResult) HighlightResult {
+	t.Helper()
+	select {
+	case result := <-results:
+		return result
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timed out waiting for a highlighting result")
+	}
+	return HighlightResult{}
+}
+
+func TestHighlightService_Debounce(t *testing.T) {
+	service, results := newTestService(20 * time.Millisecond)
+	defer service.Close()
+
+	// A burst of edits is highlighted once, for the latest version
+	service.Submit(1, "go", "package main\n")
+	service.Submit(2, "go", "package main\n\nfunc main() {}\n")
+	service.Submit(3, "go", incrementalSource)
+	service.Submit(2, "go", "stale")
+
+	result := nextResult(t, results)
+	if result.Version != 3 || result.Provisional {
+		t.Fatalf("Expected the final result for version 3, got version %d", result.Version)
+	}
+	if result.Highlighting.LineCount() != 9 || len(result.Highlighting.LineTokens(2)) == 0 {
+		t.Errorf("Expected every line to be highlighted, got %d lines", result.Highlighting.LineCount())
+	}
+
+	// A later edit only repaints the edited line
+	service.Submit(4, "go", strings.Replace(incrementalSource, "x := 1", "x := 10", 1))
+	result = nextResult(t, results)
+	if result.Version != 4 || !reflect.DeepEqual(result.Changed, []LineRange{{Start: 3, End: 4}}) {
+		t.Errorf("Expected line 3 to change in version 4, got %d %v", result.Version, result.Changed)
+	}
+
+	// Switching the language highlights everything again
+	service.Submit(5, "python", incrementalSource)
+	result = nextResult(t, results)
+	if result.Language != "python" || !reflect.DeepEqual(result.Changed, []LineRange{{Start: 0, End: 8}}) {
+		t.Errorf("Expected a full repaint for the new language, got %q %v", result.Language, result.Changed)
+	}
+}
+
+func TestHighlightService_ViewportFirst(t *testing.T) {
+	service, results := newTestService(time.Millisecond)
+	defer service.Close()
+
+	source := strings.Repeat("x := `raw`\n", 500)
+	service.SetViewport(LineRange{Start: 100, End: 120})
+	service.Submit(1, "go", source)
+
+	result := nextResult(t, results)
+	if !result.Provisional || !reflect.DeepEqual(result.Changed, []LineRange{{Start: 100, End: 120}}) {
+		t.Fatalf("Expected the viewport first, got %+v", result.Changed)
+	}
+	if len(result.Highlighting.LineTokens(110)) < 2 || len(result.Highlighting.LineTokens(300)) != 1 {
+		t.Errorf("Expected only the viewport to be colored")
+	}
+
+	result = nextResult(t, results)
+	if result.Provisional || result.Highlighting.LineCount() != 501 || len(result.Highlighting.LineTokens(300)) < 2 {
+		t.Errorf("Expected the whole document to follow")
+	}
+}
+
+func TestHighlightService_Close(t *testing.T) {
+	service, results := newTestService(time.Millisecond)
+	service.Close()
+	service.Close()
+
+	service.Submit(1, "go", "package main\n")
+	select {
+	case result := <-results:
+		t.Errorf("Expected no result after closing, got version %d", result.Version)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
@@ -0,0 +1,305 @@
+package syntax
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/alecthomas/chroma"
+	"github.com/alecthomas/chroma/styles"
+)
+
+// ThemeEditorTokens are the token types offered by the theme editor, each
+// category followed by its commonly styled sub-types. Types not listed
+// inherit from their category.
+var ThemeEditorTokens = []chroma.TokenType{
+	chroma.Keyword,
+	chroma.KeywordConstant,
+	chroma.KeywordDeclaration,
+	chroma.KeywordNamespace,
+	chroma.KeywordType,
+	chroma.Name,
+	chroma.NameAttribute,
+	chroma.NameBuiltin,
+	chroma.NameClass,
+	chroma.NameConstant,
+	chroma.NameDecorator,
+	chroma.NameFunction,
+	chroma.NameTag,
+	chroma.NameVariable,
+	chroma.Literal,
+	chroma.LiteralString,
+	chroma.LiteralStringEscape,
+	chroma.LiteralNumber,
+	chroma.Operator,
+	chroma.Punctuation,
+	chroma.Comment,
+	chroma.CommentPreproc,
+	chroma.GenericHeading,
+	chroma.GenericEmph,
+	chroma.GenericStrong,
+	chroma.GenericInserted,
+	chroma.GenericDeleted,
+	chroma.Error,
+}
+
+// PreviewSample is a snippet of code shown in the theme editor's preview
+type PreviewSample struct {
+	Language string
+	Source   string
+}
+
+// PreviewSamples are the snippets the theme editor previews themes with
+var PreviewSamples = []PreviewSample{
+	{"go", `package main
+
+import "fmt"
+
+// Greeter says hello
+type Greeter struct {
+	Name  string
+	Count int
+}
+
+func (g *Greeter) Greet() error {
+	for i := 0; i < g.Count; i++ {
+		fmt.Printf("Hello, %s!\n", g.Name)
+	}
+	return nil
+}
+`},
+	{"python", `import os
+
+@dataclass
+class Greeter:
+    """Says hello."""
+    name: str = "world"
+
+    def greet(self, count=3):
+        for _ in range(count):
+            print(f"Hello, {self.name}!")  # greet
+        return True
+`},
+	{"javascript", `const greet = async (name, count = 3) => {
+  // Say hello a few times
+  for (let i = 0; i < count; i++) {
+    console.log(` + "`Hello, ${name}!`" + `);
+  }
+  return /^h/i.test(name) ? null : undefined;
+};
+`},
+	{"html", `<!DOCTYPE html>
+<html lang="en">
+  <!-- A greeting -->
+  <body class="main">
+    <h1 id="title">Hello, &amp; welcome!</h1>
+  </body>
+</html>
+`},
+	{"css", `/* Headings */
+h1.title, #main > p {
+  color: #336699;
+  margin: 0 auto !important;
+  font-size: 1.5em;
+}
+`},
+	{"markdown", "# Heading\n\nSome *emphasis*, **strong** text and `code`.\n\n- item\n- [link](https://example.com)\n"},
+	{"diff", `--- a/greeter.go
++++ b/greeter.go
+@@ -1,3 +1,3 @@
+-fmt.Println("Hi")
++fmt.Println("Hello")
+`},
+}
+
+// TokenStyle is the part of a token type's style the theme editor changes
+type TokenStyle struct {
+	Color  string         // #rrggbb, or empty to inherit
+	Bold   chroma.Trilean // Yes for "bold", No for "nobold", Pass to inherit
+	Italic chroma.Trilean // Yes for "italic", No for "noitalic", Pass to inherit
+	rest   []string       // Other style entry parts, such as underline
+}
+
+// ParseTokenStyle parses a chroma style entry such as "#ff0000 bold"
+func ParseTokenStyle(entry string) (TokenStyle, error) {
+	var style TokenStyle
+	if _, err := chroma.ParseStyleEntry(entry); err != nil {
+		return style, err
+	}
+	for _, part := range strings.Fields(entry) {
+		switch {
+		case part == "bold":
+			style.Bold = chroma.Yes
+		case part == "nobold":
+			style.Bold = chroma.No
+		case part == "italic":
+			style.Italic = chroma.Yes
+		case part == "noitalic":
+			style.Italic = chroma.No
+		case strings.HasPrefix(part, "#"):
+			style.Color = chroma.ParseColour(part).String()
+		default:
+			style.rest = append(style.rest, part)
+		}
+	}
+	return style, nil
+}
+
+// String returns the token style as a chroma style entry
+func (s TokenStyle) String() string {
+	var parts []string
+	if s.Color != "" {
+		parts = append(parts, s.Color)
+	}
+	if s.Bold != chroma.Pass {
+		parts = append(parts, s.Bold.Prefix("bold"))
+	}
+	if s.Italic != chroma.Pass {
+		parts = append(parts, s.Italic.Prefix("italic"))
+	}
+	parts = append(parts, s.rest...)
+	return strings.Join(parts, " ")
+}
+
+// GetTokenStyle returns the style the theme gives a token type itself.
+// ok is false when the type inherits its style.
+func (config *ThemeConfig) GetTokenStyle(tokenType chroma.TokenType) (style TokenStyle, ok bool) {
+	for name, entry := range config.TokenColors {
+		if t, exists := TokenTypeByName(name); exists && t == tokenType {
+			style, err := ParseTokenStyle(entry)
+			return style, err == nil
+		}
+	}
+	return TokenStyle{}, false
+}
+
+// SetTokenStyle gives a token type its own style. An empty style makes the
+// type inherit again.
+func (config *ThemeConfig) SetTokenStyle(tokenType chroma.TokenType, style TokenStyle) {
+	if config.TokenColors == nil {
+		config.TokenColors = make(map[string]string)
+	}
+	for name := range config.TokenColors {
+		if t, exists := TokenTypeByName(name); exists && t == tokenType {
+			delete(config.TokenColors, name)
+		}
+	}
+	if entry := style.String(); entry != "" {
+		config.TokenColors[tokenType.String()] = entry
+	}
+}
+
+// Copy returns a copy of the theme configuration that can be changed
+// without affecting the original
+func (config *ThemeConfig) Copy() *ThemeConfig {
+	copied := *config
+	copied.TokenColors = make(map[string]string, len(config.TokenColors))
+	for name, entry := range config.TokenColors {
+		copied.TokenColors[name] = entry
+	}
+	return &copied
+}
+
+// GetThemeStyle returns a theme's style by name without making it the
+// current theme
+func (tm *ThemeManager) GetThemeStyle(name string) (*chroma.Style, bool) {
+	tm.mu.RLock()
+	style, exists := tm.themes[name]
+	tm.mu.RUnlock()
+	if exists {
+		return style, true
+	}
+	style, exists = styles.Registry[name]
+	return style, exists
+}
+
+// ThemeConfigFor returns an editable configuration of a theme. Custom
+// themes are copied; built-in themes are described by their editor
+// colors and the styles of ThemeEditorTokens that differ from what they
+// inherit.
+func (tm *ThemeManager) ThemeConfigFor(name string) (*ThemeConfig, error) {
+	tm.mu.RLock()
+	custom, exists := tm.customThemes[name]
+	tm.mu.RUnlock()
+	if exists {
+		return custom.Copy(), nil
+	}
+
+	style, exists := tm.GetThemeStyle(name)
+	if !exists {
+		return nil, fmt.Errorf("theme '%s' not found", name)
+	}
+
+	background := style.Get(chroma.Background)
+	config := &ThemeConfig{
+		Name:        name,
+		Background:  chromaToHex(background.Background),
+		Foreground:  chromaToHex(style.Get(chroma.Text).Colour),
+		Selection:   chromaToHex(style.Get(chroma.LineHighlight).Background),
+		LineNumber:  chromaToHex(style.Get(chroma.LineNumbers).Colour),
+		TokenColors: make(map[string]string),
+	}
+	for _, tokenType := range ThemeEditorTokens {
+		entry := styleEntryFor(style.Get(tokenType), background)
+		if entry == styleEntryFor(style.Get(inheritedFrom(tokenType)), background) {
+			continue
+		}
+		if entry != "" {
+			config.TokenColors[tokenType.String()] = entry
+		}
+	}
+	return config, nil
+}
+
+// inheritedFrom returns the token type a token type inherits its style
+// from
+func inheritedFrom(tokenType chroma.TokenType) chroma.TokenType {
+	if sub := tokenType.SubCategory(); sub != tokenType {
+		return sub
+	}
+	if category := tokenType.Category(); category != tokenType {
+		return category
+	}
+	return chroma.Text
+}
+
+// styleEntryFor writes a resolved style entry in the style entry syntax,
+// leaving out the background when it is the theme's
+func styleEntryFor(entry, background chroma.StyleEntry) string {
+	var parts []string
+	if entry.Colour.IsSet() {
+		parts = append(parts, entry.Colour.String())
+	}
+	if entry.Background.IsSet() && entry.Background != background.Background {
+		parts = append(parts, "bg:"+entry.Background.String())
+	}
+	if entry.Bold != chroma.Pass {
+		parts = append(parts, entry.Bold.Prefix("bold"))
+	}
+	if entry.Italic != chroma.Pass {
+		parts = append(parts, entry.Italic.Prefix("italic"))
+	}
+	if entry.Underline != chroma.Pass {
+		parts = append(parts, entry.Underline.Prefix("underline"))
+	}
+	return strings.Join(parts, " ")
+}
+
+// TokenAt returns the token covering a 0-based line and rune column. At
+// the end of a line the token before the column is returned.
+func (hl *Highlighting) TokenAt(line, column int) (chroma.Token, bool) {
+	var last chroma.Token
+	found := false
+	offset := 0
+	for _, token := range hl.LineTokens(line) {
+		length := utf8.RuneCountInString(strings.TrimSuffix(token.Value, "\n"))
+		if column < offset+length {
+			return token, true
+		}
+		if length > 0 {
+			last, found = token, true
+		}
+		offset += length
+	}
+	return last, found
+}
@@ -0,0 +1,133 @@
+package syntax
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/alecthomas/chroma"
+)
+
+func TestTokenStyle(t *testing.T) {
+	style, err := ParseTokenStyle("#FF0000 bold underline")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if style.Color != "#ff0000" || style.Bold != chroma.Yes || style.Italic != chroma.Pass {
+		t.Errorf("Unexpected token style %+v", style)
+	}
+	style.Bold = chroma.Pass
+	style.Italic = chroma.Yes
+	if entry := style.String(); entry != "#ff0000 italic underline" {
+		t.Errorf("Expected other parts of the entry to be kept, got %q", entry)
+	}
+
+	// Turning bold or italic off is kept apart from inheriting them
+	for _, entry := range []string{"nobold noitalic", "#00ff00 nobold italic", "bold noitalic"} {
+		style, err := ParseTokenStyle(entry)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if style.String() != entry {
+			t.Errorf("Expected %q to round-trip, got %q", entry, style.String())
+		}
+	}
+	if _, err := ParseTokenStyle("#ff0000 shiny"); err == nil {
+		t.Error("Expected an error for an invalid style entry")
+	}
+
+	config := &ThemeConfig{Name: "edited", TokenColors: map[string]string{"function": "#00ff00"}}
+	if style, ok := config.GetTokenStyle(chroma.NameFunction); !ok || style.Color != "#00ff00" {
+		t.Errorf("Expected the style of an alias, got %+v", style)
+	}
+	config.SetTokenStyle(chroma.NameFunction, TokenStyle{Color: "#0000ff", Bold: chroma.Yes})
+	if len(config.TokenColors) != 1 || config.TokenColors["NameFunction"] != "#0000ff bold" {
+		t.Errorf("Expected the alias to be replaced, got %v", config.TokenColors)
+	}
+	config.SetTokenStyle(chroma.NameFunction, TokenStyle{})
+	if _, ok := config.GetTokenStyle(chroma.NameFunction); ok {
+		t.Error("Expected an empty style to inherit")
+	}
+}
+
+func TestThemeConfigFor(t *testing.T) {
+	tm := NewThemeManager()
+
+	config, err := tm.ThemeConfigFor("monokai")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if config.Background != "#272822" || config.Foreground != "#f8f8f2" {
+		t.Errorf("Unexpected editor colors %+v", config)
+	}
+	if config.TokenColors["Keyword"] != "#66d9ef" {
+		t.Errorf("Expected the keyword color, got %v", config.TokenColors)
+	}
+	if _, exists := config.TokenColors["KeywordType"]; exists {
+		t.Error("Expected token types that inherit their style to be left out")
+	}
+
+	// The configuration describes the same highlighting as the theme
+	original, _ := tm.GetThemeStyle("monokai")
+	config.Name = "monokai-copy"
+	copied, err := config.ToStyle()
+	if err != nil {
+		t.Fatalf("Expected a valid style: %v", err)
+	}
+	for _, tokenType := range ThemeEditorTokens {
+		if original.Get(tokenType).Colour != copied.Get(tokenType).Colour {
+			t.Errorf("Expected the same color for %s", tokenType)
+		}
+	}
+
+	tm.RegisterCustomTheme(&ThemeConfig{Name: "custom", TokenColors: map[string]string{"Keyword": "#123456"}})
+	custom, err := tm.ThemeConfigFor("custom")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	custom.TokenColors["Keyword"] = "#654321"
+	if tm.GetCustomThemes()["custom"].TokenColors["Keyword"] != "#123456" {
+		t.Error("Expected editing the configuration not to change the registered theme")
+	}
+
+	if _, err := tm.ThemeConfigFor("missing"); err == nil {
+		t.Error("Expected an error for an unknown theme")
+	}
+}
+
+func TestHighlightCodeWithThemeKeepsCurrentTheme(t *testing.T) {
+	SetTheme("github")
+	segments := HighlightCodeWithTheme("x := 1", "go", "monokai")
+	if len(segments) == 0 {
+		t.Fatal("Expected segments")
+	}
+	if GetCurrentTheme() != "github" {
+		t.Errorf("Expected the current theme to be kept, got %s", GetCurrentTheme())
+	}
+}
+
+func TestHighlightingTokenAt(t *testing.T) {
+	h := NewIncrementalHighlighter("go")
+	h.Update("package main\n\nfunc héllo() {}\n")
+	hl := h.Snapshot()
+
+	cases := []struct {
+		line, column int
+		value        string
+		tokenType    chroma.TokenType
+	}{
+		{0, 0, "package", chroma.KeywordNamespace},
+		{2, 5, "héllo", chroma.NameFunction},
+		{2, 9, "héllo", chroma.NameFunction},
+		{2, 14, "{}", chroma.Punctuation},
+		{2, 40, "{}", chroma.Punctuation},
+	}
+	for _, c := range cases {
+		token, ok := hl.TokenAt(c.line, c.column)
+		if !ok || strings.TrimSpace(token.Value) != c.value || token.Type != c.tokenType {
+			t.Errorf("Expected %s %q at %d:%d, got %s %q", c.tokenType, c.value, c.line, c.column, token.Type, token.Value)
+		}
+	}
+	if _, ok := hl.TokenAt(1, 0); ok {
+		t.Error("Expected no token on an empty line")
+	}
+}
@@ -3,17 +3,24 @@ package syntax
 import (
 	"fmt"
 	"image/color"
-	"log"
+	"strings"
+	"sync"
 
 	"github.com/alecthomas/chroma"
 	"github.com/alecthomas/chroma/styles"
 )
 
+// defaultThemeName is the theme used until another is chosen, and in place
+// of a custom theme that is removed while in use
+const defaultThemeName = "github"
+
 // ThemeManager manages syntax highlighting themes
 type ThemeManager struct {
+	mu           sync.RWMutex // Guards the fields, as highlighting runs in the background
 	currentTheme string
 	themes       map[string]*chroma.Style
 	customThemes map[string]*ThemeConfig
+	themeFiles   map[string]string // Theme names by the file they were loaded from
 }
 
 // ThemeConfig represents a custom theme configuration
@@ -23,15 +30,17 @@ type ThemeConfig struct {
 	Foreground  string            `json:"foreground"`
 	Selection   string            `json:"selection"`
 	LineNumber  string            `json:"lineNumber"`
+	Cursor      string            `json:"cursor,omitempty"`
 	TokenColors map[string]string `json:"tokenColors"`
 }
 
 // NewThemeManager creates a new theme manager
 func NewThemeManager() *ThemeManager {
 	tm := &ThemeManager{
-		currentTheme: "github",
+		currentTheme: defaultThemeName,
 		themes:       make(map[string]*chroma.Style),
 		customThemes: make(map[string]*ThemeConfig),
+		themeFiles:   make(map[string]string),
 	}
 	
 	// Load default themes
@@ -64,6 +73,9 @@ func (tm *ThemeManager) loadDefaultThemes() {
 
 // GetTheme returns the current theme
 func (tm *ThemeManager) GetTheme() *chroma.Style {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+
 	if theme, exists := tm.themes[tm.currentTheme]; exists {
 		return theme
 	}
@@ -74,13 +86,16 @@ func (tm *ThemeManager) GetTheme() *chroma.Style {
 		return style
 	}
 	
-	// Fall back to default
-	log.Printf("Theme '%s' not found, using fallback", tm.currentTheme)
+	// SetTheme only selects known themes and removing a custom theme
+	// resets the current one, so this is not reached
 	return styles.Fallback
 }
 
 // SetTheme sets the current theme
 func (tm *ThemeManager) SetTheme(themeName string) error {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+
 	// Check if theme exists
 	if _, exists := tm.themes[themeName]; exists {
 		tm.currentTheme = themeName
@@ -99,11 +114,16 @@ func (tm *ThemeManager) SetTheme(themeName string) error {
 
 // GetCurrentThemeName returns the name of the current theme
 func (tm *ThemeManager) GetCurrentThemeName() string {
+	tm.mu.RLock()
+	defer tm.mu.RUnlock()
 	return tm.currentTheme
 }
 
 // GetAvailableThemes returns a list of available theme names
 func (tm *ThemeManager) GetAvailableThemes() []string {
+	tm.mu.RLock()
+	defer tm.mu.RUnlock()
+
 	var themes []string
 	
 	// Add cached themes
@@ -130,7 +150,12 @@ func (tm *ThemeManager) GetAvailableThemes() []string {
 
 // GetThemeInfo returns information about a theme
 func (tm *ThemeManager) GetThemeInfo(themeName string) *ThemeInfo {
-	style := styles.Get(themeName)
+	tm.mu.RLock()
+	style, exists := tm.themes[themeName]
+	tm.mu.RUnlock()
+	if !exists {
+		style = styles.Registry[themeName]
+	}
 	if style == nil {
 		return nil
 	}
@@ -165,9 +190,9 @@ func (tm *ThemeManager) isThemeDark(style *chroma.Style) bool {
 	}
 
 	// Convert to RGB and check brightness
-	r := uint8((bg.Background >> 16) & 0xFF)
-	g := uint8((bg.Background >> 8) & 0xFF)
-	b := uint8(bg.Background & 0xFF)
+	r := bg.Background.Red()
+	g := bg.Background.Green()
+	b := bg.Background.Blue()
 	
 	// Calculate perceived brightness
 	brightness := (0.299*float64(r) + 0.587*float64(g) + 0.114*float64(b)) / 255.0
@@ -180,7 +205,7 @@ func chromaToHex(c chroma.Colour) string {
 	if !c.IsSet() {
 		return ""
 	}
-	return fmt.Sprintf("#%06x", uint32(c)&0xFFFFFF)
+	return c.String()
 }
 
 // GetTokenColor returns the color for a specific token type in the current theme
@@ -216,7 +241,11 @@ func (tm *ThemeManager) GetBackgroundColor() color.Color {
 
 // GetForegroundColor returns the foreground color of the current theme
 func (tm *ThemeManager) GetForegroundColor() color.Color {
-	style := tm.GetTheme()
+	return tm.styleForeground(tm.GetTheme())
+}
+
+// styleForeground returns the foreground color of a style
+func (tm *ThemeManager) styleForeground(style *chroma.Style) color.Color {
 	if style == nil {
 		return color.Black
 	}
@@ -234,23 +263,101 @@ func (tm *ThemeManager) GetForegroundColor() color.Color {
 	return color.Black
 }
 
+// GetSelectionColor returns the selection color of the current theme, or
+// false when the theme defines none
+func (tm *ThemeManager) GetSelectionColor() (color.Color, bool) {
+	highlight := tm.GetTheme().Get(chroma.LineHighlight)
+	if !highlight.Background.IsSet() {
+		return nil, false
+	}
+	return chromaToRGBA(highlight.Background), true
+}
+
+// GetLineNumberColor returns the line number color of the current theme,
+// or false when the theme defines none
+func (tm *ThemeManager) GetLineNumberColor() (color.Color, bool) {
+	lineNumbers := tm.GetTheme().Get(chroma.LineNumbers)
+	if !lineNumbers.Colour.IsSet() {
+		return nil, false
+	}
+	return chromaToRGBA(lineNumbers.Colour), true
+}
+
+// GetCursorColor returns the cursor color of the current theme, which only
+// custom themes define
+func (tm *ThemeManager) GetCursorColor() (color.Color, bool) {
+	tm.mu.RLock()
+	config, exists := tm.customThemes[tm.currentTheme]
+	tm.mu.RUnlock()
+	if !exists || config.Cursor == "" {
+		return nil, false
+	}
+	return chromaToRGBA(chroma.ParseColour(config.Cursor)), true
+}
+
 // RegisterCustomTheme registers a custom theme
 func (tm *ThemeManager) RegisterCustomTheme(config *ThemeConfig) error {
-	if config.Name == "" {
-		return fmt.Errorf("theme name cannot be empty")
+	if err := checkThemeName(config.Name); err != nil {
+		return err
+	}
+	style, err := config.ToStyle()
+	if err != nil {
+		return fmt.Errorf("invalid theme %q: %w", config.Name, err)
 	}
 
+	tm.mu.Lock()
 	tm.customThemes[config.Name] = config
-	
-	// TODO: Convert ThemeConfig to chroma.Style and register it
-	// This would require more complex implementation to create a chroma.Style from config
+	tm.themes[config.Name] = style
+	tm.mu.Unlock()
 	
 	return nil
 }
 
-// GetCustomThemes returns all registered custom themes
+// checkThemeName reports why a custom theme cannot have a name
+func checkThemeName(name string) error {
+	if name == "" {
+		return fmt.Errorf("theme name cannot be empty")
+	}
+	if isBuiltinTheme(name) {
+		return fmt.Errorf("theme name %q is taken by a built-in theme", name)
+	}
+	return nil
+}
+
+// isBuiltinTheme reports whether chroma has a theme of the name, ignoring
+// case
+func isBuiltinTheme(name string) bool {
+	for builtin := range styles.Registry {
+		if strings.EqualFold(builtin, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// removeCustomTheme forgets a custom theme, switching to the default theme
+// if it is the current one. The caller holds the lock.
+func (tm *ThemeManager) removeCustomTheme(name string) {
+	if _, exists := tm.customThemes[name]; !exists {
+		return
+	}
+	delete(tm.customThemes, name)
+	delete(tm.themes, name)
+	if tm.currentTheme == name {
+		tm.currentTheme = defaultThemeName
+	}
+}
+
+// GetCustomThemes returns a copy of all registered custom themes
 func (tm *ThemeManager) GetCustomThemes() map[string]*ThemeConfig {
-	return tm.customThemes
+	tm.mu.RLock()
+	defer tm.mu.RUnlock()
+
+	customThemes := make(map[string]*ThemeConfig, len(tm.customThemes))
+	for name, config := range tm.customThemes {
+		customThemes[name] = config
+	}
+	return customThemes
 }
 
 // IsDarkTheme returns true if the current theme is dark
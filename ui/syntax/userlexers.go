@@ -0,0 +1,590 @@
+package syntax
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/alecthomas/chroma"
+	"github.com/alecthomas/chroma/lexers"
+	"github.com/dlclark/regexp2"
+	"github.com/kenelite/goeditor/backend"
+)
+
+// LexerDefinition declares a regex lexer, as read from a user lexer file.
+// Its rules are a state machine in chroma's style: each state lists rules
+// tried in order, and the lexer starts in the "root" state.
+type LexerDefinition struct {
+	Name      string   `json:"name"`
+	Aliases   []string `json:"aliases,omitempty"`
+	Filenames []string `json:"filenames,omitempty"`
+	MimeTypes []string `json:"mimeTypes,omitempty"`
+
+	// Regex flags: case-insensitive matching, "." matching newlines, and
+	// "^" and "$" matching at the start and end of the text only
+	CaseInsensitive bool `json:"caseInsensitive,omitempty"`
+	DotAll          bool `json:"dotAll,omitempty"`
+	NotMultiline    bool `json:"notMultiline,omitempty"`
+
+	// EnsureNewline ends the text with a newline before lexing it
+	EnsureNewline bool `json:"ensureNewline,omitempty"`
+
+	Rules map[string][]RuleDefinition `json:"rules"`
+}
+
+// RuleDefinition declares one rule of a lexer state. A rule matches its
+// pattern and emits the match as a token type, as one token type per
+// group, or lexed by another lexer or state. It then pushes or pops
+// states. A rule without a pattern either includes the rules of another
+// state or changes state without consuming text.
+type RuleDefinition struct {
+	Pattern   string    `json:"pattern,omitempty"`
+	Token     string    `json:"token,omitempty"`
+	ByGroups  []string  `json:"byGroups,omitempty"`
+	Using     string    `json:"using,omitempty"`
+	UsingSelf string    `json:"usingSelf,omitempty"`
+	Push      stateList `json:"push,omitempty"`
+	Pop       int       `json:"pop,omitempty"`
+	Include   string    `json:"include,omitempty"`
+}
+
+// stateList is a list of state names, written in JSON as one name or an
+// array of them. "#pop" pops a state and "#push" pushes the current one.
+type stateList []string
+
+// UnmarshalJSON accepts a state name or an array of state names
+func (states *stateList) UnmarshalJSON(data []byte) error {
+	var name string
+	if err := json.Unmarshal(data, &name); err == nil {
+		*states = stateList{name}
+		return nil
+	}
+	var names []string
+	if err := json.Unmarshal(data, &names); err != nil {
+		return fmt.Errorf("push must be a state name or an array of state names")
+	}
+	*states = names
+	return nil
+}
+
+// ReadLexerDefinition reads a lexer definition from a JSON file, or from
+// an XML file in chroma's lexer format. A lexer without a name is named
+// after its file.
+func ReadLexerDefinition(path string) (*LexerDefinition, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read lexer definition: %w", err)
+	}
+
+	var definition *LexerDefinition
+	if strings.EqualFold(filepath.Ext(path), ".xml") {
+		definition, err = ParseXMLLexer(data)
+	} else {
+		definition, err = parseJSONLexer(data)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if definition.Name == "" {
+		definition.Name = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	}
+	return definition, nil
+}
+
+// parseJSONLexer parses a lexer definition in JSON
+func parseJSONLexer(data []byte) (*LexerDefinition, error) {
+	var definition LexerDefinition
+	if err := json.Unmarshal(data, &definition); err != nil {
+		var syntaxErr *json.SyntaxError
+		if errors.As(err, &syntaxErr) {
+			line, col := lineAndColumn(data, syntaxErr.Offset)
+			return nil, fmt.Errorf("failed to parse lexer definition at line %d, column %d: %w", line, col, err)
+		}
+		return nil, fmt.Errorf("failed to parse lexer definition: %w", err)
+	}
+	return &definition, nil
+}
+
+// xmlLexer is a lexer in chroma's XML format:
+//
+//	<lexer>
+//	  <config>
+//	    <name>INI</name>
+//	    <alias>ini</alias>
+//	    <filename>*.ini</filename>
+//	  </config>
+//	  <rules>
+//	    <state name="root">
+//	      <rule pattern="[;#].*"><token type="CommentSingle"/></rule>
+//	    </state>
+//	  </rules>
+//	</lexer>
+type xmlLexer struct {
+	XMLName xml.Name `xml:"lexer"`
+	Config  struct {
+		Name            string   `xml:"name"`
+		Aliases         []string `xml:"alias"`
+		Filenames       []string `xml:"filename"`
+		MimeTypes       []string `xml:"mime_type"`
+		CaseInsensitive bool     `xml:"case_insensitive"`
+		DotAll          bool     `xml:"dot_all"`
+		NotMultiline    bool     `xml:"not_multiline"`
+		EnsureNL        bool     `xml:"ensure_nl"`
+	} `xml:"config"`
+	States []struct {
+		Name  string    `xml:"name,attr"`
+		Rules []xmlRule `xml:"rule"`
+	} `xml:"rules>state"`
+}
+
+// xmlRule is a <rule> element, read into a rule definition
+type xmlRule struct {
+	RuleDefinition
+}
+
+// UnmarshalXML reads a rule's pattern and the emitter and mutator elements
+// it holds
+func (rule *xmlRule) UnmarshalXML(decoder *xml.Decoder, start xml.StartElement) error {
+	rule.Pattern = xmlAttr(start, "pattern")
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return err
+		}
+		switch element := token.(type) {
+		case xml.EndElement:
+			return nil
+		case xml.StartElement:
+			switch element.Name.Local {
+			case "token":
+				rule.Token = xmlAttr(element, "type")
+			case "bygroups":
+				groups, err := readXMLGroups(decoder)
+				if err != nil {
+					return err
+				}
+				rule.ByGroups = groups
+				continue
+			case "using":
+				rule.Using = xmlAttr(element, "lexer")
+			case "usingself":
+				rule.UsingSelf = xmlAttr(element, "state")
+			case "push":
+				states := xmlAttrs(element, "state")
+				if len(states) == 0 {
+					states = []string{"#push"}
+				}
+				rule.Push = append(rule.Push, states...)
+			case "pop":
+				rule.Pop = 1
+				if depth := xmlAttr(element, "depth"); depth != "" {
+					n, err := strconv.Atoi(depth)
+					if err != nil {
+						return fmt.Errorf("invalid pop depth %q", depth)
+					}
+					rule.Pop = n
+				}
+			case "include":
+				rule.Include = xmlAttr(element, "state")
+			default:
+				return fmt.Errorf("unsupported rule element <%s>", element.Name.Local)
+			}
+			if err := decoder.Skip(); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// readXMLGroups reads the token types in a <bygroups> element
+func readXMLGroups(decoder *xml.Decoder) ([]string, error) {
+	var groups []string
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return nil, err
+		}
+		switch element := token.(type) {
+		case xml.EndElement:
+			return groups, nil
+		case xml.StartElement:
+			if element.Name.Local != "token" {
+				return nil, fmt.Errorf("<bygroups> supports only <token> elements, got <%s>", element.Name.Local)
+			}
+			groups = append(groups, xmlAttr(element, "type"))
+			if err := decoder.Skip(); err != nil {
+				return nil, err
+			}
+		}
+	}
+}
+
+// xmlAttr returns the value of an element's attribute
+func xmlAttr(element xml.StartElement, name string) string {
+	if values := xmlAttrs(element, name); len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+// xmlAttrs returns the values of an attribute that may be repeated, as in
+// <push state="a" state="b"/>
+func xmlAttrs(element xml.StartElement, name string) []string {
+	var values []string
+	for _, attr := range element.Attr {
+		if attr.Name.Local == name {
+			values = append(values, attr.Value)
+		}
+	}
+	return values
+}
+
+// ParseXMLLexer parses a lexer definition in chroma's XML lexer format
+func ParseXMLLexer(data []byte) (*LexerDefinition, error) {
+	var lexer xmlLexer
+	if err := xml.Unmarshal(data, &lexer); err != nil {
+		var syntaxErr *xml.SyntaxError
+		if errors.As(err, &syntaxErr) {
+			return nil, fmt.Errorf("failed to parse lexer definition at line %d: %s", syntaxErr.Line, syntaxErr.Msg)
+		}
+		if errors.Is(err, io.EOF) {
+			return nil, fmt.Errorf("failed to parse lexer definition: no <lexer> element")
+		}
+		return nil, fmt.Errorf("failed to parse lexer definition: %w", err)
+	}
+
+	definition := &LexerDefinition{
+		Name:            strings.TrimSpace(lexer.Config.Name),
+		Aliases:         lexer.Config.Aliases,
+		Filenames:       lexer.Config.Filenames,
+		MimeTypes:       lexer.Config.MimeTypes,
+		CaseInsensitive: lexer.Config.CaseInsensitive,
+		DotAll:          lexer.Config.DotAll,
+		NotMultiline:    lexer.Config.NotMultiline,
+		EnsureNewline:   lexer.Config.EnsureNL,
+		Rules:           make(map[string][]RuleDefinition),
+	}
+	for _, state := range lexer.States {
+		if _, exists := definition.Rules[state.Name]; exists {
+			return nil, fmt.Errorf("state %q is defined twice", state.Name)
+		}
+		rules := make([]RuleDefinition, len(state.Rules))
+		for i, rule := range state.Rules {
+			rules[i] = rule.RuleDefinition
+		}
+		definition.Rules[state.Name] = rules
+	}
+	return definition, nil
+}
+
+// Validate checks the definition and returns the first problem found,
+// naming the state and the 1-based number of the rule it is in. known
+// reports whether a lexer named by a "using" rule exists; when nil,
+// chroma's lexers are looked up.
+func (definition *LexerDefinition) Validate(known func(name string) bool) error {
+	if known == nil {
+		known = func(name string) bool { return lexers.Get(name) != nil }
+	}
+	if strings.TrimSpace(definition.Name) == "" {
+		return fmt.Errorf("lexer definition needs a name")
+	}
+	for _, pattern := range definition.Filenames {
+		if _, err := filepath.Match(pattern, ""); err != nil {
+			return fmt.Errorf("lexer %q: invalid filename pattern %q: %w", definition.Name, pattern, err)
+		}
+	}
+	if _, exists := definition.Rules["root"]; !exists {
+		return fmt.Errorf("lexer %q: there is no \"root\" state to start in", definition.Name)
+	}
+
+	states := make([]string, 0, len(definition.Rules))
+	for state := range definition.Rules {
+		states = append(states, state)
+	}
+	sort.Strings(states)
+	for _, state := range states {
+		for i, rule := range definition.Rules[state] {
+			if err := definition.validateRule(rule, known); err != nil {
+				return fmt.Errorf("lexer %q: state %q, rule %d: %w", definition.Name, state, i+1, err)
+			}
+		}
+	}
+	return nil
+}
+
+// validateRule checks one rule of the definition
+func (definition *LexerDefinition) validateRule(rule RuleDefinition, known func(string) bool) error {
+	stateExists := func(state string) bool {
+		_, exists := definition.Rules[state]
+		return exists
+	}
+
+	if rule.Include != "" {
+		if rule.Pattern != "" || rule.Token != "" || rule.ByGroups != nil || rule.Using != "" || rule.UsingSelf != "" || rule.Push != nil || rule.Pop != 0 {
+			return fmt.Errorf("an include rule cannot have a pattern, emit tokens or change state")
+		}
+		if !stateExists(rule.Include) {
+			return fmt.Errorf("includes undefined state %q", rule.Include)
+		}
+		return nil
+	}
+
+	emitters := 0
+	for _, set := range []bool{rule.Token != "", rule.ByGroups != nil, rule.Using != "", rule.UsingSelf != ""} {
+		if set {
+			emitters++
+		}
+	}
+	if emitters > 1 {
+		return fmt.Errorf("a rule emits either a token, byGroups, using or usingSelf")
+	}
+	if rule.Pattern == "" {
+		if emitters > 0 {
+			return fmt.Errorf("a rule without a pattern cannot emit tokens")
+		}
+		if rule.Push == nil && rule.Pop == 0 {
+			return fmt.Errorf("a rule needs a pattern, an include, or a push or pop")
+		}
+	}
+
+	if rule.Pattern != "" {
+		re, err := regexp2.Compile(definition.regexFlags()+"(?:"+rule.Pattern+")", regexp2.RE2)
+		if err != nil {
+			return fmt.Errorf("invalid pattern %q: %w", rule.Pattern, err)
+		}
+		if rule.ByGroups != nil {
+			if groups := len(re.GetGroupNumbers()) - 1; groups != len(rule.ByGroups) {
+				return fmt.Errorf("byGroups lists %d token types but the pattern has %d groups", len(rule.ByGroups), groups)
+			}
+		}
+	}
+	if rule.Token != "" {
+		if _, ok := TokenTypeByName(rule.Token); !ok {
+			return fmt.Errorf("unknown token type %q", rule.Token)
+		}
+	}
+	for _, name := range rule.ByGroups {
+		if _, ok := TokenTypeByName(name); !ok {
+			return fmt.Errorf("unknown token type %q in byGroups", name)
+		}
+	}
+	if rule.Using != "" && !strings.EqualFold(rule.Using, definition.Name) && !known(rule.Using) {
+		return fmt.Errorf("uses unknown lexer %q", rule.Using)
+	}
+	if rule.UsingSelf != "" && !stateExists(rule.UsingSelf) {
+		return fmt.Errorf("uses undefined state %q", rule.UsingSelf)
+	}
+	for _, state := range rule.Push {
+		if state != "#pop" && state != "#push" && !stateExists(state) {
+			return fmt.Errorf("pushes undefined state %q", state)
+		}
+	}
+	if rule.Pop < 0 {
+		return fmt.Errorf("pop depth cannot be negative")
+	}
+	return nil
+}
+
+// regexFlags returns the inline flags chroma compiles the patterns with
+func (definition *LexerDefinition) regexFlags() string {
+	flags := ""
+	if !definition.NotMultiline {
+		flags += "m"
+	}
+	if definition.CaseInsensitive {
+		flags += "i"
+	}
+	if definition.DotAll {
+		flags += "s"
+	}
+	if flags == "" {
+		return ""
+	}
+	return "(?" + flags + ")"
+}
+
+// compile builds a chroma lexer from a validated definition. Lexers named
+// by "using" rules are looked up through lm when the rule matches, so they
+// may be user lexers loaded along with this one.
+func (lm *LanguageManager) compile(definition *LexerDefinition) (chroma.Lexer, error) {
+	rules := make(chroma.Rules, len(definition.Rules))
+	for state, stateRules := range definition.Rules {
+		for _, rule := range stateRules {
+			rules[state] = append(rules[state], lm.compileRule(definition, state, rule))
+		}
+	}
+
+	config := &chroma.Config{
+		Name:            definition.Name,
+		Aliases:         definition.Aliases,
+		Filenames:       definition.Filenames,
+		MimeTypes:       definition.MimeTypes,
+		CaseInsensitive: definition.CaseInsensitive,
+		DotAll:          definition.DotAll,
+		NotMultiline:    definition.NotMultiline,
+		EnsureNL:        definition.EnsureNewline,
+	}
+	lexer, err := chroma.NewLazyLexer(config, func() chroma.Rules { return rules })
+	if err != nil {
+		return nil, fmt.Errorf("lexer %q: %w", definition.Name, err)
+	}
+	// Compile the patterns now rather than on first use, so that mistakes
+	// are reported when the lexer is loaded
+	if _, err := lexer.Tokenise(nil, ""); err != nil {
+		return nil, fmt.Errorf("lexer %q: %w", definition.Name, err)
+	}
+	return lexer, nil
+}
+
+// compileRule converts a rule definition of a state into a chroma rule
+func (lm *LanguageManager) compileRule(definition *LexerDefinition, state string, rule RuleDefinition) chroma.Rule {
+	if rule.Include != "" {
+		return chroma.Include(rule.Include)
+	}
+
+	var emitter chroma.Emitter
+	switch {
+	case rule.Token != "":
+		emitter, _ = TokenTypeByName(rule.Token)
+	case rule.ByGroups != nil:
+		groups := make([]chroma.Emitter, len(rule.ByGroups))
+		for i, name := range rule.ByGroups {
+			groups[i], _ = TokenTypeByName(name)
+		}
+		emitter = chroma.ByGroups(groups...)
+	case rule.Using != "":
+		name := rule.Using
+		emitter = chroma.EmitterFunc(func(groups []string, lexerState *chroma.LexerState) chroma.Iterator {
+			return chroma.Using(lm.GetLexer(name)).Emit(groups, lexerState)
+		})
+	case rule.UsingSelf != "":
+		emitter = chroma.UsingSelf(rule.UsingSelf)
+	}
+
+	var mutators []chroma.Mutator
+	if rule.Pop > 0 {
+		mutators = append(mutators, chroma.Pop(rule.Pop))
+	}
+	if rule.Push != nil {
+		states := make([]string, len(rule.Push))
+		for i, pushed := range rule.Push {
+			if pushed == "#push" {
+				pushed = state
+			}
+			states[i] = pushed
+		}
+		mutators = append(mutators, chroma.Push(states...))
+	}
+
+	compiled := chroma.Rule{Pattern: rule.Pattern, Type: emitter}
+	if len(mutators) == 1 {
+		compiled.Mutator = mutators[0]
+	} else if len(mutators) > 1 {
+		compiled.Mutator = chroma.Mutators(mutators...)
+	}
+	return compiled
+}
+
+// LoadUserLexers compiles the lexer definitions in the *.json and *.xml
+// files of dir, replacing the user lexers loaded before. User lexers take
+// precedence over chroma's lexers of the same name. A lexer the language
+// registry has no file type for is registered as one, so that its files
+// are detected; load the language definitions first. Invalid files are
+// reported together in the error, one per file, and skipped.
+func (lm *LanguageManager) LoadUserLexers(dir string) error {
+	var paths []string
+	for _, pattern := range []string{"*.json", "*.xml"} {
+		matches, err := filepath.Glob(filepath.Join(dir, pattern))
+		if err != nil {
+			return fmt.Errorf("failed to list lexer definitions: %w", err)
+		}
+		paths = append(paths, matches...)
+	}
+	sort.Strings(paths)
+
+	var errs []error
+	var definitions []*LexerDefinition
+	var definitionPaths []string
+	names := make(map[string]bool)
+	for _, path := range paths {
+		definition, err := ReadLexerDefinition(path)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", filepath.Base(path), err))
+			continue
+		}
+		definitions = append(definitions, definition)
+		definitionPaths = append(definitionPaths, path)
+		for _, name := range append([]string{definition.Name}, definition.Aliases...) {
+			names[strings.ToLower(name)] = true
+		}
+	}
+
+	// Rules may use lexers defined in other files
+	known := func(name string) bool {
+		return names[strings.ToLower(name)] || lm.lookupLexer(name) != nil
+	}
+	loaded := make(map[string]chroma.Lexer)
+	var compiled []chroma.Lexer
+	for i, definition := range definitions {
+		err := definition.Validate(known)
+		var lexer chroma.Lexer
+		if err == nil {
+			lexer, err = lm.compile(definition)
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", filepath.Base(definitionPaths[i]), err))
+			continue
+		}
+		for _, name := range append([]string{definition.Name}, definition.Aliases...) {
+			loaded[strings.ToLower(name)] = lexer
+		}
+		compiled = append(compiled, lexer)
+	}
+
+	lm.mu.Lock()
+	lm.lexers = loaded
+	lm.cache = make(map[string]chroma.Lexer)
+	lm.highlightCache = make(map[string]chroma.Lexer)
+	lm.mu.Unlock()
+
+	for _, lexer := range compiled {
+		if _, exists := lm.registry.GetFileTypeByName(lexer.Config().Name); !exists {
+			lm.registry.RegisterFileType(userLexerFileType(lexer))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// userLexerFileType describes the files of a user lexer for the language
+// registry, sorting its filename patterns into extensions, exact names and
+// other patterns
+func userLexerFileType(lexer chroma.Lexer) backend.FileType {
+	config := lexer.Config()
+	fileType := backend.FileType{
+		Name:      config.Name,
+		LexerName: languageName(lexer),
+		MimeType:  "text/plain",
+		Aliases:   config.Aliases,
+	}
+	if len(config.MimeTypes) > 0 {
+		fileType.MimeType = config.MimeTypes[0]
+	}
+	for _, pattern := range config.Filenames {
+		switch {
+		case strings.HasPrefix(pattern, "*.") && !strings.ContainsAny(pattern[2:], "*?[.\\"):
+			fileType.Extensions = append(fileType.Extensions, pattern[1:])
+		case !strings.ContainsAny(pattern, "*?[\\"):
+			fileType.Filenames = append(fileType.Filenames, pattern)
+		default:
+			fileType.Globs = append(fileType.Globs, pattern)
+		}
+	}
+	return fileType
+}
@@ -0,0 +1,217 @@
+package syntax
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/alecthomas/chroma"
+	"github.com/kenelite/goeditor/backend"
+)
+
+const acmeLexerJSON = `{
+  "name": "Acme",
+  "aliases": ["acme"],
+  "filenames": ["*.acme", "Acmefile"],
+  "rules": {
+    "root": [
+      {"pattern": "#.*$", "token": "CommentSingle"},
+      {"pattern": "(\\w+)(\\s*)(=)", "byGroups": ["NameAttribute", "Text", "Operator"]},
+      {"pattern": "\"", "token": "String", "push": "string"},
+      {"pattern": "\\s+", "token": "Text"},
+      {"pattern": "\\w+", "token": "Name"}
+    ],
+    "string": [
+      {"pattern": "\"", "token": "String", "pop": 1},
+      {"pattern": "[^\"]+", "token": "String"}
+    ]
+  }
+}`
+
+const iniLexerXML = `<lexer>
+  <config>
+    <name>Simple INI</name>
+    <alias>simpleini</alias>
+    <filename>*.sini</filename>
+  </config>
+  <rules>
+    <state name="root">
+      <rule pattern="\s+"><token type="Text"/></rule>
+      <rule pattern=";.*"><token type="CommentSingle"/></rule>
+      <rule pattern="\[.*?\]$"><token type="Keyword"/></rule>
+      <rule pattern="(.*?)(\s*)(=)(\s*)">
+        <bygroups>
+          <token type="NameAttribute"/>
+          <token type="Text"/>
+          <token type="Operator"/>
+          <token type="Text"/>
+        </bygroups>
+        <push state="value"/>
+      </rule>
+    </state>
+    <state name="value">
+      <rule pattern="\n"><token type="Text"/><pop depth="1"/></rule>
+      <rule pattern="[^\n]+"><token type="LiteralString"/></rule>
+    </state>
+  </rules>
+</lexer>`
+
+// tokenTypes lexes source and returns the type of each token by value
+func tokenTypes(t *testing.T, lexer chroma.Lexer, source string) map[string]chroma.TokenType {
+	t.Helper()
+	tokens, err := chroma.Tokenise(lexer, nil, source)
+	if err != nil {
+		t.Fatalf("Failed to tokenise: %v", err)
+	}
+	types := make(map[string]chroma.TokenType)
+	for _, token := range tokens {
+		types[token.Value] = token.Type
+	}
+	return types
+}
+
+func TestLoadUserLexers(t *testing.T) {
+	registry := backend.GetLanguageRegistry()
+	t.Cleanup(func() { registry.LoadLanguageDefinitions(t.TempDir()) })
+
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "acme.json"), []byte(acmeLexerJSON), 0644)
+	os.WriteFile(filepath.Join(dir, "ini.xml"), []byte(iniLexerXML), 0644)
+
+	lm := NewLanguageManager()
+	if err := lm.LoadUserLexers(dir); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	types := tokenTypes(t, lm.GetLexer("acme"), "# settings\nname = \"value\"\n")
+	expected := map[string]chroma.TokenType{
+		"# settings": chroma.CommentSingle,
+		"name":       chroma.NameAttribute,
+		"=":          chroma.Operator,
+		"value":      chroma.LiteralString,
+	}
+	for value, tokenType := range expected {
+		if types[value] != tokenType {
+			t.Errorf("Expected %q to be %s, got %s", value, tokenType, types[value])
+		}
+	}
+
+	types = tokenTypes(t, lm.GetLexer("simpleini"), "[core]\nname = goeditor\n")
+	if types["[core]"] != chroma.Keyword || types["name"] != chroma.NameAttribute || types["goeditor"] != chroma.LiteralString {
+		t.Errorf("Unexpected tokens from the XML lexer: %v", types)
+	}
+
+	// The lexers' files are detected through the registry
+	if language := lm.DetectLanguageFromFilename("/tmp/build.acme"); language != "acme" {
+		t.Errorf("Expected acme for an extension, got %s", language)
+	}
+	if language := lm.DetectLanguageFromFilename("/tmp/Acmefile"); language != "acme" {
+		t.Errorf("Expected acme for a file name, got %s", language)
+	}
+	if !lm.IsLanguageSupported("Simple INI") {
+		t.Error("Expected a user lexer to be found by name")
+	}
+
+	// Reloading drops the lexers whose files are gone
+	os.Remove(filepath.Join(dir, "ini.xml"))
+	if err := lm.LoadUserLexers(dir); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if lm.IsLanguageSupported("simpleini") {
+		t.Error("Expected the removed lexer to be dropped")
+	}
+}
+
+func TestUserLexerReplacesBuiltinLexer(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "go.json"), []byte(`{"name": "Go", "aliases": ["go"], "rules": {"root": [{"pattern": "(?s).+", "token": "Comment"}]}}`), 0644)
+
+	lm := NewLanguageManager()
+	if err := lm.LoadUserLexers(dir); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	types := tokenTypes(t, lm.GetLexer("go"), "package main")
+	if types["package main"] != chroma.Comment {
+		t.Errorf("Expected the user lexer to replace chroma's, got %v", types)
+	}
+}
+
+func TestUserLexerUsing(t *testing.T) {
+	registry := backend.GetLanguageRegistry()
+	t.Cleanup(func() { registry.LoadLanguageDefinitions(t.TempDir()) })
+
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "tmpl.json"), []byte(`{
+  "name": "Tmpl",
+  "rules": {
+    "root": [
+      {"pattern": "(?s)\\{\\{.*?\\}\\}", "using": "go"},
+      {"pattern": "[^{]+", "token": "Text"}
+    ]
+  }
+}`), 0644)
+
+	lm := NewLanguageManager()
+	if err := lm.LoadUserLexers(dir); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	types := tokenTypes(t, lm.GetLexer("tmpl"), "text {{func}}")
+	if !types["func"].InCategory(chroma.Keyword) {
+		t.Errorf("Expected the Go lexer to lex the embedded code, got %v", types)
+	}
+}
+
+func TestUserLexerValidation(t *testing.T) {
+	cases := []struct {
+		name       string
+		definition string
+		message    string
+	}{
+		{"no root", `{"name": "x", "rules": {"main": []}}`, `no "root" state`},
+		{"bad pattern", `{"name": "x", "rules": {"root": [{"pattern": "(", "token": "Text"}]}}`, `state "root", rule 1: invalid pattern "("`},
+		{"unknown token", `{"name": "x", "rules": {"root": [{"pattern": "a", "token": "Keyword"}, {"pattern": "b", "token": "Shiny"}]}}`, `rule 2: unknown token type "Shiny"`},
+		{"group count", `{"name": "x", "rules": {"root": [{"pattern": "(a)(b)", "byGroups": ["Text"]}]}}`, "byGroups lists 1 token types but the pattern has 2 groups"},
+		{"undefined push", `{"name": "x", "rules": {"root": [{"pattern": "a", "token": "Text", "push": "string"}]}}`, `pushes undefined state "string"`},
+		{"undefined include", `{"name": "x", "rules": {"root": [{"include": "comments"}]}}`, `includes undefined state "comments"`},
+		{"unknown lexer", `{"name": "x", "rules": {"root": [{"pattern": "a", "using": "nonexistent"}]}}`, `uses unknown lexer "nonexistent"`},
+		{"empty rule", `{"name": "x", "rules": {"root": [{"token": "Text"}]}}`, "without a pattern cannot emit tokens"},
+		{"json syntax", `{"name": "x",`, "line 1"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			dir := t.TempDir()
+			os.WriteFile(filepath.Join(dir, "bad.json"), []byte(c.definition), 0644)
+
+			err := NewLanguageManager().LoadUserLexers(dir)
+			if err == nil {
+				t.Fatal("Expected an error")
+			}
+			if !strings.HasPrefix(err.Error(), "bad.json: ") || !strings.Contains(err.Error(), c.message) {
+				t.Errorf("Expected an error containing %q, got %q", c.message, err)
+			}
+		})
+	}
+}
+
+func TestParseXMLLexerErrors(t *testing.T) {
+	if _, err := ParseXMLLexer([]byte(`<lexer><rules><state name="root"><rule pattern="a"><combined state="x"/></rule></state></rules></lexer>`)); err == nil || !strings.Contains(err.Error(), "unsupported rule element <combined>") {
+		t.Errorf("Expected an unsupported element error, got %v", err)
+	}
+	if _, err := ParseXMLLexer([]byte("<lexer>\n<config>\n</lexer>")); err == nil || !strings.Contains(err.Error(), "line 3") {
+		t.Errorf("Expected the line of a syntax error, got %v", err)
+	}
+
+	definition, err := ParseXMLLexer([]byte(`<lexer><config><name>X</name></config><rules><state name="root"><rule pattern="a"><token type="Text"/><push state="a" state="#pop"/></rule><rule><push/></rule></state><state name="a"/></rules></lexer>`))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	rules := definition.Rules["root"]
+	if len(rules) != 2 || strings.Join(rules[0].Push, ",") != "a,#pop" || strings.Join(rules[1].Push, ",") != "#push" {
+		t.Errorf("Unexpected rules %+v", rules)
+	}
+	if err := definition.Validate(nil); err != nil {
+		t.Errorf("Unexpected validation error: %v", err)
+	}
+}
@@ -0,0 +1,311 @@
+package ui
+
+import (
+	"image/color"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/canvas"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+	"github.com/alecthomas/chroma"
+	"github.com/kenelite/goeditor/ui/syntax"
+)
+
+// syntaxLayerFallbackLines is how many lines are drawn before the size of
+// the viewport is known
+const syntaxLayerFallbackLines = 100
+
+// SyntaxHighlightLayer draws the editor text in the colors of the syntax
+// theme. It is stacked over the text entry, whose own text is drawn
+// transparent meanwhile, and does not handle any input. Like the search
+// highlights it follows the layout of a non-wrapping entry; with wrapping
+// on it draws nothing and the entry shows its plain text.
+type SyntaxHighlightLayer struct {
+	widget.BaseWidget
+	entry *widget.Entry
+
+	text         string   // Entry text the lines were split from
+	lines        []string // Lines of the entry text
+	highlighting *syntax.Highlighting
+	generation   int // Changes whenever every line must be drawn again
+
+	// Visible region of the layer, used to draw only visible lines
+	viewportOffset fyne.Position
+	viewportSize   fyne.Size
+}
+
+// NewSyntaxHighlightLayer creates a layer drawing the text of entry
+func NewSyntaxHighlightLayer(entry *widget.Entry) *SyntaxHighlightLayer {
+	l := &SyntaxHighlightLayer{entry: entry}
+	l.ExtendBaseWidget(l)
+	return l
+}
+
+// IsActive reports whether the layer draws the text instead of the entry
+func (l *SyntaxHighlightLayer) IsActive() bool {
+	return l.entry.Wrapping == fyne.TextWrapOff
+}
+
+// SetHighlighting colors the text after a new highlighting. Only lines
+// whose text or tokens changed are drawn again.
+func (l *SyntaxHighlightLayer) SetHighlighting(highlighting *syntax.Highlighting) {
+	l.highlighting = highlighting
+	l.Refresh()
+}
+
+// Repaint draws every line again, as after a change of theme colors
+func (l *SyntaxHighlightLayer) Repaint() {
+	l.generation++
+	l.Refresh()
+}
+
+// SetViewport records the visible region so only visible lines are drawn
+func (l *SyntaxHighlightLayer) SetViewport(offset fyne.Position, size fyne.Size) {
+	l.viewportOffset = offset
+	l.viewportSize = size
+	l.Refresh()
+}
+
+// syncLines splits the entry text into lines when it changed
+func (l *SyntaxHighlightLayer) syncLines() {
+	if l.lines != nil && l.text == l.entry.Text {
+		return
+	}
+	l.text = l.entry.Text
+	l.lines = strings.Split(l.text, "\n")
+}
+
+// highlightedLine returns the line of the highlighting with the text of
+// line. Until the highlighter catches up with an edit, lines after the
+// edit are found where they were before it.
+func (l *SyntaxHighlightLayer) highlightedLine(line int) (int, bool) {
+	hl := l.highlighting
+	if hl == nil {
+		return 0, false
+	}
+	text := l.lines[line]
+	if hl.LineText(line) == text {
+		return line, true
+	}
+	if before := line - len(l.lines) + hl.LineCount(); before != line && hl.LineText(before) == text {
+		return before, true
+	}
+	return 0, false
+}
+
+// CreateRenderer creates the renderer for the layer
+func (l *SyntaxHighlightLayer) CreateRenderer() fyne.WidgetRenderer {
+	return &syntaxHighlightRenderer{layer: l, rows: make(map[int]*syntaxRow)}
+}
+
+// syntaxRow is the drawn text of one line
+type syntaxRow struct {
+	text       string
+	tokens     []chroma.Token // Tokens the row was colored with, nil if plain
+	generation int
+	texts      []*canvas.Text
+}
+
+// syntaxHighlightRenderer draws the visible lines, keeping the text
+// objects of lines that did not change
+type syntaxHighlightRenderer struct {
+	layer   *SyntaxHighlightLayer
+	rows    map[int]*syntaxRow
+	spare   []*canvas.Text
+	objects []fyne.CanvasObject
+	size    fyne.Size
+}
+
+func (r *syntaxHighlightRenderer) Layout(size fyne.Size) {
+	r.size = size
+	r.update()
+}
+
+func (r *syntaxHighlightRenderer) MinSize() fyne.Size {
+	return fyne.NewSize(0, 0)
+}
+
+func (r *syntaxHighlightRenderer) Refresh() {
+	r.update()
+	canvas.Refresh(r.layer)
+}
+
+func (r *syntaxHighlightRenderer) Objects() []fyne.CanvasObject {
+	return r.objects
+}
+
+func (r *syntaxHighlightRenderer) Destroy() {}
+
+// update draws the visible lines that changed and drops the rows that
+// scrolled out of view
+func (r *syntaxHighlightRenderer) update() {
+	l := r.layer
+	if !l.IsActive() {
+		r.release(0, 0)
+		r.objects = nil
+		return
+	}
+	l.syncLines()
+
+	th := l.entry.Theme()
+	textSize := th.Size(theme.SizeNameText)
+	innerPad := th.Size(theme.SizeNameInnerPadding)
+	style := l.entry.TextStyle
+	rowHeight := fyne.MeasureText("M", textSize, style).Height
+
+	first := int((l.viewportOffset.Y - overlayViewportMargin - innerPad) / rowHeight)
+	last := first + syntaxLayerFallbackLines
+	if l.viewportSize.Height > 0 {
+		last = int((l.viewportOffset.Y+l.viewportSize.Height+overlayViewportMargin-innerPad)/rowHeight) + 1
+	}
+	if first < 0 {
+		first = 0
+	}
+	if last > len(l.lines) {
+		last = len(l.lines)
+	}
+	r.release(first, last)
+
+	objects := make([]fyne.CanvasObject, 0, len(r.objects))
+	for i := first; i < last; i++ {
+		row := r.rows[i]
+		text := l.lines[i]
+		var tokens []chroma.Token
+		line, highlighted := l.highlightedLine(i)
+		if highlighted {
+			tokens = l.highlighting.LineTokens(line)
+		}
+
+		if row == nil || row.text != text || !sameTokens(row.tokens, tokens) || row.generation != l.generation {
+			if row == nil {
+				row = &syntaxRow{}
+				r.rows[i] = row
+			}
+			r.spare = append(r.spare, row.texts...)
+			row.texts = row.texts[:0]
+			row.text, row.tokens, row.generation = text, tokens, l.generation
+
+			y := innerPad + rowHeight*float32(i)
+			if highlighted {
+				offset := 0
+				for _, segment := range l.highlighting.LineSegments(line) {
+					if s, ok := segment.(*syntax.SyntaxSegment); ok {
+						r.addText(row, text, offset, s.Text, s.Color, y, textSize, innerPad, style)
+						offset += len(s.Text)
+					}
+				}
+			} else {
+				r.addText(row, text, 0, text, plainTextColor(), y, textSize, innerPad, style)
+			}
+		}
+		for _, t := range row.texts {
+			objects = append(objects, t)
+		}
+	}
+	r.objects = objects
+}
+
+// addText adds the part of line starting at offset to row. Tabs are
+// skipped and every run between them is placed by measuring the line up
+// to it, so tab stops line up with the entry's.
+func (r *syntaxHighlightRenderer) addText(row *syntaxRow, line string, offset int, part string, col color.Color, y, textSize, innerPad float32, style fyne.TextStyle) {
+	part = strings.TrimRight(part, "\r\n")
+	for part != "" {
+		run := part
+		if tab := strings.IndexByte(part, '\t'); tab >= 0 {
+			run = part[:tab]
+		}
+		if strings.TrimSpace(run) != "" && offset+len(run) <= len(line) {
+			x := innerPad + fyne.MeasureText(line[:offset], textSize, style).Width
+			t := r.newText()
+			t.Text = run
+			t.Color = col
+			t.TextSize = textSize
+			t.TextStyle = style
+			t.Move(fyne.NewPos(x, y))
+			t.Resize(t.MinSize())
+			t.Refresh()
+			row.texts = append(row.texts, t)
+		}
+		if len(run) == len(part) {
+			break
+		}
+		offset += len(run) + 1
+		part = part[len(run)+1:]
+	}
+}
+
+// newText takes a text object from the spare ones or creates one
+func (r *syntaxHighlightRenderer) newText() *canvas.Text {
+	if n := len(r.spare); n > 0 {
+		t := r.spare[n-1]
+		r.spare = r.spare[:n-1]
+		return t
+	}
+	return canvas.NewText("", nil)
+}
+
+// release keeps the rows of lines first to last and spares the text
+// objects of the others
+func (r *syntaxHighlightRenderer) release(first, last int) {
+	for i, row := range r.rows {
+		if i < first || i >= last {
+			r.spare = append(r.spare, row.texts...)
+			delete(r.rows, i)
+		}
+	}
+}
+
+// sameTokens reports whether two token slices are the same slice. Cached
+// highlighting is never modified in place, so lines whose tokens did not
+// change keep their slice.
+func sameTokens(a, b []chroma.Token) bool {
+	return len(a) == len(b) && (len(a) == 0 || &a[0] == &b[0])
+}
+
+// plainTextColor returns the color of text without highlighting
+func plainTextColor() color.Color {
+	if app := fyne.CurrentApp(); app != nil {
+		return app.Settings().Theme().Color(theme.ColorNameForeground, app.Settings().ThemeVariant())
+	}
+	return theme.ForegroundColor()
+}
+
+// syntaxEntryTheme is the theme of the text entry. It draws the entry's
+// text transparent while the syntax layer draws it, and is the app's theme
+// otherwise.
+type syntaxEntryTheme struct {
+	layer *SyntaxHighlightLayer
+}
+
+// base returns the app's theme
+func (t *syntaxEntryTheme) base() fyne.Theme {
+	if app := fyne.CurrentApp(); app != nil {
+		return app.Settings().Theme()
+	}
+	return theme.DefaultTheme()
+}
+
+// Color hides the foreground while the syntax layer is active
+func (t *syntaxEntryTheme) Color(name fyne.ThemeColorName, variant fyne.ThemeVariant) color.Color {
+	if name == theme.ColorNameForeground && t.layer.IsActive() {
+		return color.Transparent
+	}
+	return t.base().Color(name, variant)
+}
+
+// Font returns the app theme's font
+func (t *syntaxEntryTheme) Font(style fyne.TextStyle) fyne.Resource {
+	return t.base().Font(style)
+}
+
+// Icon returns the app theme's icon
+func (t *syntaxEntryTheme) Icon(name fyne.ThemeIconName) fyne.Resource {
+	return t.base().Icon(name)
+}
+
+// Size returns the app theme's size
+func (t *syntaxEntryTheme) Size(name fyne.ThemeSizeName) float32 {
+	return t.base().Size(name)
+}
@@ -0,0 +1,75 @@
+package ui
+
+import (
+	"image/color"
+	"testing"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/canvas"
+	"fyne.io/fyne/v2/test"
+	"fyne.io/fyne/v2/theme"
+	"github.com/kenelite/goeditor/ui/syntax"
+)
+
+// layerText returns the text object of the layer drawing value
+func layerText(renderer fyne.WidgetRenderer, value string) *canvas.Text {
+	for _, object := range renderer.Objects() {
+		if text, ok := object.(*canvas.Text); ok && text.Text == value {
+			return text
+		}
+	}
+	return nil
+}
+
+func TestSyntaxHighlightLayer(t *testing.T) {
+	testApp := test.NewApp()
+	defer testApp.Quit()
+
+	editor := NewEditor()
+	editor.TextWidget.Wrapping = fyne.TextWrapOff
+	renderer := test.WidgetRenderer(editor.SyntaxHighlights)
+
+	content := "package main\n\nfunc main() {}\n"
+	editor.SetContent(content)
+	highlighter := syntax.NewIncrementalHighlighter("go")
+	highlighter.Update(content)
+	editor.applyHighlighting(syntax.HighlightResult{Highlighting: highlighter.Snapshot()})
+
+	keyword := layerText(renderer, "func")
+	if keyword == nil {
+		t.Fatalf("Expected the layer to draw the keyword, got %d objects", len(renderer.Objects()))
+	}
+	expected := highlighter.LineSegments(2)[0].(*syntax.SyntaxSegment).Color
+	if keyword.Color != expected {
+		t.Errorf("Expected the keyword in %v, got %v", expected, keyword.Color)
+	}
+	if c := editor.TextWidget.Theme().Color(theme.ColorNameForeground, theme.VariantLight); c != color.Transparent {
+		t.Errorf("Expected the entry's own text to be hidden, got %v", c)
+	}
+
+	// Only the edited line is drawn again
+	first := layerText(renderer, "package")
+	edited := "package main\n\nfunc run() {}\n"
+	editor.SetContent(edited)
+	if layerText(renderer, "run()") == nil && layerText(renderer, "func run() {}") == nil {
+		t.Error("Expected the edited line to be drawn before it is highlighted")
+	}
+	highlighter.Update(edited)
+	editor.applyHighlighting(syntax.HighlightResult{Highlighting: highlighter.Snapshot()})
+	if layerText(renderer, "package") != first {
+		t.Error("Expected the unchanged line to keep its text")
+	}
+	if layerText(renderer, "run") == nil {
+		t.Error("Expected the edited line to be highlighted")
+	}
+
+	// Wrapped text is drawn by the entry
+	editor.TextWidget.Wrapping = fyne.TextWrapWord
+	editor.SyntaxHighlights.Refresh()
+	if len(renderer.Objects()) != 0 {
+		t.Errorf("Expected nothing to be drawn with wrapping on, got %d objects", len(renderer.Objects()))
+	}
+	if c := editor.TextWidget.Theme().Color(theme.ColorNameForeground, theme.VariantLight); c == color.Transparent {
+		t.Error("Expected the entry to draw its text with wrapping on")
+	}
+}
@@ -0,0 +1,358 @@
+package ui
+
+import (
+	"errors"
+	"fmt"
+	"image/color"
+	"sort"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/canvas"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+	"github.com/alecthomas/chroma"
+	"github.com/kenelite/goeditor/ui/syntax"
+)
+
+// themeEditor edits a copy of a syntax theme with a live preview and saves
+// it as a custom theme
+type themeEditor struct {
+	editor *Editor
+	window fyne.Window
+	config *syntax.ThemeConfig
+	style  *chroma.Style // Built from config, nil while config is invalid
+	saved  string        // Name of the custom theme being edited, which saving replaces
+
+	nameEntry         *widget.Entry
+	rows              *fyne.Container
+	preview           *widget.RichText
+	previewBackground *canvas.Rectangle
+	sample            syntax.PreviewSample
+	status            *widget.Label
+}
+
+// showThemeEditor opens the theme editor window, starting from the current
+// syntax theme
+func showThemeEditor(editor *Editor) {
+	if editor.themeEditorWindow != nil {
+		editor.themeEditorWindow.RequestFocus()
+		return
+	}
+	app := editor.themeApp
+	if app == nil {
+		app = fyne.CurrentApp()
+	}
+
+	te := &themeEditor{
+		editor: editor,
+		window: app.NewWindow("Theme Editor"),
+		sample: syntax.PreviewSamples[0],
+	}
+	editor.themeEditorWindow = te.window
+	te.window.SetOnClosed(func() {
+		editor.themeEditorWindow = nil
+	})
+	te.window.SetContent(te.build())
+	te.window.Resize(fyne.NewSize(960, 640))
+	te.load(syntax.GetThemeManager().GetCurrentThemeName())
+	te.window.Show()
+}
+
+// build creates the editor's widgets
+func (te *themeEditor) build() fyne.CanvasObject {
+	themes := syntax.GetAvailableThemes()
+	sort.Strings(themes)
+	baseSelect := widget.NewSelect(themes, te.load)
+	baseSelect.PlaceHolder = "Start from..."
+	te.nameEntry = widget.NewEntry()
+	te.nameEntry.SetPlaceHolder("Theme name")
+	header := widget.NewForm(
+		widget.NewFormItem("Start from", baseSelect),
+		widget.NewFormItem("Name", te.nameEntry),
+	)
+	te.rows = container.NewVBox()
+	tokens := container.NewBorder(header, nil, nil, nil, container.NewVScroll(te.rows))
+
+	languages := make([]string, len(syntax.PreviewSamples))
+	for i, sample := range syntax.PreviewSamples {
+		languages[i] = sample.Language
+	}
+	sampleSelect := widget.NewSelect(languages, func(language string) {
+		for _, sample := range syntax.PreviewSamples {
+			if sample.Language == language {
+				te.sample = sample
+			}
+		}
+		te.refreshPreview()
+	})
+	sampleSelect.SetSelected(te.sample.Language)
+	te.preview = widget.NewRichText()
+	te.preview.Wrapping = fyne.TextWrapOff
+	te.previewBackground = canvas.NewRectangle(color.Transparent)
+	preview := container.NewBorder(sampleSelect, nil, nil, nil,
+		container.NewStack(te.previewBackground, container.NewScroll(te.preview)))
+
+	split := container.NewHSplit(tokens, preview)
+	split.Offset = 0.5
+
+	te.status = widget.NewLabel("")
+	te.status.Truncation = fyne.TextTruncateEllipsis
+	saveButton := widget.NewButton("Save", te.save)
+	saveButton.Importance = widget.HighImportance
+	closeButton := widget.NewButton("Close", te.window.Close)
+	footer := container.NewBorder(nil, nil, nil, container.NewHBox(closeButton, saveButton), te.status)
+
+	return container.NewBorder(nil, footer, nil, nil, split)
+}
+
+// load starts editing a copy of a theme. Built-in themes get a new name so
+// that saving does not shadow them.
+func (te *themeEditor) load(name string) {
+	themes := syntax.GetThemeManager()
+	config, err := themes.ThemeConfigFor(name)
+	if err != nil {
+		te.status.SetText(err.Error())
+		return
+	}
+	te.saved = ""
+	if _, custom := themes.GetCustomThemes()[name]; custom {
+		te.saved = name
+	} else {
+		config.Name = name + "-custom"
+	}
+	te.config = config
+	te.nameEntry.SetText(config.Name)
+	te.refresh()
+}
+
+// refresh rebuilds the rows and the preview after a change
+func (te *themeEditor) refresh() {
+	style, err := te.config.ToStyle()
+	if err != nil {
+		te.style = nil
+		te.status.SetText(err.Error())
+	} else {
+		te.style = style
+		te.status.SetText("")
+	}
+
+	rows := []fyne.CanvasObject{
+		widget.NewLabelWithStyle("Editor", fyne.TextAlignLeading, fyne.TextStyle{Bold: true}),
+		te.colorRow("Background", &te.config.Background),
+		te.colorRow("Foreground", &te.config.Foreground),
+		te.colorRow("Selection", &te.config.Selection),
+		te.colorRow("Line Numbers", &te.config.LineNumber),
+		te.colorRow("Cursor", &te.config.Cursor),
+		widget.NewLabelWithStyle("Tokens", fyne.TextAlignLeading, fyne.TextStyle{Bold: true}),
+	}
+	for _, tokenType := range syntax.ThemeEditorTokens {
+		rows = append(rows, te.tokenRow(tokenType))
+	}
+	te.rows.Objects = rows
+	te.rows.Refresh()
+	te.refreshPreview()
+}
+
+// refreshPreview highlights the sample in the edited theme
+func (te *themeEditor) refreshPreview() {
+	if te.preview == nil || te.style == nil {
+		return
+	}
+	te.preview.Segments = syntax.HighlightCodeWithStyle(te.sample.Source, te.sample.Language, te.style)
+	te.preview.Refresh()
+
+	te.previewBackground.FillColor = color.Transparent
+	if background := te.style.Get(chroma.Background).Background; background.IsSet() {
+		te.previewBackground.FillColor = color.RGBA{R: background.Red(), G: background.Green(), B: background.Blue(), A: 0xff}
+	}
+	te.previewBackground.Refresh()
+}
+
+// colorRow edits one of the theme's editor colors
+func (te *themeEditor) colorRow(label string, value *string) fyne.CanvasObject {
+	picker := te.colorButton(label, *value, *value, func(hex string) {
+		*value = hex
+		te.refresh()
+	})
+	return container.NewBorder(nil, nil, widget.NewLabel(label), nil, picker)
+}
+
+// tokenRow edits the color, boldness and slant of a token type. Types
+// without a style of their own show the style they inherit.
+func (te *themeEditor) tokenRow(tokenType chroma.TokenType) fyne.CanvasObject {
+	own, hasOwn := te.config.GetTokenStyle(tokenType)
+	effective := ""
+	if te.style != nil {
+		if colour := te.style.Get(tokenType).Colour; colour.IsSet() {
+			effective = colour.String()
+		}
+	}
+	update := func(change func(*syntax.TokenStyle)) {
+		change(&own)
+		te.config.SetTokenStyle(tokenType, own)
+		te.refresh()
+	}
+
+	picker := te.colorButton(tokenType.String(), own.Color, effective, func(hex string) {
+		update(func(style *syntax.TokenStyle) { style.Color = hex })
+	})
+	bold := styleFlagCheck("Bold", own.Bold, func(value chroma.Trilean) {
+		update(func(style *syntax.TokenStyle) { style.Bold = value })
+	})
+	italic := styleFlagCheck("Italic", own.Italic, func(value chroma.Trilean) {
+		update(func(style *syntax.TokenStyle) { style.Italic = value })
+	})
+
+	label := tokenType.String()
+	if tokenType.Category() != tokenType {
+		label = "    " + label
+	}
+	controls := []fyne.CanvasObject{bold, italic}
+	if hasOwn {
+		controls = append(controls, widget.NewButton("Reset", func() {
+			te.config.SetTokenStyle(tokenType, syntax.TokenStyle{})
+			te.refresh()
+		}))
+	}
+	return container.NewBorder(nil, nil, widget.NewLabel(label), container.NewHBox(controls...), picker)
+}
+
+// styleFlagCheck shows a style attribute that is set, turned off or
+// inherited as a check box that is checked, unchecked or partly checked.
+// Tapping it moves on to the next of these states.
+func styleFlagCheck(label string, value chroma.Trilean, onChanged func(chroma.Trilean)) *widget.Check {
+	check := widget.NewCheck(label, func(bool) {
+		switch value {
+		case chroma.Pass:
+			onChanged(chroma.Yes)
+		case chroma.Yes:
+			onChanged(chroma.No)
+		default:
+			onChanged(chroma.Pass)
+		}
+	})
+	check.Checked = value == chroma.Yes
+	check.Partial = value == chroma.Pass
+	return check
+}
+
+// colorButton shows a color and opens a color picker for it. value is the
+// color set in the theme, shown is the color in effect, which may be
+// inherited.
+func (te *themeEditor) colorButton(title, value, shown string, onPicked func(hex string)) fyne.CanvasObject {
+	swatch := canvas.NewRectangle(color.Transparent)
+	swatch.SetMinSize(fyne.NewSize(20, 20))
+	swatch.StrokeColor = color.Gray{Y: 0x80}
+	swatch.StrokeWidth = 1
+	if c, ok := parseHexColor(shown); ok {
+		swatch.FillColor = c
+	}
+
+	text := value
+	if text == "" {
+		text = "inherited"
+	}
+	button := widget.NewButton(text, func() {
+		picker := dialog.NewColorPicker(title, "", func(c color.Color) {
+			onPicked(colorToHex(c))
+		}, te.window)
+		picker.Advanced = true
+		if c, ok := parseHexColor(shown); ok {
+			picker.SetColor(c)
+		}
+		picker.Show()
+	})
+	return container.NewBorder(nil, nil, swatch, nil, button)
+}
+
+// save writes the theme to the themes folder and switches to it. Another
+// theme of the same name is only replaced once confirmed.
+func (te *themeEditor) save() {
+	name := strings.TrimSpace(te.nameEntry.Text)
+	if name == "" {
+		dialog.ShowError(fmt.Errorf("theme name cannot be empty"), te.window)
+		return
+	}
+	config := te.config.Copy()
+	config.Name = name
+	te.saveAs(config, name == te.saved)
+}
+
+// saveAs saves a theme configuration, asking before replacing another theme
+// unless overwrite is set
+func (te *themeEditor) saveAs(config *syntax.ThemeConfig, overwrite bool) {
+	name := config.Name
+	path, err := syntax.GetThemeManager().SaveCustomTheme(te.editor.ConfigManager.GetThemesDir(), config, overwrite)
+	if errors.Is(err, syntax.ErrThemeExists) {
+		dialog.ShowConfirm("Replace Theme", fmt.Sprintf("A theme named %q already exists. Replace it?", name), func(replace bool) {
+			if replace {
+				te.saveAs(config, true)
+			}
+		}, te.window)
+		return
+	}
+	if err != nil {
+		dialog.ShowError(fmt.Errorf("failed to save theme: %w", err), te.window)
+		return
+	}
+	te.config = config
+	te.saved = name
+	if err := te.editor.UseSyntaxTheme(name); err != nil {
+		dialog.ShowError(err, te.window)
+		return
+	}
+	te.status.SetText(fmt.Sprintf("Saved %s", path))
+}
+
+// parseHexColor parses a #rrggbb color
+func parseHexColor(hex string) (color.Color, bool) {
+	c := chroma.ParseColour(hex)
+	if hex == "" || !c.IsSet() {
+		return nil, false
+	}
+	return color.RGBA{R: c.Red(), G: c.Green(), B: c.Blue(), A: 0xff}, true
+}
+
+// colorToHex formats a color as #rrggbb
+func colorToHex(c color.Color) string {
+	r, g, b, _ := c.RGBA()
+	return fmt.Sprintf("#%02x%02x%02x", r>>8, g>>8, b>>8)
+}
+
+// InspectTokenAtCursor returns the token at the cursor and its style in the
+// current syntax theme. ok is false until the content is highlighted.
+func (e *Editor) InspectTokenAtCursor() (token chroma.Token, style chroma.StyleEntry, ok bool) {
+	highlighting := e.GetHighlighting()
+	if highlighting == nil {
+		return token, style, false
+	}
+	token, ok = highlighting.TokenAt(e.TextWidget.CursorRow, e.TextWidget.CursorColumn)
+	if !ok {
+		return token, style, false
+	}
+	return token, syntax.GetThemeManager().GetTheme().Get(token.Type), true
+}
+
+// showTokenInspector shows the token type and style at the cursor
+func showTokenInspector(window fyne.Window, editor *Editor) {
+	token, style, ok := editor.InspectTokenAtCursor()
+	if !ok {
+		dialog.ShowInformation("Token Inspector", "There is no highlighted token at the cursor.", window)
+		return
+	}
+
+	text := strings.TrimSuffix(token.Value, "\n")
+	if len([]rune(text)) > 40 {
+		text = string([]rune(text)[:40]) + "..."
+	}
+	details := widget.NewForm(
+		widget.NewFormItem("Text", widget.NewLabel(fmt.Sprintf("%q", text))),
+		widget.NewFormItem("Token", widget.NewLabel(token.Type.String())),
+		widget.NewFormItem("Category", widget.NewLabel(token.Type.Category().String())),
+		widget.NewFormItem("Style", widget.NewLabel(style.String())),
+		widget.NewFormItem("Theme", widget.NewLabel(syntax.GetCurrentTheme())),
+	)
+	dialog.ShowCustom("Token Inspector", "Close", details, window)
+}
@@ -0,0 +1,64 @@
+package ui
+
+import (
+	"testing"
+
+	"fyne.io/fyne/v2/test"
+	"github.com/alecthomas/chroma"
+	"github.com/kenelite/goeditor/ui/syntax"
+)
+
+func TestInspectTokenAtCursor(t *testing.T) {
+	testApp := test.NewApp()
+	defer testApp.Quit()
+
+	editor := NewEditor()
+	if _, _, ok := editor.InspectTokenAtCursor(); ok {
+		t.Error("Expected no token before the content is highlighted")
+	}
+
+	content := "package main\n\nfunc main() {}\n"
+	editor.SetContent(content)
+	highlighter := syntax.NewIncrementalHighlighter("go")
+	highlighter.Update(content)
+	editor.highlighting = highlighter.Snapshot()
+
+	editor.TextWidget.CursorRow = 2
+	editor.TextWidget.CursorColumn = 6
+	token, style, ok := editor.InspectTokenAtCursor()
+	if !ok || token.Type != chroma.NameFunction || token.Value != "main" {
+		t.Errorf("Expected the function name at the cursor, got %s %q", token.Type, token.Value)
+	}
+	if style != syntax.GetThemeManager().GetTheme().Get(chroma.NameFunction) {
+		t.Errorf("Expected the style of the current theme, got %s", style)
+	}
+}
+
+func TestColorToHex(t *testing.T) {
+	c, ok := parseHexColor("#1a2B3c")
+	if !ok || colorToHex(c) != "#1a2b3c" {
+		t.Errorf("Expected the color to round trip, got %v", c)
+	}
+	if _, ok := parseHexColor(""); ok {
+		t.Error("Expected no color for an empty value")
+	}
+}
+
+func TestStyleFlagCheck(t *testing.T) {
+	testApp := test.NewApp()
+	defer testApp.Quit()
+
+	// Tapping cycles from inherited to set to turned off and back
+	next := map[chroma.Trilean]chroma.Trilean{chroma.Pass: chroma.Yes, chroma.Yes: chroma.No, chroma.No: chroma.Pass}
+	for value, expected := range next {
+		var changed chroma.Trilean = 255
+		check := styleFlagCheck("Bold", value, func(value chroma.Trilean) { changed = value })
+		if check.Checked != (value == chroma.Yes) || check.Partial != (value == chroma.Pass) {
+			t.Errorf("%v: unexpected check state checked=%v partial=%v", value, check.Checked, check.Partial)
+		}
+		test.Tap(check)
+		if changed != expected {
+			t.Errorf("%v: expected %v after a tap, got %v", value, expected, changed)
+		}
+	}
+}
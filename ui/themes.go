@@ -0,0 +1,124 @@
+package ui
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"time"
+
+	fyne "fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/storage"
+	"github.com/kenelite/goeditor/ui/syntax"
+)
+
+// themeReloadInterval is how often the themes folder is checked for changes
+const themeReloadInterval = time.Second
+
+// LoadCustomThemes loads the syntax themes in the themes folder of the
+// configuration directory and reloads them whenever their files change.
+// Invalid theme files are reported in window.
+func (e *Editor) LoadCustomThemes(window fyne.Window) {
+	dir := e.ConfigManager.GetThemesDir()
+	themes := syntax.GetThemeManager()
+	if err := themes.LoadCustomThemes(dir); err != nil {
+		dialog.ShowError(fmt.Errorf("failed to load custom themes from %s:\n%w", dir, err), window)
+	}
+
+	if e.stopThemeWatch != nil {
+		e.stopThemeWatch()
+	}
+	e.stopThemeWatch = themes.WatchCustomThemes(dir, themeReloadInterval, func(err error) {
+		fyne.Do(func() {
+			if err != nil {
+				dialog.ShowError(fmt.Errorf("failed to reload custom themes:\n%w", err), window)
+			}
+			e.refreshAppTheme(true)
+		})
+	})
+}
+
+// UseSyntaxTheme makes a syntax theme the default and the theme of the
+// current UI mode: the light theme in "light" mode, the dark theme in
+// "dark" mode, and whichever of the two it suits when the UI follows the
+// system. The configuration is saved.
+func (e *Editor) UseSyntaxTheme(name string) error {
+	info := syntax.GetThemeManager().GetThemeInfo(name)
+	if info == nil {
+		return fmt.Errorf("theme '%s' not found", name)
+	}
+
+	config := e.ConfigManager.GetSyntaxConfig()
+	config.DefaultTheme = name
+	switch e.ConfigManager.GetUIConfig().Theme {
+	case "light":
+		config.LightTheme = name
+	case "dark":
+		config.DarkTheme = name
+	case "auto":
+		if info.IsDark {
+			config.DarkTheme = name
+		} else {
+			config.LightTheme = name
+		}
+	}
+	e.ConfigManager.UpdateSyntaxConfig(config)
+	if err := e.ConfigManager.Save(); err != nil {
+		return err
+	}
+
+	e.refreshAppTheme(true)
+	return nil
+}
+
+// ImportColorTheme imports a VS Code or TextMate color theme, saves it to
+// the themes folder and switches to it. A theme of the same name is only
+// replaced when overwrite is set; otherwise the error wraps
+// syntax.ErrThemeExists.
+func (e *Editor) ImportColorTheme(path string, overwrite bool) (*syntax.ThemeConfig, error) {
+	config, err := syntax.ImportThemeFile(path)
+	if err != nil {
+		return nil, err
+	}
+	themes := syntax.GetThemeManager()
+	if _, err := themes.SaveCustomTheme(e.ConfigManager.GetThemesDir(), config, overwrite); err != nil {
+		return nil, err
+	}
+	if err := e.UseSyntaxTheme(config.Name); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+// showImportThemeDialog asks for a color theme file and imports it
+func showImportThemeDialog(window fyne.Window, editor *Editor) {
+	open := dialog.NewFileOpen(func(r fyne.URIReadCloser, err error) {
+		if err != nil || r == nil {
+			return
+		}
+		path := r.URI().Path()
+		r.Close()
+		importColorTheme(window, editor, path, false)
+	}, window)
+	open.SetFilter(storage.NewExtensionFileFilter([]string{".json", ".tmTheme"}))
+	open.Show()
+}
+
+// importColorTheme imports a color theme file, asking before replacing a
+// theme of the same name
+func importColorTheme(window fyne.Window, editor *Editor, path string, overwrite bool) {
+	config, err := editor.ImportColorTheme(path, overwrite)
+	if errors.Is(err, syntax.ErrThemeExists) {
+		dialog.ShowConfirm("Replace Theme", fmt.Sprintf("A theme of the same name as %s already exists. Replace it?", filepath.Base(path)), func(replace bool) {
+			if replace {
+				importColorTheme(window, editor, path, true)
+			}
+		}, window)
+		return
+	}
+	if err != nil {
+		dialog.ShowError(fmt.Errorf("failed to import %s: %w", filepath.Base(path), err), window)
+		return
+	}
+	dialog.ShowInformation("Theme Imported", fmt.Sprintf("Imported the color theme %q.", config.Name), window)
+}